@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/agent"
+	"github.com/grafana/xk6-disruptor/pkg/agent/bandwidth"
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+	"github.com/spf13/cobra"
+)
+
+// BuildBandwidthCmd returns a cobra command with the specification of the bandwidth command.
+func BuildBandwidthCmd(env runtime.Environment, config *agent.Config) *cobra.Command {
+	var duration time.Duration
+	disruptor := bandwidth.Disruptor{}
+
+	cmd := &cobra.Command{
+		Use:   "bandwidth",
+		Short: "bandwidth limit",
+		Long: "Limits the bandwidth of the traffic sent from a port." +
+			" Requires either to be run as root, or the NET_ADMIN capability.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if disruptor.Port == 0 {
+				return fmt.Errorf("target port for fault injection is required")
+			}
+
+			if disruptor.Rate == "" {
+				return fmt.Errorf("rate limit is required")
+			}
+
+			agent, err := agent.Start(env, config)
+			if err != nil {
+				return fmt.Errorf("initializing agent: %w", err)
+			}
+
+			defer agent.Stop()
+
+			disruptor.Executor = env.Executor()
+
+			return agent.ApplyDisruption(cmd.Context(), disruptor, duration)
+		},
+	}
+
+	cmd.Flags().DurationVarP(&duration, "duration", "d", 0, "duration of the disruptions")
+	cmd.Flags().StringVarP(&disruptor.Rate, "limit", "l", "", "maximum rate of the traffic (e.g. 1mbit)")
+	cmd.Flags().UintVarP(&disruptor.Port, "target", "t", 0, "source port of the traffic to be limited")
+	cmd.Flags().StringVarP(&disruptor.Interface, "interface", "i", "eth0", "interface the limit is applied to")
+
+	return cmd
+}
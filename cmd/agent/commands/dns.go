@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/agent"
+	"github.com/grafana/xk6-disruptor/pkg/agent/dns"
+	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
+	"github.com/grafana/xk6-disruptor/pkg/iptables"
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+	"github.com/spf13/cobra"
+)
+
+// BuildDNSCmd returns a cobra command with the specification of the dns command.
+func BuildDNSCmd(env runtime.Environment, config *agent.Config) *cobra.Command {
+	var (
+		duration time.Duration
+		port     uint
+		seed     int64
+	)
+	proxy := &dns.Proxy{}
+
+	cmd := &cobra.Command{
+		Use:   "dns",
+		Short: "dns fault injection",
+		Long: "Fails a fraction of the DNS queries sent by the pod over UDP with a SERVFAIL response." +
+			" Requires either to be run as root, or the NET_ADMIN capability.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if proxy.Fault.Rate < 0 || proxy.Fault.Rate > 1 {
+				return fmt.Errorf("rate must be in the range [0.0, 1.0]")
+			}
+
+			if proxy.Upstream == "" {
+				nameserver, err := dns.Nameserver(dns.ResolvConf)
+				if err != nil {
+					return fmt.Errorf("finding upstream resolver: %w", err)
+				}
+				proxy.Upstream = nameserver
+			}
+
+			agent, err := agent.Start(env, config)
+			if err != nil {
+				return fmt.Errorf("initializing agent: %w", err)
+			}
+
+			defer agent.Stop()
+
+			// the queries forwarded by the proxy are marked, so they are not redirected back to it
+			proxy.Dialer = protocol.MarkedDialer()
+			proxy.Random = protocol.NewRandom(seed)
+
+			disruptor := dns.Disruptor{
+				Iptables: iptables.New(env.Executor()),
+				Proxy:    proxy,
+				Port:     port,
+			}
+
+			return agent.ApplyDisruption(cmd.Context(), disruptor, duration)
+		},
+	}
+
+	cmd.Flags().DurationVarP(&duration, "duration", "d", 0, "duration of the disruptions")
+	cmd.Flags().Float32VarP(&proxy.Fault.Rate, "rate", "r", 0, "fraction of the DNS queries that fail")
+	cmd.Flags().StringVar(&proxy.Fault.Domain, "domain", "",
+		"fail only the queries for this domain and its subdomains. Empty means all the queries")
+	cmd.Flags().StringVar(&proxy.Upstream, "upstream", "",
+		"address (host:port) of the resolver the queries are forwarded to. Defaults to the first nameserver"+
+			" of "+dns.ResolvConf)
+	cmd.Flags().UintVarP(&port, "port", "p", 5353, "port the DNS proxy listens on")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed for the random selection of the queries that fail."+
+		" Zero means a random seed")
+
+	return cmd
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/agent"
@@ -12,18 +13,131 @@ import (
 	"github.com/grafana/xk6-disruptor/pkg/runtime"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// grpcArgs holds the arguments for building a grpc disruptor
+type grpcArgs struct {
+	disruption   grpc.Disruption
+	port         uint
+	upstreamHost string
+	targetPort   uint
+	transparent  bool
+	direction    string
+	options      protocol.DisruptorOptions
+	metadata     []string
+}
+
+// addFlags adds the flags for the grpc disruptor arguments to the flag set
+func (a *grpcArgs) addFlags(flags *pflag.FlagSet) {
+	flags.DurationVarP(&a.disruption.AverageDelay, "average-delay", "a", 0, "average request delay")
+	flags.DurationVarP(&a.disruption.DelayVariation, "delay-variation", "v", 0, "variation in request delay")
+	flags.Int32VarP(&a.disruption.StatusCode, "status", "s", 0, "status code")
+	flags.Float32VarP(&a.disruption.ErrorRate, "rate", "r", 0, "error rate")
+	flags.StringVarP(&a.disruption.StatusMessage, "message", "m", "", "error message for injected faults")
+	flags.StringArrayVar(&a.metadata, "metadata", []string{}, "trailing metadata attached to the injected"+
+		" errors, as key:value. Can be repeated")
+	flags.UintVarP(&a.port, "port", "p", 8000, "port the proxy will listen to")
+	flags.UintVarP(&a.targetPort, "target", "t", 0, "port the proxy will redirect request to")
+	flags.DurationVar(&a.options.StopGracePeriod, "stop-grace-period", protocol.DefaultStopGracePeriod,
+		"time given to in-flight requests to complete when the disruption ends")
+	flags.DurationVar(&a.disruption.GracePeriod, "grace-period", 0, "time at the start of the disruption"+
+		" during which requests are not disrupted")
+	flags.StringSliceVarP(&a.disruption.Excluded, "exclude", "x", []string{}, "comma-separated list of grpc services"+
+		" to be excluded from disruption")
+	flags.StringVar(&a.disruption.Authority, "authority", "", "authority of the requests to be disrupted."+
+		" Requests to other authorities are excluded. Empty means all")
+	flags.Int64Var(&a.disruption.Seed, "seed", 0, "seed for the random selection of delays and errors."+
+		" Zero means a random seed")
+	flags.IntVar(&a.disruption.BufferSize, "buffer-size", 0, "size in bytes of the buffers used for copying"+
+		" requests and responses. Zero means the default size")
+	flags.BoolVar(&a.disruption.UpstreamTLS, "upstream-tls", false, "use TLS in the connection with the upstream")
+	flags.BoolVar(&a.disruption.UpstreamInsecureSkipVerify, "upstream-insecure-skip-verify", false, "do not verify"+
+		" the certificate of the upstream when using TLS, for example if it is self-signed")
+	flags.BoolVar(&a.transparent, "transparent", true, "run as transparent proxy")
+	flags.StringVar(&a.direction, "direction", directionIngress, "direction of the disrupted traffic: ingress,"+
+		" for the requests the target receives, or egress, for the requests it sends to the target port")
+	flags.StringVar(&a.upstreamHost, "upstream-host", "localhost",
+		"upstream host to redirect traffic to")
+}
+
+// validate checks the arguments before the agent is started
+func (a *grpcArgs) validate() error {
+	if a.targetPort == 0 {
+		return fmt.Errorf("target port for fault injection is required")
+	}
+
+	egress, err := parseDirection(a.direction, a.transparent)
+	if err != nil {
+		return err
+	}
+	a.disruption.Egress = egress
+
+	// egress requests are forwarded to the host they are addressed to, so the upstream host is not used
+	if !egress && a.transparent && (a.upstreamHost == "localhost" || a.upstreamHost == "127.0.0.1") {
+		// When running in transparent mode, the Redirector will also redirect traffic directed to 127.0.0.1 to
+		// the proxy. Using 127.0.0.1 as the proxy upstream would cause a redirection loop.
+		return fmt.Errorf("upstream host cannot be localhost when running in transparent mode")
+	}
+
+	for _, m := range a.metadata {
+		key, value, found := strings.Cut(m, ":")
+		if !found || key == "" {
+			return fmt.Errorf("invalid metadata %q: must be in the form key:value", m)
+		}
+		if a.disruption.Metadata == nil {
+			a.disruption.Metadata = map[string]string{}
+		}
+		a.disruption.Metadata[key] = value
+	}
+
+	return nil
+}
+
+// buildDisruptor returns a disruptor for the grpc arguments
+func (a *grpcArgs) buildDisruptor(env runtime.Environment) (agent.Disruptor, error) {
+	listenAddress := net.JoinHostPort("", fmt.Sprint(a.port))
+	upstreamAddress := net.JoinHostPort(a.upstreamHost, fmt.Sprint(a.targetPort))
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("setting up listener at %q: %w", listenAddress, err)
+	}
+
+	proxy, err := grpc.NewProxy(listener, upstreamAddress, a.disruption)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redirect traffic to the proxy
+	var redirector protocol.TrafficRedirector
+	if a.transparent {
+		tr := &protocol.TrafficRedirectionSpec{
+			DestinationPort: a.targetPort, // Redirect traffic from the application (target) port...
+			RedirectPort:    a.port,       // to the proxy port.
+			Egress:          a.disruption.Egress,
+		}
+
+		redirector, err = protocol.NewTrafficRedirector(tr, iptables.New(env.Executor()))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		redirector = protocol.NoopTrafficRedirector()
+	}
+
+	return protocol.NewDisruptor(
+		env.Executor(),
+		proxy,
+		redirector,
+		a.options,
+	)
+}
+
 // BuildGrpcCmd returns a cobra command with the specification of the grpc command
-//
-//nolint:funlen
 func BuildGrpcCmd(env runtime.Environment, config *agent.Config) *cobra.Command {
-	disruption := grpc.Disruption{}
+	args := &grpcArgs{}
 	var duration time.Duration
-	var port uint
-	var upstreamHost string
-	var targetPort uint
-	transparent := true
 
 	cmd := &cobra.Command{
 		Use:   "grpc",
@@ -32,14 +146,8 @@ func BuildGrpcCmd(env runtime.Environment, config *agent.Config) *cobra.Command
 			" When running as a transparent proxy requires NET_ADMIM capabilities for setting" +
 			" iptable rules.",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			if targetPort == 0 {
-				return fmt.Errorf("target port for fault injection is required")
-			}
-
-			if transparent && (upstreamHost == "localhost" || upstreamHost == "127.0.0.1") {
-				// When running in transparent mode, the Redirector will also redirect traffic directed to 127.0.0.1 to
-				// the proxy. Using 127.0.0.1 as the proxy upstream would cause a redirection loop.
-				return fmt.Errorf("upstream host cannot be localhost when running in transparent mode")
+			if err := args.validate(); err != nil {
+				return err
 			}
 
 			agent, err := agent.Start(env, config)
@@ -49,40 +157,7 @@ func BuildGrpcCmd(env runtime.Environment, config *agent.Config) *cobra.Command
 
 			defer agent.Stop()
 
-			listenAddress := net.JoinHostPort("", fmt.Sprint(port))
-			upstreamAddress := net.JoinHostPort(upstreamHost, fmt.Sprint(targetPort))
-
-			listener, err := net.Listen("tcp", listenAddress)
-			if err != nil {
-				return fmt.Errorf("setting up listener at %q: %w", listenAddress, err)
-			}
-
-			proxy, err := grpc.NewProxy(listener, upstreamAddress, disruption)
-			if err != nil {
-				return err
-			}
-
-			// Redirect traffic to the proxy
-			var redirector protocol.TrafficRedirector
-			if transparent {
-				tr := &protocol.TrafficRedirectionSpec{
-					DestinationPort: targetPort, // Redirect traffic from the application (target) port...
-					RedirectPort:    port,       // to the proxy port.
-				}
-
-				redirector, err = protocol.NewTrafficRedirector(tr, iptables.New(env.Executor()))
-				if err != nil {
-					return err
-				}
-			} else {
-				redirector = protocol.NoopTrafficRedirector()
-			}
-
-			disruptor, err := protocol.NewDisruptor(
-				env.Executor(),
-				proxy,
-				redirector,
-			)
+			disruptor, err := args.buildDisruptor(env)
 			if err != nil {
 				return err
 			}
@@ -91,18 +166,7 @@ func BuildGrpcCmd(env runtime.Environment, config *agent.Config) *cobra.Command
 		},
 	}
 	cmd.Flags().DurationVarP(&duration, "duration", "d", 0, "duration of the disruptions")
-	cmd.Flags().DurationVarP(&disruption.AverageDelay, "average-delay", "a", 0, "average request delay")
-	cmd.Flags().DurationVarP(&disruption.DelayVariation, "delay-variation", "v", 0, "variation in request delay")
-	cmd.Flags().Int32VarP(&disruption.StatusCode, "status", "s", 0, "status code")
-	cmd.Flags().Float32VarP(&disruption.ErrorRate, "rate", "r", 0, "error rate")
-	cmd.Flags().StringVarP(&disruption.StatusMessage, "message", "m", "", "error message for injected faults")
-	cmd.Flags().UintVarP(&port, "port", "p", 8000, "port the proxy will listen to")
-	cmd.Flags().UintVarP(&targetPort, "target", "t", 0, "port the proxy will redirect request to")
-	cmd.Flags().StringSliceVarP(&disruption.Excluded, "exclude", "x", []string{}, "comma-separated list of grpc services"+
-		" to be excluded from disruption")
-	cmd.Flags().BoolVar(&transparent, "transparent", true, "run as transparent proxy")
-	cmd.Flags().StringVar(&upstreamHost, "upstream-host", "localhost",
-		"upstream host to redirect traffic to")
+	args.addFlags(cmd.Flags())
 
 	return cmd
 }
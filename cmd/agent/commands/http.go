@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/agent"
@@ -11,18 +14,286 @@ import (
 	"github.com/grafana/xk6-disruptor/pkg/iptables"
 	"github.com/grafana/xk6-disruptor/pkg/runtime"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// httpArgs holds the arguments for building a http disruptor
+type httpArgs struct {
+	disruption   http.Disruption
+	port         uint
+	upstreamHost string
+	targetPort   uint
+	transparent  bool
+	direction    string
+	options      protocol.DisruptorOptions
+	responses    []string
+	windows      []string
+	rules        []string
+	headers      []string
+	errorCodes   string
+}
+
+// addFlags adds the flags for the http disruptor arguments to the flag set
+func (a *httpArgs) addFlags(flags *pflag.FlagSet) {
+	flags.DurationVarP(&a.disruption.AverageDelay, "average-delay", "a", 0, "average request delay")
+	flags.DurationVarP(&a.disruption.DelayVariation, "delay-variation", "v", 0, "variation in request delay")
+	flags.UintVarP(&a.disruption.ErrorCode, "error", "e", 0, "error code")
+	flags.StringVarP(&a.errorCodes, "error-codes", "E", "", "error codes returned with a probability"+
+		" proportional to their weights, instead of the error code, as a comma-separated list of code:weight"+
+		" (e.g. 500:0.5,502:0.3,503:0.2)")
+	flags.Float32VarP(&a.disruption.ErrorRate, "rate", "r", 0, "error rate")
+	flags.Float32Var(&a.disruption.FaultRate, "fault-rate", 0, "fraction of requests that either return an error"+
+		" or are delayed, instead of the error rate")
+	flags.Float32Var(&a.disruption.ErrorShare, "error-share", 0, "fraction of the requests selected by the fault"+
+		" rate that return an error. The rest of them are delayed")
+	flags.StringVarP(&a.disruption.ErrorBody, "body", "b", "", "body for injected faults")
+	flags.UintVar(&a.disruption.TruncateAfterBytes, "truncate-after-bytes", 0, "bytes of the response body sent"+
+		" to the requests selected by the error rate before closing their connection, instead of returning an error")
+	flags.Float32Var(&a.disruption.RateLimit, "rate-limit", 0, "maximum requests per second before"+
+		" requests are rejected")
+	flags.UintVar(&a.disruption.RateLimitCode, "rate-limit-code", 429, "status code for requests rejected"+
+		" by the rate limit")
+	flags.UintVar(&a.disruption.DripBytesPerInterval, "drip-bytes", 0, "bytes of the response body"+
+		" sent on each drip interval")
+	flags.DurationVar(&a.disruption.DripInterval, "drip-interval", 0, "interval between chunks of the"+
+		" response body")
+	flags.UintVar(&a.disruption.ReadRateBytesPerSec, "read-rate", 0, "maximum bytes per second read from the"+
+		" request and response bodies")
+	flags.StringVar(&a.disruption.HashHeader, "hash-header", "", "header whose value is hashed for selecting"+
+		" the requests that return an error, instead of random sampling")
+	flags.StringArrayVar(&a.responses, "response", []string{}, "canned response returned in turns to the"+
+		" requests selected to return an error, as a JSON object with code, body and headers. Can be repeated")
+	flags.StringArrayVar(&a.windows, "window", []string{}, "window the disruption steps through, as its"+
+		" duration followed by the delay (a), variation (v), error rate (r) and error code (e) during the window"+
+		" (e.g. 300s,a=100ms,v=10ms,r=0.1,e=500). Can be repeated")
+	flags.StringArrayVar(&a.rules, "rule", []string{}, "rule setting the error rate and error code of the"+
+		" requests whose path starts with a prefix, as a JSON object with pathPrefix, errorRate and errorCode."+
+		" Can be repeated")
+	flags.StringArrayVarP(&a.headers, "header", "H", []string{}, "header that requests must have for being"+
+		" disrupted, as name:value. Can be repeated")
+	flags.StringSliceVarP(&a.disruption.Excluded, "exclude", "x", []string{}, "comma-separated list of path(s)"+
+		" to be excluded from disruption")
+	flags.StringVar(&a.disruption.ExcludedRegex, "exclude-regex", "", "regular expression matching the whole"+
+		" path of the requests to be excluded from disruption")
+	flags.Int64Var(&a.disruption.Seed, "seed", 0, "seed for the random selection of delays and errors."+
+		" Zero means a random seed")
+	flags.IntVar(&a.disruption.BufferSize, "buffer-size", 0, "size in bytes of the buffers used for copying"+
+		" requests and responses. Zero means the default size")
+	flags.BoolVar(&a.transparent, "transparent", true, "run as transparent proxy")
+	flags.StringVar(&a.direction, "direction", directionIngress, "direction of the disrupted traffic: ingress,"+
+		" for the requests the target receives, or egress, for the requests it sends to the target port")
+	flags.StringVar(&a.upstreamHost, "upstream-host", "localhost",
+		"upstream host to redirect traffic to")
+	flags.UintVarP(&a.port, "port", "p", 8000, "port the proxy will listen to")
+	flags.UintVarP(&a.targetPort, "target", "t", 0, "port the proxy will redirect request to")
+	flags.DurationVar(&a.options.StopGracePeriod, "stop-grace-period", protocol.DefaultStopGracePeriod,
+		"time given to in-flight requests to complete when the disruption ends")
+	flags.DurationVar(&a.disruption.GracePeriod, "grace-period", 0, "time at the start of the disruption"+
+		" during which requests are not disrupted")
+	flags.BoolVar(&a.disruption.HTTP2, "http2", false, "accept and forward requests using HTTP/2 over cleartext"+
+		" (h2c) instead of HTTP/1.1")
+}
+
+// validate checks the arguments before the agent is started
+func (a *httpArgs) validate() error {
+	if a.targetPort == 0 {
+		return fmt.Errorf("target port for fault injection is required")
+	}
+
+	egress, err := parseDirection(a.direction, a.transparent)
+	if err != nil {
+		return err
+	}
+	a.disruption.Egress = egress
+
+	// egress requests are forwarded to the host they are addressed to, so the upstream host is not used
+	if !egress && a.transparent && (a.upstreamHost == "localhost" || a.upstreamHost == "127.0.0.1") {
+		// When running in transparent mode, the Redirector will also redirect traffic directed to 127.0.0.1 to
+		// the proxy. Using 127.0.0.1 as the proxy upstream would cause a redirection loop.
+		return fmt.Errorf("upstream host cannot be localhost when running in transparent mode")
+	}
+
+	if a.errorCodes != "" {
+		codes, err := parseErrorCodes(a.errorCodes)
+		if err != nil {
+			return fmt.Errorf("parsing error codes %q: %w", a.errorCodes, err)
+		}
+		a.disruption.ErrorCodes = codes
+	}
+
+	for _, r := range a.responses {
+		response := http.CannedResponse{}
+		if err := json.Unmarshal([]byte(r), &response); err != nil {
+			return fmt.Errorf("parsing canned response %q: %w", r, err)
+		}
+		a.disruption.Responses = append(a.disruption.Responses, response)
+	}
+
+	for _, w := range a.windows {
+		window, err := parseWindow(w)
+		if err != nil {
+			return fmt.Errorf("parsing window %q: %w", w, err)
+		}
+		a.disruption.Windows = append(a.disruption.Windows, window)
+	}
+
+	for _, r := range a.rules {
+		rule := http.Rule{}
+		if err := json.Unmarshal([]byte(r), &rule); err != nil {
+			return fmt.Errorf("parsing rule %q: %w", r, err)
+		}
+		a.disruption.Rules = append(a.disruption.Rules, rule)
+	}
+
+	for _, h := range a.headers {
+		name, value, found := strings.Cut(h, ":")
+		if !found || name == "" {
+			return fmt.Errorf("invalid header %q: must be in the form name:value", h)
+		}
+		if a.disruption.Headers == nil {
+			a.disruption.Headers = map[string]string{}
+		}
+		a.disruption.Headers[name] = value
+	}
+
+	return nil
+}
+
+// Directions of the disrupted traffic
+const (
+	directionIngress = "ingress"
+	directionEgress  = "egress"
+)
+
+// parseDirection returns if the direction of the disrupted traffic is egress. Egress traffic can only be
+// disrupted in transparent mode, as it is redirected to the proxy by the Redirector.
+func parseDirection(direction string, transparent bool) (bool, error) {
+	switch direction {
+	case directionIngress:
+		return false, nil
+	case directionEgress:
+		if !transparent {
+			return false, fmt.Errorf("egress traffic can only be disrupted when running in transparent mode")
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid direction %q: must be %q or %q", direction, directionIngress, directionEgress)
+	}
+}
+
+// parseErrorCodes parses a comma-separated list of error codes with their weights in the form code:weight
+// (e.g. 500:0.5,502:0.3,503:0.2)
+func parseErrorCodes(value string) ([]http.WeightedCode, error) {
+	codes := []http.WeightedCode{}
+	for _, field := range strings.Split(value, ",") {
+		code, weight, found := strings.Cut(field, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid error code %q: must be in the form code:weight", field)
+		}
+
+		parsedCode, err := strconv.ParseUint(code, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error code %q: %w", field, err)
+		}
+
+		parsedWeight, err := strconv.ParseFloat(weight, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight %q: %w", field, err)
+		}
+
+		codes = append(codes, http.WeightedCode{Code: uint(parsedCode), Weight: float32(parsedWeight)})
+	}
+
+	return codes, nil
+}
+
+// parseWindow parses a window specified as its duration followed by a comma-separated list of attributes
+// in the form name=value (e.g. 300s,a=100ms,v=10ms,r=0.1,e=500)
+func parseWindow(value string) (http.Window, error) {
+	window := http.Window{}
+
+	fields := strings.Split(value, ",")
+	duration, err := time.ParseDuration(fields[0])
+	if err != nil {
+		return window, fmt.Errorf("invalid duration: %w", err)
+	}
+	window.Duration = duration
+
+	for _, field := range fields[1:] {
+		name, attr, found := strings.Cut(field, "=")
+		if !found {
+			return window, fmt.Errorf("invalid attribute %q", field)
+		}
+
+		switch name {
+		case "a":
+			window.AverageDelay, err = time.ParseDuration(attr)
+		case "v":
+			window.DelayVariation, err = time.ParseDuration(attr)
+		case "r":
+			var rate float64
+			rate, err = strconv.ParseFloat(attr, 32)
+			window.ErrorRate = float32(rate)
+		case "e":
+			var code uint64
+			code, err = strconv.ParseUint(attr, 10, 32)
+			window.ErrorCode = uint(code)
+		default:
+			err = fmt.Errorf("unknown attribute")
+		}
+
+		if err != nil {
+			return window, fmt.Errorf("invalid attribute %q: %w", field, err)
+		}
+	}
+
+	return window, nil
+}
+
+// buildDisruptor returns a disruptor for the http arguments
+func (a *httpArgs) buildDisruptor(env runtime.Environment) (agent.Disruptor, error) {
+	listenAddress := net.JoinHostPort("", fmt.Sprint(a.port))
+	upstreamAddress := "http://" + net.JoinHostPort(a.upstreamHost, fmt.Sprint(a.targetPort))
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("setting up listener at %q: %w", listenAddress, err)
+	}
+
+	proxy, err := http.NewProxy(listener, upstreamAddress, a.disruption)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redirect traffic to the proxy
+	var redirector protocol.TrafficRedirector
+	if a.transparent {
+		tr := &protocol.TrafficRedirectionSpec{
+			DestinationPort: a.targetPort, // Redirect traffic from the application (target) port...
+			RedirectPort:    a.port,       // to the proxy port.
+			Egress:          a.disruption.Egress,
+		}
+
+		redirector, err = protocol.NewTrafficRedirector(tr, iptables.New(env.Executor()))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		redirector = protocol.NoopTrafficRedirector()
+	}
+
+	return protocol.NewDisruptor(
+		env.Executor(),
+		proxy,
+		redirector,
+		a.options,
+	)
+}
+
 // BuildHTTPCmd returns a cobra command with the specification of the http command
-//
-//nolint:funlen
 func BuildHTTPCmd(env runtime.Environment, config *agent.Config) *cobra.Command {
-	disruption := http.Disruption{}
+	args := &httpArgs{}
 	var duration time.Duration
-	var port uint
-	var upstreamHost string
-	var targetPort uint
-	transparent := true
 
 	cmd := &cobra.Command{
 		Use:   "http",
@@ -31,14 +302,8 @@ func BuildHTTPCmd(env runtime.Environment, config *agent.Config) *cobra.Command
 			" When running as a transparent proxy requires NET_ADMIM capabilities for setting" +
 			" iptable rules.",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			if targetPort == 0 {
-				return fmt.Errorf("target port for fault injection is required")
-			}
-
-			if transparent && (upstreamHost == "localhost" || upstreamHost == "127.0.0.1") {
-				// When running in transparent mode, the Redirector will also redirect traffic directed to 127.0.0.1 to
-				// the proxy. Using 127.0.0.1 as the proxy upstream would cause a redirection loop.
-				return fmt.Errorf("upstream host cannot be localhost when running in transparent mode")
+			if err := args.validate(); err != nil {
+				return err
 			}
 
 			agent, err := agent.Start(env, config)
@@ -48,40 +313,7 @@ func BuildHTTPCmd(env runtime.Environment, config *agent.Config) *cobra.Command
 
 			defer agent.Stop()
 
-			listenAddress := net.JoinHostPort("", fmt.Sprint(port))
-			upstreamAddress := "http://" + net.JoinHostPort(upstreamHost, fmt.Sprint(targetPort))
-
-			listener, err := net.Listen("tcp", listenAddress)
-			if err != nil {
-				return fmt.Errorf("setting up listener at %q: %w", listenAddress, err)
-			}
-
-			proxy, err := http.NewProxy(listener, upstreamAddress, disruption)
-			if err != nil {
-				return err
-			}
-
-			// Redirect traffic to the proxy
-			var redirector protocol.TrafficRedirector
-			if transparent {
-				tr := &protocol.TrafficRedirectionSpec{
-					DestinationPort: targetPort, // Redirect traffic from the application (target) port...
-					RedirectPort:    port,       // to the proxy port.
-				}
-
-				redirector, err = protocol.NewTrafficRedirector(tr, iptables.New(env.Executor()))
-				if err != nil {
-					return err
-				}
-			} else {
-				redirector = protocol.NoopTrafficRedirector()
-			}
-
-			disruptor, err := protocol.NewDisruptor(
-				env.Executor(),
-				proxy,
-				redirector,
-			)
+			disruptor, err := args.buildDisruptor(env)
 			if err != nil {
 				return err
 			}
@@ -91,18 +323,7 @@ func BuildHTTPCmd(env runtime.Environment, config *agent.Config) *cobra.Command
 	}
 
 	cmd.Flags().DurationVarP(&duration, "duration", "d", 0, "duration of the disruptions")
-	cmd.Flags().DurationVarP(&disruption.AverageDelay, "average-delay", "a", 0, "average request delay")
-	cmd.Flags().DurationVarP(&disruption.DelayVariation, "delay-variation", "v", 0, "variation in request delay")
-	cmd.Flags().UintVarP(&disruption.ErrorCode, "error", "e", 0, "error code")
-	cmd.Flags().Float32VarP(&disruption.ErrorRate, "rate", "r", 0, "error rate")
-	cmd.Flags().StringVarP(&disruption.ErrorBody, "body", "b", "", "body for injected faults")
-	cmd.Flags().StringSliceVarP(&disruption.Excluded, "exclude", "x", []string{}, "comma-separated list of path(s)"+
-		" to be excluded from disruption")
-	cmd.Flags().BoolVar(&transparent, "transparent", true, "run as transparent proxy")
-	cmd.Flags().StringVar(&upstreamHost, "upstream-host", "localhost",
-		"upstream host to redirect traffic to")
-	cmd.Flags().UintVarP(&port, "port", "p", 8000, "port the proxy will listen to")
-	cmd.Flags().UintVarP(&targetPort, "target", "t", 0, "port the proxy will redirect request to")
+	args.addFlags(cmd.Flags())
 
 	return cmd
 }
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/agent"
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// disruptorArgs defines the arguments of a protocol disruption applied by the multi command
+type disruptorArgs interface {
+	addFlags(flags *pflag.FlagSet)
+	validate() error
+	buildDisruptor(env runtime.Environment) (agent.Disruptor, error)
+}
+
+// parseDisruptorArgs parses the arguments of a protocol disruption. The first argument is the protocol.
+func parseDisruptorArgs(group []string) (disruptorArgs, error) {
+	if len(group) == 0 {
+		return nil, fmt.Errorf("protocol is required")
+	}
+
+	var args disruptorArgs
+	switch group[0] {
+	case "http":
+		args = &httpArgs{}
+	case "grpc":
+		args = &grpcArgs{}
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", group[0])
+	}
+
+	flags := pflag.NewFlagSet(group[0], pflag.ContinueOnError)
+	args.addFlags(flags)
+	if err := flags.Parse(group[1:]); err != nil {
+		return nil, fmt.Errorf("parsing %s arguments: %w", group[0], err)
+	}
+
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s arguments: %w", group[0], err)
+	}
+
+	return args, nil
+}
+
+// splitGroups splits the arguments in groups separated by "--"
+func splitGroups(args []string) [][]string {
+	groups := [][]string{}
+	group := []string{}
+	for _, arg := range args {
+		if arg == "--" {
+			if len(group) > 0 {
+				groups = append(groups, group)
+			}
+			group = []string{}
+			continue
+		}
+		group = append(group, arg)
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// buildMultiDisruptor returns a disruptor that applies the disruptions of all the protocols
+func buildMultiDisruptor(env runtime.Environment, protocols []disruptorArgs) (agent.Disruptor, error) {
+	disruptors := []agent.Disruptor{}
+	for _, args := range protocols {
+		disruptor, err := args.buildDisruptor(env)
+		if err != nil {
+			return nil, err
+		}
+		disruptors = append(disruptors, disruptor)
+	}
+
+	return agent.NewMultiDisruptor(disruptors...), nil
+}
+
+// BuildMultiCmd returns a cobra command with the specification of the multi command
+func BuildMultiCmd(env runtime.Environment, config *agent.Config) *cobra.Command {
+	var duration time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "multi [flags] -- <protocol> [protocol flags] [-- <protocol> [protocol flags]...]",
+		Short: "multiple protocols disruptor",
+		Long: "Applies disruptions to multiple protocols simultaneously. Each disruption is specified by the" +
+			" protocol (http or grpc) followed by the flags of the corresponding command, except the duration.",
+		RunE: func(cmd *cobra.Command, cmdArgs []string) error {
+			groups := splitGroups(cmdArgs)
+			if len(groups) == 0 {
+				return fmt.Errorf("at least one protocol disruption is required")
+			}
+
+			protocols := []disruptorArgs{}
+			for _, group := range groups {
+				args, err := parseDisruptorArgs(group)
+				if err != nil {
+					return err
+				}
+				protocols = append(protocols, args)
+			}
+
+			agent, err := agent.Start(env, config)
+			if err != nil {
+				return fmt.Errorf("initializing agent: %w", err)
+			}
+
+			defer agent.Stop()
+
+			disruptor, err := buildMultiDisruptor(env, protocols)
+			if err != nil {
+				return err
+			}
+
+			return agent.ApplyDisruption(cmd.Context(), disruptor, duration)
+		},
+	}
+
+	cmd.Flags().DurationVarP(&duration, "duration", "d", 0, "duration of the disruptions")
+
+	return cmd
+}
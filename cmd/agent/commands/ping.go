@@ -0,0 +1,20 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// BuildPingCmd returns a cobra command with the specification of the ping command
+func BuildPingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ping",
+		Short: "checks the agent is ready",
+		Long: "Exits successfully as soon as the agent is able to execute commands." +
+			" Used for waiting the agent to start once its container is running.",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return nil
+		},
+	}
+
+	return cmd
+}
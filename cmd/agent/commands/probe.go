@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// BuildProbeCmd returns a cobra command with the specification of the probe command
+func BuildProbeCmd() *cobra.Command {
+	var (
+		targetPort   uint
+		upstreamHost string
+		timeout      time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "probe",
+		Short: "checks a port accepts connections",
+		Long: "Checks the target port accepts TCP connections." +
+			" Does not require exclusive access to the target, so it can run while a disruption is in progress.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if targetPort == 0 {
+				return fmt.Errorf("target port for probing is required")
+			}
+
+			address := net.JoinHostPort(upstreamHost, fmt.Sprint(targetPort))
+			dialer := net.Dialer{Timeout: timeout}
+			conn, err := dialer.DialContext(cmd.Context(), "tcp", address)
+			if err != nil {
+				return fmt.Errorf("port %d is not reachable: %w", targetPort, err)
+			}
+
+			return conn.Close()
+		},
+	}
+
+	cmd.Flags().UintVarP(&targetPort, "target", "t", 0, "port to probe")
+	cmd.Flags().StringVar(&upstreamHost, "upstream-host", "localhost", "host to probe")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Second, "timeout for establishing the connection")
+
+	return cmd
+}
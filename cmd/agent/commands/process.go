@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/agent"
+	"github.com/grafana/xk6-disruptor/pkg/agent/process"
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+	"github.com/spf13/cobra"
+)
+
+// BuildProcessCmd returns a cobra command with the specification of the process command.
+func BuildProcessCmd(env runtime.Environment, config *agent.Config) *cobra.Command {
+	var duration time.Duration
+	disruptor := process.Disruptor{}
+
+	cmd := &cobra.Command{
+		Use:   "process",
+		Short: "process pause",
+		Long: "Pauses the processes visible to the agent and resumes them once the disruption ends." +
+			" The agent only sees the processes of other containers if the pod shares its process namespace." +
+			" Requires either to be run as root, or the KILL capability.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if disruptor.PauseSignal == "" {
+				return fmt.Errorf("signal is required")
+			}
+
+			agent, err := agent.Start(env, config)
+			if err != nil {
+				return fmt.Errorf("initializing agent: %w", err)
+			}
+
+			defer agent.Stop()
+
+			disruptor.Signal = syscall.Kill
+
+			return agent.ApplyDisruption(cmd.Context(), disruptor, duration)
+		},
+	}
+
+	cmd.Flags().DurationVarP(&duration, "duration", "d", 0, "duration of the disruptions")
+	cmd.Flags().StringVarP(&disruptor.PauseSignal, "signal", "s", "STOP", "signal that pauses the processes"+
+		" (STOP or TSTP)")
+	cmd.Flags().StringVarP(&disruptor.Name, "name", "n", "", "name of the processes to pause. If empty, all"+
+		" the processes except the agent's are paused")
+	cmd.Flags().StringVar(&disruptor.Procfs, "procfs", "/proc", "mount point of the proc filesystem")
+
+	return cmd
+}
@@ -26,9 +26,16 @@ func NewRootCommand(env runtime.Environment) *RootCommand {
 	rootCmd := buildRootCmd(config)
 	rootCmd.AddCommand(BuildHTTPCmd(env, config))
 	rootCmd.AddCommand(BuildGrpcCmd(env, config))
+	rootCmd.AddCommand(BuildMultiCmd(env, config))
 	rootCmd.AddCommand(BuildTCPDropCmd(env, config))
+	rootCmd.AddCommand(BuildBandwidthCmd(env, config))
+	rootCmd.AddCommand(BuildDNSCmd(env, config))
+	rootCmd.AddCommand(BuildProcessCmd(env, config))
 	rootCmd.AddCommand(BuildStressCmd(env, config))
 	rootCmd.AddCommand(BuiltCleanupCmd(env))
+	rootCmd.AddCommand(BuildProbeCmd())
+	rootCmd.AddCommand(BuildPingCmd())
+	rootCmd.AddCommand(BuildStatusCmd(env))
 
 	return &RootCommand{
 		cmd: rootCmd,
@@ -68,6 +75,8 @@ func buildRootCmd(c *agent.Config) *cobra.Command {
 		"metrics output file")
 	rootCmd.PersistentFlags().DurationVar(&c.Profiler.Metrics.Rate, "metrics-rate", time.Second,
 		"frequency of metrics sampling")
+	rootCmd.PersistentFlags().DurationVar(&c.MaxDuration, "max-duration", 0,
+		"maximum duration of the disruptions. Longer durations are capped to this value (0 means no limit)")
 
 	return rootCmd
 }
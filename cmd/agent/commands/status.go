@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+	"github.com/spf13/cobra"
+)
+
+// BuildStatusCmd returns a cobra command with the specification of the status command
+func BuildStatusCmd(env runtime.Environment) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "checks a fault injection is running",
+		Long: "Exits successfully if a fault injection is running in the agent, and fails otherwise." +
+			" Used for checking the agent is still alive during long fault injections.",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			runningProcess := env.Lock().Owner()
+			// no instance is currently running
+			if runningProcess == -1 {
+				return errors.New("no fault injection is running")
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -55,6 +55,9 @@ func (m *ModuleInstance) Exports() modules.Exports {
 		Named: map[string]interface{}{
 			"PodDisruptor":     m.newPodDisruptor,
 			"ServiceDisruptor": m.newServiceDisruptor,
+
+			"listDisruptableServices": m.listDisruptableServices,
+			"listInjectedPods":        m.listInjectedPods,
 		},
 	}
 }
@@ -83,3 +86,29 @@ func (m *ModuleInstance) newServiceDisruptor(c sobek.ConstructorCall) *sobek.Obj
 
 	return disruptor
 }
+
+// lists the services that can be disrupted
+func (m *ModuleInstance) listDisruptableServices(args ...sobek.Value) sobek.Value {
+	rt := m.vu.Runtime()
+	ctx := m.vu.Context()
+
+	services, err := api.ListDisruptableServices(ctx, rt, m.k8s, args...)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("error listing disruptable services: %w", err))
+	}
+
+	return services
+}
+
+// lists the pods with the agent injected
+func (m *ModuleInstance) listInjectedPods(args ...sobek.Value) sobek.Value {
+	rt := m.vu.Runtime()
+	ctx := m.vu.Context()
+
+	pods, err := api.ListInjectedPods(ctx, rt, m.k8s, args...)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("error listing injected pods: %w", err))
+	}
+
+	return pods
+}
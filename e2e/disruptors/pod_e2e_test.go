@@ -301,6 +301,7 @@ func Test_PodDisruptor(t *testing.T) {
 		// create pod disruptor that will select all pods
 		selector := disruptors.PodSelectorSpec{
 			Namespace: namespace,
+			SelectAll: true,
 		}
 		options := disruptors.PodDisruptorOptions{}
 		disruptor, err := disruptors.NewPodDisruptor(context.TODO(), k8s, selector, options)
@@ -16,6 +16,9 @@ import (
 // Config maintains the configuration for the execution of the agent
 type Config struct {
 	Profiler *profiler.Config
+	// MaxDuration limits the duration of the disruptions. Longer durations are capped to this value.
+	// Zero means no limit.
+	MaxDuration time.Duration
 }
 
 // Agent maintains the state required for executing an agent command
@@ -23,6 +26,7 @@ type Agent struct {
 	env           runtime.Environment
 	sc            <-chan os.Signal
 	profileCloser io.Closer
+	maxDuration   time.Duration
 }
 
 // Disruptor defines the interface for applying disruptions
@@ -36,7 +40,8 @@ type Disruptor interface {
 // Callers must Stop the returned agent at the end of its lifecycle.
 func Start(env runtime.Environment, config *Config) (*Agent, error) {
 	a := &Agent{
-		env: env,
+		env:         env,
+		maxDuration: config.MaxDuration,
 	}
 
 	if err := a.start(config); err != nil {
@@ -68,8 +73,12 @@ func (a *Agent) start(config *Config) error {
 	return nil
 }
 
-// ApplyDisruption applies a disruption to the target
+// ApplyDisruption applies a disruption to the target. The duration is capped to the maximum duration of the agent.
 func (a *Agent) ApplyDisruption(ctx context.Context, disruptor Disruptor, duration time.Duration) error {
+	if a.maxDuration > 0 && duration > a.maxDuration {
+		duration = a.maxDuration
+	}
+
 	// set context for command
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -159,3 +159,73 @@ func Test_Signals(t *testing.T) {
 		})
 	}
 }
+
+// fakeDurationDisruptor implements a Disruptor that records the duration it is applied for
+type fakeDurationDisruptor struct {
+	duration time.Duration
+}
+
+// Apply implements the Apply method from the Disruptor interface
+func (d *fakeDurationDisruptor) Apply(_ context.Context, duration time.Duration) error {
+	d.duration = duration
+	return nil
+}
+
+func Test_MaxDuration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		maxDuration time.Duration
+		duration    time.Duration
+		expected    time.Duration
+	}{
+		{
+			title:       "no maximum duration",
+			maxDuration: 0,
+			duration:    60 * time.Second,
+			expected:    60 * time.Second,
+		},
+		{
+			title:       "duration below maximum",
+			maxDuration: 60 * time.Second,
+			duration:    30 * time.Second,
+			expected:    30 * time.Second,
+		},
+		{
+			title:       "duration exceeds maximum",
+			maxDuration: 30 * time.Second,
+			duration:    60 * time.Second,
+			expected:    30 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			env := runtime.NewFakeRuntime([]string{}, map[string]string{})
+
+			agent, err := Start(env, &Config{
+				Profiler:    &profiler.Config{},
+				MaxDuration: tc.maxDuration,
+			})
+			if err != nil {
+				t.Fatalf("starting agent: %v", err)
+			}
+
+			defer agent.Stop()
+
+			disruptor := &fakeDurationDisruptor{}
+			err = agent.ApplyDisruption(context.Background(), disruptor, tc.duration)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if disruptor.duration != tc.expected {
+				t.Fatalf("expected duration %s got %s", tc.expected, disruptor.duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package bandwidth contains a disruptor that limits the bandwidth of the traffic of a port.
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+)
+
+// Disruptor limits the bandwidth of the traffic sent from a port using a token bucket filter (tbf) queueing
+// discipline. Requires either to be run as root, or the NET_ADMIN capability.
+type Disruptor struct {
+	Executor runtime.Executor
+	// Interface is the network interface the limit is applied to
+	Interface string
+	// Port is the source port of the traffic to be limited
+	Port uint
+	// Rate is the maximum rate of the traffic, using the units of tc (e.g. "1mbit")
+	Rate string
+}
+
+// ErrDurationTooShort is returned when the supplied duration is smaller than 1s.
+var ErrDurationTooShort = errors.New("duration must be at least 1 second")
+
+// Apply limits the bandwidth of the traffic sent from the port for the given duration.
+func (d Disruptor) Apply(ctx context.Context, duration time.Duration) error {
+	if duration < time.Second {
+		return ErrDurationTooShort
+	}
+
+	// if the root qdisc cannot be added, for example because the interface already has one, it is not owned by
+	// the disruptor and must not be removed
+	if err := d.exec(d.rootCommand()); err != nil {
+		return err
+	}
+
+	//nolint:errcheck // Errors while removing the queueing discipline are not actionable.
+	defer d.exec(d.removeCommand())
+
+	for _, args := range d.commands() {
+		if err := d.exec(args); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(duration):
+		return nil
+	}
+}
+
+func (d Disruptor) exec(args string) error {
+	out, err := d.Executor.Exec("tc", strings.Split(args, " ")...)
+	if err != nil {
+		return fmt.Errorf("executing tc %s: %w: %s", args, err, string(out))
+	}
+
+	return nil
+}
+
+// rootCommand returns the arguments of the tc command that adds the root prio qdisc the limit is attached to.
+func (d Disruptor) rootCommand() string {
+	return fmt.Sprintf("qdisc add dev %s root handle 1: prio bands 4", d.Interface)
+}
+
+// commands returns the arguments of the tc commands that set the limit in place in the root qdisc. The traffic of
+// the port is sent to a band of the prio qdisc that is not used by its default priomap, so other traffic is not
+// affected.
+func (d Disruptor) commands() []string {
+	return []string{
+		fmt.Sprintf("qdisc add dev %s parent 1:4 handle 40: tbf rate %s burst 32kbit latency 400ms", d.Interface, d.Rate),
+		fmt.Sprintf(
+			"filter add dev %s protocol ip parent 1: prio 1 u32 match ip sport %d 0xffff flowid 1:4",
+			d.Interface, d.Port,
+		),
+	}
+}
+
+// removeCommand returns the arguments of the tc command that removes the limit.
+func (d Disruptor) removeCommand() string {
+	return fmt.Sprintf("qdisc del dev %s root", d.Interface)
+}
@@ -0,0 +1,94 @@
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+)
+
+func Test_DisruptorApply(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		duration     time.Duration
+		failOn       string
+		expectError  bool
+		expectedCmds []string
+	}{
+		{
+			title:    "limit applied and removed",
+			duration: time.Second,
+			expectedCmds: []string{
+				"tc qdisc add dev eth0 root handle 1: prio bands 4",
+				"tc qdisc add dev eth0 parent 1:4 handle 40: tbf rate 1mbit burst 32kbit latency 400ms",
+				"tc filter add dev eth0 protocol ip parent 1: prio 1 u32 match ip sport 8080 0xffff flowid 1:4",
+				"tc qdisc del dev eth0 root",
+			},
+		},
+		{
+			title:       "duration too short",
+			duration:    time.Millisecond,
+			expectError: true,
+		},
+		{
+			title:       "root qdisc fails",
+			duration:    time.Second,
+			failOn:      "root handle",
+			expectError: true,
+			expectedCmds: []string{
+				"tc qdisc add dev eth0 root handle 1: prio bands 4",
+			},
+		},
+		{
+			title:       "filter fails",
+			duration:    time.Second,
+			failOn:      "filter add",
+			expectError: true,
+			expectedCmds: []string{
+				"tc qdisc add dev eth0 root handle 1: prio bands 4",
+				"tc qdisc add dev eth0 parent 1:4 handle 40: tbf rate 1mbit burst 32kbit latency 400ms",
+				"tc filter add dev eth0 protocol ip parent 1: prio 1 u32 match ip sport 8080 0xffff flowid 1:4",
+				"tc qdisc del dev eth0 root",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			executor := runtime.NewCallbackExecutor(func(cmd string, args ...string) ([]byte, error) {
+				if tc.failOn != "" && strings.Contains(strings.Join(args, " "), tc.failOn) {
+					return nil, errors.New("tc failed")
+				}
+				return nil, nil
+			})
+			d := Disruptor{
+				Executor:  executor,
+				Interface: "eth0",
+				Port:      8080,
+				Rate:      "1mbit",
+			}
+
+			err := d.Apply(context.TODO(), tc.duration)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectedCmds, executor.CmdHistory()); diff != "" {
+				t.Fatalf("executed commands do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package dns contains a disruptor that fails the DNS queries sent by a pod.
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
+	"github.com/grafana/xk6-disruptor/pkg/iptables"
+)
+
+// maxMessageLen is the maximum length of the DNS messages sent over UDP
+const maxMessageLen = 65535
+
+// upstreamTimeout is the maximum time to wait for the response of the upstream resolver to a query
+const upstreamTimeout = 5 * time.Second
+
+// Fault defines the DNS queries that fail
+type Fault struct {
+	// Rate is the fraction (in the range 0.0 to 1.0) of the matching queries that fail
+	Rate float32
+	// Domain restricts the fault to the queries for this domain and its subdomains. Empty means all the queries.
+	Domain string
+}
+
+// Proxy answers the DNS queries sent to it, failing some of them with a SERVFAIL response and forwarding the others
+// to the upstream resolver.
+type Proxy struct {
+	Fault Fault
+	// Upstream is the address (host:port) of the resolver the queries are forwarded to
+	Upstream string
+	// Dialer opens the connections to the upstream resolver. If nil, a default dialer is used.
+	Dialer *net.Dialer
+	// Random selects the queries that fail. A nil Random uses the global source.
+	Random *protocol.Random
+}
+
+// Serve answers the queries received in the connection until the context is done
+func (p *Proxy) Serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buffer := make([]byte, maxMessageLen)
+	for {
+		n, client, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("reading query: %w", err)
+		}
+
+		query := make([]byte, n)
+		copy(query, buffer[:n])
+
+		// forwarding a query may take a while, so queries are handled concurrently
+		go func() {
+			response, err := p.handle(ctx, query)
+			if err != nil {
+				return
+			}
+
+			_, _ = conn.WriteTo(response, client)
+		}()
+	}
+}
+
+// handle returns the response to a query
+func (p *Proxy) handle(ctx context.Context, query []byte) ([]byte, error) {
+	name, questionEnd, err := questionName(query)
+	if err == nil && matchesDomain(name, p.Fault.Domain) && p.Random.Float32() < p.Fault.Rate {
+		return failureResponse(query, questionEnd), nil
+	}
+
+	return p.forward(ctx, query)
+}
+
+// forward sends the query to the upstream resolver and returns its response
+func (p *Proxy) forward(ctx context.Context, query []byte) ([]byte, error) {
+	dialer := p.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, upstreamTimeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "udp", p.Upstream)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to upstream resolver: %w", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	if _, err = conn.Write(query); err != nil {
+		return nil, fmt.Errorf("forwarding query: %w", err)
+	}
+
+	response := make([]byte, maxMessageLen)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, fmt.Errorf("reading upstream response: %w", err)
+	}
+
+	return response[:n], nil
+}
+
+// Disruptor redirects the DNS queries the pod sends over UDP to a Proxy that fails some of them.
+// Requires either to be run as root, or the NET_ADMIN capability.
+type Disruptor struct {
+	Iptables iptables.Iptables
+	Proxy    *Proxy
+	// Port is the port the proxy listens on
+	Port uint
+}
+
+// Apply fails the DNS queries of the pod for the given duration
+func (d Disruptor) Apply(ctx context.Context, duration time.Duration) error {
+	if d.Port == 0 {
+		return errors.New("proxy port is required")
+	}
+
+	conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", d.Port))
+	if err != nil {
+		return fmt.Errorf("starting DNS proxy: %w", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	rules := iptables.NewRuleSet(d.Iptables)
+	//nolint:errcheck // Errors while removing rules are not actionable.
+	defer rules.Remove()
+
+	if err = rules.Add(d.redirectRule()); err != nil {
+		return fmt.Errorf("redirecting DNS queries: %w", err)
+	}
+
+	serveCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	err = d.Proxy.Serve(serveCtx, conn)
+	// the fault ends when the duration elapses
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	return err
+}
+
+// redirectRule returns a netfilter rule that redirects the DNS queries sent by the pod to the proxy, except those
+// the proxy forwards to the upstream resolver, which are marked with the EgressMark.
+func (d Disruptor) redirectRule() iptables.Rule {
+	return iptables.Rule{
+		Table: "nat",
+		Chain: "OUTPUT", // For locally-originated traffic
+		Args: "-p udp --dport 53 " + // Sent to the DNS port
+			fmt.Sprintf("-m mark ! --mark %#x ", protocol.EgressMark) + // Not forwarded by the proxy
+			fmt.Sprintf("-j REDIRECT --to-port %d", d.Port), // Forward it to the proxy address
+	}
+}
@@ -0,0 +1,148 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/iptables"
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+)
+
+// startUpstream starts a resolver that answers all the queries with a NOERROR response and returns its address
+func startUpstream(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting upstream resolver: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buffer := make([]byte, maxMessageLen)
+		for {
+			n, client, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			response := make([]byte, n)
+			copy(response, buffer[:n])
+			response[2] |= 0x80
+			_, _ = conn.WriteTo(response, client)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func Test_ProxyServe(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		fault         Fault
+		name          string
+		expectFailure bool
+	}{
+		{
+			title:         "all queries fail",
+			fault:         Fault{Rate: 1.0},
+			name:          "backend.default.svc.cluster.local",
+			expectFailure: true,
+		},
+		{
+			title:         "no query fails",
+			fault:         Fault{Rate: 0.0},
+			name:          "backend.default.svc.cluster.local",
+			expectFailure: false,
+		},
+		{
+			title:         "query for the domain",
+			fault:         Fault{Rate: 1.0, Domain: "example.com"},
+			name:          "api.example.com",
+			expectFailure: true,
+		},
+		{
+			title:         "query for another domain",
+			fault:         Fault{Rate: 1.0, Domain: "example.com"},
+			name:          "backend.default.svc.cluster.local",
+			expectFailure: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("starting proxy: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			proxy := &Proxy{Fault: tc.fault, Upstream: startUpstream(t)}
+			go func() {
+				_ = proxy.Serve(ctx, conn)
+			}()
+
+			client, err := net.Dial("udp", conn.LocalAddr().String())
+			if err != nil {
+				t.Fatalf("connecting to proxy: %v", err)
+			}
+			defer client.Close() //nolint:errcheck
+
+			_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+
+			if _, err = client.Write(buildQuery(1, tc.name)); err != nil {
+				t.Fatalf("sending query: %v", err)
+			}
+
+			response := make([]byte, maxMessageLen)
+			n, err := client.Read(response)
+			if err != nil {
+				t.Fatalf("reading response: %v", err)
+			}
+
+			if n < headerLen || response[2]&0x80 == 0 {
+				t.Fatalf("expected a DNS response got %v", response[:n])
+			}
+
+			failed := response[3]&0x0f == rcodeServFail
+			if failed != tc.expectFailure {
+				t.Fatalf("expected failure %t got %t", tc.expectFailure, failed)
+			}
+		})
+	}
+}
+
+func Test_DisruptorApply(t *testing.T) {
+	t.Parallel()
+
+	executor := runtime.NewFakeExecutor(nil, nil)
+	d := Disruptor{
+		Iptables: iptables.New(executor),
+		Proxy:    &Proxy{Fault: Fault{Rate: 1.0}, Upstream: "127.0.0.1:53"},
+		// an unprivileged port unlikely to be used by other tests
+		Port: 25353,
+	}
+
+	err := d.Apply(context.TODO(), 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed unexpectedly: %v", err)
+	}
+
+	expected := []string{
+		"iptables -t nat -A OUTPUT -p udp --dport 53 -m mark ! --mark 0x6b36 -j REDIRECT --to-port 25353",
+		"iptables -t nat -D OUTPUT -p udp --dport 53 -m mark ! --mark 0x6b36 -j REDIRECT --to-port 25353",
+	}
+	if diff := cmp.Diff(expected, executor.CmdHistory()); diff != "" {
+		t.Fatalf("executed commands do not match expected:\n%s", diff)
+	}
+}
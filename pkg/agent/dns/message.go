@@ -0,0 +1,83 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// headerLen is the length of the header of a DNS message
+const headerLen = 12
+
+// rcodeServFail is the response code of a query that failed because of a server error
+const rcodeServFail = 2
+
+// errMalformedQuery is returned when a DNS query cannot be parsed
+var errMalformedQuery = errors.New("malformed DNS query")
+
+// questionName returns the domain name of the first question of a DNS query, in lower case and without the trailing
+// dot, and the offset where the question ends.
+func questionName(query []byte) (string, int, error) {
+	if len(query) < headerLen || binary.BigEndian.Uint16(query[4:6]) == 0 {
+		return "", 0, errMalformedQuery
+	}
+
+	labels := []string{}
+	offset := headerLen
+	for {
+		if offset >= len(query) {
+			return "", 0, errMalformedQuery
+		}
+
+		length := int(query[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+
+		// questions do not use compression, so labels are at most 63 bytes long
+		if length > 63 || offset+length > len(query) {
+			return "", 0, errMalformedQuery
+		}
+
+		labels = append(labels, string(query[offset:offset+length]))
+		offset += length
+	}
+
+	// type and class of the question
+	offset += 4
+	if offset > len(query) {
+		return "", 0, errMalformedQuery
+	}
+
+	return strings.ToLower(strings.Join(labels, ".")), offset, nil
+}
+
+// failureResponse returns the response to a query that reports a server failure (SERVFAIL). The response contains
+// the first question of the query and no records.
+func failureResponse(query []byte, questionEnd int) []byte {
+	response := make([]byte, questionEnd)
+	copy(response, query[:questionEnd])
+
+	// keep the opcode and the recursion desired flag of the query, set the response and recursion available flags
+	response[2] = 0x80 | (query[2] & 0x79)
+	response[3] = 0x80 | rcodeServFail
+
+	// a single question and no records
+	binary.BigEndian.PutUint16(response[4:6], 1)
+	binary.BigEndian.PutUint16(response[6:8], 0)
+	binary.BigEndian.PutUint16(response[8:10], 0)
+	binary.BigEndian.PutUint16(response[10:12], 0)
+
+	return response
+}
+
+// matchesDomain returns true if the name is the domain or one of its subdomains. An empty domain matches any name.
+func matchesDomain(name string, domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain == "" {
+		return true
+	}
+
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
@@ -0,0 +1,150 @@
+package dns
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// buildQuery returns a DNS query for the A records of the name
+func buildQuery(id uint16, name string) []byte {
+	query := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(query[0:2], id)
+	// recursion desired
+	query[2] = 0x01
+	binary.BigEndian.PutUint16(query[4:6], 1)
+
+	for _, label := range strings.Split(name, ".") {
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0)
+
+	// type A, class IN
+	return append(query, 0, 1, 0, 1)
+}
+
+func Test_QuestionName(t *testing.T) {
+	t.Parallel()
+
+	query := buildQuery(1, "Backend.Default.svc.cluster.local")
+
+	testCases := []struct {
+		title       string
+		query       []byte
+		expected    string
+		expectError bool
+	}{
+		{
+			title:       "valid query",
+			query:       query,
+			expected:    "backend.default.svc.cluster.local",
+			expectError: false,
+		},
+		{
+			title:       "truncated header",
+			query:       query[:8],
+			expectError: true,
+		},
+		{
+			title:       "truncated question",
+			query:       query[:len(query)-6],
+			expectError: true,
+		},
+		{
+			title:       "no questions",
+			query:       make([]byte, headerLen),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			name, end, err := questionName(tc.query)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("should had failed")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			if name != tc.expected {
+				t.Fatalf("expected name %q got %q", tc.expected, name)
+			}
+
+			if end != len(tc.query) {
+				t.Fatalf("expected question to end at %d got %d", len(tc.query), end)
+			}
+		})
+	}
+}
+
+func Test_FailureResponse(t *testing.T) {
+	t.Parallel()
+
+	query := buildQuery(0x1234, "example.com")
+	// an additional record (e.g. EDNS) that is not included in the response
+	binary.BigEndian.PutUint16(query[10:12], 1)
+	_, end, err := questionName(query)
+	if err != nil {
+		t.Fatalf("failed unexpectedly: %v", err)
+	}
+	query = append(query, 0, 0, 41, 16, 0, 0, 0, 0, 0, 0, 0)
+
+	response := failureResponse(query, end)
+
+	if id := binary.BigEndian.Uint16(response[0:2]); id != 0x1234 {
+		t.Fatalf("expected id 0x1234 got %#x", id)
+	}
+
+	if response[2] != 0x81 {
+		t.Fatalf("expected response flag and recursion desired got %#x", response[2])
+	}
+
+	if rcode := response[3] & 0x0f; rcode != rcodeServFail {
+		t.Fatalf("expected SERVFAIL response code got %d", rcode)
+	}
+
+	if additional := binary.BigEndian.Uint16(response[10:12]); additional != 0 {
+		t.Fatalf("expected no additional records got %d", additional)
+	}
+
+	if len(response) != end {
+		t.Fatalf("expected response with only the question (%d bytes) got %d bytes", end, len(response))
+	}
+}
+
+func Test_MatchesDomain(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		domain   string
+		expected bool
+	}{
+		{name: "example.com", domain: "", expected: true},
+		{name: "example.com", domain: "example.com", expected: true},
+		{name: "api.example.com", domain: "example.com", expected: true},
+		{name: "api.example.com", domain: "Example.com.", expected: true},
+		{name: "badexample.com", domain: "example.com", expected: false},
+		{name: "example.org", domain: "example.com", expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name+" "+tc.domain, func(t *testing.T) {
+			t.Parallel()
+
+			if matched := matchesDomain(tc.name, tc.domain); matched != tc.expected {
+				t.Fatalf("expected %t got %t", tc.expected, matched)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// ResolvConf is the path of the resolver configuration of the pod
+const ResolvConf = "/etc/resolv.conf"
+
+// Nameserver returns the address (host:port) of the first nameserver in the resolver configuration file
+func Nameserver(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading resolver configuration: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+
+	return "", fmt.Errorf("no nameserver found in %s", path)
+}
@@ -0,0 +1,66 @@
+package dns
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Nameserver(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		content     string
+		expected    string
+		expectError bool
+	}{
+		{
+			title: "kubernetes resolver",
+			content: "search default.svc.cluster.local svc.cluster.local cluster.local\n" +
+				"nameserver 10.96.0.10\n" +
+				"options ndots:5\n",
+			expected:    "10.96.0.10:53",
+			expectError: false,
+		},
+		{
+			title:       "IPv6 nameserver",
+			content:     "nameserver fd00::10\nnameserver 10.96.0.10\n",
+			expected:    "[fd00::10]:53",
+			expectError: false,
+		},
+		{
+			title:       "no nameserver",
+			content:     "search cluster.local\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "resolv.conf")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatalf("writing resolver configuration: %v", err)
+			}
+
+			nameserver, err := Nameserver(path)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("should had failed")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			if nameserver != tc.expected {
+				t.Fatalf("expected nameserver %q got %q", tc.expected, nameserver)
+			}
+		})
+	}
+}
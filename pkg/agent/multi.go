@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// multiDisruptor is a Disruptor that applies multiple disruptions simultaneously
+type multiDisruptor struct {
+	disruptors []Disruptor
+}
+
+// NewMultiDisruptor returns a Disruptor that applies all the given disruptors simultaneously.
+// If any of the disruptions fails, the others are cancelled.
+func NewMultiDisruptor(disruptors ...Disruptor) Disruptor {
+	return &multiDisruptor{
+		disruptors: disruptors,
+	}
+}
+
+// Apply applies all the disruptions and waits for them to complete
+func (d *multiDisruptor) Apply(ctx context.Context, duration time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(d.disruptors))
+	for _, disruptor := range d.disruptors {
+		go func(disruptor Disruptor) {
+			err := disruptor.Apply(ctx, duration)
+			if err != nil {
+				cancel()
+			}
+			errCh <- err
+		}(disruptor)
+	}
+
+	var errs []error
+	for range d.disruptors {
+		if err := <-errCh; err != nil && !errors.Is(err, context.Canceled) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeFailingDisruptor implements a Disruptor that fails after a delay
+type fakeFailingDisruptor struct {
+	delay time.Duration
+	err   error
+}
+
+// Apply implements the Apply method from the Disruptor interface
+func (d *fakeFailingDisruptor) Apply(_ context.Context, _ time.Duration) error {
+	time.Sleep(d.delay)
+	return d.err
+}
+
+// fakeCancelableDisruptor implements a Disruptor that waits for the duration or the context to be canceled
+type fakeCancelableDisruptor struct{}
+
+// Apply implements the Apply method from the Disruptor interface
+func (d *fakeCancelableDisruptor) Apply(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-time.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func Test_MultiDisruptor(t *testing.T) {
+	t.Parallel()
+
+	errFake := errors.New("fake error")
+
+	testCases := []struct {
+		title      string
+		disruptors []Disruptor
+		duration   time.Duration
+		expected   error
+	}{
+		{
+			title: "all disruptors complete",
+			disruptors: []Disruptor{
+				&FakeProtocolDisruptor{},
+				&fakeCancelableDisruptor{},
+			},
+			duration: 100 * time.Millisecond,
+			expected: nil,
+		},
+		{
+			title: "one disruptor fails",
+			disruptors: []Disruptor{
+				&fakeFailingDisruptor{err: errFake},
+				&fakeCancelableDisruptor{},
+			},
+			duration: 10 * time.Second,
+			expected: errFake,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+			err := NewMultiDisruptor(tc.disruptors...).Apply(context.Background(), tc.duration)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected error %v got %v", tc.expected, err)
+			}
+
+			// when one disruptor fails, the others must be canceled
+			if tc.expected != nil && time.Since(start) >= tc.duration {
+				t.Fatalf("disruptors were not canceled")
+			}
+		})
+	}
+}
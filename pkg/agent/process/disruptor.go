@@ -0,0 +1,144 @@
+// Package process contains a disruptor that pauses the processes running in the target.
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// agentProcess is the name of the processes of the disruptor agent, which are never paused
+const agentProcess = "xk6-disruptor-agent"
+
+// ErrDurationTooShort is returned when the supplied duration is smaller than 1s.
+var ErrDurationTooShort = errors.New("duration must be at least 1 second")
+
+// ErrNoProcesses is returned when no process to be paused is found.
+var ErrNoProcesses = errors.New("no processes found")
+
+// Signals accepted for pausing the processes
+var signals = map[string]syscall.Signal{ //nolint:gochecknoglobals
+	"STOP": syscall.SIGSTOP,
+	"TSTP": syscall.SIGTSTP,
+}
+
+// Disruptor pauses the processes visible to the agent by sending them a signal, and resumes them with SIGCONT
+// once the disruption ends. Requires either to be run as root, or the KILL capability.
+type Disruptor struct {
+	// Signal sends a signal to a process
+	Signal func(pid int, signal syscall.Signal) error
+	// Procfs is the mount point of the proc filesystem
+	Procfs string
+	// Name of the processes to pause, as the base name of their executable. If empty, all the processes are
+	// paused except the init process of the namespace and the processes of the agent.
+	Name string
+	// PauseSignal is the name of the signal that pauses the processes (STOP or TSTP)
+	PauseSignal string
+}
+
+// Apply pauses the processes for the given duration.
+func (d Disruptor) Apply(ctx context.Context, duration time.Duration) error {
+	if duration < time.Second {
+		return ErrDurationTooShort
+	}
+
+	signal, found := signals[strings.ToUpper(d.PauseSignal)]
+	if !found {
+		return fmt.Errorf("unsupported signal %q", d.PauseSignal)
+	}
+
+	pids, err := d.processes()
+	if err != nil {
+		return err
+	}
+
+	if len(pids) == 0 {
+		return ErrNoProcesses
+	}
+
+	// signal 0 checks the processes can be signaled before any of them is paused
+	for _, pid := range pids {
+		if err = d.Signal(pid, syscall.Signal(0)); err != nil {
+			return fmt.Errorf("signaling process %d (the KILL capability is required): %w", pid, err)
+		}
+	}
+
+	defer d.resume(pids)
+
+	for _, pid := range pids {
+		if err = d.Signal(pid, signal); err != nil {
+			return fmt.Errorf("pausing process %d: %w", pid, err)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(duration):
+		return nil
+	}
+}
+
+// resume sends SIGCONT to the processes. Errors are ignored, as the processes may have ended.
+func (d Disruptor) resume(pids []int) {
+	for _, pid := range pids {
+		_ = d.Signal(pid, syscall.SIGCONT)
+	}
+}
+
+// processes returns the ids of the processes to pause
+func (d Disruptor) processes() ([]int, error) {
+	entries, err := os.ReadDir(d.Procfs)
+	if err != nil {
+		return nil, fmt.Errorf("listing processes: %w", err)
+	}
+
+	pids := []int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		// the init process of the namespace (e.g. the pause container) and the agent itself are never paused
+		if pid == 1 || pid == os.Getpid() {
+			continue
+		}
+
+		name, err := d.processName(pid)
+		// the process may have ended, or be a kernel thread without command line
+		if err != nil || name == "" || name == agentProcess {
+			continue
+		}
+
+		if d.Name != "" && name != d.Name {
+			continue
+		}
+
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+// processName returns the base name of the executable of the process, as given in its command line
+func (d Disruptor) processName(pid int) (string, error) {
+	cmdline, err := os.ReadFile(filepath.Join(d.Procfs, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return "", err
+	}
+
+	// the arguments of the command line are separated by NUL characters
+	executable, _, _ := strings.Cut(string(cmdline), "\x00")
+	if executable == "" {
+		return "", nil
+	}
+
+	return filepath.Base(executable), nil
+}
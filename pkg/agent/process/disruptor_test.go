@@ -0,0 +1,158 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeSignaler records the signals sent to the processes
+type fakeSignaler struct {
+	mutex   sync.Mutex
+	err     error
+	signals []string
+}
+
+func (f *fakeSignaler) Signal(pid int, signal syscall.Signal) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.signals = append(f.signals, fmt.Sprintf("%d:%d", pid, signal))
+	return f.err
+}
+
+// buildProcfs creates a proc filesystem with the given command lines by process id
+func buildProcfs(t *testing.T, cmdlines map[string]string) string {
+	t.Helper()
+
+	procfs := t.TempDir()
+	for pid, cmdline := range cmdlines {
+		dir := filepath.Join(procfs, pid)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatalf("creating process directory: %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o600); err != nil {
+			t.Fatalf("writing command line: %v", err)
+		}
+	}
+
+	return procfs
+}
+
+func Test_DisruptorApply(t *testing.T) {
+	t.Parallel()
+
+	stop := int(syscall.SIGSTOP)
+	cont := int(syscall.SIGCONT)
+
+	testCases := []struct {
+		title           string
+		duration        time.Duration
+		name            string
+		signal          string
+		signalErr       error
+		expectError     bool
+		expectedSignals []string
+	}{
+		{
+			title:    "all processes paused and resumed",
+			duration: time.Second,
+			signal:   "STOP",
+			expectedSignals: []string{
+				"5000010:0", "5000011:0",
+				fmt.Sprintf("5000010:%d", stop), fmt.Sprintf("5000011:%d", stop),
+				fmt.Sprintf("5000010:%d", cont), fmt.Sprintf("5000011:%d", cont),
+			},
+		},
+		{
+			title:    "processes selected by name",
+			duration: time.Second,
+			name:     "app",
+			signal:   "stop",
+			expectedSignals: []string{
+				"5000010:0",
+				fmt.Sprintf("5000010:%d", stop),
+				fmt.Sprintf("5000010:%d", cont),
+			},
+		},
+		{
+			title:       "no matching processes",
+			duration:    time.Second,
+			name:        "other",
+			signal:      "STOP",
+			expectError: true,
+		},
+		{
+			title:       "processes cannot be signaled",
+			duration:    time.Second,
+			signal:      "STOP",
+			signalErr:   syscall.EPERM,
+			expectError: true,
+			// no process is paused
+			expectedSignals: []string{"5000010:0"},
+		},
+		{
+			title:       "unsupported signal",
+			duration:    time.Second,
+			signal:      "KILL",
+			expectError: true,
+		},
+		{
+			title:       "duration too short",
+			duration:    time.Millisecond,
+			signal:      "STOP",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// the ids of the processes are above the maximum process id, so they do not match the test's process
+			procfs := buildProcfs(t, map[string]string{
+				"1":       "/pause",
+				"5000010": "/usr/bin/app\x00--port\x008080",
+				"5000011": "/bin/sh",
+				"5000012": "/usr/bin/xk6-disruptor-agent\x00process",
+				// kernel threads do not have command line
+				"5000013": "",
+			})
+
+			signaler := &fakeSignaler{err: tc.signalErr}
+			d := Disruptor{
+				Signal:      signaler.Signal,
+				Procfs:      procfs,
+				Name:        tc.name,
+				PauseSignal: tc.signal,
+			}
+
+			err := d.Apply(context.TODO(), tc.duration)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.signalErr != nil && !errors.Is(err, tc.signalErr) {
+				t.Fatalf("expected error %v got %v", tc.signalErr, err)
+			}
+
+			if diff := cmp.Diff(tc.expectedSignals, signaler.signals); diff != "" {
+				t.Fatalf("signals do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
@@ -5,14 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
+	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -30,16 +32,46 @@ func NewHandler(disruption Disruption, forwardConn *grpc.ClientConn, metrics *pr
 		disruption:  disruption,
 		forwardConn: forwardConn,
 		metrics:     metrics,
+		random:      protocol.NewRandom(disruption.Seed),
+		started:     time.Now(),
 	}
 
 	// return the handler function
 	return handler.streamHandler
 }
 
+// maxEgressConns is the maximum number of connections to the authorities of egress requests kept open by the
+// handler. Requests to other authorities use a connection that is closed when the request completes.
+const maxEgressConns = 64
+
+// newEgressHandler returns a handler that forwards each request to its :authority, opening a connection
+// with the dial options for each authority. The connections must be released with close.
+func newEgressHandler(
+	disruption Disruption,
+	dialOptions []grpc.DialOption,
+	metrics *protocol.MetricMap,
+) *handler {
+	return &handler{
+		disruption:  disruption,
+		dialOptions: dialOptions,
+		conns:       map[string]*grpc.ClientConn{},
+		metrics:     metrics,
+		random:      protocol.NewRandom(disruption.Seed),
+		started:     time.Now(),
+	}
+}
+
 type handler struct {
 	disruption  Disruption
 	forwardConn *grpc.ClientConn
+	// dialOptions are used for opening the connections to the authority of egress requests, which are kept in conns
+	dialOptions []grpc.DialOption
+	conns       map[string]*grpc.ClientConn
+	mutex       sync.Mutex
 	metrics     *protocol.MetricMap
+	random      *protocol.Random
+	// started is the time the handler was created, used for applying the grace period
+	started time.Time
 }
 
 // contains verifies if a list of strings contains the given string
@@ -52,6 +84,23 @@ func contains(list []string, target string) bool {
 	return false
 }
 
+// matchAuthority returns true if the :authority of the request matches the authority of the disruption,
+// or the disruption applies to all authorities
+func (h *handler) matchAuthority(ctx context.Context) bool {
+	if h.disruption.Authority == "" {
+		return true
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authority := range md.Get(":authority") {
+		if strings.EqualFold(authority, h.disruption.Authority) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // handles requests from the client. If selected for error injection, returns an error,
 // otherwise, forwards to the server transparently
 func (h *handler) streamHandler(_ interface{}, serverStream grpc.ServerStream) error {
@@ -64,12 +113,17 @@ func (h *handler) streamHandler(_ interface{}, serverStream grpc.ServerStream) e
 
 	// full method name has the form /service/method, we want the service
 	serviceName := strings.Split(fullMethodName, "/")[1]
-	if contains(h.disruption.Excluded, serviceName) {
+	if contains(h.disruption.Excluded, serviceName) || !h.matchAuthority(serverStream.Context()) {
 		h.metrics.Inc(protocol.MetricRequestsExcluded)
 		return h.transparentForward(serverStream)
 	}
 
-	if rand.Float32() < h.disruption.ErrorRate {
+	// requests are not disrupted during the grace period
+	if time.Since(h.started) < h.disruption.GracePeriod {
+		return h.transparentForward(serverStream)
+	}
+
+	if h.random.Float32() < h.disruption.ErrorRate {
 		h.metrics.Inc(protocol.MetricRequestsDisrupted)
 		return h.injectError(serverStream)
 	}
@@ -81,7 +135,7 @@ func (h *handler) streamHandler(_ interface{}, serverStream grpc.ServerStream) e
 		delay := int64(h.disruption.AverageDelay)
 		if h.disruption.DelayVariation > 0 {
 			variation := int64(h.disruption.DelayVariation)
-			delay = delay + variation - 2*rand.Int63n(variation)
+			delay = delay + variation - 2*h.random.Int63n(variation)
 		}
 		time.Sleep(time.Duration(delay))
 	}
@@ -89,11 +143,74 @@ func (h *handler) streamHandler(_ interface{}, serverStream grpc.ServerStream) e
 	return h.transparentForward(serverStream)
 }
 
+// forwardedMetadata returns a copy of the metadata of the incoming request with the address of the client
+// appended to the x-forwarded-for entry, see https://en.wikipedia.org/wiki/X-Forwarded-For.
+func forwardedMetadata(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client := p.Addr.String()
+		// addresses that do not have a port (e.g. unix sockets) are forwarded as they are
+		if host, _, err := net.SplitHostPort(client); err == nil {
+			client = host
+		}
+		md.Append("x-forwarded-for", client)
+	}
+
+	return md
+}
+
+// upstream returns the connection the request is forwarded to and a function that releases it when the request
+// completes. Egress requests are forwarded to their :authority.
+func (h *handler) upstream(ctx context.Context) (*grpc.ClientConn, func(), error) {
+	release := func() {}
+	if !h.disruption.Egress {
+		return h.forwardConn, release, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorities := md.Get(":authority")
+	if len(authorities) == 0 || authorities[0] == "" {
+		return nil, nil, status.Errorf(codes.Unavailable, "request has no authority to be forwarded to")
+	}
+	authority := authorities[0]
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if conn, found := h.conns[authority]; found {
+		return conn, release, nil
+	}
+
+	conn, err := grpc.Dial(authority, h.dialOptions...)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Unavailable, "error dialing %s: %v", authority, err)
+	}
+
+	// once the limit is reached, connections to new authorities are not kept
+	if len(h.conns) >= maxEgressConns {
+		return conn, func() { _ = conn.Close() }, nil
+	}
+	h.conns[authority] = conn
+
+	return conn, release, nil
+}
+
+// close closes the connections kept for forwarding egress requests
+func (h *handler) close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for authority, conn := range h.conns {
+		_ = conn.Close()
+		delete(h.conns, authority)
+	}
+}
+
 func (h *handler) transparentForward(serverStream grpc.ServerStream) error {
-	// TODO: Add a `forwarded` header to metadata, https://en.wikipedia.org/wiki/X-Forwarded-For.
 	ctx := serverStream.Context()
-	md, _ := metadata.FromIncomingContext(ctx)
-	outgoingCtx := metadata.NewOutgoingContext(ctx, md.Copy())
+	outgoingCtx := metadata.NewOutgoingContext(ctx, forwardedMetadata(ctx))
 	clientCtx, clientCancel := context.WithCancel(outgoingCtx)
 	defer clientCancel()
 	fullMethodName, ok := grpc.MethodFromServerStream(serverStream)
@@ -101,10 +218,16 @@ func (h *handler) transparentForward(serverStream grpc.ServerStream) error {
 		return status.Errorf(codes.Internal, "ServerTransportStream not exists in context")
 	}
 
+	forwardConn, release, err := h.upstream(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	clientStream, err := grpc.NewClientStream(
 		clientCtx,
 		clientStreamDescForProxy(),
-		h.forwardConn,
+		forwardConn,
 		fullMethodName,
 	)
 	if err != nil {
@@ -129,6 +252,9 @@ func (h *handler) transparentForward(serverStream grpc.ServerStream) error {
 				// to cancel the clientStream to the backend, let all of its goroutines be freed up by the CancelFunc and
 				// exit with an error to the stack
 				clientCancel()
+				// once the clientStream is done, any trailers received from the backend are passed to the client
+				<-c2sErrChan
+				serverStream.SetTrailer(clientStream.Trailer())
 				return status.Errorf(codes.Internal, "failed forwarding response to client: %v", s2cErr)
 			}
 		case c2sErr := <-c2sErrChan:
@@ -202,6 +328,10 @@ func (h *handler) injectError(serverStream grpc.ServerStream) error {
 		return fmt.Errorf("error receiving request from client %w", err)
 	}
 
+	if len(h.disruption.Metadata) > 0 {
+		serverStream.SetTrailer(metadata.New(h.disruption.Metadata))
+	}
+
 	return status.Error(codes.Code(h.disruption.StatusCode), h.disruption.StatusMessage)
 }
 
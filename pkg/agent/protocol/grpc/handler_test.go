@@ -0,0 +1,474 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
+	grpcutils "github.com/grafana/xk6-disruptor/pkg/testutils/grpc"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/grpc/ping"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// connectProxy starts the upstream server and a proxy that forwards requests to it without disruptions, and
+// returns a connection to the proxy
+func connectProxy(t *testing.T, upstream *grpc.Server) *grpc.ClientConn {
+	t.Helper()
+
+	return connectWrappedProxy(t, upstream, nil, nil)
+}
+
+// connectWrappedProxy is like connectProxy, but the streams of the proxy are wrapped with the given functions, if
+// not nil: serverWrap wraps the streams of the requests received by the proxy and clientWrap the streams of the
+// requests forwarded to the upstream server, given the context of the request
+func connectWrappedProxy(
+	t *testing.T,
+	upstream *grpc.Server,
+	serverWrap func(grpc.ServerStream) grpc.ServerStream,
+	clientWrap func(context.Context, grpc.ClientStream) grpc.ClientStream,
+) *grpc.ClientConn {
+	t.Helper()
+
+	upstreamListener := bufconn.Listen(1024 * 1024)
+	go func() {
+		if err := upstream.Serve(upstreamListener); err != nil {
+			t.Logf("error in the upstream server: %v", err)
+		}
+	}()
+	t.Cleanup(upstream.Stop)
+
+	forwardConn, err := grpc.DialContext(
+		context.TODO(),
+		"bufnet",
+		grpc.WithContextDialer(grpcutils.BuffconnDialer(upstreamListener)),
+		grpc.WithInsecure(),
+		grpc.WithStreamInterceptor(func(
+			ctx context.Context,
+			desc *grpc.StreamDesc,
+			cc *grpc.ClientConn,
+			method string,
+			streamer grpc.Streamer,
+			opts ...grpc.CallOption,
+		) (grpc.ClientStream, error) {
+			stream, err := streamer(ctx, desc, cc, method, opts...)
+			if err != nil || clientWrap == nil {
+				return stream, err
+			}
+			return clientWrap(ctx, stream), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = forwardConn.Close()
+	})
+
+	proxyListener := bufconn.Listen(1024 * 1024)
+	handler := NewHandler(Disruption{}, forwardConn, protocol.NewMetricMap())
+	proxy := grpc.NewServer(grpc.UnknownServiceHandler(
+		func(srv interface{}, stream grpc.ServerStream) error {
+			if serverWrap != nil {
+				stream = serverWrap(stream)
+			}
+			return handler(srv, stream)
+		},
+	))
+	go func() {
+		if err := proxy.Serve(proxyListener); err != nil {
+			t.Logf("error in the proxy: %v", err)
+		}
+	}()
+	t.Cleanup(proxy.Stop)
+
+	conn, err := grpc.DialContext(
+		context.TODO(),
+		"bufnet",
+		grpc.WithContextDialer(grpcutils.BuffconnDialer(proxyListener)),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func Test_ForwardedMetadata(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		metadata map[string]string
+		expected []string
+	}{
+		{
+			title:    "without forwarded header",
+			metadata: map[string]string{"x-test": "value"},
+			expected: []string{"bufconn"},
+		},
+		{
+			title:    "with forwarded header",
+			metadata: map[string]string{"x-test": "value", "x-forwarded-for": "192.0.2.1"},
+			expected: []string{"192.0.2.1", "bufconn"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// upstream server that records the metadata of the requests
+			received := make(chan metadata.MD, 1)
+			upstream := grpc.NewServer(grpc.UnaryInterceptor(
+				func(
+					ctx context.Context,
+					req interface{},
+					_ *grpc.UnaryServerInfo,
+					handler grpc.UnaryHandler,
+				) (interface{}, error) {
+					md, _ := metadata.FromIncomingContext(ctx)
+					received <- md
+					return handler(ctx, req)
+				},
+			))
+			ping.RegisterPingServiceServer(upstream, ping.NewPingServer())
+
+			conn := connectProxy(t, upstream)
+
+			ctx := metadata.NewOutgoingContext(context.TODO(), metadata.New(tc.metadata))
+			_, err := ping.NewPingServiceClient(conn).Ping(ctx, &ping.PingRequest{Message: "ping"})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			md := <-received
+
+			if diff := cmp.Diff(tc.expected, md.Get("x-forwarded-for")); diff != "" {
+				t.Fatalf("forwarded header does not match expected:\n%s", diff)
+			}
+
+			// other metadata is preserved
+			if value := md.Get("x-test"); len(value) != 1 || value[0] != "value" {
+				t.Fatalf("expected metadata x-test to be preserved got %v", value)
+			}
+		})
+	}
+}
+
+// disconnectedStream is a stream of a request received by the proxy that fails receiving from the client, as if it
+// had disconnected, once the response is received from the upstream server
+type disconnectedStream struct {
+	grpc.ServerStream
+	received <-chan struct{}
+	recvs    int
+}
+
+func (s *disconnectedStream) RecvMsg(m interface{}) error {
+	s.recvs++
+	if s.recvs == 1 {
+		return s.ServerStream.RecvMsg(m)
+	}
+
+	<-s.received
+	return errors.New("client disconnected")
+}
+
+// completedStream is a stream of a request forwarded to the upstream server that notifies when it completes, and
+// waits for the request to be canceled before reporting it, so the failure of the client is handled first
+type completedStream struct {
+	grpc.ClientStream
+	// ctx is the context of the request. The context of the stream is canceled once it completes.
+	ctx      context.Context
+	received chan<- struct{}
+}
+
+func (s *completedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if errors.Is(err, io.EOF) {
+		close(s.received)
+		<-s.ctx.Done()
+	}
+
+	return err
+}
+
+func Test_ForwardedTrailers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		request      *ping.PingRequest
+		disconnect   bool
+		expectStatus codes.Code
+	}{
+		{
+			title: "successful call",
+			request: &ping.PingRequest{
+				Message:  "ping",
+				Trailers: map[string]string{"x-custom-trailer": "value"},
+			},
+			expectStatus: codes.OK,
+		},
+		{
+			title: "failed call",
+			request: &ping.PingRequest{
+				Error:    int32(codes.Internal),
+				Message:  "internal error",
+				Trailers: map[string]string{"x-custom-trailer": "value"},
+			},
+			expectStatus: codes.Internal,
+		},
+		{
+			title: "client disconnected",
+			request: &ping.PingRequest{
+				Message:  "ping",
+				Trailers: map[string]string{"x-custom-trailer": "value"},
+			},
+			disconnect:   true,
+			expectStatus: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			upstream := grpc.NewServer()
+			ping.RegisterPingServiceServer(upstream, ping.NewPingServer())
+
+			var serverWrap func(grpc.ServerStream) grpc.ServerStream
+			var clientWrap func(context.Context, grpc.ClientStream) grpc.ClientStream
+			if tc.disconnect {
+				received := make(chan struct{})
+				serverWrap = func(stream grpc.ServerStream) grpc.ServerStream {
+					return &disconnectedStream{ServerStream: stream, received: received}
+				}
+				clientWrap = func(ctx context.Context, stream grpc.ClientStream) grpc.ClientStream {
+					return &completedStream{ClientStream: stream, ctx: ctx, received: received}
+				}
+			}
+
+			conn := connectWrappedProxy(t, upstream, serverWrap, clientWrap)
+
+			var trailers metadata.MD
+			_, err := ping.NewPingServiceClient(conn).Ping(context.TODO(), tc.request, grpc.Trailer(&trailers))
+			if s := status.Convert(err); s.Code() != tc.expectStatus {
+				t.Fatalf("expected '%s' but got '%s': %v", tc.expectStatus, s.Code(), err)
+			}
+
+			if !ping.CompareHeaders(trailers, tc.request.Trailers) {
+				t.Fatalf("expected trailers %v got %v", tc.request.Trailers, trailers)
+			}
+		})
+	}
+}
+
+// startUpstream starts a server that sends its name to the received channel for each request and returns
+// its listener
+func startUpstream(t *testing.T, name string, received chan<- string) *bufconn.Listener {
+	t.Helper()
+
+	upstream := grpc.NewServer(grpc.UnaryInterceptor(
+		func(
+			ctx context.Context,
+			req interface{},
+			_ *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			received <- name
+			return handler(ctx, req)
+		},
+	))
+	ping.RegisterPingServiceServer(upstream, ping.NewPingServer())
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		if err := upstream.Serve(listener); err != nil {
+			t.Logf("error in the upstream server %s: %v", name, err)
+		}
+	}()
+	t.Cleanup(upstream.Stop)
+
+	return listener
+}
+
+// serveHandler starts a server with the handler and returns a function that connects to it with the given authority
+func serveHandler(t *testing.T, handler grpc.StreamHandler) func(authority string) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(handler))
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			t.Logf("error in the proxy: %v", err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+
+	return func(authority string) *grpc.ClientConn {
+		conn, err := grpc.DialContext(
+			context.TODO(),
+			"bufnet",
+			grpc.WithContextDialer(grpcutils.BuffconnDialer(listener)),
+			grpc.WithInsecure(),
+			grpc.WithAuthority(authority),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+
+		return conn
+	}
+}
+
+func Test_UpstreamOfRequests(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		egress   bool
+		requests []string
+		expected []string
+	}{
+		{
+			title:    "ingress requests are forwarded to the upstream",
+			egress:   false,
+			requests: []string{"service-a", "service-b"},
+			expected: []string{"upstream", "upstream"},
+		},
+		{
+			title:    "egress requests are forwarded to their authority",
+			egress:   true,
+			requests: []string{"service-a", "service-b", "service-a"},
+			expected: []string{"service-a", "service-b", "service-a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			received := make(chan string, len(tc.requests))
+			listeners := map[string]*bufconn.Listener{}
+			for _, name := range []string{"upstream", "service-a", "service-b"} {
+				listeners[name] = startUpstream(t, name, received)
+			}
+
+			dialOptions := []grpc.DialOption{
+				grpc.WithInsecure(),
+				grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+					listener, found := listeners[addr]
+					if !found {
+						return nil, fmt.Errorf("unknown address %s", addr)
+					}
+					return listener.Dial()
+				}),
+			}
+
+			var handler grpc.StreamHandler
+			if tc.egress {
+				egress := newEgressHandler(Disruption{Egress: true}, dialOptions, protocol.NewMetricMap())
+				t.Cleanup(egress.close)
+				handler = egress.streamHandler
+			} else {
+				forwardConn, err := grpc.DialContext(context.TODO(), "upstream", dialOptions...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() {
+					_ = forwardConn.Close()
+				})
+				handler = NewHandler(Disruption{}, forwardConn, protocol.NewMetricMap())
+			}
+
+			connect := serveHandler(t, handler)
+
+			upstreams := []string{}
+			for _, authority := range tc.requests {
+				client := ping.NewPingServiceClient(connect(authority))
+				_, err := client.Ping(context.TODO(), &ping.PingRequest{Message: "ping"})
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				upstreams = append(upstreams, <-received)
+			}
+
+			if diff := cmp.Diff(tc.expected, upstreams); diff != "" {
+				t.Fatalf("upstreams do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_EgressConns(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan string, 1)
+	listener := startUpstream(t, "service", received)
+	dialOptions := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(grpcutils.BuffconnDialer(listener)),
+	}
+
+	h := newEgressHandler(Disruption{Egress: true}, dialOptions, protocol.NewMetricMap())
+	connect := serveHandler(t, h.streamHandler)
+
+	// fill the connections kept by the handler, so the connection to the new authority is not kept
+	for i := 0; i < maxEgressConns; i++ {
+		conn, err := grpc.Dial(fmt.Sprintf("unused-%d", i), dialOptions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.conns[conn.Target()] = conn
+	}
+
+	_, err := ping.NewPingServiceClient(connect("service")).Ping(context.TODO(), &ping.PingRequest{Message: "ping"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	<-received
+
+	if _, found := h.conns["service"]; found {
+		t.Fatalf("expected the connection to the authority not to be kept")
+	}
+
+	conns := []*grpc.ClientConn{}
+	for _, conn := range h.conns {
+		conns = append(conns, conn)
+	}
+
+	h.close()
+
+	if len(h.conns) != 0 {
+		t.Fatalf("expected no connections after closing the handler got %d", len(h.conns))
+	}
+
+	for _, conn := range conns {
+		if state := conn.GetState(); state != connectivity.Shutdown {
+			t.Fatalf("expected connection to %s to be closed got %s", conn.Target(), state)
+		}
+	}
+}
@@ -4,13 +4,16 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // Disruption specifies disruptions in grpc requests
@@ -25,8 +28,57 @@ type Disruption struct {
 	StatusCode int32
 	// Status message to be returned in requests selected to return an error
 	StatusMessage string
+	// Trailing metadata attached to the errors returned to requests selected to return an error
+	Metadata map[string]string
 	// List of grpc services to be excluded from disruptions
 	Excluded []string
+	// Authority of the requests to be disrupted. Requests to other authorities are excluded. Empty means all.
+	Authority string
+	// Seed for the random selection of delays and errors, for reproducible disruptions. Zero means a random seed.
+	Seed int64
+	// Size in bytes of the read and write buffers of the connections with the client and the upstream server.
+	// Zero means the defaults of the grpc library.
+	BufferSize int
+	// UpstreamTLS enables TLS in the connection with the upstream server
+	UpstreamTLS bool
+	// UpstreamInsecureSkipVerify disables the verification of the certificate of the upstream server, for example
+	// when it is self-signed. It must only be used in test environments.
+	UpstreamInsecureSkipVerify bool
+	// Time since the proxy starts during which requests are forwarded without being disrupted
+	GracePeriod time.Duration
+	// Forward requests to their :authority instead of the upstream address, for disrupting the requests the target
+	// sends. Upstream connections are opened with the protocol.EgressMark.
+	Egress bool
+}
+
+// validateMetadata checks the key is a valid gRPC metadata key that is not reserved, and the value of a
+// non-binary key is printable ASCII
+func validateMetadata(key string, value string) error {
+	if key == "" {
+		return fmt.Errorf("metadata key cannot be empty")
+	}
+
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("invalid metadata key %q: must contain only lowercase letters, digits, '-', '_' or '.'", key)
+		}
+	}
+
+	if strings.HasPrefix(key, "grpc-") {
+		return fmt.Errorf("invalid metadata key %q: the 'grpc-' prefix is reserved", key)
+	}
+
+	if strings.HasSuffix(key, "-bin") {
+		return nil
+	}
+
+	for _, c := range value {
+		if c < 0x20 || c > 0x7E {
+			return fmt.Errorf("invalid value of metadata key %q: must contain only printable ASCII characters", key)
+		}
+	}
+
+	return nil
 }
 
 // Proxy defines the parameters used by the proxy for processing grpc requests and its execution state
@@ -35,6 +87,9 @@ type proxy struct {
 	srv      *grpc.Server
 	cancel   func()
 	metrics  *protocol.MetricMap
+	// egress is the handler of egress requests, which keeps the connections to their authorities. Nil if the
+	// proxy forwards requests to the upstream address.
+	egress *handler
 }
 
 // NewProxy return a new Proxy
@@ -55,15 +110,45 @@ func NewProxy(listener net.Listener, upstreamAddress string, d Disruption) (prot
 		return nil, fmt.Errorf("status code cannot be 0 (OK)")
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	conn, err := grpc.DialContext(
-		ctx,
-		upstreamAddress,
-		grpc.WithInsecure(),
-	)
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("error dialing %s: %w", upstreamAddress, err)
+	for key, value := range d.Metadata {
+		if err := validateMetadata(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.BufferSize < 0 {
+		return nil, fmt.Errorf("buffer size must be a positive number")
+	}
+
+	if d.GracePeriod < 0 {
+		return nil, fmt.Errorf("grace period must be a positive duration")
+	}
+
+	if d.UpstreamInsecureSkipVerify && !d.UpstreamTLS {
+		return nil, fmt.Errorf("skipping the verification of the upstream certificate requires TLS")
+	}
+
+	dialOptions := []grpc.DialOption{grpc.WithInsecure()}
+	if d.UpstreamTLS {
+		tlsConfig := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			//nolint:gosec // skipping verification is explicitly requested for self-signed certificates
+			InsecureSkipVerify: d.UpstreamInsecureSkipVerify,
+		}
+		dialOptions = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	}
+	serverOptions := []grpc.ServerOption{}
+	if d.BufferSize > 0 {
+		dialOptions = append(
+			dialOptions,
+			grpc.WithReadBufferSize(d.BufferSize),
+			grpc.WithWriteBufferSize(d.BufferSize),
+		)
+		serverOptions = append(
+			serverOptions,
+			grpc.ReadBufferSize(d.BufferSize),
+			grpc.WriteBufferSize(d.BufferSize),
+		)
 	}
 
 	metrics := protocol.NewMetricMap(
@@ -72,10 +157,36 @@ func NewProxy(listener net.Listener, upstreamAddress string, d Disruption) (prot
 		protocol.MetricRequestsDisrupted,
 	)
 
-	handler := NewHandler(d, conn, metrics)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var streamHandler grpc.StreamHandler
+	var egress *handler
+	if d.Egress {
+		dialer := protocol.MarkedDialer()
+		dialOptions = append(
+			dialOptions,
+			grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "tcp", addr)
+			}),
+		)
+		egress = newEgressHandler(d, dialOptions, metrics)
+		streamHandler = egress.streamHandler
+	} else {
+		conn, err := grpc.DialContext(
+			ctx,
+			upstreamAddress,
+			dialOptions...,
+		)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("error dialing %s: %w", upstreamAddress, err)
+		}
+
+		streamHandler = NewHandler(d, conn, metrics)
+	}
 
 	srv := grpc.NewServer(
-		grpc.UnknownServiceHandler(handler),
+		append(serverOptions, grpc.UnknownServiceHandler(streamHandler))...,
 	)
 
 	return &proxy{
@@ -83,6 +194,7 @@ func NewProxy(listener net.Listener, upstreamAddress string, d Disruption) (prot
 		srv:      srv,
 		cancel:   cancel,
 		metrics:  metrics,
+		egress:   egress,
 	}, nil
 }
 
@@ -100,6 +212,7 @@ func (p *proxy) Start() error {
 func (p *proxy) Stop() error {
 	p.cancel()
 	p.srv.GracefulStop()
+	p.closeEgressConns()
 
 	return nil
 }
@@ -114,6 +227,14 @@ func (p *proxy) Metrics() map[string]uint {
 func (p *proxy) Force() error {
 	p.cancel()
 	p.srv.Stop()
+	p.closeEgressConns()
 
 	return nil
 }
+
+// closeEgressConns closes the connections opened for forwarding egress requests, if any
+func (p *proxy) closeEgressConns() {
+	if p.egress != nil {
+		p.egress.close()
+	}
+}
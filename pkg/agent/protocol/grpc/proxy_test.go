@@ -2,6 +2,13 @@ package grpc
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"net"
 	"testing"
 	"time"
@@ -11,6 +18,7 @@ import (
 	"github.com/grafana/xk6-disruptor/pkg/testutils/grpc/ping"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
@@ -108,6 +116,64 @@ func Test_Validations(t *testing.T) {
 			upstream:    ":8080",
 			expectError: true,
 		},
+		{
+			title: "valid buffer size",
+			disruption: Disruption{
+				BufferSize: 256 * 1024,
+			},
+			upstream:    ":8080",
+			expectError: false,
+		},
+		{
+			title: "skip verify without TLS",
+			disruption: Disruption{
+				UpstreamInsecureSkipVerify: true,
+			},
+			upstream:    ":8080",
+			expectError: true,
+		},
+		{
+			title: "negative buffer size",
+			disruption: Disruption{
+				BufferSize: -1,
+			},
+			upstream:    ":8080",
+			expectError: true,
+		},
+		{
+			title: "valid metadata",
+			disruption: Disruption{
+				ErrorRate:  1.0,
+				StatusCode: int32(codes.Internal),
+				Metadata:   map[string]string{"x-retry-after": "10", "x-details-bin": "\x00\x01"},
+			},
+			upstream:    ":8080",
+			expectError: false,
+		},
+		{
+			title: "uppercase metadata key",
+			disruption: Disruption{
+				Metadata: map[string]string{"X-Retry-After": "10"},
+			},
+			upstream:    ":8080",
+			expectError: true,
+		},
+		{
+			title: "reserved metadata key",
+			disruption: Disruption{
+				Metadata: map[string]string{"grpc-status": "0"},
+			},
+			upstream:    ":8080",
+			expectError: true,
+		},
+		{
+			title: "non printable metadata value",
+			disruption: Disruption{
+				Metadata: map[string]string{"x-retry-after": "\x00"},
+			},
+			upstream:    ":8080",
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -141,11 +207,13 @@ func Test_ProxyHandler(t *testing.T) {
 	t.Parallel()
 
 	type TestCase struct {
-		title        string
-		disruption   Disruption
-		request      *ping.PingRequest
-		response     *ping.PingResponse
-		expectStatus codes.Code
+		title          string
+		disruption     Disruption
+		authority      string
+		request        *ping.PingRequest
+		response       *ping.PingResponse
+		expectStatus   codes.Code
+		expectTrailers map[string]string
 	}
 
 	// TODO: Add test for excluded endpoints
@@ -184,6 +252,22 @@ func Test_ProxyHandler(t *testing.T) {
 			response:     nil,
 			expectStatus: codes.Internal,
 		},
+		{
+			title: "error injection with metadata",
+			disruption: Disruption{
+				ErrorRate:     1.0,
+				StatusCode:    int32(codes.Unavailable),
+				StatusMessage: "Service unavailable",
+				Metadata:      map[string]string{"x-retry-after": "10"},
+			},
+			request: &ping.PingRequest{
+				Error:   0,
+				Message: "ping",
+			},
+			response:       nil,
+			expectStatus:   codes.Unavailable,
+			expectTrailers: map[string]string{"x-retry-after": "10"},
+		},
 		{
 			title: "delay injection",
 			disruption: Disruption{
@@ -202,6 +286,38 @@ func Test_ProxyHandler(t *testing.T) {
 			},
 			expectStatus: codes.OK,
 		},
+		{
+			title: "error injection with matching authority",
+			disruption: Disruption{
+				ErrorRate:  1.0,
+				StatusCode: int32(codes.Internal),
+				Authority:  "api.example.com",
+			},
+			authority: "API.example.com",
+			request: &ping.PingRequest{
+				Error:   0,
+				Message: "ping",
+			},
+			response:     nil,
+			expectStatus: codes.Internal,
+		},
+		{
+			title: "error injection with other authority",
+			disruption: Disruption{
+				ErrorRate:  1.0,
+				StatusCode: int32(codes.Internal),
+				Authority:  "api.example.com",
+			},
+			authority: "other.example.com",
+			request: &ping.PingRequest{
+				Error:   0,
+				Message: "ping",
+			},
+			response: &ping.PingResponse{
+				Message: "ping",
+			},
+			expectStatus: codes.OK,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -245,10 +361,14 @@ func Test_ProxyHandler(t *testing.T) {
 			time.Sleep(time.Second)
 
 			// connect client to proxy
+			dialOptions := []grpc.DialOption{grpc.WithInsecure()}
+			if tc.authority != "" {
+				dialOptions = append(dialOptions, grpc.WithAuthority(tc.authority))
+			}
 			conn, err := grpc.DialContext(
 				context.TODO(),
 				proxyListener.Addr().String(),
-				grpc.WithInsecure(),
+				dialOptions...,
 			)
 			if err != nil {
 				t.Fatal(err)
@@ -260,10 +380,12 @@ func Test_ProxyHandler(t *testing.T) {
 			client := ping.NewPingServiceClient(conn)
 
 			var headers metadata.MD
+			var trailers metadata.MD
 			response, err := client.Ping(
 				context.TODO(),
 				tc.request,
 				grpc.Header(&headers),
+				grpc.Trailer(&trailers),
 				grpc.WaitForReady(true),
 			)
 			if err != nil && tc.expectStatus == codes.OK {
@@ -287,6 +409,10 @@ func Test_ProxyHandler(t *testing.T) {
 				t.Errorf("expected '%s' but got '%s'", tc.response, response)
 				return
 			}
+
+			if !ping.CompareHeaders(trailers, tc.expectTrailers) {
+				t.Errorf("expected trailers %v got %v", tc.expectTrailers, trailers)
+			}
 		})
 	}
 }
@@ -428,3 +554,124 @@ func Test_ProxyMetrics(t *testing.T) {
 		})
 	}
 }
+
+// selfSignedCertificate returns a self-signed certificate for localhost
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func Test_ProxyUpstreamTLS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		disruption   Disruption
+		expectStatus codes.Code
+	}{
+		{
+			title: "skip verification",
+			disruption: Disruption{
+				UpstreamTLS:                true,
+				UpstreamInsecureSkipVerify: true,
+			},
+			expectStatus: codes.OK,
+		},
+		{
+			title: "verify self-signed certificate",
+			disruption: Disruption{
+				UpstreamTLS: true,
+			},
+			expectStatus: codes.Unavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			upstreamListener, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("error starting test upstream listener: %v", err)
+			}
+			cert := selfSignedCertificate(t)
+			srv := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+			ping.RegisterPingServiceServer(srv, ping.NewPingServer())
+			go func() {
+				if serr := srv.Serve(upstreamListener); serr != nil {
+					t.Logf("error in the server: %v", serr)
+				}
+			}()
+			defer srv.Stop()
+
+			proxyListener, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("error starting test proxy listener: %v", err)
+			}
+
+			proxy, err := NewProxy(proxyListener, upstreamListener.Addr().String(), tc.disruption)
+			if err != nil {
+				t.Fatalf("error creating proxy: %v", err)
+			}
+			defer func() {
+				_ = proxy.Stop()
+			}()
+
+			go func() {
+				if perr := proxy.Start(); perr != nil {
+					t.Logf("error starting proxy: %v", perr)
+				}
+			}()
+
+			conn, err := grpc.DialContext(
+				context.TODO(),
+				proxyListener.Addr().String(),
+				grpc.WithInsecure(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			client := ping.NewPingServiceClient(conn)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			response, err := client.Ping(ctx, &ping.PingRequest{Message: "ping"}, grpc.WaitForReady(true))
+			if s := status.Convert(err); s.Code() != tc.expectStatus {
+				t.Fatalf("expected '%s' but got '%s': %v", tc.expectStatus, s.Code(), err)
+			}
+
+			if tc.expectStatus == codes.OK && !ping.CompareResponses(response, &ping.PingResponse{Message: "ping"}) {
+				t.Fatalf("unexpected response '%s'", response)
+			}
+		})
+	}
+}
@@ -3,39 +3,137 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
-	"math/rand"
+	"math"
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/time/rate"
 )
 
 // Disruption specifies disruptions in http requests
 type Disruption struct {
 	// Average delay introduced to requests
 	AverageDelay time.Duration
-	// Variation in the delay (with respect of the average delay)
+	// Variation in the delay (with respect of the average delay). If there is no average delay, requests are
+	// delayed randomly up to the variation.
 	DelayVariation time.Duration
 	// Fraction (in the range 0.0 to 1.0) of requests that will return an error
 	ErrorRate float32
 	// Error code to be returned by requests selected in the error rate
 	ErrorCode uint
+	// Error codes returned by requests selected in the error rate, instead of ErrorCode, each one with a
+	// probability proportional to its weight
+	ErrorCodes []WeightedCode
 	// Body to be returned when an error is injected
 	ErrorBody string
+	// Number of bytes of the response body sent to the requests selected to return an error before closing their
+	// connection, instead of returning an error code. Zero means errors are returned.
+	TruncateAfterBytes uint
 	// List of url paths to be excluded from disruptions
 	Excluded []string
+	// Regular expression matching the whole url path of the requests to be excluded from disruptions
+	ExcludedRegex string
+	// Headers that requests must have, with the given values, to be disrupted. Requests that do not match all of
+	// them are excluded from disruptions. Empty means all requests are disrupted.
+	Headers map[string]string
+	// Maximum rate of requests per second allowed. Requests above this rate are rejected. Zero means no limit.
+	RateLimit float32
+	// Status code returned to requests rejected for exceeding the rate limit
+	RateLimitCode uint
+	// Number of bytes of the response body sent on each DripInterval. Zero means the body is sent at once.
+	DripBytesPerInterval uint
+	// Interval between chunks of the response body
+	DripInterval time.Duration
+	// Maximum number of bytes per second read from the request body sent by the client and from the response
+	// body sent by the upstream. The senders are slowed down as the socket buffers fill up. Zero means no limit.
+	ReadRateBytesPerSec uint
+	// Header whose value is hashed for selecting the requests that return an error. If empty, requests
+	// are selected randomly.
+	HashHeader string
+	// Responses returned in turns to the requests selected to return an error. If empty, ErrorCode and
+	// ErrorBody are returned.
+	Responses []CannedResponse
+	// Windows the disruption steps through, each one setting the delay and errors injected during its duration,
+	// starting when the proxy starts. Once the last window ends, its values remain in effect.
+	Windows []Window
+	// Seed for the random selection of delays and errors, for reproducible disruptions. Zero means a random seed.
+	Seed int64
+	// Rules that set the error rate and error code of requests by the prefix of their path. If not empty,
+	// the first rule matching the path of a request replaces ErrorRate and ErrorCode, and requests that do not
+	// match any rule do not return errors.
+	Rules []Rule
+	// Fraction (in the range 0.0 to 1.0) of requests selected for either an error or a delay, instead of
+	// ErrorRate. Zero means requests are not selected this way.
+	FaultRate float32
+	// Fraction (in the range 0.0 to 1.0) of the requests selected by FaultRate that return an error. The rest
+	// of them are delayed.
+	ErrorShare float32
+	// Size in bytes of the buffers used for copying the requests and responses. Zero means the defaults of the
+	// http library.
+	BufferSize int
+	// Time since the proxy starts during which requests are forwarded without being disrupted
+	GracePeriod time.Duration
+	// Accept and forward requests using HTTP/2 over cleartext (h2c) instead of HTTP/1.1
+	HTTP2 bool
+	// Forward requests to the host they are addressed to instead of the upstream address, for disrupting the
+	// requests the target sends. Upstream connections are opened with the protocol.EgressMark.
+	Egress bool
+}
+
+// WeightedCode defines an error code returned with a probability proportional to its weight
+type WeightedCode struct {
+	Code   uint
+	Weight float32
+}
+
+// Rule defines the errors returned to the requests whose path starts with a prefix
+type Rule struct {
+	PathPrefix string  `json:"pathPrefix"`
+	ErrorRate  float32 `json:"errorRate"`
+	ErrorCode  uint    `json:"errorCode"`
+}
+
+// Window defines the delay and errors injected during a window of the disruption
+type Window struct {
+	// Duration of the window
+	Duration time.Duration
+	// Average delay introduced to requests
+	AverageDelay time.Duration
+	// Variation in the delay (with respect of the average delay)
+	DelayVariation time.Duration
+	// Fraction (in the range 0.0 to 1.0) of requests that will return an error
+	ErrorRate float32
+	// Error code to be returned by requests selected in the error rate
+	ErrorCode uint
+}
+
+// CannedResponse defines a response returned to requests selected to return an error
+type CannedResponse struct {
+	Code    uint              `json:"code"`
+	Body    string            `json:"body"`
+	Headers map[string]string `json:"headers"`
 }
 
 // Proxy defines the parameters used by the proxy for processing http requests and its execution state
 type proxy struct {
 	listener   net.Listener
 	disruption Disruption
+	handler    *httpHandler
 	srv        *http.Server
 	metrics    *protocol.MetricMap
 }
@@ -46,7 +144,8 @@ func NewProxy(listener net.Listener, upstreamAddress string, d Disruption) (prot
 		return nil, fmt.Errorf("proxy's forwarding address must be provided")
 	}
 
-	if d.DelayVariation > d.AverageDelay {
+	// a variation without average delay injects only jitter
+	if d.AverageDelay > 0 && d.DelayVariation > d.AverageDelay {
 		return nil, fmt.Errorf("variation must be less that average delay")
 	}
 
@@ -54,38 +153,322 @@ func NewProxy(listener net.Listener, upstreamAddress string, d Disruption) (prot
 		return nil, fmt.Errorf("error rate must be in the range [0.0, 1.0]")
 	}
 
-	if d.ErrorRate > 0.0 && d.ErrorCode == 0 {
+	if d.ErrorRate > 0.0 && d.ErrorCode == 0 && len(d.ErrorCodes) == 0 && len(d.Responses) == 0 &&
+		d.TruncateAfterBytes == 0 {
+		return nil, fmt.Errorf("error code must be a valid http error code")
+	}
+
+	if d.TruncateAfterBytes > 0 && (d.ErrorCode != 0 || len(d.ErrorCodes) > 0 || len(d.Responses) > 0) {
+		return nil, fmt.Errorf("truncate after bytes cannot be combined with error codes or responses")
+	}
+
+	for _, code := range d.ErrorCodes {
+		if code.Code < 100 || code.Code > 599 {
+			return nil, fmt.Errorf("error code must be a valid http status code: %d", code.Code)
+		}
+
+		if code.Weight <= 0 {
+			return nil, fmt.Errorf("weight of error code %d must be positive", code.Code)
+		}
+	}
+
+	if d.RateLimit < 0 {
+		return nil, fmt.Errorf("rate limit must be a positive number")
+	}
+
+	if d.RateLimit > 0 && (d.RateLimitCode < 400 || d.RateLimitCode > 499) {
+		return nil, fmt.Errorf("rate limit code must be a 4xx http status code")
+	}
+
+	if d.DripInterval < 0 {
+		return nil, fmt.Errorf("drip interval must be a positive duration")
+	}
+
+	if (d.DripBytesPerInterval > 0) != (d.DripInterval > 0) {
+		return nil, fmt.Errorf("drip bytes per interval and drip interval must both be specified")
+	}
+
+	if d.HashHeader != "" && !httpguts.ValidHeaderFieldName(d.HashHeader) {
+		return nil, fmt.Errorf("invalid hash header name %q", d.HashHeader)
+	}
+
+	for header := range d.Headers {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return nil, fmt.Errorf("invalid match header name %q", header)
+		}
+	}
+
+	for _, response := range d.Responses {
+		if response.Code < 100 || response.Code > 599 {
+			return nil, fmt.Errorf("canned response code must be a valid http status code: %d", response.Code)
+		}
+
+		for header := range response.Headers {
+			if !httpguts.ValidHeaderFieldName(header) {
+				return nil, fmt.Errorf("invalid canned response header name %q", header)
+			}
+		}
+	}
+
+	for _, window := range d.Windows {
+		if err := validateWindow(window); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rule := range d.Rules {
+		if err := validateRule(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.FaultRate < 0.0 || d.FaultRate > 1.0 || d.ErrorShare < 0.0 || d.ErrorShare > 1.0 {
+		return nil, fmt.Errorf("fault rate and error share must be in the range [0.0, 1.0]")
+	}
+
+	if d.FaultRate > 0.0 && d.ErrorShare > 0.0 && d.ErrorCode == 0 && len(d.ErrorCodes) == 0 && len(d.Responses) == 0 {
 		return nil, fmt.Errorf("error code must be a valid http error code")
 	}
 
+	if d.BufferSize < 0 {
+		return nil, fmt.Errorf("buffer size must be a positive number")
+	}
+
+	if d.GracePeriod < 0 {
+		return nil, fmt.Errorf("grace period must be a positive duration")
+	}
+
+	if d.HTTP2 && d.BufferSize > 0 {
+		return nil, fmt.Errorf("buffer size cannot be combined with http2")
+	}
+
 	upstreamURL, err := url.Parse(upstreamAddress)
 	if err != nil {
 		return nil, err
 	}
 
+	var excludedRegex *regexp.Regexp
+	if d.ExcludedRegex != "" {
+		// the expression must match the whole path
+		excludedRegex, err = regexp.Compile("^(?:" + d.ExcludedRegex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude regex %q: %w", d.ExcludedRegex, err)
+		}
+	}
+
 	metrics := protocol.NewMetricMap(supportedMetrics()...)
 
 	handler := &httpHandler{
 		upstreamURL: *upstreamURL,
 		disruption:  d,
 		metrics:     metrics,
+		limiter:     newLimiter(d.RateLimit),
+		random:      protocol.NewRandom(d.Seed),
+		excluded:    excludedRegex,
+		client:      newClient(d.BufferSize, d.Egress),
+	}
+
+	if d.HTTP2 {
+		handler.client = newHTTP2Client(d.Egress)
+	}
+
+	if d.BufferSize > 0 {
+		handler.buffers = &sync.Pool{
+			New: func() any {
+				buffer := make([]byte, d.BufferSize)
+				return &buffer
+			},
+		}
+	}
+
+	var srvHandler http.Handler = handler
+	if d.HTTP2 {
+		srvHandler = h2c.NewHandler(handler, &http2.Server{})
 	}
 
 	return &proxy{
 		listener:   listener,
 		disruption: d,
 		metrics:    metrics,
+		handler:    handler,
 		srv: &http.Server{
-			Handler: handler,
+			Handler: srvHandler,
 		},
 	}, nil
 }
 
+// validateWindow checks the attributes of a window are consistent
+func validateWindow(w Window) error {
+	if w.Duration <= 0 {
+		return fmt.Errorf("window duration must be positive")
+	}
+
+	if w.DelayVariation > w.AverageDelay {
+		return fmt.Errorf("window variation must be less that average delay")
+	}
+
+	if w.ErrorRate < 0.0 || w.ErrorRate > 1.0 {
+		return fmt.Errorf("window error rate must be in the range [0.0, 1.0]")
+	}
+
+	if w.ErrorRate > 0.0 && w.ErrorCode == 0 {
+		return fmt.Errorf("window error code must be a valid http error code")
+	}
+
+	return nil
+}
+
+// validateRule checks the attributes of a rule are consistent
+func validateRule(r Rule) error {
+	if !strings.HasPrefix(r.PathPrefix, "/") {
+		return fmt.Errorf("rule path prefix must start with '/': %q", r.PathPrefix)
+	}
+
+	if r.ErrorRate < 0.0 || r.ErrorRate > 1.0 {
+		return fmt.Errorf("rule error rate must be in the range [0.0, 1.0]")
+	}
+
+	if r.ErrorRate > 0.0 && r.ErrorCode == 0 {
+		return fmt.Errorf("rule error code must be a valid http error code")
+	}
+
+	return nil
+}
+
+// newLimiter returns a limiter that allows the given number of requests per second, or nil if the rate is zero.
+// The burst is set to the rate (with a minimum of one request) to allow a full second of requests at once.
+func newLimiter(limit float32) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+
+	burst := int(math.Ceil(float64(limit)))
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
 // httpHandler implements a http.Handler for disrupting request to a upstream server
 type httpHandler struct {
 	upstreamURL url.URL
 	disruption  Disruption
 	metrics     *protocol.MetricMap
+	// limiter throttles requests above the rate limit. A nil limiter does not throttle requests.
+	limiter *rate.Limiter
+	// next is the number of canned responses returned, used for selecting the next one in turn
+	next atomic.Uint64
+	// started is the time the proxy started, used for selecting the current window
+	started time.Time
+	// random is the source for the random selection of delays and errors
+	random *protocol.Random
+	// client forwards the requests to the upstream URL
+	client *http.Client
+	// buffers is a pool of buffers for copying response bodies. A nil pool uses the default buffers.
+	buffers *sync.Pool
+	// excluded matches the paths of the requests excluded from disruptions. A nil expression does not match
+	// any path.
+	excluded *regexp.Regexp
+}
+
+// newClient returns a client for forwarding requests using buffers of the given size. A zero size returns the
+// default client, unless the client is used for egress traffic and its connections must be marked.
+func newClient(bufferSize int, egress bool) *http.Client {
+	if bufferSize == 0 && !egress {
+		return http.DefaultClient
+	}
+
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		ReadBufferSize:  bufferSize,
+		WriteBufferSize: bufferSize,
+	}
+	if egress {
+		transport.DialContext = protocol.MarkedDialer().DialContext
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// newHTTP2Client returns a client for forwarding requests using HTTP/2 over cleartext (h2c)
+func newHTTP2Client(egress bool) *http.Client {
+	dialer := &net.Dialer{}
+	if egress {
+		dialer = protocol.MarkedDialer()
+	}
+
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			// h2c connections are not encrypted, so the TLS configuration is ignored
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+// copyBody copies the body to the writer using a buffer from the pool, if any
+func (h *httpHandler) copyBody(w io.Writer, body io.Reader) error {
+	if h.buffers == nil {
+		_, err := io.Copy(w, body)
+		return err
+	}
+
+	buffer, _ := h.buffers.Get().(*[]byte)
+	defer h.buffers.Put(buffer)
+
+	// hide the writer's ReadFrom method, if any, as io.CopyBuffer would use it instead of the buffer
+	_, err := io.CopyBuffer(struct{ io.Writer }{w}, body, *buffer)
+	return err
+}
+
+// current returns the disruption in effect at the given time, applying the delay and errors of the
+// current window, if any
+func (h *httpHandler) current(now time.Time) Disruption {
+	d := h.disruption
+	if len(d.Windows) == 0 {
+		return d
+	}
+
+	elapsed := now.Sub(h.started)
+	window := d.Windows[len(d.Windows)-1]
+	for _, w := range d.Windows {
+		if elapsed < w.Duration {
+			window = w
+			break
+		}
+		elapsed -= w.Duration
+	}
+
+	d.AverageDelay = window.AverageDelay
+	d.DelayVariation = window.DelayVariation
+	d.ErrorRate = window.ErrorRate
+	d.ErrorCode = window.ErrorCode
+	d.ErrorCodes = nil
+
+	return d
+}
+
+// applyRules returns the disruption with the error rate and error code of the first rule matching the path,
+// if any rule is defined
+func applyRules(d Disruption, path string) Disruption {
+	if len(d.Rules) == 0 {
+		return d
+	}
+
+	d.ErrorRate = 0
+	d.ErrorCode = 0
+	d.ErrorCodes = nil
+	d.Responses = nil
+	for _, rule := range d.Rules {
+		if strings.HasPrefix(path, rule.PathPrefix) {
+			d.ErrorRate = rule.ErrorRate
+			d.ErrorCode = rule.ErrorCode
+			break
+		}
+	}
+
+	return d
 }
 
 // isExcluded checks whether a request should be proxied through without any kind of modification whatsoever.
@@ -96,21 +479,41 @@ func (h *httpHandler) isExcluded(r *http.Request) bool {
 		}
 	}
 
+	if h.excluded != nil && h.excluded.MatchString(r.URL.Path) {
+		return true
+	}
+
+	for header, value := range h.disruption.Headers {
+		if r.Header.Get(header) != value {
+			return true
+		}
+	}
+
 	return false
 }
 
 // forward forwards a request to the upstream URL.
 // Request is performed immediately, but response won't be sent before the duration specified in delay.
-func (h *httpHandler) forward(rw http.ResponseWriter, req *http.Request, delay time.Duration) {
+// If disruptBody is true, the request and response bodies are read at the read rate and the response body is
+// sent in chunks, as specified in the disruption.
+func (h *httpHandler) forward(rw http.ResponseWriter, req *http.Request, delay time.Duration, disruptBody bool) {
 	timer := time.After(delay)
 
+	throttle := disruptBody && h.disruption.ReadRateBytesPerSec > 0
+
 	upstreamReq := req.Clone(context.Background())
-	upstreamReq.Host = h.upstreamURL.Host
-	upstreamReq.URL.Host = h.upstreamURL.Host
+	if throttle && req.Body != nil && req.Body != http.NoBody {
+		upstreamReq.Body = &throttledReader{reader: req.Body, bytesPerSec: h.disruption.ReadRateBytesPerSec}
+	}
+	// egress requests are forwarded to the host they are addressed to
+	if !h.disruption.Egress {
+		upstreamReq.Host = h.upstreamURL.Host
+	}
+	upstreamReq.URL.Host = upstreamReq.Host
 	upstreamReq.URL.Scheme = h.upstreamURL.Scheme
 	upstreamReq.RequestURI = "" // It is an error to set this field in an HTTP client request.
 
-	response, err := http.DefaultClient.Do(upstreamReq)
+	response, err := h.client.Do(upstreamReq)
 	<-timer
 	if err != nil {
 		rw.WriteHeader(http.StatusBadGateway)
@@ -134,16 +537,192 @@ func (h *httpHandler) forward(rw http.ResponseWriter, req *http.Request, delay t
 	// Mirror status code.
 	rw.WriteHeader(response.StatusCode)
 
+	body := io.Reader(response.Body)
+	if throttle {
+		body = &throttledReader{reader: response.Body, bytesPerSec: h.disruption.ReadRateBytesPerSec}
+	}
+
+	if disruptBody && h.disruption.DripBytesPerInterval > 0 {
+		h.drip(rw, body)
+		return
+	}
+
 	// ignore errors writing body, nothing to do.
-	_, _ = io.Copy(rw, response.Body)
+	_ = h.copyBody(rw, body)
+}
+
+// throttledReader reads from a ReadCloser at most bytesPerSec bytes per second. Reads are limited in size, so the
+// bytes not read yet remain in the socket buffers and the sender is slowed down.
+type throttledReader struct {
+	reader      io.ReadCloser
+	bytesPerSec uint
+	start       time.Time
+	read        uint64
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	if uint(len(p)) > r.bytesPerSec {
+		p = p[:r.bytesPerSec]
+	}
+
+	n, err := r.reader.Read(p)
+	r.read += uint64(n)
+
+	// wait until the bytes read so far are within the rate
+	expected := time.Duration(float64(r.read) / float64(r.bytesPerSec) * float64(time.Second))
+	if wait := expected - time.Since(r.start); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	return n, err
+}
+
+func (r *throttledReader) Close() error {
+	return r.reader.Close()
+}
+
+// drip writes the body downstream in chunks of DripBytesPerInterval bytes, waiting DripInterval between them.
+func (h *httpHandler) drip(rw http.ResponseWriter, body io.Reader) {
+	flusher, _ := rw.(http.Flusher)
+	chunk := make([]byte, h.disruption.DripBytesPerInterval)
+	for {
+		n, err := io.ReadFull(body, chunk)
+		if n > 0 {
+			// stop on errors writing body, as the client is likely gone.
+			if _, werr := rw.Write(chunk[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		time.Sleep(h.disruption.DripInterval)
+	}
+}
+
+// truncatedWriter writes at most limit bytes of the response body downstream and then aborts the response,
+// closing the connection. Responses with a shorter body are not affected.
+type truncatedWriter struct {
+	http.ResponseWriter
+	limit   uint
+	written uint
+}
+
+func (w *truncatedWriter) Write(p []byte) (int, error) {
+	if remaining := w.limit - w.written; uint(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.written += uint(n)
+	if err != nil || w.written < w.limit {
+		return n, err
+	}
+
+	// send the bytes written so far before closing the connection
+	w.Flush()
+
+	// aborts the handler without logging, closing the connection to the client
+	panic(http.ErrAbortHandler)
+}
+
+// Flush implements http.Flusher, as the body may be sent in chunks
+func (w *truncatedWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// truncate forwards the request and closes the connection once TruncateAfterBytes bytes of the response body have
+// been sent downstream, simulating a server that drops the connection mid-body.
+func (h *httpHandler) truncate(rw http.ResponseWriter, req *http.Request, d Disruption, delay time.Duration) {
+	//nolint:contextcheck // Unclear which context the linter requires us to propagate here.
+	h.forward(&truncatedWriter{ResponseWriter: rw, limit: d.TruncateAfterBytes}, req, delay, true)
 }
 
 // injectError waits sleeps the duration specified in delay and then writes the configured error downstream.
-func (h *httpHandler) injectError(rw http.ResponseWriter, delay time.Duration) {
+func (h *httpHandler) injectError(rw http.ResponseWriter, d Disruption, delay time.Duration) {
 	time.Sleep(delay)
 
-	rw.WriteHeader(int(h.disruption.ErrorCode))
-	_, _ = rw.Write([]byte(h.disruption.ErrorBody))
+	if len(d.Responses) == 0 {
+		rw.WriteHeader(int(h.errorCode(d)))
+		_, _ = rw.Write([]byte(d.ErrorBody))
+		return
+	}
+
+	n := h.next.Add(1) - 1
+	response := d.Responses[n%uint64(len(d.Responses))]
+	for name, value := range response.Headers {
+		rw.Header().Set(name, value)
+	}
+	rw.WriteHeader(int(response.Code))
+	_, _ = rw.Write([]byte(response.Body))
+}
+
+// errorCode returns the error code for a request selected to return an error. If weighted error codes are
+// defined, one of them is selected randomly with a probability proportional to its weight.
+func (h *httpHandler) errorCode(d Disruption) uint {
+	if len(d.ErrorCodes) == 0 {
+		return d.ErrorCode
+	}
+
+	total := float32(0)
+	for _, code := range d.ErrorCodes {
+		total += code.Weight
+	}
+
+	n := h.random.Float32() * total
+	for _, code := range d.ErrorCodes {
+		if n < code.Weight {
+			return code.Code
+		}
+		n -= code.Weight
+	}
+
+	// rounding errors may leave a remainder after the last code
+	return d.ErrorCodes[len(d.ErrorCodes)-1].Code
+}
+
+// selectForError decides if a request must return an error. If a hash header is defined, the decision is taken
+// by hashing the value of the header, so requests with the same value are always selected (or not).
+func (h *httpHandler) selectForError(req *http.Request, d Disruption) bool {
+	if d.HashHeader == "" {
+		return h.random.Float32() <= d.ErrorRate
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(req.Header.Get(d.HashHeader)))
+
+	return float32(hash.Sum32()%10000)/10000 < d.ErrorRate
+}
+
+// injectMix selects requests by the fault rate and either returns an error or delays each selected request,
+// according to the error share. Requests that are not selected are forwarded without delay.
+func (h *httpHandler) injectMix(rw http.ResponseWriter, req *http.Request, d Disruption, delay time.Duration) {
+	if h.random.Float32() >= d.FaultRate {
+		//nolint:contextcheck // Unclear which context the linter requires us to propagate here.
+		h.forward(rw, req, 0, true)
+		return
+	}
+
+	h.metrics.Inc(protocol.MetricRequestsDisrupted)
+
+	if h.random.Float32() < d.ErrorShare {
+		h.injectError(rw, d, 0)
+		return
+	}
+
+	//nolint:contextcheck // Unclear which context the linter requires us to propagate here.
+	h.forward(rw, req, delay, true)
 }
 
 func (h *httpHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -152,28 +731,59 @@ func (h *httpHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	if h.isExcluded(req) {
 		h.metrics.Inc(protocol.MetricRequestsExcluded)
 		//nolint:contextcheck // Unclear which context the linter requires us to propagate here.
-		h.forward(rw, req, 0)
+		h.forward(rw, req, 0, false)
 		return
 	}
 
-	delay := h.disruption.AverageDelay
-	if h.disruption.DelayVariation > 0 {
-		variation := int64(h.disruption.DelayVariation)
-		delay += time.Duration(variation - 2*rand.Int63n(variation))
+	// requests are not disrupted during the grace period
+	if time.Since(h.started) < h.disruption.GracePeriod {
+		//nolint:contextcheck // Unclear which context the linter requires us to propagate here.
+		h.forward(rw, req, 0, false)
+		return
 	}
 
-	if h.disruption.ErrorRate > 0 && rand.Float32() <= h.disruption.ErrorRate {
+	if h.limiter != nil && !h.limiter.Allow() {
 		h.metrics.Inc(protocol.MetricRequestsDisrupted)
-		h.injectError(rw, delay)
+		rw.WriteHeader(int(h.disruption.RateLimitCode))
+		return
+	}
+
+	d := applyRules(h.current(time.Now()), req.URL.Path)
+
+	delay := d.AverageDelay
+	if d.DelayVariation > 0 {
+		variation := int64(d.DelayVariation)
+		delay += time.Duration(variation - 2*h.random.Int63n(variation))
+	}
+
+	// without an average delay, the variation can result in a negative delay
+	if delay < 0 {
+		delay = 0
+	}
+
+	if d.FaultRate > 0 {
+		h.injectMix(rw, req, d, delay)
+		return
+	}
+
+	if d.ErrorRate > 0 && h.selectForError(req, d) {
+		h.metrics.Inc(protocol.MetricRequestsDisrupted)
+		if d.TruncateAfterBytes > 0 {
+			h.truncate(rw, req, d, delay)
+			return
+		}
+		h.injectError(rw, d, delay)
 		return
 	}
 
 	//nolint:contextcheck // Unclear which context the linter requires us to propagate here.
-	h.forward(rw, req, delay)
+	h.forward(rw, req, delay, true)
 }
 
 // Start starts the execution of the proxy
 func (p *proxy) Start() error {
+	p.handler.started = time.Now()
+
 	err := p.srv.Serve(p.listener)
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
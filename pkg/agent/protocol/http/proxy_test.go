@@ -2,15 +2,21 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/grafana/xk6-disruptor/pkg/agent/protocol"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func Test_Validations(t *testing.T) {
@@ -46,6 +52,118 @@ func Test_Validations(t *testing.T) {
 			upstream:    "",
 			expectError: true,
 		},
+		{
+			title: "truncate after bytes without error code",
+			disruption: Disruption{
+				ErrorRate:          0.1,
+				TruncateAfterBytes: 100,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "truncate after bytes with error code",
+			disruption: Disruption{
+				ErrorRate:          0.1,
+				ErrorCode:          500,
+				TruncateAfterBytes: 100,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "valid windows",
+			disruption: Disruption{
+				Windows: []Window{
+					{Duration: time.Minute, AverageDelay: 100 * time.Millisecond},
+					{Duration: time.Minute, ErrorRate: 0.1, ErrorCode: 500},
+				},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "valid weighted error codes",
+			disruption: Disruption{
+				ErrorRate:  0.1,
+				ErrorCodes: []WeightedCode{{Code: 500, Weight: 0.5}, {Code: 503, Weight: 0.5}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "weighted error code with invalid code",
+			disruption: Disruption{
+				ErrorRate:  0.1,
+				ErrorCodes: []WeightedCode{{Code: 500, Weight: 0.5}, {Code: 0, Weight: 0.5}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "weighted error code with zero weight",
+			disruption: Disruption{
+				ErrorRate:  0.1,
+				ErrorCodes: []WeightedCode{{Code: 500, Weight: 1}, {Code: 503, Weight: 0}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "valid exclude regex",
+			disruption: Disruption{
+				ErrorRate:     0.1,
+				ErrorCode:     500,
+				ExcludedRegex: "/internal/.*",
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "invalid exclude regex",
+			disruption: Disruption{
+				ErrorRate:     0.1,
+				ErrorCode:     500,
+				ExcludedRegex: "/internal/(.*",
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "window without duration",
+			disruption: Disruption{
+				Windows: []Window{{AverageDelay: 100 * time.Millisecond}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "window without error code",
+			disruption: Disruption{
+				Windows: []Window{{Duration: time.Minute, ErrorRate: 0.1}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "invalid canned response code",
+			disruption: Disruption{
+				ErrorRate: 0.1,
+				Responses: []CannedResponse{{Code: 1000}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "invalid hash header",
+			disruption: Disruption{
+				ErrorRate:  0.1,
+				ErrorCode:  500,
+				HashHeader: "X User Id",
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
 		{
 			title: "variation larger than average delay",
 			disruption: Disruption{
@@ -70,6 +188,18 @@ func Test_Validations(t *testing.T) {
 			upstream:    "http://127.0.0.1:80",
 			expectError: false,
 		},
+		{
+			title: "variation without average delay",
+			disruption: Disruption{
+				AverageDelay:   0,
+				DelayVariation: 200,
+				ErrorRate:      0.0,
+				ErrorCode:      0,
+				Excluded:       nil,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
 		{
 			title: "valid delay and variation",
 			disruption: Disruption{
@@ -94,6 +224,107 @@ func Test_Validations(t *testing.T) {
 			upstream:    "http://127.0.0.1:80",
 			expectError: true,
 		},
+		{
+			title: "valid rate limit",
+			disruption: Disruption{
+				RateLimit:     10,
+				RateLimitCode: 429,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "negative rate limit",
+			disruption: Disruption{
+				RateLimit:     -1,
+				RateLimitCode: 429,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "rate limit code not 4xx",
+			disruption: Disruption{
+				RateLimit:     10,
+				RateLimitCode: 500,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "valid drip",
+			disruption: Disruption{
+				DripBytesPerInterval: 10,
+				DripInterval:         100 * time.Millisecond,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "drip interval not specified",
+			disruption: Disruption{
+				DripBytesPerInterval: 10,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "valid rules",
+			disruption: Disruption{
+				Rules: []Rule{
+					{PathPrefix: "/api/", ErrorRate: 0.5, ErrorCode: 500},
+					{PathPrefix: "/health"},
+				},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "rule without path prefix",
+			disruption: Disruption{
+				Rules: []Rule{{ErrorRate: 0.5, ErrorCode: 500}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "rule without error code",
+			disruption: Disruption{
+				Rules: []Rule{{PathPrefix: "/api/", ErrorRate: 0.5}},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "valid fault mix",
+			disruption: Disruption{
+				AverageDelay: 100 * time.Millisecond,
+				ErrorCode:    500,
+				FaultRate:    0.5,
+				ErrorShare:   0.7,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "fault mix error share larger than 1",
+			disruption: Disruption{
+				ErrorCode:  500,
+				FaultRate:  0.5,
+				ErrorShare: 1.5,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "fault mix without error code",
+			disruption: Disruption{
+				FaultRate:  0.5,
+				ErrorShare: 0.7,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
 		{
 			title: "negative error rate",
 			disruption: Disruption{
@@ -106,6 +337,39 @@ func Test_Validations(t *testing.T) {
 			upstream:    "http://127.0.0.1:80",
 			expectError: true,
 		},
+		{
+			title: "invalid match header",
+			disruption: Disruption{
+				Headers: map[string]string{"X Canary": "true"},
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "valid buffer size",
+			disruption: Disruption{
+				BufferSize: 256 * 1024,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: false,
+		},
+		{
+			title: "negative buffer size",
+			disruption: Disruption{
+				BufferSize: -1,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
+		{
+			title: "buffer size with http2",
+			disruption: Disruption{
+				BufferSize: 256 * 1024,
+				HTTP2:      true,
+			},
+			upstream:    "http://127.0.0.1:80",
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -143,6 +407,7 @@ func Test_ProxyHandler(t *testing.T) {
 		disruption      Disruption
 		method          string
 		path            string
+		headers         http.Header
 		statusCode      int
 		upstreamHeaders http.Header
 		upstreamBody    []byte
@@ -167,6 +432,18 @@ func Test_ProxyHandler(t *testing.T) {
 			expectedStatus: 200,
 			expectedBody:   []byte("content body"),
 		},
+		{
+			title: "Body is dripped",
+			disruption: Disruption{
+				DripBytesPerInterval: 5,
+				DripInterval:         time.Millisecond,
+			},
+			path:           "",
+			statusCode:     200,
+			upstreamBody:   []byte("content body"),
+			expectedStatus: 200,
+			expectedBody:   []byte("content body"),
+		},
 		{
 			title: "Error code 500",
 			disruption: Disruption{
@@ -182,6 +459,74 @@ func Test_ProxyHandler(t *testing.T) {
 			expectedStatus: 500,
 			expectedBody:   []byte(""),
 		},
+		{
+			title: "Request matching headers",
+			disruption: Disruption{
+				ErrorRate: 1.0,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X-Canary": "true"},
+			},
+			path:           "",
+			headers:        http.Header{"X-Canary": []string{"true"}},
+			statusCode:     200,
+			upstreamBody:   []byte("content body"),
+			expectedStatus: 500,
+			expectedBody:   []byte(""),
+		},
+		{
+			title: "Request not matching headers",
+			disruption: Disruption{
+				ErrorRate: 1.0,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X-Canary": "true"},
+			},
+			path:           "",
+			headers:        http.Header{"X-Canary": []string{"false"}},
+			statusCode:     200,
+			upstreamBody:   []byte("content body"),
+			expectedStatus: 200,
+			expectedBody:   []byte("content body"),
+		},
+		{
+			title: "Request without headers",
+			disruption: Disruption{
+				ErrorRate: 1.0,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X-Canary": "true"},
+			},
+			path:           "",
+			statusCode:     200,
+			upstreamBody:   []byte("content body"),
+			expectedStatus: 200,
+			expectedBody:   []byte("content body"),
+		},
+		{
+			title: "Fault mix with only errors",
+			disruption: Disruption{
+				ErrorCode:  500,
+				FaultRate:  1.0,
+				ErrorShare: 1.0,
+			},
+			path:           "",
+			statusCode:     200,
+			upstreamBody:   []byte("content body"),
+			expectedStatus: 500,
+			expectedBody:   []byte(""),
+		},
+		{
+			title: "Fault mix with only delays",
+			disruption: Disruption{
+				AverageDelay: 10 * time.Millisecond,
+				ErrorCode:    500,
+				FaultRate:    1.0,
+				ErrorShare:   0.0,
+			},
+			path:           "",
+			statusCode:     200,
+			upstreamBody:   []byte("content body"),
+			expectedStatus: 200,
+			expectedBody:   []byte("content body"),
+		},
 		{
 			title: "Exclude path",
 			disruption: Disruption{
@@ -307,6 +652,7 @@ func Test_ProxyHandler(t *testing.T) {
 				upstreamURL: *upstreamURL,
 				disruption:  tc.disruption,
 				metrics:     protocol.NewMetricMap(supportedMetrics()...),
+				client:      http.DefaultClient,
 			}
 
 			proxyServer := httptest.NewServer(handler)
@@ -315,6 +661,7 @@ func Test_ProxyHandler(t *testing.T) {
 			if err != nil {
 				t.Fatalf("building request to proxy: %v", err)
 			}
+			req.Header = tc.headers
 
 			resp, err := http.DefaultClient.Do(req)
 			if err != nil {
@@ -347,6 +694,567 @@ func Test_ProxyHandler(t *testing.T) {
 	}
 }
 
+func Test_CannedResponses(t *testing.T) {
+	t.Parallel()
+
+	handler := &httpHandler{
+		disruption: Disruption{
+			ErrorRate: 1.0,
+			Responses: []CannedResponse{
+				{Code: 500, Body: "internal error"},
+				{Code: 503, Headers: map[string]string{"Retry-After": "1"}},
+			},
+		},
+		metrics: protocol.NewMetricMap(supportedMetrics()...),
+	}
+
+	proxyServer := httptest.NewServer(handler)
+	defer proxyServer.Close()
+
+	expected := []struct {
+		status int
+		body   string
+		header string
+	}{
+		{status: 500, body: "internal error"},
+		{status: 503, header: "1"},
+		{status: 500, body: "internal error"},
+	}
+
+	for i, e := range expected {
+		resp, err := http.Get(proxyServer.URL)
+		if err != nil {
+			t.Fatalf("making request to proxy: %v", err)
+		}
+
+		var body bytes.Buffer
+		_, _ = io.Copy(&body, resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != e.status {
+			t.Fatalf("request %d: expected status %d got %d", i, e.status, resp.StatusCode)
+		}
+
+		if body.String() != e.body {
+			t.Fatalf("request %d: expected body %q got %q", i, e.body, body.String())
+		}
+
+		if resp.Header.Get("Retry-After") != e.header {
+			t.Fatalf("request %d: expected header %q got %q", i, e.header, resp.Header.Get("Retry-After"))
+		}
+	}
+}
+
+func Test_WeightedErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	handler := &httpHandler{
+		disruption: Disruption{
+			ErrorRate: 1.0,
+			ErrorCodes: []WeightedCode{
+				{Code: 500, Weight: 0.7},
+				{Code: 503, Weight: 0.3},
+			},
+		},
+		metrics: protocol.NewMetricMap(supportedMetrics()...),
+		random:  protocol.NewRandom(42),
+	}
+
+	returned := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		returned[recorder.Code]++
+	}
+
+	if len(returned) != 2 {
+		t.Fatalf("expected only the weighted error codes to be returned got %v", returned)
+	}
+
+	// allow a margin for the random selection
+	if returned[500] < 600 || returned[500] > 800 {
+		t.Fatalf("expected ~700 requests with code 500 got %d", returned[500])
+	}
+}
+
+func Test_TruncateAfterBytes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		upstreamBody []byte
+		expectedBody []byte
+		expectError  bool
+	}{
+		{
+			title:        "body longer than the limit",
+			upstreamBody: bytes.Repeat([]byte("a"), 1000),
+			expectedBody: bytes.Repeat([]byte("a"), 100),
+			expectError:  true,
+		},
+		{
+			title:        "body shorter than the limit",
+			upstreamBody: []byte("short body"),
+			expectedBody: []byte("short body"),
+			expectError:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			upstreamServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+				_, _ = rw.Write(tc.upstreamBody)
+			}))
+			t.Cleanup(upstreamServer.Close)
+
+			upstreamURL, err := url.Parse(upstreamServer.URL)
+			if err != nil {
+				t.Fatalf("error parsing httptest url")
+			}
+
+			handler := &httpHandler{
+				upstreamURL: *upstreamURL,
+				disruption: Disruption{
+					ErrorRate:          1.0,
+					TruncateAfterBytes: 100,
+				},
+				metrics: protocol.NewMetricMap(supportedMetrics()...),
+				client:  http.DefaultClient,
+			}
+
+			proxyServer := httptest.NewServer(handler)
+			t.Cleanup(proxyServer.Close)
+
+			resp, err := http.Get(proxyServer.URL)
+			if err != nil {
+				t.Fatalf("making request to proxy: %v", err)
+			}
+			// the connection is closed before the whole body is received
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if tc.expectError && err == nil {
+				t.Fatalf("expected the connection to be closed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(tc.expectedBody, body) {
+				t.Fatalf("expected body %q got %q", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func Test_HTTP2(t *testing.T) {
+	t.Parallel()
+
+	// the upstream only accepts HTTP/2 requests over cleartext
+	upstreamServer := httptest.NewServer(h2c.NewHandler(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.ProtoMajor != 2 {
+				rw.WriteHeader(http.StatusHTTPVersionNotSupported)
+				return
+			}
+			rw.WriteHeader(http.StatusOK)
+		}),
+		&http2.Server{},
+	))
+	t.Cleanup(upstreamServer.Close)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("error starting test proxy listener: %v", err)
+	}
+
+	p, err := NewProxy(listener, upstreamServer.URL, Disruption{HTTP2: true})
+	if err != nil {
+		t.Fatalf("creating proxy: %v", err)
+	}
+
+	go func() {
+		_ = p.Start()
+	}()
+	t.Cleanup(func() {
+		_ = p.Force()
+	})
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("making request to proxy: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 response got %s", resp.Proto)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func Test_UpstreamOfRequests(t *testing.T) {
+	t.Parallel()
+
+	// each server responds with its name
+	servers := map[string]*httptest.Server{}
+	for _, name := range []string{"upstream", "service-a", "service-b"} {
+		name := name
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			_, _ = rw.Write([]byte(name))
+		}))
+		t.Cleanup(server.Close)
+		servers[name] = server
+	}
+
+	testCases := []struct {
+		title    string
+		egress   bool
+		requests []string
+		expected []string
+	}{
+		{
+			title:    "ingress requests are forwarded to the upstream",
+			egress:   false,
+			requests: []string{"service-a", "service-b"},
+			expected: []string{"upstream", "upstream"},
+		},
+		{
+			title:    "egress requests are forwarded to their host",
+			egress:   true,
+			requests: []string{"service-a", "service-b", "service-a"},
+			expected: []string{"service-a", "service-b", "service-a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := NewProxy(nil, servers["upstream"].URL, Disruption{Egress: tc.egress})
+			if err != nil {
+				t.Fatalf("creating proxy: %v", err)
+			}
+
+			handler := p.(*proxy).handler
+			// marking the connections requires the NET_ADMIN capability, which is tested in the protocol package
+			handler.client = http.DefaultClient
+
+			upstreams := []string{}
+			for _, service := range tc.requests {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Host = servers[service].Listener.Addr().String()
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code %d got %d: %s", http.StatusOK, recorder.Code, recorder.Body)
+				}
+
+				upstreams = append(upstreams, recorder.Body.String())
+			}
+
+			if diff := cmp.Diff(tc.expected, upstreams); diff != "" {
+				t.Fatalf("upstreams do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ExcludedRegex(t *testing.T) {
+	t.Parallel()
+
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(upstreamServer.Close)
+
+	p, err := NewProxy(nil, upstreamServer.URL, Disruption{
+		ErrorRate:     1.0,
+		ErrorCode:     500,
+		Excluded:      []string{"/health"},
+		ExcludedRegex: "/internal/.*",
+	})
+	if err != nil {
+		t.Fatalf("creating proxy: %v", err)
+	}
+
+	handler := p.(*proxy).handler
+
+	testCases := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: "/health", expectedStatus: http.StatusOK},
+		{path: "/internal/metrics", expectedStatus: http.StatusOK},
+		{path: "/internal/", expectedStatus: http.StatusOK},
+		// the expression must match the whole path
+		{path: "/api/internal/metrics", expectedStatus: http.StatusInternalServerError},
+		{path: "/api", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("%s: expected status code %d got %d", tc.path, tc.expectedStatus, recorder.Code)
+		}
+	}
+}
+
+func Test_GracePeriod(t *testing.T) {
+	t.Parallel()
+
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(upstreamServer.Close)
+
+	upstreamURL, err := url.Parse(upstreamServer.URL)
+	if err != nil {
+		t.Fatalf("error parsing httptest url")
+	}
+
+	testCases := []struct {
+		title          string
+		started        time.Time
+		expectedStatus int
+	}{
+		{
+			title:          "during grace period",
+			started:        time.Now(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			title:          "after grace period",
+			started:        time.Now().Add(-2 * time.Minute),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &httpHandler{
+				upstreamURL: *upstreamURL,
+				disruption: Disruption{
+					ErrorRate:   1.0,
+					ErrorCode:   500,
+					GracePeriod: time.Minute,
+				},
+				metrics: protocol.NewMetricMap(supportedMetrics()...),
+				started: tc.started,
+				client:  http.DefaultClient,
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("expected status code %d got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+func Test_HashHeaderSelection(t *testing.T) {
+	t.Parallel()
+
+	handler := &httpHandler{
+		disruption: Disruption{
+			ErrorRate:  0.5,
+			ErrorCode:  500,
+			HashHeader: "X-User-Id",
+		},
+	}
+
+	selected := 0
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User-Id", fmt.Sprintf("user-%d", i))
+
+		first := handler.selectForError(req, handler.disruption)
+		// the same request must always have the same selection
+		for j := 0; j < 10; j++ {
+			if handler.selectForError(req, handler.disruption) != first {
+				t.Fatalf("selection of request with header %q is not deterministic", req.Header.Get("X-User-Id"))
+			}
+		}
+
+		if first {
+			selected++
+		}
+	}
+
+	if selected == 0 || selected == 100 {
+		t.Fatalf("expected a fraction of requests to be selected, %d of 100 were selected", selected)
+	}
+}
+
+func Test_Windows(t *testing.T) {
+	t.Parallel()
+
+	started := time.Now()
+	handler := &httpHandler{
+		disruption: Disruption{
+			ErrorBody: "internal error",
+			Windows: []Window{
+				{Duration: time.Minute, AverageDelay: 100 * time.Millisecond},
+				{Duration: time.Minute, AverageDelay: 200 * time.Millisecond, ErrorRate: 0.5, ErrorCode: 500},
+			},
+		},
+		started: started,
+	}
+
+	testCases := []struct {
+		title    string
+		elapsed  time.Duration
+		expected Window
+	}{
+		{
+			title:    "first window",
+			elapsed:  30 * time.Second,
+			expected: handler.disruption.Windows[0],
+		},
+		{
+			title:    "second window",
+			elapsed:  90 * time.Second,
+			expected: handler.disruption.Windows[1],
+		},
+		{
+			title:    "after last window",
+			elapsed:  3 * time.Minute,
+			expected: handler.disruption.Windows[1],
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			d := handler.current(started.Add(tc.elapsed))
+			if d.AverageDelay != tc.expected.AverageDelay ||
+				d.ErrorRate != tc.expected.ErrorRate ||
+				d.ErrorCode != tc.expected.ErrorCode {
+				t.Fatalf("expected disruption of window %+v got %+v", tc.expected, d)
+			}
+
+			// attributes not defined by the windows are not modified
+			if d.ErrorBody != handler.disruption.ErrorBody {
+				t.Fatalf("expected error body %q got %q", handler.disruption.ErrorBody, d.ErrorBody)
+			}
+		})
+	}
+}
+
+func Test_Rules(t *testing.T) {
+	t.Parallel()
+
+	disruption := Disruption{
+		ErrorBody: "internal error",
+		Rules: []Rule{
+			{PathPrefix: "/api/v1/", ErrorRate: 1.0, ErrorCode: 503},
+			{PathPrefix: "/api/", ErrorRate: 0.5, ErrorCode: 500},
+		},
+	}
+
+	testCases := []struct {
+		title        string
+		path         string
+		expectedRate float32
+		expectedCode uint
+	}{
+		{
+			title:        "first matching rule",
+			path:         "/api/v1/users",
+			expectedRate: 1.0,
+			expectedCode: 503,
+		},
+		{
+			title:        "second matching rule",
+			path:         "/api/v2/users",
+			expectedRate: 0.5,
+			expectedCode: 500,
+		},
+		{
+			title:        "no matching rule",
+			path:         "/health",
+			expectedRate: 0,
+			expectedCode: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			d := applyRules(disruption, tc.path)
+			if d.ErrorRate != tc.expectedRate || d.ErrorCode != tc.expectedCode {
+				t.Fatalf("expected error rate %f and code %d got %f and %d",
+					tc.expectedRate, tc.expectedCode, d.ErrorRate, d.ErrorCode)
+			}
+
+			// attributes not defined by the rules are not modified
+			if d.ErrorBody != disruption.ErrorBody {
+				t.Fatalf("expected error body %q got %q", disruption.ErrorBody, d.ErrorBody)
+			}
+		})
+	}
+}
+
+func Test_ThrottledReader(t *testing.T) {
+	t.Parallel()
+
+	body := bytes.Repeat([]byte("x"), 50)
+	reader := &throttledReader{
+		reader:      io.NopCloser(bytes.NewReader(body)),
+		bytesPerSec: 100,
+	}
+
+	start := time.Now()
+	read, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if !bytes.Equal(read, body) {
+		t.Fatalf("expected %q got %q", body, read)
+	}
+
+	// reading 50 bytes at 100 bytes per second takes at least 500ms
+	if elapsed < 500*time.Millisecond {
+		t.Fatalf("body was read in %s, faster than the read rate", elapsed)
+	}
+}
+
 // TODO: This test covers metrics generated by the handler, but not the proxy. The reason for this is that the proxy is
 // currently not easily testable, as it coupled with `http.ListenAndServe`.
 func Test_Metrics(t *testing.T) {
@@ -380,6 +1288,19 @@ func Test_Metrics(t *testing.T) {
 				protocol.MetricRequestsDisrupted: 1,
 			},
 		},
+		{
+			name: "rate limited requests",
+			config: Disruption{
+				RateLimit:     1,
+				RateLimitCode: http.StatusTooManyRequests,
+			},
+			endpoints: []string{"/included", "/included", "/included"},
+			expectedMetrics: map[string]uint{
+				protocol.MetricRequests:          3,
+				protocol.MetricRequestsExcluded:  0,
+				protocol.MetricRequestsDisrupted: 2,
+			},
+		},
 	} {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -400,6 +1321,8 @@ func Test_Metrics(t *testing.T) {
 				upstreamURL: *upstreamURL,
 				disruption:  tc.config,
 				metrics:     metrics,
+				limiter:     newLimiter(tc.config.RateLimit),
+				client:      http.DefaultClient,
 			}
 
 			proxyServer := httptest.NewServer(handler)
@@ -417,3 +1340,51 @@ func Test_Metrics(t *testing.T) {
 		})
 	}
 }
+
+// Benchmark_ProxyBufferSize benchmarks the throughput of the proxy forwarding large responses with different
+// buffer sizes.
+func Benchmark_ProxyBufferSize(b *testing.B) {
+	body := bytes.Repeat([]byte("x"), 8*1024*1024)
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(body)
+	}))
+	b.Cleanup(upstreamServer.Close)
+
+	for _, bufferSize := range []int{0, 4 * 1024, 64 * 1024, 256 * 1024, 1024 * 1024} {
+		bufferSize := bufferSize
+
+		b.Run(fmt.Sprintf("buffer size %d", bufferSize), func(b *testing.B) {
+			listener, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				b.Fatalf("error starting test proxy listener: %v", err)
+			}
+
+			proxy, err := NewProxy(listener, upstreamServer.URL, Disruption{BufferSize: bufferSize})
+			if err != nil {
+				b.Fatalf("error creating proxy: %v", err)
+			}
+
+			go func() {
+				_ = proxy.Start()
+			}()
+			b.Cleanup(func() {
+				_ = proxy.Stop()
+			})
+
+			proxyURL := "http://" + listener.Addr().String()
+
+			b.SetBytes(int64(len(body)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				resp, err := http.Get(proxyURL)
+				if err != nil {
+					b.Fatalf("making request: %v", err)
+				}
+
+				_, _ = io.Copy(io.Discard, resp.Body)
+				_ = resp.Body.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+// +build linux
+
+package protocol
+
+import (
+	"net"
+	"syscall"
+)
+
+// MarkedDialer returns a dialer that sets the EgressMark on the connections it opens
+func MarkedDialer() *net.Dialer {
+	return &net.Dialer{
+		Control: func(_, _ string, conn syscall.RawConn) error {
+			var markErr error
+			err := conn.Control(func(fd uintptr) {
+				markErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, EgressMark)
+			})
+			if err != nil {
+				return err
+			}
+
+			return markErr
+		},
+	}
+}
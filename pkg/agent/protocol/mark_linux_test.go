@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func Test_MarkedDialer(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error starting listener: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	conn, err := MarkedDialer().DialContext(context.TODO(), "tcp", listener.Addr().String())
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("marking connections requires the NET_ADMIN capability")
+	}
+	if err != nil {
+		t.Fatalf("error dialing: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("error accessing connection: %v", err)
+	}
+
+	var mark int
+	var markErr error
+	err = rawConn.Control(func(fd uintptr) {
+		mark, markErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK)
+	})
+	if err != nil {
+		t.Fatalf("error accessing connection: %v", err)
+	}
+	if markErr != nil {
+		t.Fatalf("error getting the mark of the connection: %v", markErr)
+	}
+
+	if mark != EgressMark {
+		t.Fatalf("expected mark %#x got %#x", EgressMark, mark)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// MarkedDialer is only supported in linux
+// This implementation returns a dialer that fails to open connections, so the agent can be built in other platforms
+func MarkedDialer() *net.Dialer {
+	return &net.Dialer{
+		Control: func(_, _ string, _ syscall.RawConn) error {
+			return fmt.Errorf("marking connections is only supported in linux")
+		},
+	}
+}
@@ -45,12 +45,24 @@ const (
 	MetricRequestsDisrupted = "requests_disrupted"
 )
 
+// DefaultStopGracePeriod is the default time given to the proxy for draining in-flight requests when the disruption
+// ends, before remaining connections are forcefully closed.
+const DefaultStopGracePeriod = 5 * time.Second
+
+// DisruptorOptions defines options that control the behavior of the protocol Disruptor
+type DisruptorOptions struct {
+	// StopGracePeriod is the maximum time the proxy is given to drain in-flight requests when the disruption ends.
+	// Once it expires, any remaining connection is forcefully closed. A zero value closes them immediately.
+	StopGracePeriod time.Duration
+}
+
 // disruptor is an instance of a Disruptor that applies a disruption
 // to a target
 type disruptor struct {
 	proxy      Proxy
 	redirector TrafficRedirector
 	executor   runtime.Executor
+	options    DisruptorOptions
 }
 
 // NewDisruptor creates a new instance of a Disruptor that applies a disruptions to a target
@@ -59,15 +71,21 @@ func NewDisruptor(
 	executor runtime.Executor,
 	proxy Proxy,
 	redirector TrafficRedirector,
+	options DisruptorOptions,
 ) (agent.Disruptor, error) {
 	if proxy == nil {
 		return nil, fmt.Errorf("proxy cannot be null")
 	}
 
+	if options.StopGracePeriod < 0 {
+		return nil, fmt.Errorf("stop grace period cannot be negative")
+	}
+
 	return &disruptor{
 		proxy:      proxy,
 		executor:   executor,
 		redirector: redirector,
+		options:    options,
 	}, nil
 }
 
@@ -82,10 +100,9 @@ func (d *disruptor) Apply(ctx context.Context, duration time.Duration) error {
 		wc <- d.proxy.Start()
 	}()
 
-	// On termination, restore traffic and stop proxy
-	defer func() {
-		_ = d.proxy.Stop()
-	}()
+	// On termination, restore traffic and stop proxy. As deferred functions run in reverse order, the redirection
+	// is removed before the proxy starts draining, so no new requests reach it while in-flight ones complete.
+	defer d.stopProxy()
 
 	if err := d.redirector.Start(); err != nil {
 		return fmt.Errorf(" failed traffic redirection: %w", err)
@@ -115,6 +132,21 @@ func (d *disruptor) Apply(ctx context.Context, duration time.Duration) error {
 	}
 }
 
+// stopProxy stops the proxy gracefully, giving in-flight requests up to the StopGracePeriod to complete.
+// If the proxy has not stopped when the grace period expires, remaining connections are forcefully closed.
+func (d *disruptor) stopProxy() {
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- d.proxy.Stop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(d.options.StopGracePeriod):
+		_ = d.proxy.Force()
+	}
+}
+
 // noop is a no-op traffic redirector
 type noop struct{}
 
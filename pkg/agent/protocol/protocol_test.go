@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/runtime"
+)
+
+// fakeProxy is a Proxy that takes stopDelay to stop gracefully unless forced
+type fakeProxy struct {
+	stopDelay time.Duration
+	done      chan struct{}
+	forced    atomic.Bool
+}
+
+func newFakeProxy(stopDelay time.Duration) *fakeProxy {
+	return &fakeProxy{
+		stopDelay: stopDelay,
+		done:      make(chan struct{}),
+	}
+}
+
+func (p *fakeProxy) Start() error {
+	<-p.done
+	return nil
+}
+
+func (p *fakeProxy) Stop() error {
+	time.Sleep(p.stopDelay)
+	return nil
+}
+
+func (p *fakeProxy) Force() error {
+	p.forced.Store(true)
+	close(p.done)
+	return nil
+}
+
+func (p *fakeProxy) Metrics() map[string]uint {
+	return nil
+}
+
+func Test_StopGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		stopDelay   time.Duration
+		gracePeriod time.Duration
+		expectForce bool
+	}{
+		{
+			title:       "Proxy stops within grace period",
+			stopDelay:   0,
+			gracePeriod: 5 * time.Second,
+			expectForce: false,
+		},
+		{
+			title:       "Proxy forced after grace period",
+			stopDelay:   5 * time.Second,
+			gracePeriod: 100 * time.Millisecond,
+			expectForce: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			proxy := newFakeProxy(tc.stopDelay)
+			disruptor, err := NewDisruptor(
+				runtime.NewFakeExecutor(nil, nil),
+				proxy,
+				NoopTrafficRedirector(),
+				DisruptorOptions{StopGracePeriod: tc.gracePeriod},
+			)
+			if err != nil {
+				t.Fatalf("creating disruptor: %v", err)
+			}
+
+			err = disruptor.Apply(context.TODO(), time.Second)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if proxy.forced.Load() != tc.expectForce {
+				t.Fatalf("expected forced stop to be %t", tc.expectForce)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Random is a source of random numbers that is safe for concurrent use. A nil Random uses the global source.
+type Random struct {
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+// NewRandom returns a Random that generates a reproducible sequence from the given seed.
+// A zero seed returns a nil Random, which uses the global source.
+func NewRandom(seed int64) *Random {
+	if seed == 0 {
+		return nil
+	}
+
+	return &Random{
+		rand: rand.New(rand.NewSource(seed)), //nolint:gosec // not used for cryptographic purposes
+	}
+}
+
+// Float32 returns a number in the range [0.0, 1.0)
+func (r *Random) Float32() float32 {
+	if r == nil {
+		return rand.Float32()
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.rand.Float32()
+}
+
+// Int63n returns a number in the range [0, n)
+func (r *Random) Int63n(n int64) int64 {
+	if r == nil {
+		return rand.Int63n(n)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.rand.Int63n(n)
+}
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func Test_Random(t *testing.T) {
+	t.Parallel()
+
+	sequence := func(r *Random) []int64 {
+		values := []int64{}
+		for i := 0; i < 10; i++ {
+			values = append(values, r.Int63n(1000000))
+		}
+		return values
+	}
+
+	first := sequence(NewRandom(42))
+	second := sequence(NewRandom(42))
+	other := sequence(NewRandom(43))
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("sequences with the same seed differ: %v %v", first, second)
+		}
+	}
+
+	equal := true
+	for i := range first {
+		equal = equal && first[i] == other[i]
+	}
+	if equal {
+		t.Fatalf("sequences with different seeds are equal: %v", first)
+	}
+
+	if NewRandom(0) != nil {
+		t.Fatalf("zero seed should use the global source")
+	}
+
+	// a nil Random uses the global source
+	var global *Random
+	if f := global.Float32(); f < 0 || f >= 1 {
+		t.Fatalf("unexpected value %f", f)
+	}
+}
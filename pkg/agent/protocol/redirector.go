@@ -13,8 +13,15 @@ type TrafficRedirectionSpec struct {
 	// RedirectPort is the port where the traffic should be redirected to.
 	// Typically, this would be where a transparent proxy is listening.
 	RedirectPort uint
+	// Egress redirects the traffic the pod sends to DestinationPort, instead of the traffic it receives.
+	// Connections marked with EgressMark, such as those opened by the proxy, are not redirected.
+	Egress bool
 }
 
+// EgressMark is the mark set on the connections opened by the proxy when egress traffic is redirected to it,
+// so they are not redirected back to the proxy.
+const EgressMark = 0x6b36
+
 // Redirector is an implementation of TrafficRedirector that uses iptables rules.
 type Redirector struct {
 	*TrafficRedirectionSpec
@@ -66,6 +73,10 @@ func NewTrafficRedirector(
 // | lo        | ! 127.0.0.0/8 | Proxy traffic          |
 // +-----------+---------------+------------------------+
 func (tr *Redirector) rules() []iptables.Rule {
+	if tr.Egress {
+		return tr.egressRules()
+	}
+
 	// redirectLocalRule is a netfilter rule that intercepts locally-originated traffic, such as that coming from sidecars
 	// or `kubectl port-forward, directed to the application and redirects it to the proxy.
 	// As per https://upload.wikimedia.org/wikipedia/commons/3/37/Netfilter-packet-flow.svg, locally originated traffic
@@ -129,6 +140,37 @@ func (tr *Redirector) rules() []iptables.Rule {
 	}
 }
 
+// egressRules returns the iptables rules that cause the traffic sent by the pod to the destination port to be
+// forwarded to the proxy.
+// The proxy opens its upstream connections with the EgressMark, which the rules use for excluding them.
+func (tr *Redirector) egressRules() []iptables.Rule {
+	// redirectEgressRule is a netfilter rule that intercepts locally-originated traffic directed to the destination
+	// port, on any address, and redirects it to the proxy.
+	redirectEgressRule := iptables.Rule{
+		Table: "nat",
+		Chain: "OUTPUT", // For locally-originated traffic
+		Args: fmt.Sprintf("-p tcp --dport %d ", tr.DestinationPort) + // Sent to the destination port
+			fmt.Sprintf("-m mark ! --mark %#x ", EgressMark) + // Not opened by the proxy
+			fmt.Sprintf("-j REDIRECT --to-port %d", tr.RedirectPort), // Forward it to the proxy address
+	}
+
+	// resetEgressRule is a netfilter rule that resets established connections (i.e. that have not been redirected)
+	// to the destination port, except those of the proxy itself.
+	resetEgressRule := iptables.Rule{
+		Table: "filter",
+		Chain: "OUTPUT", // For locally-originated traffic
+		Args: fmt.Sprintf("-p tcp --dport %d ", tr.DestinationPort) + // Sent to the destination port
+			fmt.Sprintf("-m mark ! --mark %#x ", EgressMark) + // Not opened by the proxy
+			"-m state --state ESTABLISHED " + // That are already ESTABLISHED, i.e. not before they are redirected
+			"-j REJECT --reject-with tcp-reset", // Reject it
+	}
+
+	return []iptables.Rule{
+		redirectEgressRule,
+		resetEgressRule,
+	}
+}
+
 // proxyResetRule returns a netfilter rule that rejects traffic to the proxy.
 // This rule is set up after injection finishes to kill any leftover connection to the proxy.
 // TODO: Run some tests to check if this is really necessary, as the proxy may already be killing conns on termination.
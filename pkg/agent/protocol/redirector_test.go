@@ -116,6 +116,26 @@ func Test_Commands(t *testing.T) {
 			fakeError:   nil,
 			fakeOutput:  []byte{},
 		},
+		{
+			title: "Start valid egress redirect",
+			redirect: TrafficRedirectionSpec{
+				DestinationPort: 80,
+				RedirectPort:    8080,
+				Egress:          true,
+			},
+			testFunction: func(tr TrafficRedirector) error {
+				return tr.Start()
+			},
+			//nolint:lll
+			expectedCmds: []string{
+				"iptables -t filter -D INPUT -p tcp --dport 8080 -j REJECT --reject-with tcp-reset",
+				"iptables -t nat -A OUTPUT -p tcp --dport 80 -m mark ! --mark 0x6b36 -j REDIRECT --to-port 8080",
+				"iptables -t filter -A OUTPUT -p tcp --dport 80 -m mark ! --mark 0x6b36 -m state --state ESTABLISHED -j REJECT --reject-with tcp-reset",
+			},
+			expectError: false,
+			fakeError:   nil,
+			fakeOutput:  []byte{},
+		},
 		{
 			title: "Error invoking iptables command in Start",
 			redirect: TrafficRedirectionSpec{
@@ -14,6 +14,7 @@ import (
 	"github.com/grafana/sobek"
 	"github.com/grafana/xk6-disruptor/pkg/disruptors"
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
 	"go.k6.io/k6/js/common"
 )
 
@@ -57,6 +58,16 @@ func (p *jsDisruptor) Targets() sobek.Value {
 	return p.rt.ToValue(targets)
 }
 
+// TargetsDetailed is a proxy method. Delegates to the Disruptor method and returns the targets as JS objects
+func (p *jsDisruptor) TargetsDetailed() sobek.Value {
+	targets, err := p.Disruptor.TargetsDetailed(p.ctx)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error getting targets: %w", err))
+	}
+
+	return p.rt.ToValue(targets)
+}
+
 // jsProtocolFaultInjector implements the JS interface for jsProtocolFaultInjector
 type jsProtocolFaultInjector struct {
 	ctx context.Context // this context controls the object's lifecycle
@@ -154,10 +165,410 @@ func (p *jsPodFaultInjector) TerminatePods(args ...sobek.Value) {
 	}
 }
 
+// jsPortFault is the JS representation of a PortFault. The attributes of the fault depend on the protocol.
+type jsPortFault struct {
+	Port      intstr.IntOrString
+	Protocol  string
+	Fault     map[string]interface{}
+	ProxyPort uint
+}
+
+// jsPortFaultInjector implements the JS interface for PortFaultInjector
+type jsPortFaultInjector struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.PortFaultInjector
+}
+
+// InjectPortFaults is a proxy method. Validates parameters and delegates to the PortFaultInjector method
+func (p *jsPortFaultInjector) InjectPortFaults(args ...sobek.Value) {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("list of port faults and duration are required"))
+	}
+
+	jsFaults := []jsPortFault{}
+	err := convertValue(p.rt, args[0], &jsFaults)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid port faults argument: %w", err))
+	}
+
+	faults := make([]disruptors.PortFault, 0, len(jsFaults))
+	for _, jsFault := range jsFaults {
+		fault := disruptors.PortFault{
+			Port:      jsFault.Port,
+			Protocol:  jsFault.Protocol,
+			ProxyPort: jsFault.ProxyPort,
+		}
+
+		switch jsFault.Protocol {
+		case disruptors.ProtocolHTTP:
+			err = Convert(jsFault.Fault, &fault.HTTPFault)
+		case disruptors.ProtocolGrpc:
+			err = Convert(jsFault.Fault, &fault.GrpcFault)
+		default:
+			err = fmt.Errorf("unsupported protocol %q", jsFault.Protocol)
+		}
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid port faults argument: %w", err))
+		}
+
+		faults = append(faults, fault)
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	err = p.PortFaultInjector.InjectPortFaults(p.ctx, faults, duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error injecting fault: %w", err))
+	}
+}
+
+// jsTCPFaultInjector implements the JS interface for TCPFaultInjector
+type jsTCPFaultInjector struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.TCPFaultInjector
+}
+
+// InjectTCPFaults is a proxy method. Validates parameters and delegates to the TCPFaultInjector method
+func (p *jsTCPFaultInjector) InjectTCPFaults(args ...sobek.Value) {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("TCPFault and duration are required"))
+	}
+
+	fault := disruptors.TCPFault{}
+	err := convertValue(p.rt, args[0], &fault)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid fault argument: %w", err))
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	opts := disruptors.TCPDisruptionOptions{}
+	if len(args) > 2 {
+		err = convertValue(p.rt, args[2], &opts)
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid options argument: %w", err))
+		}
+	}
+
+	err = p.TCPFaultInjector.InjectTCPFaults(p.ctx, fault, duration, opts)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error injecting fault: %w", err))
+	}
+}
+
+// jsDNSFaultInjector implements the JS interface for DNSFaultInjector
+type jsDNSFaultInjector struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.DNSFaultInjector
+}
+
+// InjectDNSFaults is a proxy method. Validates parameters and delegates to the DNSFaultInjector method
+func (p *jsDNSFaultInjector) InjectDNSFaults(args ...sobek.Value) {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("DNSFault and duration are required"))
+	}
+
+	fault := disruptors.DNSFault{}
+	err := convertValue(p.rt, args[0], &fault)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid fault argument: %w", err))
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	opts := disruptors.DNSDisruptionOptions{}
+	if len(args) > 2 {
+		err = convertValue(p.rt, args[2], &opts)
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid options argument: %w", err))
+		}
+	}
+
+	err = p.DNSFaultInjector.InjectDNSFaults(p.ctx, fault, duration, opts)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error injecting fault: %w", err))
+	}
+}
+
+// jsBandwidthFaultInjector implements the JS interface for BandwidthFaultInjector
+type jsBandwidthFaultInjector struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.BandwidthFaultInjector
+}
+
+// InjectBandwidthFaults is a proxy method. Validates parameters and delegates to the BandwidthFaultInjector method
+func (p *jsBandwidthFaultInjector) InjectBandwidthFaults(args ...sobek.Value) {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("BandwidthFault and duration are required"))
+	}
+
+	fault := disruptors.BandwidthFault{}
+	err := convertValue(p.rt, args[0], &fault)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid fault argument: %w", err))
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	opts := disruptors.BandwidthDisruptionOptions{}
+	if len(args) > 2 {
+		err = convertValue(p.rt, args[2], &opts)
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid options argument: %w", err))
+		}
+	}
+
+	err = p.BandwidthFaultInjector.InjectBandwidthFaults(p.ctx, fault, duration, opts)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error injecting fault: %w", err))
+	}
+}
+
+// jsProcessFaultInjector implements the JS interface for ProcessFaultInjector
+type jsProcessFaultInjector struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.ProcessFaultInjector
+}
+
+// InjectProcessFaults is a proxy method. Validates parameters and delegates to the ProcessFaultInjector method
+func (p *jsProcessFaultInjector) InjectProcessFaults(args ...sobek.Value) {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("ProcessFault and duration are required"))
+	}
+
+	fault := disruptors.ProcessFault{}
+	err := convertValue(p.rt, args[0], &fault)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid fault argument: %w", err))
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	opts := disruptors.ProcessDisruptionOptions{}
+	if len(args) > 2 {
+		err = convertValue(p.rt, args[2], &opts)
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid options argument: %w", err))
+		}
+	}
+
+	err = p.ProcessFaultInjector.InjectProcessFaults(p.ctx, fault, duration, opts)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error injecting fault: %w", err))
+	}
+}
+
+// jsProber implements the JS interface for Prober
+type jsProber struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.Prober
+}
+
+// Probe is a proxy method. Validates parameters and delegates to the Prober method
+func (p *jsProber) Probe(args ...sobek.Value) {
+	if len(args) < 1 {
+		common.Throw(p.rt, fmt.Errorf("port is required"))
+	}
+
+	var port intstr.IntOrString
+	err := convertValue(p.rt, args[0], &port)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid port argument: %w", err))
+	}
+
+	err = p.Prober.Probe(p.ctx, port)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error probing targets: %w", err))
+	}
+}
+
+// jsStopper implements the JS interface for Stopper
+type jsStopper struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.Stopper
+}
+
+// Stop is a proxy method. Delegates to the Stopper method
+func (p *jsStopper) Stop() {
+	err := p.Stopper.Stop(p.ctx)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error stopping faults: %w", err))
+	}
+}
+
+// jsDryRunner implements the JS interface for DryRunner
+type jsDryRunner struct {
+	rt *sobek.Runtime
+	disruptors.DryRunner
+}
+
+// DryRunCommands is a proxy method. Delegates to the DryRunner method and returns the commands as a JS object
+func (p *jsDryRunner) DryRunCommands() sobek.Value {
+	return p.rt.ToValue(p.DryRunner.DryRunCommands())
+}
+
+// jsResolvedFaultsReporter implements the JS interface for ResolvedFaultsReporter
+type jsResolvedFaultsReporter struct {
+	rt *sobek.Runtime
+	disruptors.ResolvedFaultsReporter
+}
+
+// ResolvedFaults is a proxy method. Delegates to the ResolvedFaultsReporter method and returns the configurations
+// as a JS object
+func (p *jsResolvedFaultsReporter) ResolvedFaults() sobek.Value {
+	return p.rt.ToValue(p.ResolvedFaultsReporter.ResolvedFaults())
+}
+
+// jsWarningsReporter implements the JS interface for WarningsReporter
+type jsWarningsReporter struct {
+	rt *sobek.Runtime
+	disruptors.WarningsReporter
+}
+
+// Warnings is a proxy method. Delegates to the WarningsReporter method and returns the warnings as a JS object
+func (p *jsWarningsReporter) Warnings() sobek.Value {
+	return p.rt.ToValue(p.WarningsReporter.Warnings())
+}
+
+// jsStatusReporter implements the JS interface for StatusReporter
+type jsStatusReporter struct {
+	rt *sobek.Runtime
+	disruptors.StatusReporter
+}
+
+// Status is a proxy method. Delegates to the StatusReporter method and returns the status as a JS object
+func (p *jsStatusReporter) Status() sobek.Value {
+	status, err := p.StatusReporter.Status()
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error getting status: %w", err))
+	}
+
+	return p.rt.ToValue(map[string]interface{}{
+		"activeTargets": status.ActiveTargets,
+		"totalTargets":  status.TotalTargets,
+		"remaining":     status.Remaining.String(),
+	})
+}
+
+// jsCommandBuilder implements the JS interface for CommandBuilder
+type jsCommandBuilder struct {
+	ctx context.Context // this context controls the object's lifecycle
+	rt  *sobek.Runtime
+	disruptors.CommandBuilder
+}
+
+// BuildHTTPFaultCommand is a proxy method. Validates parameters and returns the command built by the
+// CommandBuilder method as a JS array
+func (p *jsCommandBuilder) BuildHTTPFaultCommand(args ...sobek.Value) sobek.Value {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("HTTPFault and duration are required"))
+	}
+
+	fault := disruptors.HTTPFault{}
+	err := convertValue(p.rt, args[0], &fault)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid fault argument: %w", err))
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	opts := disruptors.HTTPDisruptionOptions{}
+	if len(args) > 2 {
+		err = convertValue(p.rt, args[2], &opts)
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid options argument: %w", err))
+		}
+	}
+
+	cmd, err := p.CommandBuilder.BuildHTTPFaultCommand(p.ctx, fault, duration, opts)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error building command: %w", err))
+	}
+
+	return p.rt.ToValue(cmd)
+}
+
+// BuildGrpcFaultCommand is a proxy method. Validates parameters and returns the command built by the
+// CommandBuilder method as a JS array
+func (p *jsCommandBuilder) BuildGrpcFaultCommand(args ...sobek.Value) sobek.Value {
+	if len(args) < 2 {
+		common.Throw(p.rt, fmt.Errorf("GrpcFault and duration are required"))
+	}
+
+	fault := disruptors.GrpcFault{}
+	err := convertValue(p.rt, args[0], &fault)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid fault argument: %w", err))
+	}
+
+	var duration time.Duration
+	err = convertValue(p.rt, args[1], &duration)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("invalid duration argument: %w", err))
+	}
+
+	opts := disruptors.GrpcDisruptionOptions{}
+	if len(args) > 2 {
+		err = convertValue(p.rt, args[2], &opts)
+		if err != nil {
+			common.Throw(p.rt, fmt.Errorf("invalid options argument: %w", err))
+		}
+	}
+
+	cmd, err := p.CommandBuilder.BuildGrpcFaultCommand(p.ctx, fault, duration, opts)
+	if err != nil {
+		common.Throw(p.rt, fmt.Errorf("error building command: %w", err))
+	}
+
+	return p.rt.ToValue(cmd)
+}
+
 type jsPodDisruptor struct {
 	jsDisruptor
 	jsProtocolFaultInjector
 	jsPodFaultInjector
+	jsTCPFaultInjector
+	jsDNSFaultInjector
+	jsBandwidthFaultInjector
+	jsProcessFaultInjector
+	jsProber
+	jsStopper
+	jsDryRunner
+	jsCommandBuilder
+	jsStatusReporter
+	jsResolvedFaultsReporter
+	jsWarningsReporter
 }
 
 // buildJsPodDisruptor builds a goja object that implements the PodDisruptor API
@@ -182,6 +593,57 @@ func buildJsPodDisruptor(
 			rt:               rt,
 			PodFaultInjector: disruptor,
 		},
+		jsTCPFaultInjector: jsTCPFaultInjector{
+			ctx:              ctx,
+			rt:               rt,
+			TCPFaultInjector: disruptor,
+		},
+		jsDNSFaultInjector: jsDNSFaultInjector{
+			ctx:              ctx,
+			rt:               rt,
+			DNSFaultInjector: disruptor,
+		},
+		jsBandwidthFaultInjector: jsBandwidthFaultInjector{
+			ctx:                    ctx,
+			rt:                     rt,
+			BandwidthFaultInjector: disruptor,
+		},
+		jsProcessFaultInjector: jsProcessFaultInjector{
+			ctx:                  ctx,
+			rt:                   rt,
+			ProcessFaultInjector: disruptor,
+		},
+		jsProber: jsProber{
+			ctx:    ctx,
+			rt:     rt,
+			Prober: disruptor,
+		},
+		jsStopper: jsStopper{
+			ctx:     ctx,
+			rt:      rt,
+			Stopper: disruptor,
+		},
+		jsDryRunner: jsDryRunner{
+			rt:        rt,
+			DryRunner: disruptor,
+		},
+		jsCommandBuilder: jsCommandBuilder{
+			ctx:            ctx,
+			rt:             rt,
+			CommandBuilder: disruptor,
+		},
+		jsStatusReporter: jsStatusReporter{
+			rt:             rt,
+			StatusReporter: disruptor,
+		},
+		jsResolvedFaultsReporter: jsResolvedFaultsReporter{
+			rt:                     rt,
+			ResolvedFaultsReporter: disruptor,
+		},
+		jsWarningsReporter: jsWarningsReporter{
+			rt:               rt,
+			WarningsReporter: disruptor,
+		},
 	}
 
 	return buildObject(rt, d)
@@ -191,6 +653,10 @@ type jsServiceDisruptor struct {
 	jsDisruptor
 	jsProtocolFaultInjector
 	jsPodFaultInjector
+	jsPortFaultInjector
+	jsProber
+	jsResolvedFaultsReporter
+	jsWarningsReporter
 }
 
 // buildJsServiceDisruptor builds a goja object that implements the ServiceDisruptor API
@@ -215,6 +681,24 @@ func buildJsServiceDisruptor(
 			rt:               rt,
 			PodFaultInjector: disruptor,
 		},
+		jsPortFaultInjector: jsPortFaultInjector{
+			ctx:               ctx,
+			rt:                rt,
+			PortFaultInjector: disruptor,
+		},
+		jsProber: jsProber{
+			ctx:    ctx,
+			rt:     rt,
+			Prober: disruptor,
+		},
+		jsResolvedFaultsReporter: jsResolvedFaultsReporter{
+			rt:                     rt,
+			ResolvedFaultsReporter: disruptor,
+		},
+		jsWarningsReporter: jsWarningsReporter{
+			rt:               rt,
+			WarningsReporter: disruptor,
+		},
 	}
 
 	return buildObject(rt, d)
@@ -305,3 +789,62 @@ func NewServiceDisruptor(
 
 	return obj, nil
 }
+
+// ListDisruptableServices returns the services that can be disrupted in the namespaces passed as optional argument,
+// as a JS array. If no namespaces are passed, the services of all the namespaces are returned.
+func ListDisruptableServices(
+	ctx context.Context,
+	rt *sobek.Runtime,
+	k8s kubernetes.Kubernetes,
+	args ...sobek.Value,
+) (sobek.Value, error) {
+	namespaces := []string{}
+	if len(args) > 0 {
+		err := convertValue(rt, args[0], &namespaces)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaces argument: %w", err)
+		}
+	}
+
+	services, err := disruptors.ListDisruptableServices(ctx, k8s, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return rt.ToValue(services), nil
+}
+
+// ListInjectedPods returns the names of the pods with the agent injected in the namespace passed as argument,
+// as a JS array. The ListInjectedPodsOptions are an optional second argument.
+func ListInjectedPods(
+	ctx context.Context,
+	rt *sobek.Runtime,
+	k8s kubernetes.Kubernetes,
+	args ...sobek.Value,
+) (sobek.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("namespace argument is required")
+	}
+
+	var namespace string
+	err := convertValue(rt, args[0], &namespace)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace argument: %w", err)
+	}
+
+	options := disruptors.ListInjectedPodsOptions{}
+	// options argument is optional
+	if len(args) > 1 {
+		err = convertValue(rt, args[1], &options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ListInjectedPodsOptions: %w", err)
+		}
+	}
+
+	pods, err := disruptors.ListInjectedPods(ctx, k8s, namespace, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return rt.ToValue(pods), nil
+}
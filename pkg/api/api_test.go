@@ -67,6 +67,7 @@ func testSetup() (*testEnv, error) {
 		WithLabel("app", "app").
 		WithContainer(builders.NewContainerBuilder("main").
 			WithPort("http", 80).
+			WithPort("grpc", 3000).
 			WithImage("fake.registry.local/main").
 			Build(),
 		).
@@ -86,6 +87,10 @@ func testSetup() (*testEnv, error) {
 
 	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, agentContainer)
 
+	// process faults can only be injected in pods that share their process namespace
+	shareProcessNamespace := true
+	pod.Spec.ShareProcessNamespace = &shareProcessNamespace
+
 	_, err = k8s.Client().CoreV1().Pods(ns.Name).Create(context.TODO(), &pod, metav1.CreateOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("creating namespace: %w", err)
@@ -95,6 +100,7 @@ func testSetup() (*testEnv, error) {
 		WithNamespace(ns.Name).
 		WithSelectorLabel("app", "app").
 		WithPort("http", 80, intstr.FromString("http")).
+		WithPort("grpc", 3000, intstr.FromString("grpc")).
 		Build()
 
 	_, err = k8s.Client().CoreV1().Services(ns.Name).Create(context.TODO(), &svc, metav1.CreateOptions{})
@@ -254,6 +260,79 @@ func Test_JsPodDisruptor(t *testing.T) {
 			`,
 			expectError: false,
 		},
+		{
+			description: "get detailed targets",
+			script: `
+			const targets = JSON.stringify(d.targetsDetailed())
+			const expected = '[{"name":"some-pod","namespace":"namespace","node":"","ip":"192.0.2.6","labels":{"app":"app"}}]'
+			if (targets !== expected) {
+				throw new Error("expected " + expected + " got " + targets)
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "build HTTP fault command",
+			script: `
+			const fault = {
+				port: 80,
+				errorRate: 0.1,
+				errorCode: 500,
+			}
+
+			const cmd = JSON.stringify(d.buildHTTPFaultCommand(fault, "1m"))
+			const expected = JSON.stringify([
+				"xk6-disruptor-agent", "http", "-d", "60s", "-t", "80", "-r", "0.1", "-e", "500",
+				"-p", "8080", "--upstream-host", "192.0.2.6",
+			])
+			if (cmd !== expected) {
+				throw new Error("expected " + expected + " got " + cmd)
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "build gRPC fault command",
+			script: `
+			const fault = {
+				port: 3000,
+				errorRate: 0.1,
+				statusCode: 14,
+			}
+
+			const cmd = d.buildGrpcFaultCommand(fault, "1m")
+			if (cmd[1] !== "grpc" || cmd[cmd.indexOf("--upstream-host") + 1] !== "192.0.2.6") {
+				throw new Error("unexpected command " + JSON.stringify(cmd))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "build HTTP fault command with invalid fault",
+			script: `
+			const fault = {
+				port: 80,
+				errorRate: 1.5,
+			}
+
+			d.buildHTTPFaultCommand(fault, "1m")
+			`,
+			expectError: true,
+		},
+		{
+			description: "probe targets",
+			script: `
+			d.probe(80)
+			`,
+			expectError: false,
+		},
+		{
+			description: "probe targets without port",
+			script: `
+			d.probe()
+			`,
+			expectError: true,
+		},
 		{
 			description: "inject HTTP Fault with full arguments",
 			script: `
@@ -430,6 +509,159 @@ func Test_JsPodDisruptor(t *testing.T) {
 			`,
 			expectError: true,
 		},
+		{
+			description: "stop faults",
+			script: `
+			d.stop()
+			`,
+			expectError: false,
+		},
+		{
+			description: "dry run commands without dry run",
+			script: `
+			const commands = JSON.stringify(d.dryRunCommands())
+			if (commands !== '{}') {
+				throw new Error("expected no commands got " + commands)
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "resolved faults without fault injections",
+			script: `
+			const faults = JSON.stringify(d.resolvedFaults())
+			if (faults !== '{}') {
+				throw new Error("expected no resolved faults got " + faults)
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "warnings without fault injections",
+			script: `
+			const warnings = JSON.stringify(d.warnings())
+			if (warnings !== '{}') {
+				throw new Error("expected no warnings got " + warnings)
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "status without fault injections",
+			script: `
+			const status = d.status()
+			if (status.activeTargets !== 0 || status.totalTargets !== 0 || status.remaining !== "0s") {
+				throw new Error("unexpected status " + JSON.stringify(status))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "inject TCP Fault",
+			script: `
+			const fault = {
+				port: 80,
+				resetRate: 0.1,
+			}
+
+			d.injectTCPFaults(fault, "1m")
+			`,
+			expectError: false,
+		},
+		{
+			description: "inject TCP Fault without duration",
+			script: `
+			const fault = {
+				port: 80,
+				resetRate: 0.1,
+			}
+
+			d.injectTCPFaults(fault)
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject TCP Fault with invalid reset rate",
+			script: `
+			const fault = {
+				port: 80,
+				resetRate: 1.5,
+			}
+
+			d.injectTCPFaults(fault, "1m")
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject DNS Fault",
+			script: `
+			const fault = {
+				rate: 0.5,
+				domain: "example.com",
+			}
+
+			d.injectDNSFaults(fault, "1m", {seed: 42})
+			`,
+			expectError: false,
+		},
+		{
+			description: "inject DNS Fault with invalid rate",
+			script: `
+			const fault = {
+				rate: 1.5,
+			}
+
+			d.injectDNSFaults(fault, "1m")
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject Bandwidth Fault",
+			script: `
+			const fault = {
+				port: 80,
+				rate: "1mbit",
+			}
+
+			d.injectBandwidthFaults(fault, "1m")
+			`,
+			expectError: false,
+		},
+		{
+			description: "inject Bandwidth Fault with invalid rate",
+			script: `
+			const fault = {
+				port: 80,
+				rate: "fast",
+			}
+
+			d.injectBandwidthFaults(fault, "1m")
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject Process Fault",
+			script: `
+			const fault = {
+				signal: "STOP",
+				process: "app",
+			}
+
+			d.injectProcessFaults(fault, "1m")
+			`,
+			expectError: false,
+		},
+		{
+			description: "inject Process Fault with invalid signal",
+			script: `
+			const fault = {
+				signal: "KILL",
+			}
+
+			d.injectProcessFaults(fault, "1m")
+			`,
+			expectError: true,
+		},
 		{
 			description: "Terminate Pods (integer count)",
 			script: `
@@ -519,6 +751,143 @@ func Test_JsPodDisruptor(t *testing.T) {
 	}
 }
 
+const setupServiceDisruptor = `
+const d = new ServiceDisruptor("some-service", "namespace")
+`
+
+func Test_JsServiceDisruptor(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		description string
+		script      string
+		expectError bool
+	}{
+		{
+			description: "inject port faults",
+			script: `
+			const faults = [
+				{
+					port: 80,
+					protocol: "http",
+					fault: {
+						errorRate: 0.1,
+						errorCode: 500
+					}
+				},
+				{
+					port: "grpc",
+					protocol: "grpc",
+					proxyPort: 9000,
+					fault: {
+						averageDelay: "100ms"
+					}
+				}
+			]
+			d.injectPortFaults(faults, "1s")
+			`,
+			expectError: false,
+		},
+		{
+			description: "inject port faults without duration",
+			script: `
+			const faults = [
+				{
+					port: 80,
+					protocol: "http",
+					fault: {}
+				}
+			]
+			d.injectPortFaults(faults)
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject port faults with unsupported protocol",
+			script: `
+			const faults = [
+				{
+					port: 80,
+					protocol: "tcp",
+					fault: {}
+				}
+			]
+			d.injectPortFaults(faults, "1s")
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject port faults with invalid fault attribute",
+			script: `
+			const faults = [
+				{
+					port: "grpc",
+					protocol: "grpc",
+					fault: {
+						errorCode: 500
+					}
+				}
+			]
+			d.injectPortFaults(faults, "1s")
+			`,
+			expectError: true,
+		},
+		{
+			description: "inject port faults in port not exposed by service",
+			script: `
+			const faults = [
+				{
+					port: 8080,
+					protocol: "http",
+					fault: {}
+				}
+			]
+			d.injectPortFaults(faults, "1s")
+			`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			t.Parallel()
+
+			env, err := testSetup()
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			err = env.registerConstructor("ServiceDisruptor", func(e *testEnv, c sobek.ConstructorCall) (*sobek.Object, error) {
+				return NewServiceDisruptor(context.TODO(), e.rt, c, e.k8s)
+			})
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			_, err = env.rt.RunString(setupServiceDisruptor)
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			_, err = env.rt.RunString(tc.script)
+
+			if !tc.expectError && err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+		})
+	}
+}
+
 func Test_ServiceDisruptorConstructor(t *testing.T) {
 	t.Parallel()
 
@@ -623,3 +992,185 @@ func Test_ServiceDisruptorConstructor(t *testing.T) {
 		})
 	}
 }
+
+func Test_ListDisruptableServices(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		description string
+		script      string
+		expectError bool
+	}{
+		{
+			description: "all namespaces",
+			script: `
+			const services = listDisruptableServices()
+			if (services.length != 1 || services[0].name != "some-service" || services[0].readyPods != 1) {
+				throw new Error("unexpected services: " + JSON.stringify(services))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "selected namespaces",
+			script: `
+			const services = listDisruptableServices(["other-namespace"])
+			if (services.length != 0) {
+				throw new Error("unexpected services: " + JSON.stringify(services))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "invalid namespaces",
+			script: `
+			listDisruptableServices("namespace")
+			`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			t.Parallel()
+			env, err := testSetup()
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			endpoints := builders.NewEndPointsBuilder("some-service").
+				WithNamespace("namespace").
+				WithSubset("http", 80, []string{"some-pod"}).
+				Build()
+			_, err = env.client.CoreV1().Endpoints("namespace").Create(context.TODO(), &endpoints, metav1.CreateOptions{})
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			err = env.rt.Set("listDisruptableServices", func(args ...sobek.Value) sobek.Value {
+				services, err := ListDisruptableServices(context.TODO(), env.rt, env.k8s, args...)
+				if err != nil {
+					common.Throw(env.rt, err)
+				}
+				return services
+			})
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			_, err = env.rt.RunString(tc.script)
+
+			if !tc.expectError && err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+		})
+	}
+}
+
+func Test_ListInjectedPods(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		description string
+		script      string
+		expectError bool
+	}{
+		{
+			description: "injected pods",
+			script: `
+			const pods = listInjectedPods("namespace")
+			if (pods.length != 1 || pods[0] != "some-pod") {
+				throw new Error("unexpected pods: " + JSON.stringify(pods))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "namespace without injected pods",
+			script: `
+			const pods = listInjectedPods("other-namespace")
+			if (pods.length != 0) {
+				throw new Error("unexpected pods: " + JSON.stringify(pods))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "pods injected in another agent container",
+			script: `
+			const pods = listInjectedPods("namespace", { agentContainerName: "chaos-agent" })
+			if (pods.length != 0) {
+				throw new Error("unexpected pods: " + JSON.stringify(pods))
+			}
+			`,
+			expectError: false,
+		},
+		{
+			description: "invalid options",
+			script: `
+			listInjectedPods("namespace", { containerName: "chaos-agent" })
+			`,
+			expectError: true,
+		},
+		{
+			description: "missing namespace",
+			script: `
+			listInjectedPods()
+			`,
+			expectError: true,
+		},
+		{
+			description: "invalid namespace",
+			script: `
+			listInjectedPods(["namespace"])
+			`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			t.Parallel()
+			env, err := testSetup()
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			err = env.rt.Set("listInjectedPods", func(args ...sobek.Value) sobek.Value {
+				pods, err := ListInjectedPods(context.TODO(), env.rt, env.k8s, args...)
+				if err != nil {
+					common.Throw(env.rt, err)
+				}
+				return pods
+			})
+			if err != nil {
+				t.Errorf("error in test setup %v", err)
+				return
+			}
+
+			_, err = env.rt.RunString(tc.script)
+
+			if !tc.expectError && err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+		})
+	}
+}
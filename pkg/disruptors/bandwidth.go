@@ -0,0 +1,119 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BandwidthFaultInjector defines the methods for limiting the bandwidth of the traffic of the disruptor's targets
+type BandwidthFaultInjector interface {
+	// InjectBandwidthFaults limits the bandwidth of the traffic sent from the disruptor's targets for the
+	// specified duration
+	InjectBandwidthFaults(
+		ctx context.Context,
+		fault BandwidthFault,
+		duration time.Duration,
+		options BandwidthDisruptionOptions,
+	) error
+}
+
+// BandwidthFault specifies a limit to the bandwidth of the traffic sent from a port
+type BandwidthFault struct {
+	// port the limit will be applied to
+	Port intstr.IntOrString
+	// maximum rate of the traffic, as a number followed by a unit (e.g. "1mbit", "100kbps")
+	Rate string `js:"rate"`
+}
+
+// BandwidthDisruptionOptions defines options for the injection of bandwidth faults in a target pod
+type BandwidthDisruptionOptions struct{}
+
+// rateRegexp matches a rate as a number followed by a unit
+var rateRegexp = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([a-z]+)$`) //nolint:gochecknoglobals
+
+// rateUnits are the units of rate accepted by the agent
+var rateUnits = map[string]bool{ //nolint:gochecknoglobals
+	"bit": true, "kbit": true, "mbit": true, "gbit": true, "tbit": true,
+	"kibit": true, "mibit": true, "gibit": true, "tibit": true,
+	"bps": true, "kbps": true, "mbps": true, "gbps": true, "tbps": true,
+	"kibps": true, "mibps": true, "gibps": true, "tibps": true,
+}
+
+// validate checks the fault's attributes are consistent
+func (f BandwidthFault) validate() error {
+	if f.Port.IsNull() || f.Port.IsZero() {
+		return fmt.Errorf("port must be specified for bandwidth faults")
+	}
+
+	return validateRate(f.Rate)
+}
+
+// validateRate checks the rate is a positive number followed by a known unit
+func validateRate(rate string) error {
+	match := rateRegexp.FindStringSubmatch(strings.ToLower(rate))
+	if match == nil {
+		return fmt.Errorf("invalid rate %q: must be a number followed by a unit (e.g. 1mbit)", rate)
+	}
+
+	if !rateUnits[match[2]] {
+		return fmt.Errorf("invalid rate %q: unknown unit %q", rate, match[2])
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid rate %q: %w", rate, err)
+	}
+
+	if value == 0 {
+		return fmt.Errorf("invalid rate %q: must be greater than zero", rate)
+	}
+
+	return nil
+}
+
+func buildBandwidthFaultCmd(fault BandwidthFault, duration time.Duration) []string {
+	return []string{
+		"xk6-disruptor-agent",
+		"bandwidth",
+		"-d", utils.DurationSeconds(duration),
+		"-l", strings.ToLower(fault.Rate),
+		"-t", fault.Port.Str(),
+	}
+}
+
+// PodBandwidthFaultCommand implements the PodVisitCommands interface for injecting BandwidthFaults in a Pod
+type PodBandwidthFaultCommand struct {
+	fault    BandwidthFault
+	duration time.Duration
+	options  BandwidthDisruptionOptions
+}
+
+// Commands return the command for injecting a BandwidthFault in a Pod
+func (c PodBandwidthFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
+	if utils.HasHostNetwork(pod) {
+		return VisitCommands{}, fmt.Errorf("fault cannot be safely injected because pod %q uses hostNetwork", pod.Name)
+	}
+
+	// find the container port for fault injection
+	port, container, err := utils.FindContainerPort(c.fault.Port, pod, "")
+	if err != nil {
+		return VisitCommands{}, err
+	}
+	podFault := c.fault
+	podFault.Port = port
+
+	return VisitCommands{
+		Exec:    buildBandwidthFaultCmd(podFault, c.duration),
+		Cleanup: buildCleanupCmd(),
+		Ports:   []ResolvedPort{{Port: port, Container: container}},
+	}, nil
+}
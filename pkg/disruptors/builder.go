@@ -0,0 +1,143 @@
+package disruptors
+
+import (
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+// HTTPFaultBuilder builds a HTTPFault, validating its attributes when the fault is built
+type HTTPFaultBuilder struct {
+	fault HTTPFault
+}
+
+// NewHTTPFaultBuilder returns a HTTPFaultBuilder for a fault with default attributes
+func NewHTTPFaultBuilder() *HTTPFaultBuilder {
+	return &HTTPFaultBuilder{}
+}
+
+// WithPort sets the port the fault will be applied to
+func (b *HTTPFaultBuilder) WithPort(port intstr.IntOrString) *HTTPFaultBuilder {
+	b.fault.Port = port
+	return b
+}
+
+// WithAverageDelay sets the average delay introduced to requests
+func (b *HTTPFaultBuilder) WithAverageDelay(delay time.Duration) *HTTPFaultBuilder {
+	b.fault.AverageDelay = delay
+	return b
+}
+
+// WithDelayVariation sets the variation in the delay with respect to the average delay
+func (b *HTTPFaultBuilder) WithDelayVariation(variation time.Duration) *HTTPFaultBuilder {
+	b.fault.DelayVariation = variation
+	return b
+}
+
+// WithErrorRate sets the fraction of requests that will return an error
+func (b *HTTPFaultBuilder) WithErrorRate(rate float32) *HTTPFaultBuilder {
+	b.fault.ErrorRate = rate
+	return b
+}
+
+// WithErrorCode sets the error code returned by requests selected in the error rate
+func (b *HTTPFaultBuilder) WithErrorCode(code uint) *HTTPFaultBuilder {
+	b.fault.ErrorCode = code
+	return b
+}
+
+// WithErrorBody sets the body returned when an error is injected
+func (b *HTTPFaultBuilder) WithErrorBody(body string) *HTTPFaultBuilder {
+	b.fault.ErrorBody = body
+	return b
+}
+
+// WithExclude sets the comma-separated list of url paths excluded from disruptions
+func (b *HTTPFaultBuilder) WithExclude(exclude string) *HTTPFaultBuilder {
+	b.fault.Exclude = exclude
+	return b
+}
+
+// WithRateLimit sets the maximum rate of requests per second and the status code returned above this rate
+func (b *HTTPFaultBuilder) WithRateLimit(rate float32, code uint) *HTTPFaultBuilder {
+	b.fault.RateLimit = rate
+	b.fault.RateLimitCode = code
+	return b
+}
+
+// WithDrip sets the number of bytes of the response body sent in each interval
+func (b *HTTPFaultBuilder) WithDrip(bytesPerInterval uint, interval time.Duration) *HTTPFaultBuilder {
+	b.fault.DripBytesPerInterval = bytesPerInterval
+	b.fault.DripInterval = interval
+	return b
+}
+
+// Build returns the HTTPFault or an error if its attributes are not valid
+func (b *HTTPFaultBuilder) Build() (HTTPFault, error) {
+	if err := b.fault.validate(); err != nil {
+		return HTTPFault{}, err
+	}
+
+	return b.fault, nil
+}
+
+// GrpcFaultBuilder builds a GrpcFault, validating its attributes when the fault is built
+type GrpcFaultBuilder struct {
+	fault GrpcFault
+}
+
+// NewGrpcFaultBuilder returns a GrpcFaultBuilder for a fault with default attributes
+func NewGrpcFaultBuilder() *GrpcFaultBuilder {
+	return &GrpcFaultBuilder{}
+}
+
+// WithPort sets the port the fault will be applied to
+func (b *GrpcFaultBuilder) WithPort(port intstr.IntOrString) *GrpcFaultBuilder {
+	b.fault.Port = port
+	return b
+}
+
+// WithAverageDelay sets the average delay introduced to requests
+func (b *GrpcFaultBuilder) WithAverageDelay(delay time.Duration) *GrpcFaultBuilder {
+	b.fault.AverageDelay = delay
+	return b
+}
+
+// WithDelayVariation sets the variation in the delay with respect to the average delay
+func (b *GrpcFaultBuilder) WithDelayVariation(variation time.Duration) *GrpcFaultBuilder {
+	b.fault.DelayVariation = variation
+	return b
+}
+
+// WithErrorRate sets the fraction of requests that will return an error
+func (b *GrpcFaultBuilder) WithErrorRate(rate float32) *GrpcFaultBuilder {
+	b.fault.ErrorRate = rate
+	return b
+}
+
+// WithStatusCode sets the status code returned by requests selected in the error rate
+func (b *GrpcFaultBuilder) WithStatusCode(code int32) *GrpcFaultBuilder {
+	b.fault.StatusCode = code
+	return b
+}
+
+// WithStatusMessage sets the status message returned when an error is injected
+func (b *GrpcFaultBuilder) WithStatusMessage(message string) *GrpcFaultBuilder {
+	b.fault.StatusMessage = message
+	return b
+}
+
+// WithExclude sets the comma-separated list of grpc services excluded from disruptions
+func (b *GrpcFaultBuilder) WithExclude(exclude string) *GrpcFaultBuilder {
+	b.fault.Exclude = exclude
+	return b
+}
+
+// Build returns the GrpcFault or an error if its attributes are not valid
+func (b *GrpcFaultBuilder) Build() (GrpcFault, error) {
+	if err := b.fault.validate(); err != nil {
+		return GrpcFault{}, err
+	}
+
+	return b.fault, nil
+}
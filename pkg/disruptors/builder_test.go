@@ -0,0 +1,171 @@
+package disruptors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+func Test_HTTPFaultBuilder(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		builder     *HTTPFaultBuilder
+		expected    HTTPFault
+		expectError bool
+	}{
+		{
+			title:       "default fault",
+			builder:     NewHTTPFaultBuilder(),
+			expected:    HTTPFault{},
+			expectError: false,
+		},
+		{
+			title: "error fault",
+			builder: NewHTTPFaultBuilder().
+				WithPort(intstr.FromInt32(8080)).
+				WithErrorRate(0.1).
+				WithErrorCode(500).
+				WithErrorBody("error"),
+			expected: HTTPFault{
+				Port:      intstr.FromInt32(8080),
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				ErrorBody: "error",
+			},
+			expectError: false,
+		},
+		{
+			title: "delay fault",
+			builder: NewHTTPFaultBuilder().
+				WithAverageDelay(100 * time.Millisecond).
+				WithDelayVariation(10 * time.Millisecond).
+				WithExclude("/health"),
+			expected: HTTPFault{
+				AverageDelay:   100 * time.Millisecond,
+				DelayVariation: 10 * time.Millisecond,
+				Exclude:        "/health",
+			},
+			expectError: false,
+		},
+		{
+			title: "rate limit and drip fault",
+			builder: NewHTTPFaultBuilder().
+				WithRateLimit(10, 429).
+				WithDrip(16, time.Second),
+			expected: HTTPFault{
+				RateLimit:            10,
+				RateLimitCode:        429,
+				DripBytesPerInterval: 16,
+				DripInterval:         time.Second,
+			},
+			expectError: false,
+		},
+		{
+			title:       "error rate without error code",
+			builder:     NewHTTPFaultBuilder().WithErrorRate(0.1),
+			expectError: true,
+		},
+		{
+			title:       "invalid rate limit code",
+			builder:     NewHTTPFaultBuilder().WithRateLimit(10, 500),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			fault, err := tc.builder.Build()
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, fault); diff != "" {
+				t.Fatalf("expected fault does not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_GrpcFaultBuilder(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		builder     *GrpcFaultBuilder
+		expected    GrpcFault
+		expectError bool
+	}{
+		{
+			title:       "default fault",
+			builder:     NewGrpcFaultBuilder(),
+			expected:    GrpcFault{},
+			expectError: false,
+		},
+		{
+			title: "error fault",
+			builder: NewGrpcFaultBuilder().
+				WithPort(intstr.FromInt32(3000)).
+				WithErrorRate(0.1).
+				WithStatusCode(14).
+				WithStatusMessage("unavailable").
+				WithExclude("service1"),
+			expected: GrpcFault{
+				Port:          intstr.FromInt32(3000),
+				ErrorRate:     0.1,
+				StatusCode:    14,
+				StatusMessage: "unavailable",
+				Exclude:       "service1",
+			},
+			expectError: false,
+		},
+		{
+			title: "delay fault",
+			builder: NewGrpcFaultBuilder().
+				WithAverageDelay(100 * time.Millisecond).
+				WithDelayVariation(10 * time.Millisecond),
+			expected: GrpcFault{
+				AverageDelay:   100 * time.Millisecond,
+				DelayVariation: 10 * time.Millisecond,
+			},
+			expectError: false,
+		},
+		{
+			title:       "error rate without status code",
+			builder:     NewGrpcFaultBuilder().WithErrorRate(0.1),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			fault, err := tc.builder.Build()
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, fault); diff != "" {
+				t.Fatalf("expected fault does not match returned:\n%s", diff)
+			}
+		})
+	}
+}
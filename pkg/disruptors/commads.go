@@ -1,7 +1,12 @@
 package disruptors
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
@@ -20,9 +25,65 @@ func buildGrpcFaultCmd(
 		"xk6-disruptor-agent",
 		"grpc",
 		"-d", utils.DurationSeconds(duration),
-		"-t", fmt.Sprint(fault.Port),
 	}
 
+	return append(cmd, buildGrpcFaultArgs(targetAddress, fault, options)...)
+}
+
+// cannedResponseArg returns the canned response serialized as expected by the agent
+func cannedResponseArg(response CannedResponse) string {
+	arg, _ := json.Marshal(struct {
+		Code    uint              `json:"code"`
+		Body    string            `json:"body,omitempty"`
+		Headers map[string]string `json:"headers,omitempty"`
+	}{
+		Code:    response.Code,
+		Body:    response.Body,
+		Headers: response.Headers,
+	})
+
+	return string(arg)
+}
+
+// errorCodesArg returns the weighted error codes serialized as expected by the agent, as a comma-separated list
+// of code:weight pairs. The weights are normalized to add up to 1.
+func errorCodesArg(codes []WeightedErrorCode) string {
+	total := float32(0)
+	for _, code := range codes {
+		total += code.Weight
+	}
+
+	pairs := make([]string, 0, len(codes))
+	for _, code := range codes {
+		pairs = append(pairs, fmt.Sprintf("%d:%v", code.Code, code.Weight/total))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// ruleArg returns the rule serialized as expected by the agent
+func ruleArg(rule HTTPFaultRule) string {
+	arg, _ := json.Marshal(struct {
+		PathPrefix string  `json:"pathPrefix"`
+		ErrorRate  float32 `json:"errorRate"`
+		ErrorCode  uint    `json:"errorCode"`
+	}{
+		PathPrefix: rule.PathPrefix,
+		ErrorRate:  rule.ErrorRate,
+		ErrorCode:  rule.ErrorCode,
+	})
+
+	return string(arg)
+}
+
+// buildGrpcFaultArgs returns the arguments of the agent's grpc command for the fault
+func buildGrpcFaultArgs(
+	targetAddress string,
+	fault GrpcFault,
+	options GrpcDisruptionOptions,
+) []string {
+	cmd := []string{}
+
 	// TODO: make port mandatory
 	if fault.Port != intstr.NullValue {
 		cmd = append(cmd, "-t", fault.Port.Str())
@@ -49,16 +110,58 @@ func buildGrpcFaultCmd(
 		if fault.StatusMessage != "" {
 			cmd = append(cmd, "-m", fault.StatusMessage)
 		}
+
+		// sort the keys for a stable command line
+		keys := make([]string, 0, len(fault.Metadata))
+		for key := range fault.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			cmd = append(cmd, "--metadata", key+":"+fault.Metadata[key])
+		}
 	}
 
 	if len(fault.Exclude) > 0 {
 		cmd = append(cmd, "-x", fault.Exclude)
 	}
 
+	if fault.MatchAuthority != "" {
+		cmd = append(cmd, "--authority", fault.MatchAuthority)
+	}
+
 	if options.ProxyPort != 0 {
 		cmd = append(cmd, "-p", fmt.Sprint(options.ProxyPort))
 	}
 
+	if options.StopGracePeriod > 0 {
+		cmd = append(cmd, "--stop-grace-period", utils.DurationSeconds(options.StopGracePeriod))
+	}
+
+	if options.GracePeriod > 0 {
+		cmd = append(cmd, "--grace-period", utils.DurationSeconds(options.GracePeriod))
+	}
+
+	if options.Seed != 0 {
+		cmd = append(cmd, "--seed", fmt.Sprint(options.Seed))
+	}
+
+	if options.BufferSize != 0 {
+		cmd = append(cmd, "--buffer-size", fmt.Sprint(options.BufferSize))
+	}
+
+	if options.UpstreamTLS {
+		cmd = append(cmd, "--upstream-tls")
+	}
+
+	if options.UpstreamInsecureSkipVerify {
+		cmd = append(cmd, "--upstream-insecure-skip-verify")
+	}
+
+	if options.Direction != "" {
+		cmd = append(cmd, "--direction", options.Direction)
+	}
+
 	cmd = append(cmd, "--upstream-host", targetAddress)
 
 	return cmd
@@ -76,12 +179,23 @@ func buildHTTPFaultCmd(
 		"-d", utils.DurationSeconds(duration),
 	}
 
+	return append(cmd, buildHTTPFaultArgs(targetAddress, fault, options)...)
+}
+
+// buildHTTPFaultArgs returns the arguments of the agent's http command for the fault
+func buildHTTPFaultArgs(
+	targetAddress string,
+	fault HTTPFault,
+	options HTTPDisruptionOptions,
+) []string {
+	cmd := []string{}
+
 	// TODO: make port mandatory
 	if fault.Port != intstr.NullValue {
 		cmd = append(cmd, "-t", fault.Port.Str())
 	}
 
-	if fault.AverageDelay > 0 {
+	if fault.AverageDelay > 0 || fault.DelayVariation > 0 {
 		cmd = append(
 			cmd,
 			"-a",
@@ -92,35 +206,301 @@ func buildHTTPFaultCmd(
 	}
 
 	if fault.ErrorRate > 0 {
+		cmd = append(cmd, "-r", fmt.Sprint(fault.ErrorRate))
+	}
+
+	if fault.FaultMix.Rate > 0 {
 		cmd = append(
 			cmd,
-			"-e",
-			fmt.Sprint(fault.ErrorCode),
-			"-r",
-			fmt.Sprint(fault.ErrorRate),
+			"--fault-rate",
+			fmt.Sprint(fault.FaultMix.Rate),
+			"--error-share",
+			fmt.Sprint(fault.FaultMix.ErrorShare),
 		)
+	}
+
+	if fault.ErrorRate > 0 || fault.FaultMix.Rate > 0 {
+		if fault.ErrorCode != 0 {
+			cmd = append(cmd, "-e", fmt.Sprint(fault.ErrorCode))
+		}
+		if len(fault.ErrorCodes) > 0 {
+			cmd = append(cmd, "-E", errorCodesArg(fault.ErrorCodes))
+		}
 		if fault.ErrorBody != "" {
 			cmd = append(cmd, "-b", fault.ErrorBody)
 		}
+		if fault.TruncateAfterBytes > 0 {
+			cmd = append(cmd, "--truncate-after-bytes", fmt.Sprint(fault.TruncateAfterBytes))
+		}
+		if fault.HashHeader != "" {
+			cmd = append(cmd, "--hash-header", fault.HashHeader)
+		}
+		for _, response := range fault.Responses {
+			cmd = append(cmd, "--response", cannedResponseArg(response))
+		}
+	}
+
+	if fault.RateLimit > 0 {
+		code := fault.RateLimitCode
+		if code == 0 {
+			code = DefaultRateLimitCode
+		}
+		cmd = append(
+			cmd,
+			"--rate-limit",
+			fmt.Sprint(fault.RateLimit),
+			"--rate-limit-code",
+			fmt.Sprint(code),
+		)
+	}
+
+	if fault.DripBytesPerInterval > 0 {
+		cmd = append(
+			cmd,
+			"--drip-bytes",
+			fmt.Sprint(fault.DripBytesPerInterval),
+			"--drip-interval",
+			utils.DurationMillSeconds(fault.DripInterval),
+		)
+	}
+
+	if fault.ReadRateBytesPerSec > 0 {
+		cmd = append(cmd, "--read-rate", fmt.Sprint(fault.ReadRateBytesPerSec))
+	}
+
+	for _, window := range fault.Windows {
+		cmd = append(cmd, "--window", windowArg(window))
+	}
+
+	for _, rule := range fault.Rules {
+		cmd = append(cmd, "--rule", ruleArg(rule))
 	}
 
 	if len(fault.Exclude) > 0 {
 		cmd = append(cmd, "-x", fault.Exclude)
 	}
 
+	if fault.ExcludeRegex != "" {
+		cmd = append(cmd, "--exclude-regex", fault.ExcludeRegex)
+	}
+
+	// sort the headers for a stable command line
+	headers := make([]string, 0, len(fault.Headers))
+	for header := range fault.Headers {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		cmd = append(cmd, "-H", header+":"+fault.Headers[header])
+	}
+
 	if options.ProxyPort != 0 {
 		cmd = append(cmd, "-p", fmt.Sprint(options.ProxyPort))
 	}
 
+	if options.StopGracePeriod > 0 {
+		cmd = append(cmd, "--stop-grace-period", utils.DurationSeconds(options.StopGracePeriod))
+	}
+
+	if options.GracePeriod > 0 {
+		cmd = append(cmd, "--grace-period", utils.DurationSeconds(options.GracePeriod))
+	}
+
+	if options.Seed != 0 {
+		cmd = append(cmd, "--seed", fmt.Sprint(options.Seed))
+	}
+
+	if options.BufferSize != 0 {
+		cmd = append(cmd, "--buffer-size", fmt.Sprint(options.BufferSize))
+	}
+
+	if options.HTTP2 {
+		cmd = append(cmd, "--http2")
+	}
+
+	if options.Direction != "" {
+		cmd = append(cmd, "--direction", options.Direction)
+	}
+
 	cmd = append(cmd, "--upstream-host", targetAddress)
 
 	return cmd
 }
 
+// windowArg returns the argument of the agent's http command for a window. The argument is the duration of
+// the window followed by the attributes that are set, using the names of the corresponding flags
+// (e.g. "300s,a=100ms,v=10ms,r=0.1,e=500").
+func windowArg(window HTTPFaultWindow) string {
+	attrs := []string{utils.DurationSeconds(window.Duration)}
+
+	if window.AverageDelay > 0 {
+		attrs = append(
+			attrs,
+			"a="+utils.DurationMillSeconds(window.AverageDelay),
+			"v="+utils.DurationMillSeconds(window.DelayVariation),
+		)
+	}
+
+	if window.ErrorRate > 0 {
+		attrs = append(attrs, "r="+fmt.Sprint(window.ErrorRate), "e="+fmt.Sprint(window.ErrorCode))
+	}
+
+	return strings.Join(attrs, ",")
+}
+
+// buildPortFaultsCmd returns the command for applying the faults to multiple ports simultaneously.
+// The arguments of each fault are preceded by "--" and the protocol.
+func buildPortFaultsCmd(targetAddress string, faults []PortFault, duration time.Duration) []string {
+	cmd := []string{
+		"xk6-disruptor-agent",
+		"multi",
+		"-d", utils.DurationSeconds(duration),
+	}
+
+	for _, fault := range faults {
+		switch fault.Protocol {
+		case ProtocolHTTP:
+			httpFault := fault.HTTPFault
+			httpFault.Port = fault.Port
+			options := HTTPDisruptionOptions{ProxyPort: fault.ProxyPort}
+			cmd = append(cmd, "--", ProtocolHTTP)
+			cmd = append(cmd, buildHTTPFaultArgs(targetAddress, httpFault, options)...)
+		case ProtocolGrpc:
+			grpcFault := fault.GrpcFault
+			grpcFault.Port = fault.Port
+			options := GrpcDisruptionOptions{ProxyPort: fault.ProxyPort}
+			cmd = append(cmd, "--", ProtocolGrpc)
+			cmd = append(cmd, buildGrpcFaultArgs(targetAddress, grpcFault, options)...)
+		}
+	}
+
+	return cmd
+}
+
+// targetSeed derives the seed for a target from the base seed and the name of the target, so the disruptions
+// are reproducible but each target has a different sequence of random numbers. A zero base seed means no seed.
+func targetSeed(seed int64, target string) int64 {
+	if seed == 0 {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	_ = binary.Write(hash, binary.BigEndian, seed)
+	_, _ = hash.Write([]byte(target))
+
+	// keep the seed positive, as zero means no seed and negative values are confusing as arguments
+	derived := int64(hash.Sum64() >> 1)
+	if derived == 0 {
+		derived = 1
+	}
+
+	return derived
+}
+
+func buildProbeCmd(targetAddress string, port intstr.IntOrString) []string {
+	return []string{
+		"xk6-disruptor-agent",
+		"probe",
+		"-t", port.Str(),
+		"--upstream-host", targetAddress,
+	}
+}
+
 func buildCleanupCmd() []string {
 	return []string{"xk6-disruptor-agent", "cleanup"}
 }
 
+func buildPingCmd() []string {
+	return []string{"xk6-disruptor-agent", "ping"}
+}
+
+func buildStatusCmd() []string {
+	return []string{"xk6-disruptor-agent", "status"}
+}
+
+// findTargetPort returns the port of the pod a fault is injected in, and the container that exposes it. The port
+// of egress faults is the port the pod sends requests to, which is not exposed by any container of the pod,
+// so it must be a number.
+func findTargetPort(
+	port intstr.IntOrString,
+	pod corev1.Pod,
+	container string,
+	direction string,
+) (intstr.IntOrString, string, error) {
+	if direction != DirectionEgress {
+		return utils.FindContainerPort(port, pod, container)
+	}
+
+	if !port.IsInt() || port.IsZero() {
+		return intstr.NullValue, "", fmt.Errorf("port of egress faults must be a port number: %q", port)
+	}
+
+	if container != "" {
+		return intstr.NullValue, "", fmt.Errorf("container cannot be specified for egress faults")
+	}
+
+	return port, "", nil
+}
+
+// podPorts returns the ports used by the containers of the pod, including init and sidecar containers
+func podPorts(pod corev1.Pod) map[uint]bool {
+	ports := map[uint]bool{}
+	containers := append([]corev1.Container{}, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			ports[uint(port.ContainerPort)] = true //nolint:gosec // ports are positive
+		}
+	}
+
+	return ports
+}
+
+// proxyPortAllocator allocates the ports the agent's proxy listens on in a pod, so they do not collide with the
+// ports used by the containers of the pod, the target ports of the faults or the ports already allocated
+type proxyPortAllocator struct {
+	pod  corev1.Pod
+	used map[uint]bool
+}
+
+// newProxyPortAllocator returns an allocator for the proxy ports in the pod
+func newProxyPortAllocator(pod corev1.Pod) *proxyPortAllocator {
+	return &proxyPortAllocator{pod: pod, used: podPorts(pod)}
+}
+
+// reserve prevents the target port of a fault from being allocated as a proxy port
+func (a *proxyPortAllocator) reserve(targetPort intstr.IntOrString) {
+	if targetPort.Type() == intstr.ValueTypeInt {
+		a.used[uint(targetPort.Int32())] = true //nolint:gosec // ports are positive
+	}
+}
+
+// allocate returns the proxy port for a fault. If the proxy port is not set, the default port is used, skipping
+// the ports already in use. An explicit proxy port cannot be a port already in use.
+func (a *proxyPortAllocator) allocate(proxyPort uint, defaultPort uint) (uint, error) {
+	if proxyPort == 0 {
+		proxyPort = defaultPort
+		for a.used[proxyPort] {
+			proxyPort++
+		}
+	} else if a.used[proxyPort] {
+		return 0, fmt.Errorf("proxy port %d is already used by pod %q", proxyPort, a.pod.Name)
+	}
+
+	a.used[proxyPort] = true
+
+	return proxyPort, nil
+}
+
+// defaultProxyPort returns the default proxy port for the protocol
+func defaultProxyPort(protocol string) uint {
+	if protocol == ProtocolGrpc {
+		return DefaultGrpcProxyPort
+	}
+
+	return DefaultHTTPProxyPort
+}
+
 // PodHTTPFaultCommand implements the PodVisitCommands interface for injecting
 // HttpFaults in a Pod
 type PodHTTPFaultCommand struct {
@@ -135,8 +515,12 @@ func (c PodHTTPFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
 		return VisitCommands{}, fmt.Errorf("fault cannot be safely injected because pod %q uses hostNetwork", pod.Name)
 	}
 
+	if len(c.fault.Ports) > 0 {
+		return c.portsCommands(pod)
+	}
+
 	// find the container port for fault injection
-	port, err := utils.FindPort(c.fault.Port, pod)
+	port, container, err := findTargetPort(c.fault.Port, pod, c.fault.Container, c.options.Direction)
 	if err != nil {
 		return VisitCommands{}, err
 	}
@@ -148,9 +532,72 @@ func (c PodHTTPFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
 		return VisitCommands{}, err
 	}
 
+	options := c.options
+	options.Seed = targetSeed(options.Seed, pod.Name)
+	proxyPorts := newProxyPortAllocator(pod)
+	proxyPorts.reserve(port)
+	options.ProxyPort, err = proxyPorts.allocate(options.ProxyPort, DefaultHTTPProxyPort)
+	if err != nil {
+		return VisitCommands{}, err
+	}
+
 	return VisitCommands{
-		Exec:    buildHTTPFaultCmd(targetAddress, podFault, c.duration, c.options),
+		Exec:    buildHTTPFaultCmd(targetAddress, podFault, c.duration, options),
 		Cleanup: buildCleanupCmd(),
+		Ports:   []ResolvedPort{{Port: port, ProxyPort: options.ProxyPort, Container: container}},
+	}, nil
+}
+
+// portsCommands returns the command for injecting the HttpFault in all the Ports of the fault simultaneously. The
+// agent listens for each port on its own proxy port, assigned from DefaultHTTPProxyPort skipping the ports used by
+// the pod.
+func (c PodHTTPFaultCommand) portsCommands(pod corev1.Pod) (VisitCommands, error) {
+	targetAddress, err := utils.PodIP(pod)
+	if err != nil {
+		return VisitCommands{}, err
+	}
+
+	// find the container ports for fault injection
+	ports := make([]ResolvedPort, 0, len(c.fault.Ports))
+	proxyPorts := newProxyPortAllocator(pod)
+	for _, faultPort := range c.fault.Ports {
+		port, container, err := findTargetPort(faultPort, pod, c.fault.Container, c.options.Direction)
+		if err != nil {
+			return VisitCommands{}, err
+		}
+		ports = append(ports, ResolvedPort{Port: port, Container: container})
+		proxyPorts.reserve(port)
+	}
+
+	cmd := []string{
+		"xk6-disruptor-agent",
+		"multi",
+		"-d", utils.DurationSeconds(c.duration),
+	}
+
+	for i := range ports {
+		proxyPort, err := proxyPorts.allocate(0, DefaultHTTPProxyPort)
+		if err != nil {
+			return VisitCommands{}, err
+		}
+
+		podFault := c.fault
+		podFault.Port = ports[i].Port
+		podFault.Ports = nil
+
+		options := c.options
+		options.Seed = targetSeed(options.Seed, pod.Name)
+		options.ProxyPort = proxyPort
+
+		cmd = append(cmd, "--", ProtocolHTTP)
+		cmd = append(cmd, buildHTTPFaultArgs(targetAddress, podFault, options)...)
+		ports[i].ProxyPort = proxyPort
+	}
+
+	return VisitCommands{
+		Exec:    cmd,
+		Cleanup: buildCleanupCmd(),
+		Ports:   ports,
 	}, nil
 }
 
@@ -168,7 +615,7 @@ func (c PodGrpcFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
 	}
 
 	// find the container port for fault injection
-	port, err := utils.FindPort(c.fault.Port, pod)
+	port, container, err := findTargetPort(c.fault.Port, pod, c.fault.Container, c.options.Direction)
 	if err != nil {
 		return VisitCommands{}, err
 	}
@@ -180,8 +627,95 @@ func (c PodGrpcFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
 		return VisitCommands{}, err
 	}
 
+	options := c.options
+	options.Seed = targetSeed(options.Seed, pod.Name)
+	proxyPorts := newProxyPortAllocator(pod)
+	proxyPorts.reserve(port)
+	options.ProxyPort, err = proxyPorts.allocate(options.ProxyPort, DefaultGrpcProxyPort)
+	if err != nil {
+		return VisitCommands{}, err
+	}
+
+	return VisitCommands{
+		Exec:    buildGrpcFaultCmd(targetAddress, podFault, c.duration, options),
+		Cleanup: buildCleanupCmd(),
+		Ports:   []ResolvedPort{{Port: port, ProxyPort: options.ProxyPort, Container: container}},
+	}, nil
+}
+
+// PodPortFaultsCommand implements the PodVisitCommands interface for injecting faults in multiple ports of a Pod
+type PodPortFaultsCommand struct {
+	faults   []PortFault
+	duration time.Duration
+}
+
+// Commands return the command for injecting the faults in a Pod
+func (c PodPortFaultsCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
+	if utils.HasHostNetwork(pod) {
+		return VisitCommands{}, fmt.Errorf("fault cannot be safely injected because pod %q uses hostNetwork", pod.Name)
+	}
+
+	podFaults := make([]PortFault, 0, len(c.faults))
+	ports := make([]ResolvedPort, 0, len(c.faults))
+	proxyPorts := newProxyPortAllocator(pod)
+	for _, fault := range c.faults {
+		// find the container port for fault injection
+		port, container, err := utils.FindContainerPort(fault.Port, pod, "")
+		if err != nil {
+			return VisitCommands{}, err
+		}
+		fault.Port = port
+		podFaults = append(podFaults, fault)
+		ports = append(ports, ResolvedPort{Port: port, Container: container})
+		proxyPorts.reserve(port)
+	}
+
+	// explicit proxy ports are allocated first, so they are not taken by the default proxy port of another fault
+	for _, explicit := range []bool{true, false} {
+		for i := range podFaults {
+			if (podFaults[i].ProxyPort != 0) != explicit {
+				continue
+			}
+
+			proxyPort, err := proxyPorts.allocate(podFaults[i].ProxyPort, defaultProxyPort(podFaults[i].Protocol))
+			if err != nil {
+				return VisitCommands{}, err
+			}
+			podFaults[i].ProxyPort = proxyPort
+			ports[i].ProxyPort = proxyPort
+		}
+	}
+
+	targetAddress, err := utils.PodIP(pod)
+	if err != nil {
+		return VisitCommands{}, err
+	}
+
 	return VisitCommands{
-		Exec:    buildGrpcFaultCmd(targetAddress, c.fault, c.duration, c.options),
+		Exec:    buildPortFaultsCmd(targetAddress, podFaults, c.duration),
 		Cleanup: buildCleanupCmd(),
+		Ports:   ports,
+	}, nil
+}
+
+// PodProbeCommand implements the PodVisitCommands interface for probing a port of a Pod
+type PodProbeCommand struct {
+	port intstr.IntOrString
+}
+
+// Commands return the command for probing the port of a Pod
+func (c PodProbeCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
+	port, err := utils.FindPort(c.port, pod)
+	if err != nil {
+		return VisitCommands{}, err
+	}
+
+	targetAddress, err := utils.PodIP(pod)
+	if err != nil {
+		return VisitCommands{}, err
+	}
+
+	return VisitCommands{
+		Exec: buildProbeCmd(targetAddress, port),
 	}, nil
 }
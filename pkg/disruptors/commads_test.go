@@ -1,15 +1,19 @@
 package disruptors
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
 	"github.com/grafana/xk6-disruptor/pkg/testutils/command"
 	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
 	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func buildPodWithPort(name string, portName string, port int32) corev1.Pod {
@@ -26,126 +30,1591 @@ func buildPodWithPort(name string, portName string, port int32) corev1.Pod {
 	return pod
 }
 
+// injectAgent adds the agent container to the pod, as if the agent was already injected, so it is not waited for to
+// be running
+func injectAgent(pod *corev1.Pod) {
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+		{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "xk6-agent"},
+		},
+	}
+}
+
+// buildInjectedPod returns a pod labeled with app=my-app that exposes the given port and has the agent injected
+func buildInjectedPod(name string, portName string, port int32) corev1.Pod {
+	pod := buildPodWithPort(name, portName, port)
+	pod.Labels = map[string]string{"app": "my-app"}
+	injectAgent(&pod)
+
+	return pod
+}
+
+// newTestPodDisruptor returns a disruptor of the pods labeled with app=my-app in the test-ns namespace of a fake
+// cluster with the given objects
+func newTestPodDisruptor(
+	t *testing.T,
+	options PodDisruptorOptions,
+	objs ...runtime.Object,
+) (PodDisruptor, *kubernetes.FakeKubernetes) {
+	t.Helper()
+
+	k, _ := kubernetes.NewFakeKubernetes(fake.NewSimpleClientset(objs...))
+
+	disruptor, err := NewPodDisruptor(
+		context.TODO(),
+		k,
+		PodSelectorSpec{
+			Namespace: "test-ns",
+			Select:    PodAttributes{Labels: map[string]string{"app": "my-app"}},
+		},
+		options,
+	)
+	if err != nil {
+		t.Fatalf("creating disruptor: %v", err)
+	}
+
+	return disruptor, k
+}
+
+// buildPodWithSidecar returns a pod with a sidecar container exposing the given port
+func buildPodWithSidecar(name string, portName string, port int32, sidecarPort int32) corev1.Pod {
+	pod := buildPodWithPort(name, portName, port)
+	sidecar := builders.NewContainerBuilder("sidecar").
+		WithPort("sidecar", sidecarPort).
+		Build()
+	always := corev1.ContainerRestartPolicyAlways
+	sidecar.RestartPolicy = &always
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, sidecar)
+
+	return pod
+}
+
 func Test_PodHTTPFaultCommandGenerator(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		title       string
-		target      corev1.Pod
-		expectedCmd string
-		expectError bool
-		cmdError    error
-		fault       HTTPFault
-		opts        HTTPDisruptionOptions
-		duration    time.Duration
+		title       string
+		target      corev1.Pod
+		expectedCmd string
+		expectError bool
+		cmdError    error
+		fault       HTTPFault
+		opts        HTTPDisruptionOptions
+		duration    time.Duration
+		// flags expected in the command in addition to the expected command, for values with spaces
+		expectedFlags map[string]string
+	}{
+		{
+			title:  "Test error 500",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Port:      intstr.FromInt32(80),
+			},
+			opts:        HTTPDisruptionOptions{},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test error 500 with error body",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 -b {\"error\": 500}" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectedFlags: map[string]string{
+				"-b": `{"error": 500}`,
+			},
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				ErrorBody: "{\"error\": 500}",
+				Port:      intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test error body with spaces, commas and quotes",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500" +
+				" -b {\"error\": \"not found, retry later\"} -p 8080 --upstream-host 192.0.2.6",
+			expectedFlags: map[string]string{
+				"-b": `{"error": "not found, retry later"}`,
+			},
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				ErrorBody: `{"error": "not found, retry later"}`,
+				Port:      intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test error with hash header",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 --hash-header X-User-Id" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCode:  500,
+				HashHeader: "X-User-Id",
+				Port:       intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test error with canned responses",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 --response {\"code\":500,\"body\":\"internal\"} --response {\"code\":503,\"headers\":{\"Retry-After\":\"1\"}}" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				Responses: []CannedResponse{
+					{Code: 500, Body: "internal"},
+					{Code: 503, Headers: map[string]string{"Retry-After": "1"}},
+				},
+				Port: intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test weighted error codes",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCodes: []WeightedErrorCode{
+					{Code: 500, Weight: 0.5},
+					{Code: 502, Weight: 0.3},
+					{Code: 503, Weight: 0.2},
+				},
+				Port: intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -E 500:0.5,502:0.3,503:0.2" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test truncate after bytes",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			fault: HTTPFault{
+				ErrorRate:          0.1,
+				TruncateAfterBytes: 1024,
+				Port:               intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 --truncate-after-bytes 1024" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test weighted error codes not adding up to 1",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCodes: []WeightedErrorCode{
+					{Code: 500, Weight: 5},
+					{Code: 502, Weight: 3},
+					{Code: 503, Weight: 2},
+				},
+				Port: intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -E 500:0.5,502:0.3,503:0.2" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test fault mix",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -a 100ms -v 0ms --fault-rate 0.5 --error-share 0.7 -e 500" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ErrorCode:    500,
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 0.5, ErrorShare: 0.7},
+				Port:         intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test seed",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500" +
+				" -p 8080 --seed 7658614687045355738 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Port:      intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				Seed: 42,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test windows",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 600s -t 80 --window 300s,a=100ms,v=10ms --window 300s,r=0.5,e=500" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+				Windows: []HTTPFaultWindow{
+					{Duration: 5 * time.Minute, AverageDelay: 100 * time.Millisecond, DelayVariation: 10 * time.Millisecond},
+					{Duration: 5 * time.Minute, ErrorRate: 0.5, ErrorCode: 500},
+				},
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 600 * time.Second,
+		},
+		{
+			title:  "Test rule",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 --rule {\"pathPrefix\":\"/api/\",\"errorRate\":0.5,\"errorCode\":500}" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+				Rules: []HTTPFaultRule{
+					{PathPrefix: "/api/", ErrorRate: 0.5, ErrorCode: 500},
+				},
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test Average delay",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -a 100ms -v 0ms -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				AverageDelay: 100 * time.Millisecond,
+				Port:         intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test delay variation without average delay",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -a 0ms -v 50ms -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				DelayVariation: 50 * time.Millisecond,
+				Port:           intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test exclude list",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -x /path1,/path2 -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Exclude: "/path1,/path2",
+				Port:    intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test exclude regex",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -x /health --exclude-regex /internal/.*" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Exclude:      "/health",
+				ExcludeRegex: "/internal/.*",
+				Port:         intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test match header",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 1 -e 500 -H X-Canary:true -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port:      intstr.FromInt32(80),
+				ErrorRate: 1.0,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X-Canary": "true"},
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test multiple match headers",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 1 -e 500" +
+				" -H X-Canary:true -H X-Region:eu-west -H X-Tenant:acme -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port:      intstr.FromInt32(80),
+				ErrorRate: 1.0,
+				ErrorCode: 500,
+				Headers: map[string]string{
+					"X-Tenant": "acme",
+					"X-Canary": "true",
+					"X-Region": "eu-west",
+				},
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test rate limit",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 --rate-limit 10 --rate-limit-code 429" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				RateLimit: 10,
+				Port:      intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test rate limit with status code",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 --rate-limit 0.5 --rate-limit-code 403" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				RateLimit:     0.5,
+				RateLimitCode: 403,
+				Port:          intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:  "Test drip",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 --drip-bytes 16 --drip-interval 100ms" +
+				" -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				DripBytesPerInterval: 16,
+				DripInterval:         100 * time.Millisecond,
+				Port:                 intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test read rate",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 --read-rate 1024 -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				ReadRateBytesPerSec: 1024,
+				Port:                intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test stop grace period",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8080 --stop-grace-period 10s --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				StopGracePeriod: 10 * time.Second,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test explicit proxy port",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 9000 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				ProxyPort: 9000,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test default proxy port is target port",
+			target:      buildPodWithPort("my-app-pod", "http", 8080),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 8080 -p 8081 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(8080),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test default proxy port is used by a sidecar",
+			target:      buildPodWithSidecar("my-app-pod", "http", 80, 8080),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8081 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test explicit proxy port is used by a sidecar",
+			target:      buildPodWithSidecar("my-app-pod", "http", 80, 9000),
+			expectedCmd: "",
+			expectError: true,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				ProxyPort: 9000,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test explicit proxy port is target port",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "",
+			expectError: true,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				ProxyPort: 80,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test buffer size",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8080 --buffer-size 262144 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				BufferSize: 256 * 1024,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test grace period",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8080 --grace-period 10s --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				GracePeriod: 10 * time.Second,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test http2",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8080 --http2 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				HTTP2: true,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test ingress direction",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8080 --direction ingress --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts: HTTPDisruptionOptions{
+				Direction: DirectionIngress,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test egress direction",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 5432 -p 8080 --direction egress --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(5432),
+			},
+			opts: HTTPDisruptionOptions{
+				Direction: DirectionEgress,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Test egress direction with named port",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "",
+			expectError: true,
+			fault: HTTPFault{
+				Port: intstr.FromString("http"),
+			},
+			opts: HTTPDisruptionOptions{
+				Direction: DirectionEgress,
+			},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Container port not found",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "",
+			expectError: true,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(8080),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60,
+		},
+		{
+			title:       "Port in named container",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+			fault: HTTPFault{
+				Port:      intstr.FromInt32(80),
+				Container: "my-app-pod",
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title:       "Port not in named container",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			expectedCmd: "",
+			expectError: true,
+			fault: HTTPFault{
+				Port:      intstr.FromInt32(80),
+				Container: "sidecar",
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60 * time.Second,
+		},
+		{
+			title: "Pod without PodIP",
+			target: builders.NewPodBuilder("noip").
+				WithNamespace("test-ns").
+				WithLabel("app", "myapp").
+				WithContainer(
+					builders.NewContainerBuilder("noip").
+						WithPort("http", 80).
+						Build(),
+				).
+				Build(),
+			expectedCmd: "",
+			expectError: true,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60,
+		},
+		{
+			title: "Pod with hostNetwork",
+			target: builders.NewPodBuilder("hostnet").
+				WithNamespace("test-ns").
+				WithLabel("app", "myapp").
+				WithHostNetwork(true).
+				WithIP("192.0.2.6").
+				WithContainer(
+					builders.NewContainerBuilder("myapp").
+						WithPort("http", 80).
+						Build(),
+				).
+				Build(),
+			expectedCmd: "",
+			expectError: true,
+			fault: HTTPFault{
+				Port: intstr.FromInt32(80),
+			},
+			opts:     HTTPDisruptionOptions{},
+			duration: 60,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := PodHTTPFaultCommand{
+				fault:    tc.fault,
+				duration: tc.duration,
+				options:  tc.opts,
+			}
+
+			cmds, err := cmd.Commands(tc.target)
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error : %v", err)
+				return
+			}
+
+			if !command.AssertCmdEquals(strings.Join(cmds.Exec, " "), tc.expectedCmd) {
+				t.Errorf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+			}
+
+			for flag, value := range tc.expectedFlags {
+				if !command.AssertCmdContainsFlag(cmds.Exec, flag, value) {
+					t.Errorf("expected flag %s with value %q in command: %q", flag, value, cmds.Exec)
+				}
+			}
+		})
+	}
+}
+
+// Test_HTTPFaultRulesArgs checks the arguments for multiple rules, as AssertCmdEquals only compares the last value
+// of repeated flags
+func Test_HTTPFaultRulesArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		rules    []HTTPFaultRule
+		expected []string
+	}{
+		{
+			title:    "no rules",
+			rules:    nil,
+			expected: []string{"-t", "80", "--upstream-host", "192.0.2.6"},
+		},
+		{
+			title: "multiple rules",
+			rules: []HTTPFaultRule{
+				{PathPrefix: "/api/v1/", ErrorRate: 1, ErrorCode: 503},
+				{PathPrefix: "/api/", ErrorRate: 0.5, ErrorCode: 500},
+				{PathPrefix: "/health"},
+			},
+			expected: []string{
+				"-t", "80",
+				"--rule", `{"pathPrefix":"/api/v1/","errorRate":1,"errorCode":503}`,
+				"--rule", `{"pathPrefix":"/api/","errorRate":0.5,"errorCode":500}`,
+				"--rule", `{"pathPrefix":"/health","errorRate":0,"errorCode":0}`,
+				"--upstream-host", "192.0.2.6",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			fault := HTTPFault{Port: intstr.FromInt32(80), Rules: tc.rules}
+			args := buildHTTPFaultArgs("192.0.2.6", fault, HTTPDisruptionOptions{})
+			if strings.Join(args, " ") != strings.Join(tc.expected, " ") {
+				t.Errorf("expected args %q got %q", tc.expected, args)
+			}
+		})
+	}
+}
+
+func Test_GrpcFaultMetadataArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		fault    GrpcFault
+		expected []string
+	}{
+		{
+			title:    "no metadata",
+			fault:    GrpcFault{ErrorRate: 0.1, StatusCode: 14},
+			expected: []string{},
+		},
+		{
+			title: "multiple keys",
+			fault: GrpcFault{
+				ErrorRate:  0.1,
+				StatusCode: 14,
+				Metadata:   map[string]string{"x-retry-after": "10", "x-details-bin": "details", "x-region": "eu"},
+			},
+			expected: []string{"x-details-bin:details", "x-region:eu", "x-retry-after:10"},
+		},
+		{
+			title: "metadata without error rate",
+			fault: GrpcFault{
+				AverageDelay: time.Second,
+				Metadata:     map[string]string{"x-retry-after": "10"},
+			},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			tc.fault.Port = intstr.FromInt32(3000)
+			args := buildGrpcFaultArgs("192.0.2.6", tc.fault, GrpcDisruptionOptions{})
+
+			// the metadata is sorted by key for a stable command line
+			metadata := []string{}
+			for i := 0; i < len(args)-1; i++ {
+				if args[i] == "--metadata" {
+					metadata = append(metadata, args[i+1])
+				}
+			}
+
+			if strings.Join(metadata, " ") != strings.Join(tc.expected, " ") {
+				t.Errorf("expected metadata %q got %q", tc.expected, metadata)
+			}
+		})
+	}
+}
+
+func Test_WindowArg(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		window   HTTPFaultWindow
+		expected string
+	}{
+		{
+			title:    "only duration",
+			window:   HTTPFaultWindow{Duration: 5 * time.Minute},
+			expected: "300s",
+		},
+		{
+			title: "delay",
+			window: HTTPFaultWindow{
+				Duration:       90 * time.Second,
+				AverageDelay:   100 * time.Millisecond,
+				DelayVariation: 10 * time.Millisecond,
+			},
+			expected: "90s,a=100ms,v=10ms",
+		},
+		{
+			title: "errors",
+			window: HTTPFaultWindow{
+				Duration:  time.Minute,
+				ErrorRate: 0.1,
+				ErrorCode: 503,
+			},
+			expected: "60s,r=0.1,e=503",
+		},
+		{
+			title: "delay and errors",
+			window: HTTPFaultWindow{
+				Duration:     time.Minute,
+				AverageDelay: 200 * time.Millisecond,
+				ErrorRate:    0.5,
+				ErrorCode:    500,
+			},
+			expected: "60s,a=200ms,v=0ms,r=0.5,e=500",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			arg := windowArg(tc.window)
+			if arg != tc.expected {
+				t.Errorf("expected %q got %q", tc.expected, arg)
+			}
+		})
+	}
+}
+
+func Test_SeedArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		seed     int64
+		expected string
+	}{
+		{
+			title:    "no seed",
+			seed:     0,
+			expected: "-t 80 --upstream-host 192.0.2.6",
+		},
+		{
+			title:    "seed",
+			seed:     42,
+			expected: "-t 80 --seed 42 --upstream-host 192.0.2.6",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			httpArgs := buildHTTPFaultArgs(
+				"192.0.2.6",
+				HTTPFault{Port: intstr.FromInt32(80)},
+				HTTPDisruptionOptions{Seed: tc.seed},
+			)
+			if !command.AssertCmdEquals(tc.expected, strings.Join(httpArgs, " ")) {
+				t.Errorf("expected http args %q got %q", tc.expected, httpArgs)
+			}
+
+			grpcArgs := buildGrpcFaultArgs(
+				"192.0.2.6",
+				GrpcFault{Port: intstr.FromInt32(80)},
+				GrpcDisruptionOptions{Seed: tc.seed},
+			)
+			if !command.AssertCmdEquals(tc.expected, strings.Join(grpcArgs, " ")) {
+				t.Errorf("expected grpc args %q got %q", tc.expected, grpcArgs)
+			}
+		})
+	}
+}
+
+func Test_TargetSeed(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		seed     int64
+		target   string
+		expected int64
+	}{
+		{
+			title:    "no seed",
+			seed:     0,
+			target:   "pod-1",
+			expected: 0,
+		},
+		{
+			title:    "first target",
+			seed:     42,
+			target:   "pod-1",
+			expected: 2559755441659093004,
+		},
+		{
+			title:    "second target",
+			seed:     42,
+			target:   "pod-2",
+			expected: 2559757090926535320,
+		},
+		{
+			title:    "different base seed",
+			seed:     7,
+			target:   "pod-1",
+			expected: 4431503261874043484,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// the seed must be stable across invocations
+			for i := 0; i < 3; i++ {
+				seed := targetSeed(tc.seed, tc.target)
+				if seed != tc.expected {
+					t.Fatalf("expected seed %d got %d", tc.expected, seed)
+				}
+			}
+		})
+	}
+}
+
+func Test_PodGrpcPFaultCommandGenerator(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		target      corev1.Pod
+		fault       GrpcFault
+		opts        GrpcDisruptionOptions
+		duration    time.Duration
+		expectedCmd string
+		expectError bool
+		cmdError    error
+		// flags expected in the command in addition to the expected command, for values with spaces
+		expectedFlags map[string]string
+	}{
+		{
+			title:  "Test error",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				ErrorRate:  0.1,
+				StatusCode: 14,
+				Port:       intstr.FromInt32(3000),
+			},
+			opts:        GrpcDisruptionOptions{},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14 -p 3001 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test seed",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				ErrorRate:  0.1,
+				StatusCode: 14,
+				Port:       intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				Seed: 42,
+			},
+			duration: 60 * time.Second,
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14" +
+				" -p 3001 --seed 7658614687045355738 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test error with status message",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				ErrorRate:     0.1,
+				StatusCode:    14,
+				StatusMessage: "internal error",
+				Port:          intstr.FromInt32(3000),
+			},
+			opts:     GrpcDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14 -m internal error" +
+				" -p 3001 --upstream-host 192.0.2.6",
+			expectedFlags: map[string]string{
+				"-m": "internal error",
+			},
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test error with message with commas and quotes",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				ErrorRate:     0.1,
+				StatusCode:    14,
+				StatusMessage: `service "users" unavailable, retry`,
+				Port:          intstr.FromInt32(3000),
+			},
+			opts:     GrpcDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14 -m service \"users\" unavailable, retry" +
+				" -p 3001 --upstream-host 192.0.2.6",
+			expectedFlags: map[string]string{
+				"-m": `service "users" unavailable, retry`,
+			},
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test error with metadata",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				ErrorRate:  0.1,
+				StatusCode: 14,
+				Metadata:   map[string]string{"x-retry-after": "10"},
+				Port:       intstr.FromInt32(3000),
+			},
+			opts:     GrpcDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14 --metadata x-retry-after:10" +
+				" -p 3001 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test Average delay",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				AverageDelay: 100 * time.Millisecond,
+				Port:         intstr.FromInt32(3000),
+			},
+			opts:        GrpcDisruptionOptions{},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -a 100ms -v 0ms -p 3001 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test exclude list",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Exclude: "service1,service2",
+				Port:    intstr.FromInt32(3000),
+			},
+			opts:        GrpcDisruptionOptions{},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -x service1,service2 -p 3001 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test match authority",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				MatchAuthority: "api.example.com:443",
+				Port:           intstr.FromInt32(3000),
+			},
+			opts:     GrpcDisruptionOptions{},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 --authority api.example.com:443" +
+				" -p 3001 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test stop grace period",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				StopGracePeriod: 10 * time.Second,
+			},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -p 3001 --stop-grace-period 10s --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test explicit proxy port",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				ProxyPort: 4000,
+			},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -p 4000 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test default proxy port",
+			target: buildPodWithPort("my-app-pod", "grpc", 9000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(9000),
+			},
+			opts:        GrpcDisruptionOptions{},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 9000 -p 3000 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test named port",
+			target: buildPodWithPort("my-app-pod", "grpc", 9000),
+			fault: GrpcFault{
+				ErrorRate:  0.1,
+				StatusCode: 14,
+				Port:       intstr.FromString("grpc"),
+			},
+			opts:        GrpcDisruptionOptions{},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 9000 -r 0.1 -s 14 -p 3000 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test explicit proxy port is target port",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				ProxyPort: 3000,
+			},
+			duration:    60 * time.Second,
+			expectedCmd: "",
+			expectError: true,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test buffer size",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				BufferSize: 256 * 1024,
+			},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -p 3001 --buffer-size 262144 --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test grace period",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				GracePeriod: 10 * time.Second,
+			},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -p 3001 --grace-period 10s --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test upstream TLS",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(3000),
+			},
+			opts: GrpcDisruptionOptions{
+				UpstreamTLS:                true,
+				UpstreamInsecureSkipVerify: true,
+			},
+			duration: 60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -p 3001 --upstream-tls --upstream-insecure-skip-verify" +
+				" --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:  "Test egress direction",
+			target: buildPodWithPort("my-app-pod", "grpc", 3000),
+			fault: GrpcFault{
+				Port: intstr.FromInt32(4000),
+			},
+			opts: GrpcDisruptionOptions{
+				Direction: DirectionEgress,
+			},
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 4000 -p 3001 --direction egress --upstream-host 192.0.2.6",
+			expectError: false,
+			cmdError:    nil,
+		},
+		{
+			title:       "Container port not found",
+			target:      buildPodWithPort("my-app-pod", "grpc", 3000),
+			expectError: true,
+			fault:       GrpcFault{Port: intstr.FromInt32(8080)},
+			opts:        GrpcDisruptionOptions{},
+			duration:    60,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := PodGrpcFaultCommand{
+				fault:    tc.fault,
+				duration: tc.duration,
+				options:  tc.opts,
+			}
+
+			cmds, err := cmd.Commands(tc.target)
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error : %v", err)
+				return
+			}
+
+			if !command.AssertCmdEquals(strings.Join(cmds.Exec, " "), tc.expectedCmd) {
+				t.Errorf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+			}
+
+			for flag, value := range tc.expectedFlags {
+				if !command.AssertCmdContainsFlag(cmds.Exec, flag, value) {
+					t.Errorf("expected flag %s with value %q in command: %q", flag, value, cmds.Exec)
+				}
+			}
+		})
+	}
+}
+
+func Test_PodPortFaultsCommandGenerator(t *testing.T) {
+	t.Parallel()
+
+	container := builders.NewContainerBuilder("my-app").
+		WithPort("http", 80).
+		WithPort("grpc", 3000).
+		Build()
+
+	pod := builders.NewPodBuilder("my-app-pod").
+		WithNamespace("test-ns").
+		WithContainer(container).
+		WithIP("192.0.2.6").
+		Build()
+
+	testCases := []struct {
+		title       string
+		target      corev1.Pod
+		faults      []PortFault
+		duration    time.Duration
+		expectedCmd string
+		expectError bool
+	}{
+		{
+			title:  "http and grpc faults",
+			target: pod,
+			faults: []PortFault{
+				{
+					Port:      intstr.FromInt32(80),
+					Protocol:  ProtocolHTTP,
+					HTTPFault: HTTPFault{ErrorRate: 0.1, ErrorCode: 500},
+					ProxyPort: 8000,
+				},
+				{
+					Port:      intstr.FromString("grpc"),
+					Protocol:  ProtocolGrpc,
+					GrpcFault: GrpcFault{ErrorRate: 0.1, StatusCode: 14},
+					ProxyPort: 8001,
+				},
+			},
+			duration: 60 * time.Second,
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent multi -d 60s -- http -t 80 -r 0.1 -e 500 -p 8000 --upstream-host 192.0.2.6 -- grpc -t 3000 -r 0.1 -s 14 -p 8001 --upstream-host 192.0.2.6",
+			expectError: false,
+		},
+		{
+			title: "default proxy ports used by the pod",
+			target: builders.NewPodBuilder("my-app-pod").
+				WithContainer(
+					builders.NewContainerBuilder("my-app").
+						WithPort("http", 80).
+						WithPort("grpc", 3000).
+						WithPort("admin", 8080).
+						Build(),
+				).
+				WithIP("192.0.2.6").
+				Build(),
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP},
+				{Port: intstr.FromString("grpc"), Protocol: ProtocolGrpc},
+			},
+			duration: 60 * time.Second,
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent multi -d 60s -- http -t 80 -p 8081 --upstream-host 192.0.2.6 -- grpc -t 3000 -p 3001 --upstream-host 192.0.2.6",
+			expectError: false,
+		},
+		{
+			title:  "default proxy port taken by an explicit proxy port",
+			target: pod,
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP},
+				{Port: intstr.FromString("grpc"), Protocol: ProtocolGrpc, ProxyPort: 8080},
+			},
+			duration: 60 * time.Second,
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent multi -d 60s -- http -t 80 -p 8081 --upstream-host 192.0.2.6 -- grpc -t 3000 -p 8080 --upstream-host 192.0.2.6",
+			expectError: false,
+		},
+		{
+			title:  "explicit proxy port used by the pod",
+			target: pod,
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, ProxyPort: 3000},
+			},
+			duration:    60 * time.Second,
+			expectError: true,
+		},
+		{
+			title:  "container port not found",
+			target: pod,
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, ProxyPort: 8000},
+				{Port: intstr.FromInt32(8080), Protocol: ProtocolGrpc, ProxyPort: 8001},
+			},
+			duration:    60 * time.Second,
+			expectError: true,
+		},
+		{
+			title: "host network",
+			target: builders.NewPodBuilder("my-app-pod").
+				WithContainer(container).
+				WithIP("192.0.2.6").
+				WithHostNetwork(true).
+				Build(),
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, ProxyPort: 8000},
+			},
+			duration:    60 * time.Second,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := PodPortFaultsCommand{
+				faults:   tc.faults,
+				duration: tc.duration,
+			}
+
+			cmds, err := cmd.Commands(tc.target)
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error : %v", err)
+				return
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			// each protocol has its own set of flags, so they are compared group by group
+			expected := strings.Split(tc.expectedCmd, " -- ")
+			actual := strings.Split(strings.Join(cmds.Exec, " "), " -- ")
+			if len(expected) != len(actual) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+			}
+
+			for i := range expected {
+				if !command.AssertCmdEquals(expected[i], actual[i]) {
+					t.Errorf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+				}
+			}
+		})
+	}
+}
+
+func Test_PodHTTPFaultPortsCommandGenerator(t *testing.T) {
+	t.Parallel()
+
+	container := builders.NewContainerBuilder("my-app").
+		WithPort("http", 8080).
+		WithPort("admin", 8081).
+		Build()
+
+	pod := builders.NewPodBuilder("my-app-pod").
+		WithNamespace("test-ns").
+		WithContainer(container).
+		WithIP("192.0.2.6").
+		Build()
+
+	testCases := []struct {
+		title         string
+		target        corev1.Pod
+		fault         HTTPFault
+		duration      time.Duration
+		expectedCmd   string
+		expectedPorts []ResolvedPort
+		expectError   bool
 	}{
 		{
-			title:  "Test error 500",
-			target: buildPodWithPort("my-app-pod", "http", 80),
+			title:  "single port",
+			target: pod,
 			fault: HTTPFault{
 				ErrorRate: 0.1,
 				ErrorCode: 500,
-				Port:      intstr.FromInt32(80),
+				Ports:     []intstr.IntOrString{intstr.FromInt32(8080)},
 			},
-			opts:        HTTPDisruptionOptions{},
 			duration:    60 * time.Second,
-			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 --upstream-host 192.0.2.6",
-			expectError: false,
-			cmdError:    nil,
+			expectedCmd: "xk6-disruptor-agent multi -d 60s -- http -t 8080 -r 0.1 -e 500 -p 8082 --upstream-host 192.0.2.6",
+			expectedPorts: []ResolvedPort{
+				{Port: intstr.FromInt32(8080), ProxyPort: 8082, Container: "my-app"},
+			},
 		},
 		{
-			title:  "Test error 500 with error body",
-			target: buildPodWithPort("my-app-pod", "http", 80),
-			// TODO: Make expectedCmd better represent the actual result ([]string), as it currently looks like we
-			// are asserting a broken behavior (e.g. lack of quotes in -b) which is not the case.
-			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 -b {\"error\": 500} --upstream-host 192.0.2.6",
-			expectError: false,
-			cmdError:    nil,
+			title:  "multiple ports",
+			target: pod,
 			fault: HTTPFault{
 				ErrorRate: 0.1,
 				ErrorCode: 500,
-				ErrorBody: "{\"error\": 500}",
-				Port:      intstr.FromInt32(80),
+				Ports:     []intstr.IntOrString{intstr.FromInt32(8080), intstr.FromString("admin")},
 			},
-			opts:     HTTPDisruptionOptions{},
 			duration: 60 * time.Second,
-		},
-		{
-			title:       "Test Average delay",
-			target:      buildPodWithPort("my-app-pod", "http", 80),
-			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -a 100ms -v 0ms --upstream-host 192.0.2.6",
-			expectError: false,
-			cmdError:    nil,
-			fault: HTTPFault{
-				AverageDelay: 100 * time.Millisecond,
-				Port:         intstr.FromInt32(80),
+			//nolint:lll
+			expectedCmd: "xk6-disruptor-agent multi -d 60s -- http -t 8080 -r 0.1 -e 500 -p 8082 --upstream-host 192.0.2.6 -- http -t 8081 -r 0.1 -e 500 -p 8083 --upstream-host 192.0.2.6",
+			expectedPorts: []ResolvedPort{
+				{Port: intstr.FromInt32(8080), ProxyPort: 8082, Container: "my-app"},
+				{Port: intstr.FromInt32(8081), ProxyPort: 8083, Container: "my-app"},
 			},
-			opts:     HTTPDisruptionOptions{},
-			duration: 60 * time.Second,
 		},
 		{
-			title:       "Test exclude list",
-			target:      buildPodWithPort("my-app-pod", "http", 80),
-			expectedCmd: "xk6-disruptor-agent http -d 60s -t 80 -x /path1,/path2 --upstream-host 192.0.2.6",
-			expectError: false,
-			cmdError:    nil,
+			title:  "container port not found",
+			target: pod,
 			fault: HTTPFault{
-				Exclude: "/path1,/path2",
-				Port:    intstr.FromInt32(80),
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Ports:     []intstr.IntOrString{intstr.FromInt32(8080), intstr.FromInt32(9090)},
 			},
-			opts:     HTTPDisruptionOptions{},
-			duration: 60 * time.Second,
+			duration:    60 * time.Second,
+			expectError: true,
 		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := PodHTTPFaultCommand{
+				fault:    tc.fault,
+				duration: tc.duration,
+			}
+
+			cmds, err := cmd.Commands(tc.target)
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error : %v", err)
+				return
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			// each port has its own set of flags, so they are compared group by group
+			expected := strings.Split(tc.expectedCmd, " -- ")
+			actual := strings.Split(strings.Join(cmds.Exec, " "), " -- ")
+			if len(expected) != len(actual) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+			}
+
+			for i := range expected {
+				if !command.AssertCmdEquals(expected[i], actual[i]) {
+					t.Errorf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+				}
+			}
+
+			if len(cmds.Ports) != len(tc.expectedPorts) {
+				t.Fatalf("expected ports: %v got: %v", tc.expectedPorts, cmds.Ports)
+			}
+
+			for i := range tc.expectedPorts {
+				if cmds.Ports[i] != tc.expectedPorts[i] {
+					t.Errorf("expected ports: %v got: %v", tc.expectedPorts, cmds.Ports)
+				}
+			}
+		})
+	}
+}
+
+func Test_PodBandwidthFaultCommandGenerator(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		target      corev1.Pod
+		fault       BandwidthFault
+		duration    time.Duration
+		expectedCmd string
+		expectError bool
+	}{
 		{
-			title:       "Container port not found",
-			target:      buildPodWithPort("my-app-pod", "http", 80),
-			expectedCmd: "",
-			expectError: true,
-			fault: HTTPFault{
-				Port: intstr.FromInt32(8080),
+			title:  "Test numeric port",
+			target: buildPodWithPort("my-app-pod", "http", 80),
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+				Rate: "1mbit",
 			},
-			opts:     HTTPDisruptionOptions{},
-			duration: 60,
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent bandwidth -d 60s -l 1mbit -t 80",
+			expectError: false,
 		},
 		{
-			title: "Pod without PodIP",
-			target: builders.NewPodBuilder("noip").
-				WithNamespace("test-ns").
-				WithLabel("app", "myapp").
-				WithContainer(
-					builders.NewContainerBuilder("noip").
-						WithPort("http", 80).
-						Build(),
-				).
-				Build(),
-			expectedCmd: "",
-			expectError: true,
-			fault: HTTPFault{
-				Port: intstr.FromInt32(80),
+			title:  "Test named port",
+			target: buildPodWithPort("my-app-pod", "http", 8080),
+			fault: BandwidthFault{
+				Port: intstr.FromString("http"),
+				Rate: "100KBps",
 			},
-			opts:     HTTPDisruptionOptions{},
-			duration: 60,
+			duration:    60 * time.Second,
+			expectedCmd: "xk6-disruptor-agent bandwidth -d 60s -l 100kbps -t 8080",
+			expectError: false,
 		},
 		{
-			title: "Pod with hostNetwork",
-			target: builders.NewPodBuilder("hostnet").
-				WithNamespace("test-ns").
-				WithLabel("app", "myapp").
-				WithHostNetwork(true).
-				WithIP("192.0.2.6").
-				WithContainer(
-					builders.NewContainerBuilder("myapp").
-						WithPort("http", 80).
-						Build(),
-				).
-				Build(),
-			expectedCmd: "",
+			title:       "Container port not found",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			fault:       BandwidthFault{Port: intstr.FromInt32(8080), Rate: "1mbit"},
+			duration:    60 * time.Second,
 			expectError: true,
-			fault: HTTPFault{
-				Port: intstr.FromInt32(80),
-			},
-			opts:     HTTPDisruptionOptions{},
-			duration: 60,
 		},
 	}
 
@@ -154,13 +1623,13 @@ func Test_PodHTTPFaultCommandGenerator(t *testing.T) {
 		t.Run(tc.title, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := PodHTTPFaultCommand{
+			cmd := PodBandwidthFaultCommand{
 				fault:    tc.fault,
 				duration: tc.duration,
-				options:  tc.opts,
 			}
 
 			cmds, err := cmd.Commands(tc.target)
+
 			if tc.expectError && err == nil {
 				t.Errorf("should had failed")
 				return
@@ -178,81 +1647,122 @@ func Test_PodHTTPFaultCommandGenerator(t *testing.T) {
 	}
 }
 
-func Test_PodGrpcPFaultCommandGenerator(t *testing.T) {
+func Test_PodDNSFaultCommandGenerator(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
 		title       string
 		target      corev1.Pod
-		fault       GrpcFault
-		opts        GrpcDisruptionOptions
+		fault       DNSFault
+		options     DNSDisruptionOptions
 		duration    time.Duration
 		expectedCmd string
 		expectError bool
-		cmdError    error
 	}{
 		{
-			title:  "Test error",
-			target: buildPodWithPort("my-app-pod", "grpc", 3000),
-			fault: GrpcFault{
-				ErrorRate:  0.1,
-				StatusCode: 14,
-				Port:       intstr.FromInt32(3000),
-			},
-			opts:        GrpcDisruptionOptions{},
+			title:       "Test all the queries",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			fault:       DNSFault{Rate: 0.5},
 			duration:    60 * time.Second,
-			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14 --upstream-host 192.0.2.6",
+			expectedCmd: "xk6-disruptor-agent dns -d 60s -r 0.5",
 			expectError: false,
-			cmdError:    nil,
 		},
 		{
-			title:  "Test error with status message",
-			target: buildPodWithPort("my-app-pod", "grpc", 3000),
-			fault: GrpcFault{
-				ErrorRate:     0.1,
-				StatusCode:    14,
-				StatusMessage: "internal error",
-				Port:          intstr.FromInt32(3000),
-			},
-			opts:        GrpcDisruptionOptions{},
+			title:       "Test domain",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			fault:       DNSFault{Rate: 1, Domain: "example.com"},
 			duration:    60 * time.Second,
-			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -r 0.1 -s 14 -m internal error --upstream-host 192.0.2.6",
+			expectedCmd: "xk6-disruptor-agent dns -d 60s -r 1 --domain example.com",
 			expectError: false,
-			cmdError:    nil,
 		},
 		{
-			title:  "Test Average delay",
-			target: buildPodWithPort("my-app-pod", "grpc", 3000),
-			fault: GrpcFault{
-				AverageDelay: 100 * time.Millisecond,
-				Port:         intstr.FromInt32(3000),
-			},
-			opts:        GrpcDisruptionOptions{},
+			title:       "Test seed",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			fault:       DNSFault{Rate: 0.5},
+			options:     DNSDisruptionOptions{Seed: 42},
 			duration:    60 * time.Second,
-			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -a 100ms -v 0ms --upstream-host 192.0.2.6",
+			expectedCmd: "xk6-disruptor-agent dns -d 60s -r 0.5 --seed 7658614687045355738",
 			expectError: false,
-			cmdError:    nil,
 		},
 		{
-			title:  "Test exclude list",
-			target: buildPodWithPort("my-app-pod", "grpc", 3000),
-			fault: GrpcFault{
-				Exclude: "service1,service2",
-				Port:    intstr.FromInt32(3000),
-			},
-			opts:        GrpcDisruptionOptions{},
+			title: "Test pod with hostNetwork",
+			target: builders.NewPodBuilder("my-app-pod").
+				WithNamespace("test-ns").
+				WithHostNetwork(true).
+				Build(),
+			fault:       DNSFault{Rate: 0.5},
 			duration:    60 * time.Second,
-			expectedCmd: "xk6-disruptor-agent grpc -d 60s -t 3000 -x service1,service2 --upstream-host 192.0.2.6",
-			expectError: false,
-			cmdError:    nil,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := PodDNSFaultCommand{
+				fault:    tc.fault,
+				duration: tc.duration,
+				options:  tc.options,
+			}
+
+			cmds, err := cmd.Commands(tc.target)
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error : %v", err)
+				return
+			}
+
+			if !command.AssertCmdEquals(strings.Join(cmds.Exec, " "), tc.expectedCmd) {
+				t.Errorf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+			}
+		})
+	}
+}
+
+func Test_PodProcessFaultCommandGenerator(t *testing.T) {
+	t.Parallel()
+
+	shareProcessNamespace := true
+
+	testCases := []struct {
+		title                 string
+		fault                 ProcessFault
+		shareProcessNamespace *bool
+		duration              time.Duration
+		expectedCmd           string
+		expectError           bool
+	}{
+		{
+			title:                 "Test default signal",
+			fault:                 ProcessFault{},
+			shareProcessNamespace: &shareProcessNamespace,
+			duration:              60 * time.Second,
+			expectedCmd:           "xk6-disruptor-agent process -d 60s -s STOP",
+			expectError:           false,
 		},
 		{
-			title:       "Container port not found",
-			target:      buildPodWithPort("my-app-pod", "grpc", 3000),
+			title: "Test process name",
+			fault: ProcessFault{
+				Signal:  "tstp",
+				Process: "nginx",
+			},
+			shareProcessNamespace: &shareProcessNamespace,
+			duration:              60 * time.Second,
+			expectedCmd:           "xk6-disruptor-agent process -d 60s -s TSTP -n nginx",
+			expectError:           false,
+		},
+		{
+			title:       "Process namespace not shared",
+			fault:       ProcessFault{},
+			duration:    60 * time.Second,
 			expectError: true,
-			fault:       GrpcFault{Port: intstr.FromInt32(8080)},
-			opts:        GrpcDisruptionOptions{},
-			duration:    60,
 		},
 	}
 
@@ -261,13 +1771,15 @@ func Test_PodGrpcPFaultCommandGenerator(t *testing.T) {
 		t.Run(tc.title, func(t *testing.T) {
 			t.Parallel()
 
-			cmd := PodGrpcFaultCommand{
+			cmd := PodProcessFaultCommand{
 				fault:    tc.fault,
 				duration: tc.duration,
-				options:  tc.opts,
 			}
 
-			cmds, err := cmd.Commands(tc.target)
+			target := buildPodWithPort("my-app-pod", "http", 80)
+			target.Spec.ShareProcessNamespace = tc.shareProcessNamespace
+
+			cmds, err := cmd.Commands(target)
 
 			if tc.expectError && err == nil {
 				t.Errorf("should had failed")
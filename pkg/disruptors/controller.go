@@ -4,15 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/internal/version"
 
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"go.opentelemetry.io/otel/attribute"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+// ErrExecTimeout is returned when the command executed in a target does not complete within the exec timeout,
+// for example because the agent hangs
+var ErrExecTimeout = errors.New("command execution timed out")
+
+// ErrAgentNotResponding is returned when the agent in a target does not respond to a heartbeat during the execution
+// of a command, for example because it died
+var ErrAgentNotResponding = errors.New("agent not responding")
+
+// agentStartupInterval is the interval between the pings to an agent that is starting
+const agentStartupInterval = 200 * time.Millisecond
+
+// DefaultAgentContainerName is the name of the ephemeral container of the agent injected in the targets
+const DefaultAgentContainerName = "xk6-agent"
+
+// agentContainerName returns the name of the agent container, using the default if the given name is empty
+func agentContainerName(name string) string {
+	if name == "" {
+		return DefaultAgentContainerName
+	}
+
+	return name
+}
+
 // PodController uses a PodVisitor to perform a certain action (Visit) on a list of pods.
 // The PodVisitor is responsible for executing the action in one target pod, while the PorController
 // is responsible for coordinating the action of the PodVisitor on multiple target pods
@@ -27,7 +53,113 @@ func NewPodController(targets []corev1.Pod) *PodController {
 	}
 }
 
-// Visit allows executing a different command on each target returned by a visiting function
+// waitAgentStartup pings the agent until it responds or the startup timeout expires, as the agent may not be ready
+// to execute commands as soon as its container is running
+func (c *PodAgentVisitor) waitAgentStartup(ctx context.Context, pod corev1.Pod) error {
+	if c.options.StartupTimeout == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.StartupTimeout)
+	defer cancel()
+
+	helper := c.helperFor(pod)
+	for {
+		_, stderr, err := helper.Exec(ctx, pod.Name, c.options.ContainerName, buildPingCmd(), []byte{})
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("agent did not start in %s: %w \n%s", c.options.StartupTimeout, err, string(stderr))
+		case <-time.After(agentStartupInterval):
+		}
+	}
+}
+
+// heartbeat checks periodically that the fault injection is still running in the agent of the pod, until the
+// context is done. It returns an error if the agent does not respond.
+func (c *PodAgentVisitor) heartbeat(ctx context.Context, pod corev1.Pod) error {
+	ticker := time.NewTicker(c.options.HeartbeatInterval)
+	defer ticker.Stop()
+
+	helper := c.helperFor(pod)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		_, stderr, err := helper.Exec(ctx, pod.Name, c.options.ContainerName, buildStatusCmd(), []byte{})
+		// the heartbeat may fail because the command completed while it was executed
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: %v \n%s", ErrAgentNotResponding, err, string(stderr))
+		}
+	}
+}
+
+// WaitAgentReady waits for the given agent container to be running in all the targets for up to the given timeout,
+// using the PodHelper of the namespace of each target. After the agent is injected, its container may still be
+// starting and commands executed in it would fail.
+func (c *PodController) WaitAgentReady(
+	ctx context.Context,
+	helper PodHelperFunc,
+	container string,
+	timeout time.Duration,
+) error {
+	return c.Visit(ctx, PodVisitorFunc(func(ctx context.Context, pod corev1.Pod) error {
+		running, err := helper(pod.Namespace).WaitEphemeralContainerRunning(ctx, pod.Name, container, timeout)
+		if err != nil {
+			return fmt.Errorf("waiting agent: %w", err)
+		}
+		if !running {
+			return fmt.Errorf("agent is not running after %s", timeout)
+		}
+
+		return nil
+	}))
+}
+
+// AgentLogs returns the logs of the given agent container in a target, using the PodHelper of the namespace of the
+// target. The logs report why the agent failed to inject a fault.
+func (c *PodController) AgentLogs(
+	ctx context.Context,
+	helper PodHelperFunc,
+	container string,
+	pod string,
+) ([]byte, error) {
+	for _, target := range c.targets {
+		if target.Name == pod {
+			return helper(target.Namespace).Logs(ctx, target.Name, container)
+		}
+	}
+
+	return nil, fmt.Errorf("pod %q is not a target", pod)
+}
+
+// TargetError is the error of the visit to one of the targets of a PodController
+type TargetError struct {
+	// name of the target pod
+	Pod string
+	Err error
+}
+
+// Error implements the error interface
+func (e TargetError) Error() string {
+	return fmt.Sprintf("target %q: %v", e.Pod, e.Err)
+}
+
+// Unwrap returns the error of the visit
+func (e TargetError) Unwrap() error {
+	return e.Err
+}
+
+// Visit allows executing a different command on each target returned by a visiting function.
+// If a visit fails, the visits in progress are cancelled and Visit returns the errors of all the failed visits,
+// each one wrapped in a TargetError.
+// If the context is cancelled, Visit waits for the visits in progress to return before returning.
 func (c *PodController) Visit(ctx context.Context, visitor PodVisitor) error {
 	// if there are no targets, nothing to do
 	if len(c.targets) == 0 {
@@ -43,31 +175,49 @@ func (c *PodController) Visit(ctx context.Context, visitor PodVisitor) error {
 
 	for _, pod := range c.targets {
 		go func(pod corev1.Pod) {
-			doneCh <- visitor.Visit(visitCtx, pod)
+			if err := visitor.Visit(visitCtx, pod); err != nil {
+				doneCh <- TargetError{Pod: pod.Name, Err: err}
+				return
+			}
+			doneCh <- nil
 		}(pod)
 	}
 
+	var errs []error
 	pending := len(c.targets)
-	for {
+	for pending > 0 {
 		select {
 		case e := <-doneCh:
-			if e != nil {
-				return e
-			}
 			pending--
-			if pending == 0 {
-				return nil
+			// visits cancelled because of a previous failure are not reported as failed
+			if e != nil && (len(errs) == 0 || !errors.Is(e, context.Canceled)) {
+				errs = append(errs, e)
+				cancelVisit()
 			}
 		case <-ctx.Done():
-			return ctx.Err()
+			// wait for the visitors to return, so they can undo their actions (e.g. stop the agent)
+			// before the cancellation is reported
+			for ; pending > 0; pending-- {
+				<-doneCh
+			}
+			if len(errs) == 0 {
+				return ctx.Err()
+			}
 		}
 	}
+
+	return errors.Join(errs...)
 }
 
 // VisitCommands contains the commands to be executed when visiting a pod
 type VisitCommands struct {
 	Exec    []string
 	Cleanup []string
+	// Stdin is the payload sent to the standard input of the Exec command, for example a specification too large
+	// to be passed as arguments. If nil, nothing is sent.
+	Stdin []byte
+	// Ports of the pod the command applies a fault to, with the defaults resolved
+	Ports []ResolvedPort
 }
 
 // PodVisitor is the interface implemented by objects that perform actions on a Pod
@@ -89,6 +239,19 @@ type PodAgentVisitor struct {
 	helper  helpers.PodHelper
 	options PodAgentVisitorOptions
 	command PodVisitCommand
+	// slots for executing the command. nil if the concurrency is not bounded
+	execSlots chan struct{}
+	mutex     sync.Mutex
+	// stderr output of the commands that completed successfully, by key of the pod
+	warnings map[string]string
+	// commands recorded instead of executed in dry-run mode, by key of the pod
+	dryRunCommands map[string][]string
+	// configuration of the faults applied by the commands, by key of the pod
+	resolvedFaults map[string]ResolvedFault
+	// called when the execution of the command starts in a pod, if not nil
+	onExec func(pod corev1.Pod)
+	// called when the execution of the command ends in a pod, if not nil
+	onExecDone func(pod corev1.Pod)
 }
 
 // NewPodAgentVisitor creates a new pod visitor
@@ -104,71 +267,214 @@ func NewPodAgentVisitor(
 	if options.Timeout < 0 {
 		options.Timeout = 0
 	}
+	if options.InjectBackoff == 0 {
+		options.InjectBackoff = time.Second
+	}
+	if options.StartupTimeout == 0 {
+		options.StartupTimeout = 10 * time.Second
+	}
+	if options.StartupTimeout < 0 {
+		options.StartupTimeout = 0
+	}
+	options.ContainerName = agentContainerName(options.ContainerName)
+
+	var execSlots chan struct{}
+	if options.MaxConcurrency > 0 {
+		execSlots = make(chan struct{}, options.MaxConcurrency)
+	}
 
 	return &PodAgentVisitor{
-		helper:  helper,
-		options: options,
-		command: command,
+		helper:         helper,
+		options:        options,
+		command:        command,
+		execSlots:      execSlots,
+		warnings:       map[string]string{},
+		dryRunCommands: map[string][]string{},
+		resolvedFaults: map[string]ResolvedFault{},
+	}
+}
+
+// helperFor returns the PodHelper for the namespace of the pod
+func (c *PodAgentVisitor) helperFor(pod corev1.Pod) helpers.PodHelper {
+	if c.options.NamespaceHelper == nil {
+		return c.helper
 	}
+
+	return c.options.NamespaceHelper(pod.Namespace)
 }
 
 // injectDisruptorAgent injects the Disruptor agent in the target pods
-func (c *PodAgentVisitor) injectDisruptorAgent(ctx context.Context, pod corev1.Pod) error {
-	var (
-		rootUser     = int64(0)
-		rootGroup    = int64(0)
-		runAsNonRoot = false
+func (c *PodAgentVisitor) injectDisruptorAgent(ctx context.Context, pod corev1.Pod) (err error) {
+	ctx, span := startSpan(
+		ctx,
+		"InjectDisruptorAgent",
+		attribute.String("disruptor.pod", pod.Name),
+		attribute.String("disruptor.namespace", pod.Namespace),
 	)
+	defer func() { endSpan(span, err) }()
+
+	image := c.options.AgentImage
+	if image == "" {
+		image = version.AgentImage()
+	}
 
 	agentContainer := corev1.EphemeralContainer{
 		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
-			Name:            "xk6-agent",
-			Image:           version.AgentImage(),
+			Name:            c.options.ContainerName,
+			Image:           image,
 			ImagePullPolicy: corev1.PullIfNotPresent,
-			SecurityContext: &corev1.SecurityContext{
-				Capabilities: &corev1.Capabilities{
-					Add: []corev1.Capability{"NET_ADMIN"},
-				},
-				RunAsUser:    &rootUser,
-				RunAsGroup:   &rootGroup,
-				RunAsNonRoot: &runAsNonRoot,
-			},
-			TTY:   true,
-			Stdin: true,
+			SecurityContext: c.options.SecurityContext.build(),
+			TTY:             true,
+			Stdin:           true,
 		},
 	}
 
-	return c.helper.AttachEphemeralContainer(
-		ctx,
-		pod.Name,
-		agentContainer,
-		helpers.AttachOptions{
-			Timeout:        c.options.Timeout,
-			IgnoreIfExists: true,
-		},
-	)
+	// retry transient failures, doubling the backoff after each attempt
+	backoff := c.options.InjectBackoff
+	for attempt := uint(0); ; attempt++ {
+		err = c.helperFor(pod).AttachEphemeralContainer(
+			ctx,
+			pod.Name,
+			agentContainer,
+			helpers.AttachOptions{
+				Timeout:              c.options.Timeout,
+				IgnoreIfExists:       true,
+				FailOnImagePullError: c.options.FailOnImagePullError,
+			},
+		)
+		if err == nil || attempt >= c.options.InjectRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
 }
 
-// Visit allows executing a different command on each target returned by a visiting function
-func (c *PodAgentVisitor) Visit(ctx context.Context, pod corev1.Pod) error {
+// startAgent injects the agent in the pod and waits for it to start, unless it was already injected
+func (c *PodAgentVisitor) startAgent(ctx context.Context, pod corev1.Pod) error {
+	// an agent injected before the pod was selected has already started
+	startedAgent := hasAgent(pod, c.options.ContainerName)
+
 	err := c.injectDisruptorAgent(ctx, pod)
+	if isSecurityPolicyViolation(err) {
+		return fmt.Errorf(
+			"injecting agent in the pod %q: %w, its security context can be changed with the options: %w",
+			pod.Name,
+			ErrAgentSecurityPolicy,
+			err,
+		)
+	}
 	if err != nil {
 		return fmt.Errorf("injecting agent in the pod %q: %w", pod.Name, err)
 	}
 
+	if !startedAgent {
+		err = c.waitAgentStartup(ctx, pod)
+		if err != nil {
+			return fmt.Errorf("waiting agent in the pod %q: %w", pod.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Visit allows executing a different command on each target returned by a visiting function
+func (c *PodAgentVisitor) Visit(ctx context.Context, pod corev1.Pod) error {
+	if c.options.DryRun {
+		return c.recordCommand(pod)
+	}
+
+	if !c.options.AgentRunning {
+		err := c.startAgent(ctx, pod)
+		if err != nil {
+			return err
+		}
+	}
+
 	// get the command to execute in the target
 	commands, err := c.command.Commands(pod)
 	if err != nil {
 		return fmt.Errorf("unable to get command for pod %q: %w", pod.Name, err)
 	}
 
-	_, stderr, err := c.helper.Exec(ctx, pod.Name, "xk6-agent", commands.Exec, []byte{})
+	// wait for a slot for executing the command, if the concurrency is bounded
+	if c.execSlots != nil {
+		select {
+		case c.execSlots <- struct{}{}:
+			defer func() { <-c.execSlots }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	stdin := commands.Stdin
+	if stdin == nil {
+		stdin = []byte{}
+	}
+
+	c.recordResolvedFault(pod, commands)
+
+	if c.onExec != nil {
+		c.onExec(pod)
+	}
+
+	execCtx := ctx
+	if c.options.ExecTimeout > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(ctx, c.options.ExecTimeout)
+		defer cancel()
+	}
 
-	if err != nil && commands.Cleanup != nil {
+	// the command is stopped if the agent does not respond to the heartbeat
+	var heartbeatErr error
+	stopHeartbeat := func() {}
+	if c.options.HeartbeatInterval > 0 {
+		var cancelExec context.CancelFunc
+		execCtx, cancelExec = context.WithCancel(execCtx)
+		defer cancelExec()
+
+		heartbeatCtx, cancelHeartbeat := context.WithCancel(execCtx)
+		heartbeatDone := make(chan struct{})
+		go func() {
+			defer close(heartbeatDone)
+			if heartbeatErr = c.heartbeat(heartbeatCtx, pod); heartbeatErr != nil {
+				cancelExec()
+			}
+		}()
+		stopHeartbeat = func() {
+			cancelHeartbeat()
+			<-heartbeatDone
+		}
+	}
+
+	helper := c.helperFor(pod)
+	_, stderr, err := helper.Exec(execCtx, pod.Name, c.options.ContainerName, commands.Exec, stdin)
+	stopHeartbeat()
+	if c.onExecDone != nil {
+		c.onExecDone(pod)
+	}
+
+	// the agent is also stopped if the context was cancelled or expired, in case the exec stream was closed
+	// without error
+	if (err != nil || execCtx.Err() != nil) && commands.Cleanup != nil {
 		// we ignore errors because we are reporting the reason of the exec failure
 		// we use a fresh context because the context used in exec may have been cancelled or expired
 		//nolint:contextcheck
-		_, _, _ = c.helper.Exec(context.TODO(), pod.Name, "xk6-agent", commands.Cleanup, []byte{})
+		_, _, _ = helper.Exec(context.TODO(), pod.Name, c.options.ContainerName, commands.Cleanup, []byte{})
+	}
+
+	if heartbeatErr != nil {
+		return fmt.Errorf("agent in pod %q stopped responding: %w", pod.Name, heartbeatErr)
+	}
+
+	// the exec timeout expired while the context of the visit is still active
+	if ctx.Err() == nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("command in pod %q did not complete in %s: %w", pod.Name, c.options.ExecTimeout, ErrExecTimeout)
 	}
 
 	// if the context is cancelled, don't report error (we assume the caller is reporting this error)
@@ -176,18 +482,142 @@ func (c *PodAgentVisitor) Visit(ctx context.Context, pod corev1.Pod) error {
 		return fmt.Errorf("failed command execution for pod %q: %w \n%s", pod.Name, err, string(stderr))
 	}
 
+	// the agent may report non-fatal issues even if the command succeeds
+	if err == nil && len(stderr) > 0 {
+		c.mutex.Lock()
+		c.warnings[targetKey(pod)] = string(stderr)
+		c.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// recordCommand records the command to execute in the pod instead of executing it
+func (c *PodAgentVisitor) recordCommand(pod corev1.Pod) error {
+	commands, err := c.command.Commands(pod)
+	if err != nil {
+		return fmt.Errorf("unable to get command for pod %q: %w", pod.Name, err)
+	}
+
+	c.mutex.Lock()
+	c.dryRunCommands[targetKey(pod)] = commands.Exec
+	c.mutex.Unlock()
+
+	c.recordResolvedFault(pod, commands)
+
 	return nil
 }
 
+// recordResolvedFault records the configuration of the fault applied by the commands to the pod
+func (c *PodAgentVisitor) recordResolvedFault(pod corev1.Pod, commands VisitCommands) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.resolvedFaults[targetKey(pod)] = ResolvedFault{
+		Ports:   commands.Ports,
+		Command: commands.Exec,
+	}
+}
+
+// DryRunCommands returns the commands recorded instead of executed in dry-run mode, by the key of the pod
+func (c *PodAgentVisitor) DryRunCommands() map[string][]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	commands := make(map[string][]string, len(c.dryRunCommands))
+	for pod, command := range c.dryRunCommands {
+		commands[pod] = command
+	}
+
+	return commands
+}
+
+// ResolvedFaults returns the configuration of the faults applied by the commands, by the key of the pod
+func (c *PodAgentVisitor) ResolvedFaults() map[string]ResolvedFault {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	faults := make(map[string]ResolvedFault, len(c.resolvedFaults))
+	for pod, fault := range c.resolvedFaults {
+		faults[pod] = fault
+	}
+
+	return faults
+}
+
+// Warnings returns the output to stderr of the commands that completed successfully, by the key of the pod
+func (c *PodAgentVisitor) Warnings() map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	warnings := make(map[string]string, len(c.warnings))
+	for pod, stderr := range c.warnings {
+		warnings[pod] = stderr
+	}
+
+	return warnings
+}
+
+// targetKey returns the key of the pod in the results of the fault injections, its namespace and name separated by
+// "/", so the results of pods with the same name in different namespaces do not overwrite each other
+func targetKey(pod corev1.Pod) string {
+	return types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}.String()
+}
+
 // PodAgentVisitorOptions defines the options for the PodVisitor
 type PodAgentVisitorOptions struct {
 	// Defines the timeout for injecting the agent
 	Timeout time.Duration
+	// Number of times the injection of the agent is retried if it fails. Zero means no retries.
+	InjectRetries uint
+	// Time to wait before the first retry of the injection of the agent. It is doubled after each retry.
+	// A zero value forces default.
+	InjectBackoff time.Duration
+	// Defines the timeout for the agent to respond to a ping once its container is running. A zero value forces
+	// default. A negative value forces no waiting.
+	StartupTimeout time.Duration
+	// Maximum time the command is executed in each pod. If it does not complete, it is stopped and ErrExecTimeout
+	// is returned. Zero means no limit.
+	ExecTimeout time.Duration
+	// Interval between the checks that the agent is still running the command in each pod. If the agent does not
+	// respond, the command is stopped and ErrAgentNotResponding is returned. Zero means no checks.
+	HeartbeatInterval time.Duration
+	// Fail as soon as the agent image cannot be pulled instead of waiting for the timeout
+	FailOnImagePullError bool
+	// Maximum number of pods the command is executed in concurrently. Zero means no limit.
+	MaxConcurrency uint
+	// Image of the agent container. If empty, the image matching the version of the disruptor is used
+	AgentImage string
+	// Name of the agent container. If empty, DefaultAgentContainerName is used. Visitors using different names
+	// inject separate agents in the pods.
+	ContainerName string
+	// Overrides of the security context of the agent container
+	SecurityContext AgentSecurityContext
+	// Record the commands instead of executing them. The agent is not injected in the pods.
+	DryRun bool
+	// The agent already runs in the container of the pods, so it is not injected. For example, in the agent pods
+	// created in the nodes by the NodeDisruptor.
+	AgentRunning bool
+	// Returns the PodHelper for the namespace of each pod, for visiting pods in multiple namespaces.
+	// If nil, the visitor's helper is used for all pods.
+	NamespaceHelper PodHelperFunc
 }
 
+// PodHelperFunc returns the PodHelper for the pods in the given namespace
+type PodHelperFunc func(namespace string) helpers.PodHelper
+
 // PodVisitCommand is a command that can be run on a given pod.
 // Implementations build the VisitCommands according to properties of the pod where it is going to run
 type PodVisitCommand interface {
 	// Commands defines the command to be executed, and optionally a cleanup command
 	Commands(corev1.Pod) (VisitCommands, error)
 }
+
+// PodVisitCommandFunc defines a function that implements the PodVisitCommand interface.
+// This allows building the commands of each pod with anonymous functions, for example using the pod's IP.
+type PodVisitCommandFunc func(corev1.Pod) (VisitCommands, error)
+
+// Commands implements PodVisitCommand interface's Commands function
+func (f PodVisitCommandFunc) Commands(pod corev1.Pod) (VisitCommands, error) {
+	return f(pod)
+}
@@ -4,14 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/grafana/xk6-disruptor/pkg/internal/version"
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
 	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
 )
@@ -20,12 +28,14 @@ type fakeCommand struct {
 	err     error
 	exec    []string
 	cleanup []string
+	stdin   []byte
 }
 
 func (f fakeCommand) Commands(_ corev1.Pod) (VisitCommands, error) {
 	return VisitCommands{
 		Exec:    f.exec,
 		Cleanup: f.cleanup,
+		Stdin:   f.stdin,
 	}, f.err
 }
 
@@ -50,6 +60,8 @@ func Test_PodAgentVisitor(t *testing.T) {
 		options     PodAgentVisitorOptions
 		expectError bool
 		expected    []helpers.Command
+		// stderr output expected to be captured from successful executions
+		expectedWarnings map[string]string
 	}{
 		{
 			title:     "successful execution",
@@ -61,13 +73,62 @@ func Test_PodAgentVisitor(t *testing.T) {
 			visitCmds: visitCommands(),
 			err:       nil,
 			options: PodAgentVisitorOptions{
-				Timeout: -1,
+				Timeout:        -1,
+				StartupTimeout: -1,
 			},
 			expectError: false,
 			expected: []helpers.Command{
 				{Pod: "pod1", Container: "xk6-agent", Namespace: "test-ns", Command: []string{"command"}, Stdin: []byte{}},
 			},
 		},
+		{
+			title:     "successful execution with stdin",
+			namespace: "test-ns",
+			pod: builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				WithIP("192.0.2.6").
+				Build(),
+			visitCmds: fakeCommand{
+				exec:    []string{"command"},
+				cleanup: []string{"cleanup"},
+				stdin:   []byte(`{"errorRate":0.1}`),
+			},
+			err: nil,
+			options: PodAgentVisitorOptions{
+				Timeout:        -1,
+				StartupTimeout: -1,
+			},
+			expectError: false,
+			expected: []helpers.Command{
+				{
+					Pod:       "pod1",
+					Container: "xk6-agent",
+					Namespace: "test-ns",
+					Command:   []string{"command"},
+					Stdin:     []byte(`{"errorRate":0.1}`),
+				},
+			},
+		},
+		{
+			title:     "successful execution with warnings",
+			namespace: "test-ns",
+			pod: builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				WithIP("192.0.2.6").
+				Build(),
+			visitCmds: visitCommands(),
+			err:       nil,
+			stderr:    []byte("warning output"),
+			options: PodAgentVisitorOptions{
+				Timeout:        -1,
+				StartupTimeout: -1,
+			},
+			expectError: false,
+			expected: []helpers.Command{
+				{Pod: "pod1", Container: "xk6-agent", Namespace: "test-ns", Command: []string{"command"}, Stdin: []byte{}},
+			},
+			expectedWarnings: map[string]string{"test-ns/pod1": "warning output"},
+		},
 		{
 			title:     "failed execution",
 			namespace: "test-ns",
@@ -79,7 +140,8 @@ func Test_PodAgentVisitor(t *testing.T) {
 			err:       fmt.Errorf("fake error"),
 			stderr:    []byte("error output"),
 			options: PodAgentVisitorOptions{
-				Timeout: -1,
+				Timeout:        -1,
+				StartupTimeout: -1,
 			},
 			expectError: true,
 			expected: []helpers.Command{
@@ -143,10 +205,336 @@ func Test_PodAgentVisitor(t *testing.T) {
 			if diff := cmp.Diff(tc.expected, executor.GetHistory()); diff != "" {
 				t.Errorf("Expected command did not match returned:\n%s", diff)
 			}
+
+			if diff := cmp.Diff(tc.expectedWarnings, visitor.Warnings(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Expected warnings did not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodAgentVisitorDryRun(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod1").
+		WithNamespace("test-ns").
+		WithIP("192.0.2.6").
+		Build()
+
+	client := fake.NewSimpleClientset(&pod)
+	executor := helpers.NewFakePodCommandExecutor()
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+	visitor := NewPodAgentVisitor(
+		helper,
+		PodAgentVisitorOptions{
+			Timeout:        -1,
+			StartupTimeout: -1,
+			DryRun:         true,
+		},
+		visitCommands(),
+	)
+
+	err := visitor.Visit(context.TODO(), pod)
+	if err != nil {
+		t.Fatalf("failed unexpectedly: %v", err)
+	}
+
+	if history := executor.GetHistory(); len(history) != 0 {
+		t.Fatalf("expected no command executed got %v", history)
+	}
+
+	expected := map[string][]string{"test-ns/pod1": {"command"}}
+	if diff := cmp.Diff(expected, visitor.DryRunCommands()); diff != "" {
+		t.Fatalf("expected commands do not match recorded:\n%s", diff)
+	}
+
+	injected, err := client.CoreV1().Pods("test-ns").Get(context.TODO(), "pod1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("retrieving pod: %v", err)
+	}
+
+	if len(injected.Spec.EphemeralContainers) != 0 {
+		t.Fatalf("expected agent not to be injected")
+	}
+}
+
+func Test_PodAgentVisitorAgentImage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		image    string
+		expected string
+	}{
+		{
+			title:    "default image",
+			image:    "",
+			expected: version.AgentImage(),
+		},
+		{
+			title:    "custom image",
+			image:    "registry.example.com/mirror/xk6-disruptor-agent:latest",
+			expected: "registry.example.com/mirror/xk6-disruptor-agent:latest",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				WithIP("192.0.2.6").
+				Build()
+
+			client := fake.NewSimpleClientset(&pod)
+			executor := helpers.NewFakePodCommandExecutor()
+			helper := helpers.NewPodHelper(client, executor, "test-ns")
+			visitor := NewPodAgentVisitor(
+				helper,
+				PodAgentVisitorOptions{
+					Timeout:        -1,
+					StartupTimeout: -1,
+					AgentImage:     tc.image,
+				},
+				visitCommands(),
+			)
+
+			err := visitor.Visit(context.TODO(), pod)
+			if err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			injected, err := client.CoreV1().Pods("test-ns").Get(context.TODO(), "pod1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("retrieving pod: %v", err)
+			}
+
+			if len(injected.Spec.EphemeralContainers) != 1 {
+				t.Fatalf("expected 1 ephemeral container got %d", len(injected.Spec.EphemeralContainers))
+			}
+
+			image := injected.Spec.EphemeralContainers[0].Image
+			if image != tc.expected {
+				t.Fatalf("expected image %q got %q", tc.expected, image)
+			}
 		})
 	}
 }
 
+func Test_PodAgentVisitorSecurityContext(t *testing.T) {
+	t.Parallel()
+
+	root := int64(0)
+	user := int64(1000)
+	group := int64(3000)
+	runAsNonRoot := true
+	runAsRoot := false
+
+	testCases := []struct {
+		title           string
+		securityContext AgentSecurityContext
+		expected        *corev1.SecurityContext
+	}{
+		{
+			title:           "default security context",
+			securityContext: AgentSecurityContext{},
+			expected: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{
+					Add: []corev1.Capability{"NET_ADMIN"},
+				},
+				RunAsUser:    &root,
+				RunAsGroup:   &root,
+				RunAsNonRoot: &runAsRoot,
+			},
+		},
+		{
+			title: "overridden security context",
+			securityContext: AgentSecurityContext{
+				Capabilities:     []string{"NET_ADMIN", "NET_RAW"},
+				DropCapabilities: []string{"ALL"},
+				RunAsUser:        1000,
+				RunAsGroup:       3000,
+				RunAsNonRoot:     true,
+				SeccompProfile:   "RuntimeDefault",
+			},
+			expected: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{
+					Add:  []corev1.Capability{"NET_ADMIN", "NET_RAW"},
+					Drop: []corev1.Capability{"ALL"},
+				},
+				RunAsUser:    &user,
+				RunAsGroup:   &group,
+				RunAsNonRoot: &runAsNonRoot,
+				SeccompProfile: &corev1.SeccompProfile{
+					Type: corev1.SeccompProfileTypeRuntimeDefault,
+				},
+			},
+		},
+		{
+			title: "NET_ADMIN dropped",
+			securityContext: AgentSecurityContext{
+				DropCapabilities: []string{"NET_ADMIN"},
+			},
+			expected: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{
+					Drop: []corev1.Capability{"NET_ADMIN"},
+				},
+				RunAsUser:    &root,
+				RunAsGroup:   &root,
+				RunAsNonRoot: &runAsRoot,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				WithIP("192.0.2.6").
+				Build()
+
+			client := fake.NewSimpleClientset(&pod)
+			executor := helpers.NewFakePodCommandExecutor()
+			helper := helpers.NewPodHelper(client, executor, "test-ns")
+			visitor := NewPodAgentVisitor(
+				helper,
+				PodAgentVisitorOptions{
+					Timeout:         -1,
+					StartupTimeout:  -1,
+					SecurityContext: tc.securityContext,
+				},
+				visitCommands(),
+			)
+
+			err := visitor.Visit(context.TODO(), pod)
+			if err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			injected, err := client.CoreV1().Pods("test-ns").Get(context.TODO(), "pod1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("retrieving pod: %v", err)
+			}
+
+			if len(injected.Spec.EphemeralContainers) != 1 {
+				t.Fatalf("expected 1 ephemeral container got %d", len(injected.Spec.EphemeralContainers))
+			}
+
+			securityContext := injected.Spec.EphemeralContainers[0].SecurityContext
+			if diff := cmp.Diff(tc.expected, securityContext); diff != "" {
+				t.Fatalf("expected security context does not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodAgentVisitorSecurityPolicy(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod1").
+		WithNamespace("test-ns").
+		Build()
+
+	client := fake.NewSimpleClientset(&pod)
+	client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "ephemeralcontainers" {
+			return false, nil, nil
+		}
+
+		return true, nil, apierrors.NewForbidden(
+			corev1.Resource("pods"),
+			"pod1",
+			errors.New(`violates PodSecurity "baseline:latest": non-default capabilities`+
+				` (container "xk6-agent" must not include "NET_ADMIN" in securityContext.capabilities.add)`),
+		)
+	})
+
+	visitor := NewPodAgentVisitor(
+		helpers.NewPodHelper(client, helpers.NewFakePodCommandExecutor(), "test-ns"),
+		PodAgentVisitorOptions{
+			Timeout:        -1,
+			StartupTimeout: -1,
+		},
+		visitCommands(),
+	)
+
+	err := visitor.Visit(context.TODO(), pod)
+	if !errors.Is(err, ErrAgentSecurityPolicy) {
+		t.Fatalf("expected error %v got %v", ErrAgentSecurityPolicy, err)
+	}
+
+	if !apierrors.IsForbidden(err) {
+		t.Fatalf("expected forbidden error to be recovered from %v", err)
+	}
+}
+
+func Test_PodAgentVisitorContainerName(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod1").
+		WithNamespace("test-ns").
+		WithIP("192.0.2.6").
+		Build()
+
+	client := fake.NewSimpleClientset(&pod)
+	executor := helpers.NewFakePodCommandExecutor()
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+
+	names := []string{"", "xk6-agent-2"}
+	for _, name := range names {
+		visitor := NewPodAgentVisitor(
+			helper,
+			PodAgentVisitorOptions{
+				Timeout:        -1,
+				StartupTimeout: -1,
+				ContainerName:  name,
+			},
+			visitCommands(),
+		)
+
+		// visit the pod as updated by the previous injections
+		current, err := client.CoreV1().Pods("test-ns").Get(context.TODO(), "pod1", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("retrieving pod: %v", err)
+		}
+
+		if err = visitor.Visit(context.TODO(), *current); err != nil {
+			t.Fatalf("failed unexpectedly: %v", err)
+		}
+	}
+
+	injected, err := client.CoreV1().Pods("test-ns").Get(context.TODO(), "pod1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("retrieving pod: %v", err)
+	}
+
+	containers := []string{}
+	for _, container := range injected.Spec.EphemeralContainers {
+		containers = append(containers, container.Name)
+	}
+
+	expected := []string{DefaultAgentContainerName, "xk6-agent-2"}
+	if diff := cmp.Diff(expected, containers); diff != "" {
+		t.Fatalf("injected containers do not match expected:\n%s", diff)
+	}
+
+	executed := []string{}
+	for _, command := range executor.GetHistory() {
+		executed = append(executed, command.Container)
+	}
+
+	if diff := cmp.Diff(expected, executed); diff != "" {
+		t.Fatalf("commands were not executed in the expected containers:\n%s", diff)
+	}
+}
+
 var errFailed = errors.New("failed")
 
 func Test_PodController(t *testing.T) {
@@ -212,7 +600,7 @@ func Test_PodController(t *testing.T) {
 				time.Sleep(2 * time.Second)
 				return nil
 			}),
-			expectError: errFailed, // if error is not handled immediately, DeadlineExceeded would be returned
+			expectError: errFailed, // the error is reported even if the context expires waiting for pod2
 		},
 		{
 			title: "context expired",
@@ -252,3 +640,761 @@ func Test_PodController(t *testing.T) {
 		})
 	}
 }
+
+func Test_PodControllerTargetErrors(t *testing.T) {
+	t.Parallel()
+
+	targets := []corev1.Pod{
+		builders.NewPodBuilder("pod1").WithNamespace("test-ns").Build(),
+		builders.NewPodBuilder("pod2").WithNamespace("test-ns").Build(),
+		builders.NewPodBuilder("pod3").WithNamespace("test-ns").Build(),
+	}
+
+	// pod1 and pod3 fail, pod2 runs until it is cancelled
+	visitor := PodVisitorFunc(func(ctx context.Context, pod corev1.Pod) error {
+		if pod.Name == "pod2" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return errFailed
+	})
+
+	err := NewPodController(targets).Visit(context.TODO(), visitor)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v got %v", errFailed, err)
+	}
+
+	var targetErr TargetError
+	if !errors.As(err, &targetErr) {
+		t.Fatalf("expected a TargetError got %v", err)
+	}
+
+	for _, pod := range []string{"pod1", "pod3"} {
+		if !strings.Contains(err.Error(), fmt.Sprintf("%q", pod)) {
+			t.Errorf("error does not report failed target %q: %v", pod, err)
+		}
+	}
+
+	if strings.Contains(err.Error(), `"pod2"`) {
+		t.Errorf("error reports cancelled target pod2 as failed: %v", err)
+	}
+}
+
+func Test_PodControllerVisitCommandFunc(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title            string
+		failing          string
+		expectedCommands []helpers.Command
+	}{
+		{
+			title: "command of each target",
+			expectedCommands: []helpers.Command{
+				{Pod: "pod1", Namespace: "ns-1", Container: "xk6-agent", Command: []string{"ping", "192.0.2.6"}},
+				{Pod: "pod2", Namespace: "ns-2", Container: "xk6-agent", Command: []string{"ping", "192.0.2.7"}},
+			},
+		},
+		{
+			title:   "failed command of a target",
+			failing: "pod2",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			targets := []corev1.Pod{
+				builders.NewPodBuilder("pod1").WithNamespace("ns-1").WithIP("192.0.2.6").Build(),
+				builders.NewPodBuilder("pod2").WithNamespace("ns-2").WithIP("192.0.2.7").Build(),
+			}
+
+			client := fake.NewSimpleClientset(&targets[0], &targets[1])
+			executor := helpers.NewFakePodCommandExecutor()
+
+			// the command of each target is built from the target's IP
+			command := PodVisitCommandFunc(func(pod corev1.Pod) (VisitCommands, error) {
+				if pod.Name == tc.failing {
+					return VisitCommands{}, errFailed
+				}
+
+				return VisitCommands{Exec: []string{"ping", pod.Status.PodIP}}, nil
+			})
+
+			visitor := NewPodAgentVisitor(
+				helpers.NewPodHelper(client, executor, "ns-1"),
+				PodAgentVisitorOptions{
+					Timeout:        -1,
+					StartupTimeout: -1,
+					NamespaceHelper: func(namespace string) helpers.PodHelper {
+						return helpers.NewPodHelper(client, executor, namespace)
+					},
+				},
+				command,
+			)
+
+			err := NewPodController(targets).Visit(context.TODO(), visitor)
+			if tc.failing != "" {
+				if !errors.Is(err, errFailed) {
+					t.Fatalf("expected %v got %v", errFailed, err)
+				}
+
+				if !strings.Contains(err.Error(), fmt.Sprintf("%q", tc.failing)) {
+					t.Fatalf("error does not report failed target %q: %v", tc.failing, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			history := executor.GetHistory()
+			sort.Slice(history, func(i, j int) bool { return history[i].Pod < history[j].Pod })
+			if diff := cmp.Diff(tc.expectedCommands, history, cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("executed commands do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+// agentStatus returns the status of the agent container, running or waiting
+func agentStatus(running bool) []corev1.ContainerStatus {
+	state := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+	if running {
+		state = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	}
+
+	return []corev1.ContainerStatus{{Name: "xk6-agent", State: state}}
+}
+
+func Test_PodControllerWaitAgentReady(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		pods          int
+		runningPods   int
+		expectError   bool
+		expectWaiting []string
+	}{
+		{
+			title:       "agents start running",
+			pods:        2,
+			runningPods: 2,
+			expectError: false,
+		},
+		{
+			title:         "agent keeps waiting",
+			pods:          2,
+			runningPods:   1,
+			expectError:   true,
+			expectWaiting: []string{"pod-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+			targets := []corev1.Pod{}
+			for i := 0; i < tc.pods; i++ {
+				// each pod is in its own namespace, as the fake client does not filter the watched pods by name
+				pod := builders.NewPodBuilder(fmt.Sprintf("pod-%d", i)).WithNamespace(fmt.Sprintf("ns-%d", i)).Build()
+				pod.Status.EphemeralContainerStatuses = agentStatus(false)
+
+				_, err := client.CoreV1().Pods(pod.Namespace).Create(context.TODO(), &pod, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("creating pod: %v", err)
+				}
+				targets = append(targets, pod)
+			}
+
+			// the agents start running after a delay
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				for i := 0; i < tc.runningPods; i++ {
+					pod := targets[i]
+					pod.Status.EphemeralContainerStatuses = agentStatus(true)
+					_, err := client.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), &pod, metav1.UpdateOptions{})
+					if err != nil {
+						t.Errorf("updating pod: %v", err)
+					}
+				}
+			}()
+
+			helper := func(namespace string) helpers.PodHelper {
+				return helpers.NewPodHelper(client, nil, namespace)
+			}
+
+			err := NewPodController(targets).WaitAgentReady(context.TODO(), helper, DefaultAgentContainerName, time.Second)
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			for _, pod := range tc.expectWaiting {
+				if !strings.Contains(err.Error(), fmt.Sprintf("%q", pod)) {
+					t.Errorf("error does not report target %q: %v", pod, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_PodControllerAgentLogs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		pod          string
+		expectedLogs string
+		expectError  bool
+	}{
+		{
+			title:        "target",
+			pod:          "pod-1",
+			expectedLogs: "fake logs",
+			expectError:  false,
+		},
+		{
+			title:       "not a target",
+			pod:         "pod-2",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod-1").WithNamespace("test-ns").Build()
+			client := fake.NewSimpleClientset(&pod)
+
+			helper := func(namespace string) helpers.PodHelper {
+				return helpers.NewPodHelper(client, nil, namespace)
+			}
+
+			logs, err := NewPodController([]corev1.Pod{pod}).
+				AgentLogs(context.TODO(), helper, DefaultAgentContainerName, tc.pod)
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if string(logs) != tc.expectedLogs {
+				t.Fatalf("expected logs %q got %q", tc.expectedLogs, string(logs))
+			}
+		})
+	}
+}
+
+// concurrencyExecutor is a PodCommandExecutor that records the maximum number of concurrent executions
+type concurrencyExecutor struct {
+	mutex    sync.Mutex
+	running  uint
+	max      uint
+	executed uint
+}
+
+func (e *concurrencyExecutor) Exec(
+	_ context.Context,
+	_ string,
+	_ string,
+	_ string,
+	_ []string,
+	_ []byte,
+) ([]byte, []byte, error) {
+	e.mutex.Lock()
+	e.running++
+	e.executed++
+	if e.running > e.max {
+		e.max = e.running
+	}
+	e.mutex.Unlock()
+
+	// give other executions the chance to start
+	time.Sleep(10 * time.Millisecond)
+
+	e.mutex.Lock()
+	e.running--
+	e.mutex.Unlock()
+
+	return nil, nil, nil
+}
+
+func Test_PodAgentVisitorMaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title          string
+		targets        int
+		maxConcurrency uint
+	}{
+		{
+			title:          "no limit",
+			targets:        10,
+			maxConcurrency: 0,
+		},
+		{
+			title:          "sequential execution",
+			targets:        10,
+			maxConcurrency: 1,
+		},
+		{
+			title:          "limit lower than targets",
+			targets:        10,
+			maxConcurrency: 3,
+		},
+		{
+			title:          "limit higher than targets",
+			targets:        3,
+			maxConcurrency: 5,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			targets := []corev1.Pod{}
+			for i := 0; i < tc.targets; i++ {
+				pod := builders.NewPodBuilder(fmt.Sprintf("pod-%d", i)).
+					WithNamespace("test-ns").
+					Build()
+				injectAgent(&pod)
+				targets = append(targets, pod)
+			}
+
+			objs := []runtime.Object{}
+			for i := range targets {
+				objs = append(objs, &targets[i])
+			}
+
+			client := fake.NewSimpleClientset(objs...)
+			executor := &concurrencyExecutor{}
+			helper := helpers.NewPodHelper(client, executor, "test-ns")
+			visitor := NewPodAgentVisitor(
+				helper,
+				PodAgentVisitorOptions{
+					Timeout:        -1,
+					StartupTimeout: -1,
+					MaxConcurrency: tc.maxConcurrency,
+				},
+				visitCommands(),
+			)
+
+			err := NewPodController(targets).Visit(context.TODO(), visitor)
+			if err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			if executor.executed != uint(tc.targets) {
+				t.Fatalf("expected %d executions got %d", tc.targets, executor.executed)
+			}
+
+			if tc.maxConcurrency > 0 && executor.max > tc.maxConcurrency {
+				t.Fatalf("expected at most %d concurrent executions got %d", tc.maxConcurrency, executor.max)
+			}
+		})
+	}
+}
+
+// cancelExecutor is a PodCommandExecutor that blocks the execution of the command until its context is cancelled
+type cancelExecutor struct {
+	mutex sync.Mutex
+	// started is closed when the execution of the command starts
+	started chan struct{}
+	// closed is set if the execution of the command returned because its context was cancelled
+	closed bool
+	// cleanup is set if the cleanup command was executed after the execution of the command returned
+	cleanup bool
+}
+
+func (e *cancelExecutor) Exec(
+	ctx context.Context,
+	_ string,
+	_ string,
+	_ string,
+	command []string,
+	_ []byte,
+) ([]byte, []byte, error) {
+	if command[0] == "cleanup" {
+		e.mutex.Lock()
+		e.cleanup = e.closed
+		e.mutex.Unlock()
+		return nil, nil, nil
+	}
+
+	close(e.started)
+	<-ctx.Done()
+
+	e.mutex.Lock()
+	e.closed = true
+	e.mutex.Unlock()
+
+	return nil, nil, ctx.Err()
+}
+
+func Test_PodAgentVisitorCancellation(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod-1").
+		WithNamespace("test-ns").
+		Build()
+	injectAgent(&pod)
+
+	client := fake.NewSimpleClientset(&pod)
+	executor := &cancelExecutor{started: make(chan struct{})}
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+	visitor := NewPodAgentVisitor(helper, PodAgentVisitorOptions{Timeout: -1}, visitCommands())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- NewPodController([]corev1.Pod{pod}).Visit(ctx, visitor)
+	}()
+
+	select {
+	case <-executor.started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("command was not executed")
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected %v got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("visit did not return after the context was cancelled")
+	}
+
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+
+	if !executor.closed {
+		t.Fatalf("command execution was not cancelled")
+	}
+
+	if !executor.cleanup {
+		t.Fatalf("cleanup command was not executed after the command execution was cancelled")
+	}
+}
+
+// startupExecutor is a PodCommandExecutor that fails the pings to the agent a number of times before succeeding
+type startupExecutor struct {
+	mutex    sync.Mutex
+	failures int
+	pings    int
+	executed bool
+}
+
+func (e *startupExecutor) Exec(
+	_ context.Context,
+	_ string,
+	_ string,
+	_ string,
+	command []string,
+	_ []byte,
+) ([]byte, []byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if strings.Join(command, " ") != "xk6-disruptor-agent ping" {
+		e.executed = true
+		return nil, nil, nil
+	}
+
+	e.pings++
+	if e.pings <= e.failures {
+		return nil, []byte("agent not ready"), fmt.Errorf("exec failed")
+	}
+
+	return nil, nil, nil
+}
+
+func Test_PodAgentVisitorStartup(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title          string
+		injected       bool
+		failures       int
+		startupTimeout time.Duration
+		expectError    bool
+		expectedPings  int
+	}{
+		{
+			title:          "agent starts immediately",
+			failures:       0,
+			startupTimeout: time.Second,
+			expectError:    false,
+			expectedPings:  1,
+		},
+		{
+			title:          "agent starts after retries",
+			failures:       2,
+			startupTimeout: 5 * time.Second,
+			expectError:    false,
+			expectedPings:  3,
+		},
+		{
+			title:          "agent does not start",
+			failures:       100,
+			startupTimeout: 300 * time.Millisecond,
+			expectError:    true,
+		},
+		{
+			title:          "agent already injected",
+			injected:       true,
+			failures:       100,
+			startupTimeout: time.Second,
+			expectError:    false,
+			expectedPings:  0,
+		},
+		{
+			title:          "startup wait disabled",
+			failures:       100,
+			startupTimeout: -1,
+			expectError:    false,
+			expectedPings:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				Build()
+			if tc.injected {
+				pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+					{
+						EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "xk6-agent"},
+					},
+				}
+			}
+
+			client := fake.NewSimpleClientset(&pod)
+			executor := &startupExecutor{failures: tc.failures}
+			helper := helpers.NewPodHelper(client, executor, "test-ns")
+			visitor := NewPodAgentVisitor(
+				helper,
+				PodAgentVisitorOptions{
+					Timeout:        -1,
+					StartupTimeout: tc.startupTimeout,
+				},
+				visitCommands(),
+			)
+
+			err := visitor.Visit(context.TODO(), pod)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			executor.mutex.Lock()
+			defer executor.mutex.Unlock()
+
+			if executor.executed == tc.expectError {
+				t.Fatalf("expected command executed %t got %t", !tc.expectError, executor.executed)
+			}
+
+			if !tc.expectError && executor.pings != tc.expectedPings {
+				t.Fatalf("expected %d pings got %d", tc.expectedPings, executor.pings)
+			}
+		})
+	}
+}
+
+func Test_PodAgentVisitorInjectRetries(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title            string
+		failures         int
+		retries          uint
+		expectError      bool
+		expectedAttempts int
+	}{
+		{
+			title:            "attach succeeds",
+			failures:         0,
+			retries:          0,
+			expectError:      false,
+			expectedAttempts: 1,
+		},
+		{
+			title:            "attach fails without retries",
+			failures:         1,
+			retries:          0,
+			expectError:      true,
+			expectedAttempts: 1,
+		},
+		{
+			title:            "attach succeeds after retries",
+			failures:         2,
+			retries:          3,
+			expectError:      false,
+			expectedAttempts: 3,
+		},
+		{
+			title:            "attach fails after retries",
+			failures:         3,
+			retries:          2,
+			expectError:      true,
+			expectedAttempts: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				Build()
+
+			client := fake.NewSimpleClientset(&pod)
+
+			// fail the first attempts to attach the ephemeral container
+			attempts := 0
+			client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "ephemeralcontainers" {
+					return false, nil, nil
+				}
+
+				attempts++
+				if attempts <= tc.failures {
+					return true, nil, errors.New("server is busy")
+				}
+
+				return false, nil, nil
+			})
+
+			executor := helpers.NewFakePodCommandExecutor()
+			helper := helpers.NewPodHelper(client, executor, "test-ns")
+			visitor := NewPodAgentVisitor(
+				helper,
+				PodAgentVisitorOptions{
+					Timeout:        -1,
+					StartupTimeout: -1,
+					InjectRetries:  tc.retries,
+					InjectBackoff:  time.Millisecond,
+				},
+				visitCommands(),
+			)
+
+			err := visitor.Visit(context.TODO(), pod)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			if attempts != tc.expectedAttempts {
+				t.Fatalf("expected %d attempts got %d", tc.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+func Test_PodAgentVisitorStatusErrors(t *testing.T) {
+	t.Parallel()
+
+	forbidden := apierrors.NewForbidden(
+		corev1.Resource("pods"),
+		"pod1",
+		errors.New("user cannot patch pods"),
+	)
+
+	testCases := []struct {
+		title     string
+		injectErr error
+		execErr   error
+	}{
+		{
+			title:     "forbidden injecting agent",
+			injectErr: forbidden,
+		},
+		{
+			title:   "forbidden executing command",
+			execErr: forbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod1").
+				WithNamespace("test-ns").
+				Build()
+
+			client := fake.NewSimpleClientset(&pod)
+			client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "ephemeralcontainers" || tc.injectErr == nil {
+					return false, nil, nil
+				}
+
+				return true, nil, tc.injectErr
+			})
+
+			executor := helpers.NewFakePodCommandExecutor()
+			executor.SetResult([]byte{}, []byte{}, tc.execErr)
+
+			visitor := NewPodAgentVisitor(
+				helpers.NewPodHelper(client, executor, "test-ns"),
+				PodAgentVisitorOptions{
+					Timeout:        -1,
+					StartupTimeout: -1,
+				},
+				visitCommands(),
+			)
+
+			err := NewPodController([]corev1.Pod{pod}).Visit(context.TODO(), visitor)
+			if !apierrors.IsForbidden(err) {
+				t.Fatalf("expected forbidden error got %v", err)
+			}
+
+			var statusErr *apierrors.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("expected status error to be recovered from %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceInfo describes a service that can be disrupted by a ServiceDisruptor
+type ServiceInfo struct {
+	// Name of the service
+	Name string `js:"name"`
+	// Namespace of the service
+	Namespace string `js:"namespace"`
+	// Number of ready pods backing the service
+	ReadyPods int `js:"readyPods"`
+}
+
+// ListDisruptableServices returns the services in the given namespaces that have at least one ready backing pod,
+// as reported by their endpoints. If no namespace is given, the services of all the namespaces are returned.
+func ListDisruptableServices(
+	ctx context.Context,
+	k8s kubernetes.Kubernetes,
+	namespaces []string,
+) ([]ServiceInfo, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	services := []ServiceInfo{}
+	for _, namespace := range namespaces {
+		svcs, err := k8s.Client().CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("listing services: %w", err)
+		}
+
+		endpoints, err := k8s.Client().CoreV1().Endpoints(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("listing endpoints: %w", err)
+		}
+
+		// the endpoints of a service have the same namespace and name as the service
+		readyPods := map[string]int{}
+		for i := range endpoints.Items {
+			ep := &endpoints.Items[i]
+			readyPods[ep.Namespace+"/"+ep.Name] = countReadyPods(ep)
+		}
+
+		for _, svc := range svcs.Items {
+			ready := readyPods[svc.Namespace+"/"+svc.Name]
+			if ready == 0 {
+				continue
+			}
+
+			services = append(services, ServiceInfo{
+				Name:      svc.Name,
+				Namespace: svc.Namespace,
+				ReadyPods: ready,
+			})
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Namespace != services[j].Namespace {
+			return services[i].Namespace < services[j].Namespace
+		}
+		return services[i].Name < services[j].Name
+	})
+
+	return services, nil
+}
+
+// countReadyPods returns the number of pods referenced by the ready addresses of the endpoints
+func countReadyPods(ep *corev1.Endpoints) int {
+	pods := 0
+	for _, address := range helpers.ReadyAddresses(ep) {
+		if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+			pods++
+		}
+	}
+
+	return pods
+}
+
+// ListInjectedPodsOptions defines options for listing the pods that have the agent injected
+type ListInjectedPodsOptions struct {
+	// name of the ephemeral container of the agent (default "xk6-agent"). It must match the name used when
+	// injecting the faults.
+	AgentContainerName string `js:"agentContainerName"`
+}
+
+// ListInjectedPods returns the names of the pods in the given namespace that have the agent injected in an
+// ephemeral container, for example for cleaning up the injections left by a test that crashed. As ephemeral
+// containers cannot be removed, the pods keep the container after the agent stops.
+func ListInjectedPods(
+	ctx context.Context,
+	k8s kubernetes.Kubernetes,
+	namespace string,
+	options ListInjectedPodsOptions,
+) ([]string, error) {
+	pods, err := k8s.PodHelper(namespace).List(ctx, helpers.PodFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	injected := []string{}
+	for _, pod := range pods {
+		if hasAgent(pod, agentContainerName(options.AgentContainerName)) {
+			injected = append(injected, pod.Name)
+		}
+	}
+
+	sort.Strings(injected)
+
+	return injected, nil
+}
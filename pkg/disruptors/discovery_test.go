@@ -0,0 +1,178 @@
+package disruptors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sintstr "k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_ListDisruptableServices(t *testing.T) {
+	t.Parallel()
+
+	objs := []runtime.Object{}
+	for _, svc := range []struct {
+		name      string
+		namespace string
+	}{
+		{name: "frontend", namespace: "team-a"},
+		{name: "backend", namespace: "team-a"},
+		{name: "not-ready", namespace: "team-a"},
+		{name: "no-endpoints", namespace: "team-a"},
+		{name: "api", namespace: "team-b"},
+	} {
+		objs = append(objs, builders.NewServiceBuilder(svc.name).
+			WithNamespace(svc.namespace).
+			WithSelectorLabel("app", svc.name).
+			WithPort("http", 80, k8sintstr.FromInt(80)).
+			BuildAsPtr(),
+		)
+	}
+
+	objs = append(
+		objs,
+		builders.NewEndPointsBuilder("frontend").
+			WithNamespace("team-a").
+			WithSubset("http", 80, []string{"frontend-1", "frontend-2"}).
+			WithNotReadyAddresses("http", 80, []string{"frontend-3"}).
+			BuildAsPtr(),
+		builders.NewEndPointsBuilder("backend").
+			WithNamespace("team-a").
+			WithSubset("http", 80, []string{"backend-1"}).
+			BuildAsPtr(),
+		builders.NewEndPointsBuilder("not-ready").
+			WithNamespace("team-a").
+			WithNotReadyAddresses("http", 80, []string{"not-ready-1"}).
+			BuildAsPtr(),
+		builders.NewEndPointsBuilder("api").
+			WithNamespace("team-b").
+			WithSubset("http", 80, []string{"api-1", "api-2", "api-3"}).
+			BuildAsPtr(),
+	)
+
+	testCases := []struct {
+		title      string
+		namespaces []string
+		expected   []ServiceInfo
+	}{
+		{
+			title:      "all namespaces",
+			namespaces: nil,
+			expected: []ServiceInfo{
+				{Name: "backend", Namespace: "team-a", ReadyPods: 1},
+				{Name: "frontend", Namespace: "team-a", ReadyPods: 2},
+				{Name: "api", Namespace: "team-b", ReadyPods: 3},
+			},
+		},
+		{
+			title:      "selected namespace",
+			namespaces: []string{"team-b"},
+			expected: []ServiceInfo{
+				{Name: "api", Namespace: "team-b", ReadyPods: 3},
+			},
+		},
+		{
+			title:      "namespace without services",
+			namespaces: []string{"team-c"},
+			expected:   []ServiceInfo{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(objs...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			services, err := ListDisruptableServices(context.TODO(), k, tc.namespaces)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, services); diff != "" {
+				t.Fatalf("expected services do not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ListInjectedPods(t *testing.T) {
+	t.Parallel()
+
+	objs := []runtime.Object{}
+	for _, pod := range []struct {
+		name      string
+		namespace string
+		container string
+	}{
+		{name: "injected-2", namespace: "test-ns", container: "xk6-agent"},
+		{name: "injected-1", namespace: "test-ns", container: "xk6-agent"},
+		{name: "other-container", namespace: "test-ns", container: "debugger"},
+		{name: "not-injected", namespace: "test-ns"},
+		{name: "other-namespace", namespace: "other-ns", container: "xk6-agent"},
+	} {
+		p := builders.NewPodBuilder(pod.name).WithNamespace(pod.namespace).Build()
+		if pod.container != "" {
+			p.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+				{
+					EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: pod.container},
+				},
+			}
+		}
+		objs = append(objs, &p)
+	}
+
+	testCases := []struct {
+		title     string
+		namespace string
+		options   ListInjectedPodsOptions
+		expected  []string
+	}{
+		{
+			title:     "injected pods",
+			namespace: "test-ns",
+			expected:  []string{"injected-1", "injected-2"},
+		},
+		{
+			title:     "pods injected in another agent container",
+			namespace: "test-ns",
+			options:   ListInjectedPodsOptions{AgentContainerName: "debugger"},
+			expected:  []string{"other-container"},
+		},
+		{
+			title:     "namespace without injected pods",
+			namespace: "empty-ns",
+			expected:  []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(objs...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			pods, err := ListInjectedPods(context.TODO(), k, tc.namespace, tc.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, pods); diff != "" {
+				t.Fatalf("expected pods do not match returned:\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,9 +1,89 @@
 package disruptors
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
 
 // Disruptor defines the generic interface implemented by all disruptors
 type Disruptor interface {
 	// Targets returns the names of the targets for the disruptor
 	Targets(ctx context.Context) ([]string, error)
+	// TargetsDetailed returns the description of the targets for the disruptor
+	TargetsDetailed(ctx context.Context) ([]Target, error)
+}
+
+// Target describes a target of a disruptor
+type Target struct {
+	// Name of the target pod
+	Name string `js:"name"`
+	// Namespace of the target pod
+	Namespace string `js:"namespace"`
+	// Node the target pod is scheduled on
+	Node string `js:"node"`
+	// IP address of the target pod
+	IP string `js:"ip"`
+	// Labels of the target pod
+	Labels map[string]string `js:"labels"`
+}
+
+// podTargets returns the description of a list of target pods
+func podTargets(pods []corev1.Pod) []Target {
+	targets := make([]Target, 0, len(pods))
+	for _, pod := range pods {
+		targets = append(targets, Target{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Node:      pod.Spec.NodeName,
+			IP:        pod.Status.PodIP,
+			Labels:    pod.Labels,
+		})
+	}
+
+	return targets
+}
+
+// capDuration limits the duration of a fault to the maximum duration. A zero maximum duration means no limit.
+func capDuration(duration time.Duration, maxDuration time.Duration) time.Duration {
+	if maxDuration <= 0 || duration <= maxDuration {
+		return duration
+	}
+
+	return maxDuration
+}
+
+// durationWarning returns the warning for a fault whose duration exceeds the maximum duration, which is used
+// instead. Empty if the duration does not exceed it.
+func durationWarning(duration time.Duration, maxDuration time.Duration) string {
+	if capDuration(duration, maxDuration) == duration {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"requested fault duration %s exceeds the maximum duration %s. Using the maximum duration instead.",
+		duration,
+		maxDuration,
+	)
+}
+
+// injectionWarnings returns the warnings of a fault injection by the key of the target. The warning that applies
+// to all the targets, if any, precedes the warnings reported by the agent in each target.
+func injectionWarnings(targets []corev1.Pod, warning string, agentWarnings map[string]string) map[string]string {
+	if warning == "" {
+		return agentWarnings
+	}
+
+	warnings := make(map[string]string, len(targets))
+	for _, target := range targets {
+		key := targetKey(target)
+		warnings[key] = warning
+		if agentWarning, found := agentWarnings[key]; found {
+			warnings[key] += "\n" + agentWarning
+		}
+	}
+
+	return warnings
 }
@@ -0,0 +1,89 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DNSFaultInjector defines the methods for injecting faults in the DNS resolution
+type DNSFaultInjector interface {
+	// InjectDNSFaults injects faults in the DNS queries sent by the disruptor's targets for the specified duration
+	InjectDNSFaults(ctx context.Context, fault DNSFault, duration time.Duration, options DNSDisruptionOptions) error
+}
+
+// DNSFault specifies a fault to be injected in the DNS queries sent by a pod. The queries that fail receive a
+// SERVFAIL response. Only the queries sent over UDP are disrupted.
+type DNSFault struct {
+	// Fraction (in the range 0.0 to 1.0) of the DNS queries that will fail
+	Rate float32 `js:"rate"`
+	// fail only the queries for this domain and its subdomains. Empty means all the queries.
+	Domain string `js:"domain"`
+}
+
+// DNSDisruptionOptions defines options for the injection of DNS faults in a target pod
+type DNSDisruptionOptions struct {
+	// Base seed for the random selection of the queries that fail, for reproducible disruptions. The seed of each
+	// target is derived from this seed and the target's name. Zero means a random seed.
+	Seed int64 `js:"seed"`
+}
+
+// validate checks the fault's attributes are consistent
+func (f DNSFault) validate() error {
+	if f.Rate < 0 || f.Rate > 1 {
+		return fmt.Errorf("rate must be in the range [0.0, 1.0]: %f", f.Rate)
+	}
+
+	if strings.ContainsAny(f.Domain, " \t/:") {
+		return fmt.Errorf("invalid domain: %q", f.Domain)
+	}
+
+	return nil
+}
+
+func buildDNSFaultCmd(fault DNSFault, duration time.Duration, options DNSDisruptionOptions) []string {
+	cmd := []string{
+		"xk6-disruptor-agent",
+		"dns",
+		"-d", utils.DurationSeconds(duration),
+		"-r", fmt.Sprint(fault.Rate),
+	}
+
+	if fault.Domain != "" {
+		cmd = append(cmd, "--domain", fault.Domain)
+	}
+
+	if options.Seed != 0 {
+		cmd = append(cmd, "--seed", fmt.Sprint(options.Seed))
+	}
+
+	return cmd
+}
+
+// PodDNSFaultCommand implements the PodVisitCommands interface for injecting DNSFaults in a Pod
+type PodDNSFaultCommand struct {
+	fault    DNSFault
+	duration time.Duration
+	options  DNSDisruptionOptions
+}
+
+// Commands return the command for injecting a DNSFault in a Pod
+func (c PodDNSFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
+	// the queries of the node would be disrupted
+	if utils.HasHostNetwork(pod) {
+		return VisitCommands{}, fmt.Errorf("fault cannot be safely injected because pod %q uses hostNetwork", pod.Name)
+	}
+
+	options := c.options
+	options.Seed = targetSeed(options.Seed, pod.Name)
+
+	return VisitCommands{
+		Exec:    buildDNSFaultCmd(c.fault, c.duration, options),
+		Cleanup: buildCleanupCmd(),
+	}, nil
+}
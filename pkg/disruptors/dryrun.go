@@ -0,0 +1,59 @@
+package disruptors
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DryRunner defines the method for inspecting the agent commands of a disruptor in dry-run mode
+type DryRunner interface {
+	// DryRunCommands returns the agent commands that the last fault injection would have executed in each
+	// target, by the namespace/name of the target. Empty if the disruptor is not in dry-run mode.
+	DryRunCommands() map[string][]string
+}
+
+// CommandBuilder defines the methods for inspecting the agent command generated for a fault without injecting it
+type CommandBuilder interface {
+	// BuildHTTPFaultCommand returns the agent command that would inject the HTTPFault in the first target
+	BuildHTTPFaultCommand(
+		ctx context.Context,
+		fault HTTPFault,
+		duration time.Duration,
+		options HTTPDisruptionOptions,
+	) ([]string, error)
+	// BuildGrpcFaultCommand returns the agent command that would inject the GrpcFault in the first target
+	BuildGrpcFaultCommand(
+		ctx context.Context,
+		fault GrpcFault,
+		duration time.Duration,
+		options GrpcDisruptionOptions,
+	) ([]string, error)
+}
+
+// dryRunLog records the agent commands of the last fault injection in dry-run mode
+type dryRunLog struct {
+	mutex    sync.Mutex
+	commands map[string][]string
+}
+
+// set replaces the commands recorded in the log
+func (l *dryRunLog) set(commands map[string][]string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.commands = commands
+}
+
+// get returns a copy of the commands recorded in the log
+func (l *dryRunLog) get() map[string][]string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	commands := make(map[string][]string, len(l.commands))
+	for target, command := range l.commands {
+		commands[target] = command
+	}
+
+	return commands
+}
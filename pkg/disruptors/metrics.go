@@ -0,0 +1,20 @@
+package disruptors
+
+// Kinds of the faults recorded by InjectionMetrics
+const (
+	InjectionKindHTTP = "http"
+	InjectionKindGrpc = "grpc"
+)
+
+// InjectionMetrics defines the methods for recording the faults injected by a disruptor, for example for exporting
+// them as Prometheus or k6 metrics
+type InjectionMetrics interface {
+	// IncInjection records the injection of a fault of the given kind (e.g. InjectionKindHTTP) in a number
+	// of targets
+	IncInjection(kind string, targets int)
+}
+
+// noopInjectionMetrics is an InjectionMetrics that does not record the injections
+type noopInjectionMetrics struct{}
+
+func (noopInjectionMetrics) IncInjection(_ string, _ int) {}
@@ -0,0 +1,308 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/internal/version"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// ErrSelectorNoNodes is returned by NewNodeDisruptor when the selector passed to it does not match any node in the
+// cluster.
+var ErrSelectorNoNodes = errors.New("no nodes found matching selector")
+
+// agentPodDeleteTimeout is the maximum time to wait for the deletion of an agent pod once the fault ends
+const agentPodDeleteTimeout = 30 * time.Second
+
+// NodeDisruptor defines the types of faults that can be injected in a Node
+type NodeDisruptor interface {
+	Disruptor
+	NodeNetworkFaultInjector
+}
+
+// NodeNetworkFaultInjector defines the methods for injecting faults in the network of the disruptor's target nodes
+type NodeNetworkFaultInjector interface {
+	// InjectNetworkFaults injects faults in the TCP connections to a port of the disruptor's target nodes for the
+	// specified duration
+	InjectNetworkFaults(
+		ctx context.Context,
+		fault NetworkFault,
+		duration time.Duration,
+		options NetworkDisruptionOptions,
+	) error
+}
+
+// NodeSelector defines the criteria for selecting a node for disruption
+type NodeSelector struct {
+	// Select Nodes that match these labels
+	Labels map[string]string `js:"labels"`
+}
+
+// NodeDisruptorOptions defines options that controls the NodeDisruptor's behavior
+type NodeDisruptorOptions struct {
+	// namespace where the agent pods are created. Defaults to "default".
+	Namespace string `js:"namespace"`
+	// timeout when waiting the agent pods to be running. A zero value forces default.
+	// A Negative value forces no waiting.
+	InjectTimeout time.Duration `js:"injectTimeout"`
+	// image of the agent pods, for example from a private registry. If empty, the image matching the
+	// version of the disruptor is used.
+	AgentImage string `js:"agentImage"`
+	// name of the container of the agent pods (default "xk6-agent"). The fault commands are executed in it.
+	AgentContainerName string `js:"agentContainerName"`
+}
+
+// NetworkFault specifies a fault to be injected in the TCP connections to a port of a node
+type NetworkFault struct {
+	// port of the node the disruptions will be applied to (e.g. 10250 for the kubelet)
+	Port uint `js:"port"`
+	// Fraction (in the range 0.0 to 1.0) of connections that will be reset
+	ResetRate float32 `js:"resetRate"`
+}
+
+// NetworkDisruptionOptions defines options for the injection of network faults in a target node
+type NetworkDisruptionOptions struct{}
+
+// validate checks the fault's attributes are consistent
+func (f NetworkFault) validate() error {
+	if f.Port == 0 || f.Port > 65535 {
+		return fmt.Errorf("port must be in the range [1, 65535] for network faults: %d", f.Port)
+	}
+
+	if f.ResetRate < 0 || f.ResetRate > 1 {
+		return fmt.Errorf("reset rate must be in the range [0.0, 1.0]: %f", f.ResetRate)
+	}
+
+	return nil
+}
+
+// nodeDisruptor is an instance of a NodeDisruptor that injects the faults by means of an agent pod
+// running in the network of each target node
+type nodeDisruptor struct {
+	// helper of the namespace of the agent pods
+	helper   helpers.PodHelper
+	nodes    helpers.NodeHelper
+	selector NodeSelector
+	options  NodeDisruptorOptions
+}
+
+// NewNodeDisruptor creates a new instance of a NodeDisruptor that acts on the nodes that match the given
+// NodeSelector. At least one node must match the selector.
+func NewNodeDisruptor(
+	ctx context.Context,
+	k8s kubernetes.Kubernetes,
+	selector NodeSelector,
+	options NodeDisruptorOptions,
+) (NodeDisruptor, error) {
+	// prevent disrupting all the nodes of the cluster by mistake
+	if len(selector.Labels) == 0 {
+		return nil, fmt.Errorf("node selector must specify labels")
+	}
+
+	if options.Namespace == "" {
+		options.Namespace = metav1.NamespaceDefault
+	}
+
+	if options.InjectTimeout == 0 {
+		options.InjectTimeout = 30 * time.Second
+	}
+
+	options.AgentContainerName = agentContainerName(options.AgentContainerName)
+
+	d := &nodeDisruptor{
+		helper:   k8s.PodHelper(options.Namespace),
+		nodes:    k8s.NodeHelper(),
+		selector: selector,
+		options:  options,
+	}
+
+	if _, err := d.targets(ctx); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// targets returns the nodes that match the selector
+func (d *nodeDisruptor) targets(ctx context.Context) ([]corev1.Node, error) {
+	nodes, err := d.nodes.List(ctx, d.selector.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("finding nodes matching %v: %w", d.selector.Labels, ErrSelectorNoNodes)
+	}
+
+	return nodes, nil
+}
+
+func (d *nodeDisruptor) Targets(ctx context.Context) ([]string, error) {
+	nodes, err := d.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	return names, nil
+}
+
+func (d *nodeDisruptor) TargetsDetailed(ctx context.Context) ([]Target, error) {
+	nodes, err := d.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]Target, 0, len(nodes))
+	for _, node := range nodes {
+		targets = append(targets, Target{
+			Name:   node.Name,
+			Node:   node.Name,
+			IP:     nodeInternalIP(node),
+			Labels: node.Labels,
+		})
+	}
+
+	return targets, nil
+}
+
+// nodeInternalIP returns the internal IP address of the node, if any
+func nodeInternalIP(node corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address
+		}
+	}
+
+	return ""
+}
+
+// InjectNetworkFaults injects faults in the TCP connections to a port of the disruptor's target nodes
+func (d *nodeDisruptor) InjectNetworkFaults(
+	ctx context.Context,
+	fault NetworkFault,
+	duration time.Duration,
+	_ NetworkDisruptionOptions,
+) (err error) {
+	ctx, span := startSpan(ctx, "NodeDisruptor.InjectNetworkFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	nodes, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	agentPods := make([]corev1.Pod, 0, len(nodes))
+	for _, node := range nodes {
+		agentPods = append(agentPods, d.agentPod(node))
+	}
+
+	commands := VisitCommands{
+		Exec: buildTCPFaultCmd(
+			TCPFault{Port: intstr.FromInt32(int32(fault.Port)), ResetRate: fault.ResetRate}, //nolint:gosec // port is in range
+			duration,
+		),
+		Cleanup: buildCleanupCmd(),
+	}
+
+	// the agent runs in the container of the agent pods, so it is not injected
+	visitor := NewPodAgentVisitor(
+		d.helper,
+		PodAgentVisitorOptions{
+			ContainerName: d.options.AgentContainerName,
+			AgentRunning:  true,
+		},
+		PodVisitCommandFunc(func(corev1.Pod) (VisitCommands, error) {
+			return commands, nil
+		}),
+	)
+
+	return NewPodController(agentPods).Visit(ctx, PodVisitorFunc(func(ctx context.Context, pod corev1.Pod) error {
+		return d.visitAgentPod(ctx, pod, visitor)
+	}))
+}
+
+// agentPod returns the specification of a privileged pod that runs the agent in the network of the node
+func (d *nodeDisruptor) agentPod(node corev1.Node) corev1.Pod {
+	privileged := true
+
+	image := d.options.AgentImage
+	if image == "" {
+		image = version.AgentImage()
+	}
+
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			// the suffix prevents conflicts with the agent pods of concurrent fault injections in the node
+			Name:      "xk6-agent-" + node.Name + "-" + utilrand.String(5),
+			Namespace: d.options.Namespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      node.Name,
+			HostNetwork:   true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			// as pods of a DaemonSet, the agent must run in the node regardless of its taints
+			Tolerations: []corev1.Toleration{
+				{Operator: corev1.TolerationOpExists},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            d.options.AgentContainerName,
+					Image:           image,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: &privileged,
+					},
+					TTY:   true,
+					Stdin: true,
+				},
+			},
+		},
+	}
+}
+
+// visitAgentPod creates the agent pod and, once it is running, visits it with the visitor that executes the fault
+// command. The agent pod is deleted once the visit ends.
+func (d *nodeDisruptor) visitAgentPod(ctx context.Context, pod corev1.Pod, visitor *PodAgentVisitor) (err error) {
+	err = d.helper.Create(ctx, pod)
+	if err != nil {
+		return fmt.Errorf("creating agent in node %q: %w", pod.Spec.NodeName, err)
+	}
+
+	// we use a fresh context because the context of the fault may have been cancelled or expired
+	//nolint:contextcheck
+	defer func() {
+		if deleteErr := d.helper.Terminate(context.TODO(), pod.Name, agentPodDeleteTimeout); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("deleting agent pod %q: %w", pod.Name, deleteErr))
+		}
+	}()
+
+	if d.options.InjectTimeout > 0 {
+		running, err := d.helper.WaitPodRunning(ctx, pod.Name, d.options.InjectTimeout)
+		if err != nil {
+			return fmt.Errorf("waiting agent in node %q: %w", pod.Spec.NodeName, err)
+		}
+		if !running {
+			return fmt.Errorf("agent in node %q is not running after %s", pod.Spec.NodeName, d.options.InjectTimeout)
+		}
+	}
+
+	return visitor.Visit(ctx, pod)
+}
@@ -0,0 +1,305 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_NewNodeDisruptor(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		nodes         []runtime.Object
+		selector      NodeSelector
+		expectError   error
+		expectTargets []string
+	}{
+		{
+			title: "matching nodes",
+			nodes: []runtime.Object{
+				builders.NewNodeBuilder("node1").WithLabel("pool", "workers").BuildAsPtr(),
+				builders.NewNodeBuilder("node2").WithLabel("pool", "workers").BuildAsPtr(),
+				builders.NewNodeBuilder("node3").WithLabel("pool", "system").BuildAsPtr(),
+			},
+			selector:      NodeSelector{Labels: map[string]string{"pool": "workers"}},
+			expectError:   nil,
+			expectTargets: []string{"node1", "node2"},
+		},
+		{
+			title: "no matching nodes",
+			nodes: []runtime.Object{
+				builders.NewNodeBuilder("node1").WithLabel("pool", "system").BuildAsPtr(),
+			},
+			selector:    NodeSelector{Labels: map[string]string{"pool": "workers"}},
+			expectError: ErrSelectorNoNodes,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(tc.nodes...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			disruptor, err := NewNodeDisruptor(context.TODO(), k, tc.selector, NodeDisruptorOptions{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected error %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			targets, err := disruptor.Targets(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectTargets, targets); diff != "" {
+				t.Fatalf("targets do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_NewNodeDisruptorWithoutLabels(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(builders.NewNodeBuilder("node1").BuildAsPtr())
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	_, err := NewNodeDisruptor(context.TODO(), k, NodeSelector{}, NodeDisruptorOptions{})
+	if err == nil {
+		t.Fatalf("should had failed")
+	}
+}
+
+func Test_NodeDisruptorNetworkFaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		fault       NetworkFault
+		expectedCmd string
+		expectError bool
+	}{
+		{
+			title: "valid fault",
+			fault: NetworkFault{
+				Port:      10250,
+				ResetRate: 1.0,
+			},
+			expectedCmd: "xk6-disruptor-agent tcp-drop -d 60s -p 10250 -r 1",
+			expectError: false,
+		},
+		{
+			title: "missing port",
+			fault: NetworkFault{
+				ResetRate: 1.0,
+			},
+			expectError: true,
+		},
+		{
+			title: "invalid reset rate",
+			fault: NetworkFault{
+				Port:      10250,
+				ResetRate: 1.5,
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(
+				builders.NewNodeBuilder("node1").WithLabel("pool", "workers").BuildAsPtr(),
+			)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			disruptor, err := NewNodeDisruptor(
+				context.TODO(),
+				k,
+				NodeSelector{Labels: map[string]string{"pool": "workers"}},
+				// the agent pods created by the fake client never run
+				NodeDisruptorOptions{Namespace: "test-ns", InjectTimeout: -1},
+			)
+			if err != nil {
+				t.Fatalf("creating disruptor: %v", err)
+			}
+
+			err = disruptor.InjectNetworkFaults(context.TODO(), tc.fault, 60*time.Second, NetworkDisruptionOptions{})
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) != 1 {
+				t.Fatalf("expected 1 command executed got %d", len(history))
+			}
+
+			if !strings.HasPrefix(history[0].Pod, "xk6-agent-node1-") || history[0].Namespace != "test-ns" {
+				t.Fatalf("command executed in unexpected pod %s/%s", history[0].Namespace, history[0].Pod)
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if cmd != tc.expectedCmd {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+
+			// the agent pod is deleted once the fault ends
+			pods, err := client.CoreV1().Pods("test-ns").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("listing pods: %v", err)
+			}
+			if len(pods.Items) != 0 {
+				t.Fatalf("agent pod was not deleted")
+			}
+		})
+	}
+}
+
+func Test_NodeDisruptorAgentPodDeleteError(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		builders.NewNodeBuilder("node1").WithLabel("pool", "workers").BuildAsPtr(),
+	)
+	client.PrependReactor("delete", "pods", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	disruptor, err := NewNodeDisruptor(
+		context.TODO(),
+		k,
+		NodeSelector{Labels: map[string]string{"pool": "workers"}},
+		// the agent pods created by the fake client never run
+		NodeDisruptorOptions{Namespace: "test-ns", InjectTimeout: -1},
+	)
+	if err != nil {
+		t.Fatalf("creating disruptor: %v", err)
+	}
+
+	fault := NetworkFault{Port: 10250, ResetRate: 1.0}
+	err = disruptor.InjectNetworkFaults(context.TODO(), fault, 60*time.Second, NetworkDisruptionOptions{})
+	if err == nil || !strings.Contains(err.Error(), "deleting agent pod") {
+		t.Fatalf("expected error deleting the agent pod got %v", err)
+	}
+}
+
+func Test_NodeDisruptorAgentPodNames(t *testing.T) {
+	t.Parallel()
+
+	d := &nodeDisruptor{options: NodeDisruptorOptions{Namespace: "test-ns"}}
+	node := builders.NewNodeBuilder("node1").Build()
+
+	first := d.agentPod(node)
+	second := d.agentPod(node)
+	if first.Name == second.Name {
+		t.Fatalf("expected agent pods with different names got %q", first.Name)
+	}
+}
+
+func Test_NodeDisruptorAgentContainerName(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		builders.NewNodeBuilder("node1").WithLabel("pool", "workers").BuildAsPtr(),
+	)
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	disruptor, err := NewNodeDisruptor(
+		context.TODO(),
+		k,
+		NodeSelector{Labels: map[string]string{"pool": "workers"}},
+		// the agent pods created by the fake client never run
+		NodeDisruptorOptions{Namespace: "test-ns", InjectTimeout: -1, AgentContainerName: "chaos-agent"},
+	)
+	if err != nil {
+		t.Fatalf("creating disruptor: %v", err)
+	}
+
+	fault := NetworkFault{Port: 10250, ResetRate: 1.0}
+	err = disruptor.InjectNetworkFaults(context.TODO(), fault, 60*time.Second, NetworkDisruptionOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history := k.GetFakeProcessExecutor().GetHistory()
+	if len(history) != 1 {
+		t.Fatalf("expected 1 command executed got %d", len(history))
+	}
+
+	if history[0].Container != "chaos-agent" {
+		t.Fatalf("expected command executed in container %q got %q", "chaos-agent", history[0].Container)
+	}
+}
+
+func Test_NodeDisruptorAgentPod(t *testing.T) {
+	t.Parallel()
+
+	d := &nodeDisruptor{
+		options: NodeDisruptorOptions{
+			Namespace:          "test-ns",
+			AgentImage:         "registry.example.com/agent:v1",
+			AgentContainerName: "chaos-agent",
+		},
+	}
+
+	pod := d.agentPod(builders.NewNodeBuilder("node1").Build())
+
+	if pod.Spec.NodeName != "node1" {
+		t.Errorf("expected agent pod scheduled in node1 got %q", pod.Spec.NodeName)
+	}
+
+	if !pod.Spec.HostNetwork {
+		t.Errorf("agent pod must use the network of the node")
+	}
+
+	container := pod.Spec.Containers[0]
+	if container.Name != "chaos-agent" {
+		t.Errorf("unexpected agent container name %q", container.Name)
+	}
+
+	if container.Image != "registry.example.com/agent:v1" {
+		t.Errorf("unexpected agent image %q", container.Image)
+	}
+
+	if container.SecurityContext == nil || container.SecurityContext.Privileged == nil ||
+		!*container.SecurityContext.Privileged {
+		t.Errorf("agent container must be privileged")
+	}
+
+	if len(pod.Spec.Tolerations) == 0 || pod.Spec.Tolerations[0].Operator != corev1.TolerationOpExists {
+		t.Errorf("agent pod must tolerate the taints of the node")
+	}
+}
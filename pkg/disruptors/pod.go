@@ -3,12 +3,20 @@ package disruptors
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
 	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
 	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // DefaultTargetPort defines the default value for a target HTTP
@@ -18,7 +26,21 @@ var DefaultTargetPort = intstr.FromInt32(80) //nolint:gochecknoglobals
 type PodDisruptor interface {
 	Disruptor
 	ProtocolFaultInjector
+	AsyncProtocolFaultInjector
+	GroupProtocolFaultInjector
 	PodFaultInjector
+	TCPFaultInjector
+	DNSFaultInjector
+	BandwidthFaultInjector
+	ProcessFaultInjector
+	Prober
+	Stopper
+	DryRunner
+	CommandBuilder
+	StatusReporter
+	SelectionHasher
+	ResolvedFaultsReporter
+	WarningsReporter
 }
 
 // PodDisruptorOptions defines options that controls the PodDisruptor's behavior
@@ -26,22 +48,208 @@ type PodDisruptorOptions struct {
 	// timeout when waiting agent to be injected in seconds. A zero value forces default.
 	// A Negative value forces no waiting.
 	InjectTimeout time.Duration `js:"injectTimeout"`
+	// timeout when waiting the agent to respond to a ping once its container is running (default 10s).
+	// A zero value forces default. A negative value forces no waiting.
+	AgentStartupTimeout time.Duration `js:"agentStartupTimeout"`
+	// number of times the injection of the agent in a target is retried if it fails, for example because the
+	// API server is busy. Zero means no retries.
+	InjectRetries uint `js:"injectRetries"`
+	// time to wait before the first retry of the injection of the agent (default 1s). It is doubled after
+	// each retry. A zero value forces default.
+	InjectBackoff time.Duration `js:"injectBackoff"`
+	// fail the injection of the agent as soon as its image cannot be pulled, instead of waiting
+	// for the InjectTimeout to expire.
+	FailOnImagePullError bool `js:"failOnImagePullError"`
+	// maximum duration of the faults. Longer durations are capped to this value. Zero means no limit.
+	MaxDuration time.Duration `js:"maxDuration"`
+	// maximum number of targets the fault command is executed in concurrently. The injection of the agent
+	// is not limited by this option. Zero means no limit.
+	MaxConcurrency uint `js:"maxConcurrency"`
+	// image of the agent injected in the targets, for example from a private registry. If empty, the
+	// image matching the version of the disruptor is used.
+	AgentImage string `js:"agentImage"`
+	// name of the ephemeral container of the agent injected in the targets (default "xk6-agent"). Disruptors
+	// using different names inject separate agents, so they do not interfere when disrupting the same targets.
+	AgentContainerName string `js:"agentContainerName"`
+	// record the agent commands of the fault injections instead of executing them. The agent is not injected
+	// in the targets. The commands are returned by DryRunCommands.
+	DryRun bool `js:"dryRun"`
+	// verification invoked once the fault command is running in all the targets, for example for checking
+	// the fault is observed by a client of the targets. As the agent may still be setting up the fault, the
+	// verification should retry its checks for a while. If the verification fails, the faults are stopped
+	// and its error is returned. Not invoked in dry-run mode.
+	VerifyFunc func() error `js:"-"`
+	// select also the pods that are not running (e.g. Pending or terminating), where the agent likely cannot
+	// be injected. By default, only running pods are selected.
+	IncludeNotRunning bool `js:"includeNotRunning"`
+	// minimum number of ready targets. NewPodDisruptor selects the targets periodically until this number of them
+	// are ready, for up to ReadyTimeout. Zero means no waiting.
+	MinReadyTargets uint `js:"minReadyTargets"`
+	// timeout when waiting for MinReadyTargets ready targets (default 30s). A zero value forces default.
+	ReadyTimeout time.Duration `js:"readyTimeout"`
+	// wait in NewPodDisruptor for the agent to be running in the targets where it has already been injected,
+	// for example by another disruptor, for up to the InjectTimeout. Otherwise, commands may be executed in
+	// these targets while the agent is still starting.
+	WaitAgentReady bool `js:"waitAgentReady"`
+	// records the HTTP and gRPC faults injected in the targets, for example for exporting them as metrics.
+	// The injections are recorded when the fault command is sent to the targets. Not invoked in dry-run mode.
+	Metrics InjectionMetrics `js:"-"`
+	// number or percentage (e.g. "25%") of the targets the faults are injected into, chosen randomly from the
+	// pods matching the selector. A percentage selects at least one target. Empty means all the targets.
+	Sample intstr.IntOrString `js:"sample"`
+	// seed for the random selection of the Sample of the targets, for reproducible disruptions. Zero means a
+	// random seed, chosen when the disruptor is created.
+	SampleSeed int64 `js:"sampleSeed"`
+	// skip the targets that are not ready (their PodReady condition is not True) when injecting the faults, as
+	// the results of disrupting them are misleading. By default, not-ready targets are disrupted.
+	SkipNotReady bool `js:"skipNotReady"`
+	// fail in NewPodDisruptor if any target is not ready, instead of skipping it. Cannot be combined with
+	// SkipNotReady.
+	RequireReady bool `js:"requireReady"`
+	// record a Kubernetes Event on each target when the fault command starts (reason FaultInjected) and when it
+	// ends (reason FaultEnded), for auditing the disruptions. Not recorded in dry-run mode.
+	Events bool `js:"events"`
+	// time the agent is given to complete the fault command once the duration of the fault has elapsed (default
+	// 30s). If the command does not complete, for example because the agent hangs, it is stopped and an error
+	// naming the target is returned. It must be longer than the StopGracePeriod of the faults. A zero value forces
+	// default. A negative value means no timeout.
+	ExecTimeout time.Duration `js:"execTimeout"`
+	// interval between the checks that the agent is still running the fault in each target, for detecting agents
+	// that die during long fault injections. If the agent in a target does not respond, the fault is stopped and
+	// an error naming the target is returned. The interval should be longer than the time the agent takes to
+	// start the fault. Zero means no checks.
+	HeartbeatInterval time.Duration `js:"heartbeatInterval"`
+	// overrides of the security context of the agent injected in the targets, for example for complying with the
+	// Pod Security Admission policy of their namespace. Network faults require the NET_ADMIN capability.
+	AgentSecurityContext AgentSecurityContext `js:"agentSecurityContext"`
 }
 
+// DefaultExecTimeout is the default time the agent is given to complete the fault command once the duration of the
+// fault has elapsed
+const DefaultExecTimeout = 30 * time.Second
+
+// ErrNotEnoughReadyTargets is returned by NewPodDisruptor when fewer than MinReadyTargets targets are ready
+// after the ReadyTimeout
+var ErrNotEnoughReadyTargets = errors.New("not enough ready targets")
+
+// Reasons of the Kubernetes Events recorded on the targets when the Events option is set
+const (
+	EventReasonFaultInjected = "FaultInjected"
+	EventReasonFaultEnded    = "FaultEnded"
+)
+
+// ErrTargetsNotReady is returned by NewPodDisruptor when RequireReady is set and some target is not ready
+var ErrTargetsNotReady = errors.New("targets not ready")
+
+// readyTargetsInterval is the interval between the selections of the targets while waiting for them to be ready
+const readyTargetsInterval = 200 * time.Millisecond
+
 // podDisruptor is an instance of a PodDisruptor that uses a PodController to interact with target pods
 type podDisruptor struct {
-	helper   helpers.PodHelper
-	selector *PodSelector
-	options  PodDisruptorOptions
+	helper helpers.PodHelper
+	// returns the helper of the namespace of each target
+	namespaceHelper PodHelperFunc
+	spec            PodSelectorSpec
+	// selectors of the targets in each namespace
+	selectors []*PodSelector
+	options   PodDisruptorOptions
+	dryRun    dryRunLog
+	resolved  resolvedFaultsLog
+	warnings  warningsLog
+	status    statusTracker
+	// records the Kubernetes Events of the fault injections. nil if the Events option is not set
+	events helpers.EventHelper
+
+	// seed of the random selection of the sample of the targets
+	sampleSeed int64
 }
 
 // PodSelectorSpec defines the criteria for selecting a pod for disruption
 type PodSelectorSpec struct {
 	Namespace string
+	// Select Pods in any of these namespaces, instead of a single Namespace
+	Namespaces []string `js:"namespaces"`
 	// Select Pods that match these PodAttributes
 	Select PodAttributes
-	// Select Pods that match these PodAttributes
+	// Exclude Pods that match any of these PodAttributes. Exclusion is applied after the
+	// selection, so it takes precedence over Select
 	Exclude PodAttributes
+	// Select only Pods that became ready (or were started, if not ready) within this duration. Zero means no limit.
+	MaxAge time.Duration `js:"maxAge"`
+	// Select only Pods scheduled on nodes with any of these conditions (e.g. DiskPressure) set to True
+	NodeConditions []string `js:"nodeConditions"`
+	// Select only Pods whose labels satisfy all these relations
+	LabelRelations []LabelRelation `js:"labelRelations"`
+	// Select only the leader pod of a leader-elected workload. The leader is identified by LeaderAnnotation
+	// or LeaderLabel.
+	Leader bool `js:"leader"`
+	// Annotation that marks the leader pod. Defaults to DefaultLeaderAnnotation if LeaderLabel is not set.
+	// If the value of the annotation is a leader election record, its holder identity must match the pod's name.
+	LeaderAnnotation string `js:"leaderAnnotation"`
+	// Label that marks the leader pod, in the form "key=value" or "key"
+	LeaderLabel string `js:"leaderLabel"`
+	// Select only Pods owned by the ReplicaSet with this name
+	ReplicaSet string `js:"replicaSet"`
+	// Select only Pods matching the labels of the selector of the Deployment with this name
+	Deployment string `js:"deployment"`
+	// Select only Pods currently using more than this percentage of the CPU they request. Pods that do not
+	// request CPU are not selected. The usage is obtained from the metrics-server, which must be installed in
+	// the cluster. Zero means no limit.
+	MinCPUUtilization uint `js:"minCPUUtilization"`
+	// Select only Pods currently using more than this percentage of the memory they request. Pods that do not
+	// request memory are not selected. The usage is obtained from the metrics-server, which must be installed in
+	// the cluster. Zero means no limit.
+	MinMemoryUtilization uint `js:"minMemoryUtilization"`
+	// Exclude Pods where the disruptor agent has been injected, for example because another experiment is injecting
+	// faults in them
+	ExcludeDisrupted bool `js:"excludeDisrupted"`
+	// Fail the selection if any of the selected Pods does not have all these labels with the given values. This is
+	// a guardrail against selectors that match unrelated Pods by mistake.
+	RequireCommonLabel map[string]string `js:"requireCommonLabel"`
+	// Select all the Pods of the namespaces when no labels, label relations, leader, replica set or deployment
+	// are specified. Without it, such a selector is rejected, as it is likely a mistake.
+	SelectAll bool `js:"selectAll"`
+}
+
+// ErrSelectorNoCriteria is returned by PodSelectorSpec.Validate when the spec would select all the pods of its
+// namespaces without SelectAll being set
+var ErrSelectorNoCriteria = errors.New("pod selector does not specify any selection criteria")
+
+// Validate checks the spec selects the pods by their labels, label relations, leadership, replica set or
+// deployment, unless SelectAll is set. Otherwise, all the pods of the namespaces would be selected.
+func (p PodSelectorSpec) Validate() error {
+	if p.SelectAll {
+		return nil
+	}
+
+	if len(p.Select.Labels) > 0 || len(p.LabelRelations) > 0 || p.Leader || p.ReplicaSet != "" || p.Deployment != "" {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"specify labels, label relations, leader, replica set or deployment, or select all the pods: %w",
+		ErrSelectorNoCriteria,
+	)
+}
+
+// DefaultLeaderAnnotation is the annotation used by default for identifying the leader pod
+const DefaultLeaderAnnotation = "control-plane.alpha.kubernetes.io/leader"
+
+// Operators for comparing the values of two labels in a LabelRelation
+const (
+	LabelRelationEquals    = "Equals"
+	LabelRelationNotEquals = "NotEquals"
+)
+
+// LabelRelation defines a relation between the values of two labels of a Pod.
+// Pods that do not have both labels do not satisfy the relation.
+type LabelRelation struct {
+	// Key of the label to compare
+	Label string `js:"label"`
+	// Operator used for comparing the values of the labels. Defaults to Equals.
+	Operator string `js:"operator"`
+	// Key of the label to compare with
+	OtherLabel string `js:"otherLabel"`
 }
 
 // PodAttributes defines the attributes a Pod must match for being selected/excluded
@@ -52,30 +260,285 @@ type PodAttributes struct {
 // NewPodDisruptor creates a new instance of a PodDisruptor that acts on the pods
 // that match the given PodSelector
 func NewPodDisruptor(
-	_ context.Context,
+	ctx context.Context,
 	k8s kubernetes.Kubernetes,
 	spec PodSelectorSpec,
 	options PodDisruptorOptions,
 ) (PodDisruptor, error) {
-	// ensure selector and controller use default namespace if none specified
-	namespace := spec.NamespaceOrDefault()
+	if spec.Namespace != "" && len(spec.Namespaces) > 0 {
+		return nil, fmt.Errorf("namespace and namespaces in pod selector cannot both be specified")
+	}
 
-	helper := k8s.PodHelper(namespace)
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
 
-	selector, err := NewPodSelector(spec, helper)
-	if err != nil {
+	// select the targets of each namespace with its own selector
+	specs := []PodSelectorSpec{spec}
+	if len(spec.Namespaces) > 0 {
+		specs = []PodSelectorSpec{}
+		for _, namespace := range spec.Namespaces {
+			namespaceSpec := spec
+			namespaceSpec.Namespace = namespace
+			namespaceSpec.Namespaces = nil
+			specs = append(specs, namespaceSpec)
+		}
+	}
+
+	selectors := []*PodSelector{}
+	for _, namespaceSpec := range specs {
+		// ensure selector and controller use default namespace if none specified
+		namespace := namespaceSpec.NamespaceOrDefault()
+
+		selector, err := NewPodSelector(
+			namespaceSpec,
+			k8s.PodHelper(namespace),
+			k8s.NodeHelper(),
+			k8s.ReplicaSetHelper(namespace),
+			k8s.DeploymentHelper(namespace),
+			k8s.PodMetricsHelper(namespace),
+			options.AgentContainerName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		selectors = append(selectors, selector)
+	}
+
+	if err := validateSample(options.Sample); err != nil {
 		return nil, err
 	}
 
-	return &podDisruptor{
-		helper:   helper,
-		options:  options,
-		selector: selector,
-	}, nil
+	if err := options.AgentSecurityContext.validate(); err != nil {
+		return nil, err
+	}
+
+	if options.SkipNotReady && options.RequireReady {
+		return nil, fmt.Errorf("skip not ready and require ready cannot both be specified")
+	}
+
+	if options.ReadyTimeout == 0 {
+		options.ReadyTimeout = 30 * time.Second
+	}
+
+	if options.Metrics == nil {
+		options.Metrics = noopInjectionMetrics{}
+	}
+
+	d := &podDisruptor{
+		helper:          k8s.PodHelper(specs[0].NamespaceOrDefault()),
+		namespaceHelper: k8s.PodHelper,
+		spec:            spec,
+		options:         options,
+		selectors:       selectors,
+		sampleSeed:      options.SampleSeed,
+	}
+
+	if options.Events {
+		d.events = k8s.EventHelper()
+	}
+
+	// the same seed is used in all the selections, so the sample does not change while the targets do not change
+	if d.sampleSeed == 0 {
+		d.sampleSeed = time.Now().UnixNano()
+	}
+
+	if options.MinReadyTargets > 0 {
+		if err := d.waitReadyTargets(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.RequireReady {
+		if err := d.checkReadyTargets(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.WaitAgentReady {
+		if err := d.waitAgentReady(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// checkReadyTargets returns an error listing the targets that are not ready, if any
+func (d *podDisruptor) checkReadyTargets(ctx context.Context) error {
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	notReady := []string{}
+	for _, target := range targets {
+		if !utils.PodReady(target) {
+			notReady = append(notReady, target.Name)
+		}
+	}
+
+	if len(notReady) > 0 {
+		return fmt.Errorf("%w: %s", ErrTargetsNotReady, strings.Join(notReady, ", "))
+	}
+
+	return nil
+}
+
+// waitAgentReady waits for the agent to be running in the targets where it has been injected
+func (d *podDisruptor) waitAgentReady(ctx context.Context) error {
+	timeout := d.options.InjectTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	if timeout < 0 {
+		return nil
+	}
+
+	targets, err := d.targets(ctx)
+	// targets may not have been created yet
+	if err != nil && !errors.Is(err, ErrSelectorNoPods) {
+		return err
+	}
+
+	injected := []corev1.Pod{}
+	for _, target := range targets {
+		if hasAgent(target, agentContainerName(d.options.AgentContainerName)) {
+			injected = append(injected, target)
+		}
+	}
+
+	return NewPodController(injected).WaitAgentReady(
+		ctx,
+		d.namespaceHelper,
+		agentContainerName(d.options.AgentContainerName),
+		timeout,
+	)
+}
+
+// waitReadyTargets selects the targets periodically until at least MinReadyTargets of them are ready or the
+// ReadyTimeout expires
+func (d *podDisruptor) waitReadyTargets(ctx context.Context) error {
+	expired := time.After(d.options.ReadyTimeout)
+	for {
+		targets, err := d.targets(ctx)
+		// targets may not have been created yet
+		if err != nil && !errors.Is(err, ErrSelectorNoPods) {
+			return err
+		}
+
+		ready := 0
+		for _, target := range targets {
+			if utils.PodReady(target) {
+				ready++
+			}
+		}
+
+		if ready >= int(d.options.MinReadyTargets) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			return fmt.Errorf(
+				"%w: %d of %d targets matching '%s' ready after %s",
+				ErrNotEnoughReadyTargets,
+				ready,
+				d.options.MinReadyTargets,
+				d.spec,
+				d.options.ReadyTimeout,
+			)
+		case <-time.After(readyTargetsInterval):
+		}
+	}
+}
+
+// targets returns the targets of the selectors of all the namespaces where the agent can be injected.
+// Unless IncludeNotRunning is set, only running pods are returned. If SkipNotReady is set, only ready pods are
+// returned. If a Sample is set, only the sampled targets are returned.
+func (d *podDisruptor) targets(ctx context.Context) ([]corev1.Pod, error) {
+	targets, err := d.selectedTargets(ctx)
+	if err != nil {
+		return targets, err
+	}
+
+	if !d.options.IncludeNotRunning {
+		targets = filterRunning(targets)
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("finding running pods matching '%s': %w", d.spec, ErrSelectorNoPods)
+		}
+	}
+
+	if d.options.SkipNotReady {
+		targets = filterReady(targets)
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("finding ready pods matching '%s': %w", d.spec, ErrSelectorNoPods)
+		}
+	}
+
+	if d.options.Sample.IsNull() {
+		return targets, nil
+	}
+
+	return samplePods(targets, d.options.Sample, d.sampleSeed)
+}
+
+// filterRunning returns the pods that are running and not being terminated
+func filterRunning(pods []corev1.Pod) []corev1.Pod {
+	running := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			running = append(running, pod)
+		}
+	}
+
+	return running
+}
+
+// filterReady returns the pods that are ready
+func filterReady(pods []corev1.Pod) []corev1.Pod {
+	ready := []corev1.Pod{}
+	for _, pod := range pods {
+		if utils.PodReady(pod) {
+			ready = append(ready, pod)
+		}
+	}
+
+	return ready
+}
+
+// selectedTargets returns the targets of the selectors of all the namespaces. Namespaces without targets are ignored
+// as long as some namespace has targets.
+func (d *podDisruptor) selectedTargets(ctx context.Context) ([]corev1.Pod, error) {
+	if len(d.selectors) == 1 {
+		return d.selectors[0].Targets(ctx)
+	}
+
+	targets := []corev1.Pod{}
+	for _, selector := range d.selectors {
+		namespaceTargets, err := selector.Targets(ctx)
+		if errors.Is(err, ErrSelectorNoPods) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, namespaceTargets...)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("finding pods matching '%s': %w", d.spec, ErrSelectorNoPods)
+	}
+
+	return targets, nil
 }
 
 func (d *podDisruptor) Targets(ctx context.Context) ([]string, error) {
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -83,39 +546,193 @@ func (d *podDisruptor) Targets(ctx context.Context) ([]string, error) {
 	return utils.PodNames(targets), nil
 }
 
+func (d *podDisruptor) TargetsDetailed(ctx context.Context) ([]Target, error) {
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return podTargets(targets), nil
+}
+
+func (d *podDisruptor) SelectionHash(ctx context.Context) (string, error) {
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return podSelectionHash(targets), nil
+}
+
 // InjectHTTPFault injects faults in the http requests sent to the disruptor's targets
 func (d *podDisruptor) InjectHTTPFaults(
 	ctx context.Context,
 	fault HTTPFault,
 	duration time.Duration,
 	options HTTPDisruptionOptions,
-) error {
-	// Handle default port mapping
-	// TODO: make port mandatory instead of using a default
-	if fault.Port.IsNull() || fault.Port.IsZero() {
-		fault.Port = DefaultTargetPort
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectHTTPFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	inject, err := d.prepareHTTPFaults(ctx, fault, duration, options)
+	if err != nil {
+		return err
 	}
 
-	command := PodHTTPFaultCommand{
-		fault:    fault,
-		duration: duration,
-		options:  options,
+	return inject(ctx)
+}
+
+// InjectHTTPFaultsAsync injects faults in the http requests sent to the disruptor's targets without waiting for
+// the faults to end. Errors setting up the faults, such as an invalid fault, are returned immediately. Otherwise,
+// the returned channel delivers the result of the fault injection once it ends and is then closed.
+func (d *podDisruptor) InjectHTTPFaultsAsync(
+	ctx context.Context,
+	fault HTTPFault,
+	duration time.Duration,
+	options HTTPDisruptionOptions,
+) (<-chan error, error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectHTTPFaultsAsync", faultAttributes(fault, duration)...)
+
+	inject, err := d.prepareHTTPFaults(ctx, fault, duration, options)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+
+		err := inject(ctx)
+		endSpan(span, err)
+		done <- err
+	}()
+
+	return done, nil
+}
+
+// prepareHTTPFaults validates the fault and selects the targets, returning a function that injects the fault in
+// the selected targets
+func (d *podDisruptor) prepareHTTPFaults(
+	ctx context.Context,
+	fault HTTPFault,
+	duration time.Duration,
+	options HTTPDisruptionOptions,
+) (func(context.Context) error, error) {
+	if err := requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return nil, err
+	}
+
+	command, err := d.httpFaultCommand(fault, duration, options)
+	if err != nil {
+		return nil, err
 	}
 
 	visitor := NewPodAgentVisitor(
 		d.helper,
-		PodAgentVisitorOptions{Timeout: d.options.InjectTimeout},
+		d.visitorOptions(duration),
 		command,
 	)
 
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
+		return nil, err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(targetsAttribute(targets))
+
+	return func(ctx context.Context) error {
+		if !d.options.DryRun {
+			d.options.Metrics.IncInjection(InjectionKindHTTP, len(targets))
+		}
+
+		err := d.visit(ctx, targets, visitor, duration)
+		d.resolved.set(visitor.ResolvedFaults())
+		if d.options.DryRun {
+			d.dryRun.set(visitor.DryRunCommands())
+		}
+
+		return err
+	}, nil
+}
+
+// InjectHTTPFaultsByGroup injects a different fault in the http requests sent to each group of the disruptor's
+// targets. A target must not match more than one group.
+func (d *podDisruptor) InjectHTTPFaultsByGroup(
+	ctx context.Context,
+	faults map[string]HTTPFault,
+	duration time.Duration,
+	options HTTPDisruptionOptions,
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectHTTPFaultsByGroup", faultAttributes(faults, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
 		return err
 	}
 
-	controller := NewPodController(targets)
+	if len(faults) == 0 {
+		return fmt.Errorf("at least one group must be specified")
+	}
 
-	return controller.Visit(ctx, visitor)
+	groups := make([]string, 0, len(faults))
+	for group := range faults {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var command PodHTTPFaultCommand
+	groupCommands := make(map[string]PodHTTPFaultCommand, len(groups))
+	for _, group := range groups {
+		command, err = d.httpFaultCommand(faults[group], duration, options)
+		if err != nil {
+			return fmt.Errorf("fault of group %q: %w", group, err)
+		}
+		groupCommands[group] = command
+	}
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	grouped, err := groupPods(targets, groups)
+	if err != nil {
+		return err
+	}
+
+	// the command of the group of each target, by the namespace and name of the target
+	commands := map[types.NamespacedName]PodHTTPFaultCommand{}
+	targets = []corev1.Pod{}
+	for _, group := range groups {
+		for _, pod := range grouped[group] {
+			commands[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = groupCommands[group]
+			targets = append(targets, pod)
+		}
+	}
+
+	span.SetAttributes(targetsAttribute(targets))
+
+	visitor := NewPodAgentVisitor(
+		d.helper,
+		d.visitorOptions(duration),
+		PodVisitCommandFunc(func(pod corev1.Pod) (VisitCommands, error) {
+			return commands[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}].Commands(pod)
+		}),
+	)
+
+	if !d.options.DryRun {
+		d.options.Metrics.IncInjection(InjectionKindHTTP, len(targets))
+	}
+
+	// all the groups share the duration
+	err = d.visit(ctx, targets, visitor, duration)
+	d.resolved.set(visitor.ResolvedFaults())
+	if d.options.DryRun {
+		d.dryRun.set(visitor.DryRunCommands())
+	}
+
+	return err
 }
 
 // InjectGrpcFaults injects faults in the grpc requests sent to the disruptor's targets
@@ -124,27 +741,456 @@ func (d *podDisruptor) InjectGrpcFaults(
 	fault GrpcFault,
 	duration time.Duration,
 	options GrpcDisruptionOptions,
-) error {
-	command := PodGrpcFaultCommand{
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectGrpcFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	command, err := d.grpcFaultCommand(fault, duration, options)
+	if err != nil {
+		return err
+	}
+
+	visitor := NewPodAgentVisitor(
+		d.helper,
+		d.visitorOptions(duration),
+		command,
+	)
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(targetsAttribute(targets))
+
+	if !d.options.DryRun {
+		d.options.Metrics.IncInjection(InjectionKindGrpc, len(targets))
+	}
+
+	err = d.visit(ctx, targets, visitor, duration)
+	d.resolved.set(visitor.ResolvedFaults())
+	if d.options.DryRun {
+		d.dryRun.set(visitor.DryRunCommands())
+	}
+
+	return err
+}
+
+// httpFaultCommand validates the HTTPFault and returns the command that injects it in the targets
+func (d *podDisruptor) httpFaultCommand(
+	fault HTTPFault,
+	duration time.Duration,
+	options HTTPDisruptionOptions,
+) (PodHTTPFaultCommand, error) {
+	if err := fault.validate(); err != nil {
+		return PodHTTPFaultCommand{}, err
+	}
+
+	if err := options.validate(duration, d.execTimeout(0)); err != nil {
+		return PodHTTPFaultCommand{}, err
+	}
+
+	if err := fault.validateWindows(duration); err != nil {
+		return PodHTTPFaultCommand{}, err
+	}
+
+	if len(fault.Ports) > 0 && options.ProxyPort != 0 {
+		return PodHTTPFaultCommand{}, fmt.Errorf("proxy port cannot be specified when the fault has multiple ports")
+	}
+
+	// Handle default port mapping
+	// TODO: make port mandatory instead of using a default
+	if len(fault.Ports) == 0 && (fault.Port.IsNull() || fault.Port.IsZero()) {
+		fault.Port = DefaultTargetPort
+	}
+
+	return PodHTTPFaultCommand{
+		fault:    fault,
+		duration: capDuration(duration, d.options.MaxDuration),
+		options:  options,
+	}, nil
+}
+
+// grpcFaultCommand validates the GrpcFault and returns the command that injects it in the targets
+func (d *podDisruptor) grpcFaultCommand(
+	fault GrpcFault,
+	duration time.Duration,
+	options GrpcDisruptionOptions,
+) (PodGrpcFaultCommand, error) {
+	if err := fault.validate(); err != nil {
+		return PodGrpcFaultCommand{}, err
+	}
+
+	if err := options.validate(duration, d.execTimeout(0)); err != nil {
+		return PodGrpcFaultCommand{}, err
+	}
+
+	return PodGrpcFaultCommand{
+		fault:    fault,
+		duration: capDuration(duration, d.options.MaxDuration),
+		options:  options,
+	}, nil
+}
+
+// BuildHTTPFaultCommand returns the agent command that injects the HTTPFault in the first target
+func (d *podDisruptor) BuildHTTPFaultCommand(
+	ctx context.Context,
+	fault HTTPFault,
+	duration time.Duration,
+	options HTTPDisruptionOptions,
+) ([]string, error) {
+	command, err := d.httpFaultCommand(fault, duration, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.buildCommand(ctx, command)
+}
+
+// BuildGrpcFaultCommand returns the agent command that injects the GrpcFault in the first target
+func (d *podDisruptor) BuildGrpcFaultCommand(
+	ctx context.Context,
+	fault GrpcFault,
+	duration time.Duration,
+	options GrpcDisruptionOptions,
+) ([]string, error) {
+	command, err := d.grpcFaultCommand(fault, duration, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.buildCommand(ctx, command)
+}
+
+// buildCommand returns the command generated for the first target. The command depends on the target (e.g. its IP),
+// but is otherwise the same for all of them.
+func (d *podDisruptor) buildCommand(ctx context.Context, command PodVisitCommand) ([]string, error) {
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := command.Commands(targets[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.Exec, nil
+}
+
+// InjectTCPFaults injects faults in the TCP connections to the target pods
+func (d *podDisruptor) InjectTCPFaults(
+	ctx context.Context,
+	fault TCPFault,
+	duration time.Duration,
+	options TCPDisruptionOptions,
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectTCPFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	// Handle default port mapping
+	if fault.Port.IsNull() || fault.Port.IsZero() {
+		fault.Port = DefaultTargetPort
+	}
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	command := PodTCPFaultCommand{
+		fault:    fault,
+		duration: capDuration(duration, d.options.MaxDuration),
+		options:  options,
+	}
+
+	return d.injectAgentFault(ctx, span, command, duration)
+}
+
+// InjectDNSFaults injects faults in the DNS queries sent by the target pods
+func (d *podDisruptor) InjectDNSFaults(
+	ctx context.Context,
+	fault DNSFault,
+	duration time.Duration,
+	options DNSDisruptionOptions,
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectDNSFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	command := PodDNSFaultCommand{
 		fault:    fault,
-		duration: duration,
+		duration: capDuration(duration, d.options.MaxDuration),
 		options:  options,
 	}
 
+	return d.injectAgentFault(ctx, span, command, duration)
+}
+
+// InjectBandwidthFaults limits the bandwidth of the traffic sent from the target pods
+func (d *podDisruptor) InjectBandwidthFaults(
+	ctx context.Context,
+	fault BandwidthFault,
+	duration time.Duration,
+	options BandwidthDisruptionOptions,
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectBandwidthFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	// Handle default port mapping
+	if fault.Port.IsNull() || fault.Port.IsZero() {
+		fault.Port = DefaultTargetPort
+	}
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	command := PodBandwidthFaultCommand{
+		fault:    fault,
+		duration: capDuration(duration, d.options.MaxDuration),
+		options:  options,
+	}
+
+	return d.injectAgentFault(ctx, span, command, duration)
+}
+
+// InjectProcessFaults pauses the processes of the target pods for the duration of the fault
+func (d *podDisruptor) InjectProcessFaults(
+	ctx context.Context,
+	fault ProcessFault,
+	duration time.Duration,
+	options ProcessDisruptionOptions,
+) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.InjectProcessFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireKill(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	command := PodProcessFaultCommand{
+		fault:    fault,
+		duration: capDuration(duration, d.options.MaxDuration),
+		options:  options,
+	}
+
+	return d.injectAgentFault(ctx, span, command, duration)
+}
+
+// injectAgentFault executes the command of a fault in the targets for the given duration, recording the faults
+// resolved in each target and, in dry run mode, the commands that would have been executed
+func (d *podDisruptor) injectAgentFault(
+	ctx context.Context,
+	span trace.Span,
+	command PodVisitCommand,
+	duration time.Duration,
+) error {
 	visitor := NewPodAgentVisitor(
 		d.helper,
-		PodAgentVisitorOptions{Timeout: d.options.InjectTimeout},
+		d.visitorOptions(duration),
 		command,
 	)
 
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return err
 	}
 
+	span.SetAttributes(targetsAttribute(targets))
+
+	err = d.visit(ctx, targets, visitor, duration)
+	d.resolved.set(visitor.ResolvedFaults())
+	if d.options.DryRun {
+		d.dryRun.set(visitor.DryRunCommands())
+	}
+
+	return err
+}
+
+// visit executes the visitor in the targets, tracking the progress of the fault injection for the given duration,
+// capped to the maximum duration. The warnings of the fault injection are recorded once it ends. If a verification
+// is set in the options, it is invoked once the command is running in all the targets. A failed verification stops
+// the faults in the targets.
+func (d *podDisruptor) visit(
+	ctx context.Context,
+	targets []corev1.Pod,
+	visitor *PodAgentVisitor,
+	duration time.Duration,
+) error {
+	warning := durationWarning(duration, d.options.MaxDuration)
+	duration = capDuration(duration, d.options.MaxDuration)
+
+	progress := d.status.start(len(targets), duration)
+	defer d.status.finish(progress)
+	defer func() { d.warnings.set(injectionWarnings(targets, warning, visitor.Warnings())) }()
+
+	started := progress.started
+	ended := progress.ended
+	if d.events != nil {
+		started = func(pod corev1.Pod) {
+			progress.started(pod)
+			d.events.Record(pod, EventReasonFaultInjected, fmt.Sprintf("Injecting fault for %s", duration))
+		}
+		ended = func(pod corev1.Pod) {
+			progress.ended(pod)
+			d.events.Record(pod, EventReasonFaultEnded, "Fault injection ended")
+		}
+	}
+
+	visitor.onExec = started
+	visitor.onExecDone = ended
+
 	controller := NewPodController(targets)
 
-	return controller.Visit(ctx, visitor)
+	if d.options.VerifyFunc == nil || d.options.DryRun {
+		return controller.Visit(ctx, visitor)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	execs := make(chan struct{}, len(targets))
+	visitor.onExec = func(pod corev1.Pod) {
+		started(pod)
+		execs <- struct{}{}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- controller.Visit(ctx, visitor)
+	}()
+
+	for pending := len(targets); pending > 0; pending-- {
+		select {
+		case <-execs:
+		case err := <-done:
+			// the visit ended before the command started in all the targets
+			if err != nil || len(execs) < pending {
+				return err
+			}
+			// the command started in all the targets, but already completed
+			done <- err
+		}
+	}
+
+	if err := d.options.VerifyFunc(); err != nil {
+		cancel()
+		<-done
+		return fmt.Errorf("verifying fault injection: %w", err)
+	}
+
+	return <-done
+}
+
+// execTimeout returns the maximum time the command of a fault of the given duration is executed for in each target.
+// Zero means no limit.
+func (d *podDisruptor) execTimeout(duration time.Duration) time.Duration {
+	switch {
+	case d.options.ExecTimeout < 0:
+		return 0
+	case d.options.ExecTimeout == 0:
+		return duration + DefaultExecTimeout
+	default:
+		return duration + d.options.ExecTimeout
+	}
+}
+
+// visitorOptions returns the options of the visitor that injects the agent in the targets and executes a fault of
+// the given duration
+func (d *podDisruptor) visitorOptions(duration time.Duration) PodAgentVisitorOptions {
+	return PodAgentVisitorOptions{
+		Timeout:              d.options.InjectTimeout,
+		StartupTimeout:       d.options.AgentStartupTimeout,
+		InjectRetries:        d.options.InjectRetries,
+		InjectBackoff:        d.options.InjectBackoff,
+		FailOnImagePullError: d.options.FailOnImagePullError,
+		MaxConcurrency:       d.options.MaxConcurrency,
+		AgentImage:           d.options.AgentImage,
+		ContainerName:        d.options.AgentContainerName,
+		SecurityContext:      d.options.AgentSecurityContext,
+		DryRun:               d.options.DryRun,
+		NamespaceHelper:      d.namespaceHelper,
+		ExecTimeout:          d.execTimeout(capDuration(duration, d.options.MaxDuration)),
+		HeartbeatInterval:    d.options.HeartbeatInterval,
+	}
+}
+
+// Probe checks the port accepts connections in all the target pods
+func (d *podDisruptor) Probe(ctx context.Context, port intstr.IntOrString) error {
+	if port.IsNull() {
+		port = DefaultTargetPort
+	}
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the probe runs even in dry-run mode, and there is no fault for the agent to time out or report the status of
+	options := d.visitorOptions(0)
+	options.DryRun = false
+	options.ExecTimeout = 0
+	options.HeartbeatInterval = 0
+
+	return probeTargets(ctx, d.helper, options, targets, port)
+}
+
+// Stop stops the faults injected in the target pods
+func (d *podDisruptor) Stop(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "PodDisruptor.Stop")
+	defer func() { endSpan(span, err) }()
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(targetsAttribute(targets))
+
+	return stopTargets(ctx, d.namespaceHelper, agentContainerName(d.options.AgentContainerName), targets)
+}
+
+// Status returns the status of the fault injections in progress in the target pods
+func (d *podDisruptor) Status() (DisruptionStatus, error) {
+	return d.status.status(), nil
+}
+
+// DryRunCommands returns the agent commands recorded by the last fault injection in dry-run mode
+func (d *podDisruptor) DryRunCommands() map[string][]string {
+	return d.dryRun.get()
+}
+
+// ResolvedFaults returns the configuration applied to each target by the last fault injection
+func (d *podDisruptor) ResolvedFaults() map[string]ResolvedFault {
+	return d.resolved.get()
+}
+
+// Warnings returns the warnings of the last fault injection in each target
+func (d *podDisruptor) Warnings() map[string]string {
+	return d.warnings.get()
 }
 
 // TerminatePods terminates a subset of the target pods of the disruptor
@@ -152,7 +1198,7 @@ func (d *podDisruptor) TerminatePods(
 	ctx context.Context,
 	fault PodTerminationFault,
 ) ([]string, error) {
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -164,7 +1210,7 @@ func (d *podDisruptor) TerminatePods(
 
 	controller := NewPodController(targets)
 
-	visitor := PodTerminationVisitor{helper: d.helper, timeout: fault.Timeout}
+	visitor := PodTerminationVisitor{helper: d.helper, timeout: fault.Timeout, namespaceHelper: d.namespaceHelper}
 
 	return utils.PodNames(targets), controller.Visit(ctx, visitor)
 }
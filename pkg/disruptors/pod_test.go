@@ -0,0 +1,1914 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/command"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+func Test_PodDisruptorMaxDuration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		maxDuration time.Duration
+		duration    time.Duration
+		expectedCmd string
+		// the disruptor warns that the duration was capped
+		expectWarning bool
+	}{
+		{
+			title:         "no maximum duration",
+			maxDuration:   0,
+			duration:      60 * time.Second,
+			expectedCmd:   "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 -p 8080 --upstream-host 192.0.2.6",
+			expectWarning: false,
+		},
+		{
+			title:         "duration below maximum",
+			maxDuration:   120 * time.Second,
+			duration:      60 * time.Second,
+			expectedCmd:   "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 -p 8080 --upstream-host 192.0.2.6",
+			expectWarning: false,
+		},
+		{
+			title:         "duration exceeds maximum",
+			maxDuration:   30 * time.Second,
+			duration:      60 * time.Second,
+			expectedCmd:   "xk6-disruptor-agent http -d 30s -t 80 -r 0.1 -e 500 -p 8080 --upstream-host 192.0.2.6",
+			expectWarning: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{MaxDuration: tc.maxDuration}, &pod)
+
+			fault := HTTPFault{
+				Port:      intstr.FromInt32(80),
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+			}
+			err := disruptor.InjectHTTPFaults(context.TODO(), fault, tc.duration, HTTPDisruptionOptions{})
+			if err != nil {
+				t.Fatalf("injecting fault: %v", err)
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if !command.AssertCmdEquals(tc.expectedCmd, cmd) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+
+			if _, warned := disruptor.Warnings()["test-ns/my-app-pod"]; warned != tc.expectWarning {
+				t.Fatalf("expected warning: %t got: %v", tc.expectWarning, disruptor.Warnings())
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorInjectHTTPFaultsByGroup(t *testing.T) {
+	t.Parallel()
+
+	canaryFault := HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 1.0, ErrorCode: 500}
+	baselineFault := HTTPFault{Port: intstr.FromInt32(80), AverageDelay: 100 * time.Millisecond}
+
+	testCases := []struct {
+		title       string
+		faults      map[string]HTTPFault
+		expectError bool
+		// expected command by target
+		expectedCmds map[string]string
+	}{
+		{
+			title: "fault by group",
+			faults: map[string]HTTPFault{
+				"track=canary":   canaryFault,
+				"track=baseline": baselineFault,
+			},
+			expectError: false,
+			expectedCmds: map[string]string{
+				"canary":   "xk6-disruptor-agent http -d 60s -t 80 -r 1 -e 500 -p 8080 --upstream-host 192.0.2.6",
+				"baseline": "xk6-disruptor-agent http -d 60s -t 80 -a 100ms -v 0ms -p 8080 --upstream-host 192.0.2.6",
+			},
+		},
+		{
+			title: "targets not matching any group",
+			faults: map[string]HTTPFault{
+				"track=canary": canaryFault,
+			},
+			expectError: false,
+			expectedCmds: map[string]string{
+				"canary": "xk6-disruptor-agent http -d 60s -t 80 -r 1 -e 500 -p 8080 --upstream-host 192.0.2.6",
+			},
+		},
+		{
+			title: "target in multiple groups",
+			faults: map[string]HTTPFault{
+				"track=canary":                canaryFault,
+				"track in (canary, baseline)": baselineFault,
+			},
+			expectError: true,
+		},
+		{
+			title: "group without targets",
+			faults: map[string]HTTPFault{
+				"track=canary": canaryFault,
+				"track=other":  baselineFault,
+			},
+			expectError: true,
+		},
+		{
+			title: "group selecting all the targets",
+			faults: map[string]HTTPFault{
+				"": canaryFault,
+			},
+			expectError: true,
+		},
+		{
+			title:       "no groups",
+			faults:      map[string]HTTPFault{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objs := []runtime.Object{}
+			for _, track := range []string{"canary", "baseline", "stable"} {
+				pod := buildPodWithPort(track, "http", 80)
+				pod.Labels = map[string]string{"app": "my-app", "track": track}
+				injectAgent(&pod)
+				objs = append(objs, &pod)
+			}
+
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{AgentStartupTimeout: -1}, objs...)
+
+			err := disruptor.InjectHTTPFaultsByGroup(context.TODO(), tc.faults, 60*time.Second, HTTPDisruptionOptions{})
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("should had failed")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cmds := map[string]string{}
+			for _, cmd := range k.GetFakeProcessExecutor().GetHistory() {
+				cmds[cmd.Pod] = strings.Join(cmd.Command, " ")
+			}
+
+			if len(cmds) != len(tc.expectedCmds) {
+				t.Fatalf("expected commands in %d targets got %v", len(tc.expectedCmds), cmds)
+			}
+
+			for pod, expected := range tc.expectedCmds {
+				if !command.AssertCmdEquals(expected, cmds[pod]) {
+					t.Fatalf("expected command in %s: %s got: %s", pod, expected, cmds[pod])
+				}
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorInjectHTTPFaultsAsync(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		fault       HTTPFault
+		execErr     error
+		expectError bool
+		expectLate  bool
+	}{
+		{
+			title:       "fault injected",
+			fault:       HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500},
+			execErr:     nil,
+			expectError: false,
+			expectLate:  false,
+		},
+		{
+			title:       "invalid fault",
+			fault:       HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 1.5, ErrorCode: 500},
+			execErr:     nil,
+			expectError: true,
+			expectLate:  false,
+		},
+		{
+			title:       "failed command execution",
+			fault:       HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500},
+			execErr:     errors.New("exit status 1"),
+			expectError: false,
+			expectLate:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{AgentStartupTimeout: -1}, &pod)
+			k.GetFakeProcessExecutor().SetResult([]byte{}, []byte{}, tc.execErr)
+
+			done, err := disruptor.InjectHTTPFaultsAsync(context.TODO(), tc.fault, 60*time.Second, HTTPDisruptionOptions{})
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("should had failed")
+				}
+				if done != nil {
+					t.Fatalf("expected no channel when the setup fails")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("fault injection did not end")
+			}
+
+			if tc.expectLate && err == nil {
+				t.Fatalf("expected error delivered in the channel")
+			}
+
+			if !tc.expectLate && err != nil {
+				t.Fatalf("unexpected error delivered in the channel: %v", err)
+			}
+
+			if _, open := <-done; open {
+				t.Fatalf("channel was not closed after delivering the result")
+			}
+		})
+	}
+}
+
+func Test_CapDuration(t *testing.T) {
+	t.Parallel()
+
+	if d := capDuration(time.Minute, 0); d != time.Minute {
+		t.Fatalf("expected duration not to be capped, got %s", d)
+	}
+
+	if d := capDuration(time.Minute, time.Second); d != time.Second {
+		t.Fatalf("expected duration to be capped to %s, got %s", time.Second, d)
+	}
+
+	if w := durationWarning(time.Minute, 0); w != "" {
+		t.Fatalf("expected no warning, got %q", w)
+	}
+
+	if w := durationWarning(time.Minute, time.Second); w == "" {
+		t.Fatalf("expected warning for a capped duration")
+	}
+}
+
+func Test_PodDisruptorTCPFaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		fault       TCPFault
+		hostNetwork bool
+		expectedCmd string
+		expectError bool
+	}{
+		{
+			title: "numeric port",
+			fault: TCPFault{
+				Port:      intstr.FromInt32(80),
+				ResetRate: 0.1,
+			},
+			expectedCmd: "xk6-disruptor-agent tcp-drop -d 60s -p 80 -r 0.1",
+			expectError: false,
+		},
+		{
+			title: "named port",
+			fault: TCPFault{
+				Port:      intstr.FromString("http"),
+				ResetRate: 0.5,
+			},
+			expectedCmd: "xk6-disruptor-agent tcp-drop -d 60s -p 80 -r 0.5",
+			expectError: false,
+		},
+		{
+			title: "default port",
+			fault: TCPFault{
+				ResetRate: 1.0,
+			},
+			expectedCmd: "xk6-disruptor-agent tcp-drop -d 60s -p 80 -r 1",
+			expectError: false,
+		},
+		{
+			title: "port not exposed",
+			fault: TCPFault{
+				Port:      intstr.FromInt32(8080),
+				ResetRate: 0.1,
+			},
+			expectError: true,
+		},
+		{
+			title: "invalid reset rate",
+			fault: TCPFault{
+				Port:      intstr.FromInt32(80),
+				ResetRate: 1.1,
+			},
+			expectError: true,
+		},
+		{
+			title: "negative reset rate",
+			fault: TCPFault{
+				Port:      intstr.FromInt32(80),
+				ResetRate: -0.1,
+			},
+			expectError: true,
+		},
+		{
+			title: "pod with hostNetwork",
+			fault: TCPFault{
+				Port:      intstr.FromInt32(80),
+				ResetRate: 0.1,
+			},
+			hostNetwork: true,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			pod.Spec.HostNetwork = tc.hostNetwork
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{}, &pod)
+
+			err := disruptor.InjectTCPFaults(context.TODO(), tc.fault, 60*time.Second, TCPDisruptionOptions{})
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if !command.AssertCmdEquals(tc.expectedCmd, cmd) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorDNSFaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		fault           DNSFault
+		securityContext AgentSecurityContext
+		expectedCmd     string
+		expectError     bool
+	}{
+		{
+			title:       "all the queries",
+			fault:       DNSFault{Rate: 0.1},
+			expectedCmd: "xk6-disruptor-agent dns -d 60s -r 0.1",
+			expectError: false,
+		},
+		{
+			title:       "queries for a domain",
+			fault:       DNSFault{Rate: 1.0, Domain: "example.com"},
+			expectedCmd: "xk6-disruptor-agent dns -d 60s -r 1 --domain example.com",
+			expectError: false,
+		},
+		{
+			title:       "invalid rate",
+			fault:       DNSFault{Rate: 1.1},
+			expectError: true,
+		},
+		{
+			title:       "invalid domain",
+			fault:       DNSFault{Rate: 0.1, Domain: "http://example.com"},
+			expectError: true,
+		},
+		{
+			title:           "agent without NET_ADMIN",
+			fault:           DNSFault{Rate: 0.1},
+			securityContext: AgentSecurityContext{DropCapabilities: []string{"NET_ADMIN"}},
+			expectError:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{AgentSecurityContext: tc.securityContext}, &pod)
+
+			err := disruptor.InjectDNSFaults(context.TODO(), tc.fault, 60*time.Second, DNSDisruptionOptions{})
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if !command.AssertCmdEquals(tc.expectedCmd, cmd) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorBandwidthFaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		fault       BandwidthFault
+		hostNetwork bool
+		expectedCmd string
+		expectError bool
+	}{
+		{
+			title: "numeric port",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+				Rate: "1mbit",
+			},
+			expectedCmd: "xk6-disruptor-agent bandwidth -d 60s -l 1mbit -t 80",
+			expectError: false,
+		},
+		{
+			title: "named port",
+			fault: BandwidthFault{
+				Port: intstr.FromString("http"),
+				Rate: "512kbit",
+			},
+			expectedCmd: "xk6-disruptor-agent bandwidth -d 60s -l 512kbit -t 80",
+			expectError: false,
+		},
+		{
+			title: "default port",
+			fault: BandwidthFault{
+				Rate: "1.5mbps",
+			},
+			expectedCmd: "xk6-disruptor-agent bandwidth -d 60s -l 1.5mbps -t 80",
+			expectError: false,
+		},
+		{
+			title: "port not exposed",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(8080),
+				Rate: "1mbit",
+			},
+			expectError: true,
+		},
+		{
+			title: "missing rate",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+			},
+			expectError: true,
+		},
+		{
+			title: "rate without unit",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+				Rate: "1000",
+			},
+			expectError: true,
+		},
+		{
+			title: "unknown rate unit",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+				Rate: "1mb",
+			},
+			expectError: true,
+		},
+		{
+			title: "zero rate",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+				Rate: "0mbit",
+			},
+			expectError: true,
+		},
+		{
+			title: "pod with hostNetwork",
+			fault: BandwidthFault{
+				Port: intstr.FromInt32(80),
+				Rate: "1mbit",
+			},
+			hostNetwork: true,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			pod.Spec.HostNetwork = tc.hostNetwork
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{}, &pod)
+
+			err := disruptor.InjectBandwidthFaults(context.TODO(), tc.fault, 60*time.Second, BandwidthDisruptionOptions{})
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if !command.AssertCmdEquals(tc.expectedCmd, cmd) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorProcessFaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title                 string
+		fault                 ProcessFault
+		shareProcessNamespace bool
+		expectedCmd           string
+		expectError           bool
+	}{
+		{
+			title:                 "default signal",
+			fault:                 ProcessFault{},
+			shareProcessNamespace: true,
+			expectedCmd:           "xk6-disruptor-agent process -d 60s -s STOP",
+			expectError:           false,
+		},
+		{
+			title: "process name",
+			fault: ProcessFault{
+				Signal:  "TSTP",
+				Process: "app",
+			},
+			shareProcessNamespace: true,
+			expectedCmd:           "xk6-disruptor-agent process -d 60s -s TSTP -n app",
+			expectError:           false,
+		},
+		{
+			title: "unsupported signal",
+			fault: ProcessFault{
+				Signal: "KILL",
+			},
+			shareProcessNamespace: true,
+			expectError:           true,
+		},
+		{
+			title: "invalid process name",
+			fault: ProcessFault{
+				Process: "my app",
+			},
+			shareProcessNamespace: true,
+			expectError:           true,
+		},
+		{
+			title:                 "process namespace not shared",
+			fault:                 ProcessFault{},
+			shareProcessNamespace: false,
+			expectError:           true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			pod.Spec.ShareProcessNamespace = &tc.shareProcessNamespace
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{}, &pod)
+
+			err := disruptor.InjectProcessFaults(context.TODO(), tc.fault, 60*time.Second, ProcessDisruptionOptions{})
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if !command.AssertCmdEquals(tc.expectedCmd, cmd) {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorTargetsDetailed(t *testing.T) {
+	t.Parallel()
+
+	pods := []corev1.Pod{
+		builders.NewPodBuilder("pod-1").
+			WithNamespace("test-ns").
+			WithLabels(map[string]string{"app": "my-app", "zone": "a"}).
+			WithNodeName("node-1").
+			WithIP("192.0.2.6").
+			Build(),
+		builders.NewPodBuilder("pod-2").
+			WithNamespace("test-ns").
+			WithLabels(map[string]string{"app": "my-app", "zone": "b"}).
+			WithNodeName("node-2").
+			WithIP("192.0.2.7").
+			Build(),
+	}
+
+	disruptor, _ := newTestPodDisruptor(t, PodDisruptorOptions{}, &pods[0], &pods[1])
+
+	targets, err := disruptor.TargetsDetailed(context.TODO())
+	if err != nil {
+		t.Fatalf("getting targets: %v", err)
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+
+	expected := []Target{
+		{
+			Name:      "pod-1",
+			Namespace: "test-ns",
+			Node:      "node-1",
+			IP:        "192.0.2.6",
+			Labels:    map[string]string{"app": "my-app", "zone": "a"},
+		},
+		{
+			Name:      "pod-2",
+			Namespace: "test-ns",
+			Node:      "node-2",
+			IP:        "192.0.2.7",
+			Labels:    map[string]string{"app": "my-app", "zone": "b"},
+		},
+	}
+
+	if diff := cmp.Diff(expected, targets); diff != "" {
+		t.Fatalf("expected targets do not match returned:\n%s", diff)
+	}
+}
+
+func Test_PodDisruptorDryRun(t *testing.T) {
+	t.Parallel()
+
+	pod := buildPodWithPort("my-app-pod", "http", 80)
+	pod.Labels = map[string]string{"app": "my-app"}
+
+	disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{DryRun: true}, &pod)
+
+	fault := HTTPFault{
+		Port:      intstr.FromInt32(80),
+		ErrorRate: 0.1,
+		ErrorCode: 500,
+	}
+
+	err := disruptor.InjectHTTPFaults(context.TODO(), fault, 60*time.Second, HTTPDisruptionOptions{})
+	if err != nil {
+		t.Fatalf("injecting fault: %v", err)
+	}
+
+	if history := k.GetFakeProcessExecutor().GetHistory(); len(history) != 0 {
+		t.Fatalf("expected no command executed got %v", history)
+	}
+
+	commands := disruptor.DryRunCommands()
+	if len(commands) != 1 {
+		t.Fatalf("expected the command of 1 target got %v", commands)
+	}
+
+	expectedCmd := "xk6-disruptor-agent http -d 60s -t 80 -r 0.1 -e 500 -p 8080 --upstream-host 192.0.2.6"
+	if !command.AssertCmdEquals(expectedCmd, strings.Join(commands["test-ns/my-app-pod"], " ")) {
+		t.Fatalf("expected command %q got %q", expectedCmd, commands["test-ns/my-app-pod"])
+	}
+}
+
+func Test_PodDisruptorWarnings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		stderr   []byte
+		expected map[string]string
+	}{
+		{
+			title:    "no warnings",
+			stderr:   []byte{},
+			expected: map[string]string{},
+		},
+		{
+			title:    "agent reports warnings",
+			stderr:   []byte("warning output"),
+			expected: map[string]string{"test-ns/my-app-pod": "warning output"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{AgentStartupTimeout: -1}, &pod)
+			k.GetFakeProcessExecutor().SetResult([]byte{}, tc.stderr, nil)
+
+			fault := HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500}
+			err := disruptor.InjectHTTPFaults(context.TODO(), fault, 60*time.Second, HTTPDisruptionOptions{})
+			if err != nil {
+				t.Fatalf("injecting fault: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, disruptor.Warnings()); diff != "" {
+				t.Fatalf("warnings do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+// fakeInjectionMetrics records the injections reported to an InjectionMetrics
+type fakeInjectionMetrics struct {
+	mutex      sync.Mutex
+	injections []string
+}
+
+func (f *fakeInjectionMetrics) IncInjection(kind string, targets int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.injections = append(f.injections, fmt.Sprintf("%s:%d", kind, targets))
+}
+
+func Test_PodDisruptorMetrics(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		dryRun   bool
+		expected []string
+	}{
+		{
+			title:    "faults injected",
+			dryRun:   false,
+			expected: []string{"http:2", "grpc:2"},
+		},
+		{
+			title:    "dry run",
+			dryRun:   true,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objs := []runtime.Object{}
+			for _, name := range []string{"pod-1", "pod-2"} {
+				pod := buildInjectedPod(name, "http", 80)
+				objs = append(objs, &pod)
+			}
+
+			metrics := &fakeInjectionMetrics{}
+			disruptor, _ := newTestPodDisruptor(t, PodDisruptorOptions{DryRun: tc.dryRun, Metrics: metrics}, objs...)
+
+			httpFault := HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500}
+			err := disruptor.InjectHTTPFaults(context.TODO(), httpFault, 60*time.Second, HTTPDisruptionOptions{})
+			if err != nil {
+				t.Fatalf("injecting http fault: %v", err)
+			}
+
+			grpcFault := GrpcFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, StatusCode: 14}
+			err = disruptor.InjectGrpcFaults(context.TODO(), grpcFault, 60*time.Second, GrpcDisruptionOptions{})
+			if err != nil {
+				t.Fatalf("injecting grpc fault: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, metrics.injections); diff != "" {
+				t.Fatalf("recorded injections do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorEvents(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		events   bool
+		dryRun   bool
+		expected []string
+	}{
+		{
+			title:  "events enabled",
+			events: true,
+			expected: []string{
+				"pod-1:" + EventReasonFaultEnded + ":Fault injection ended",
+				"pod-1:" + EventReasonFaultInjected + ":Injecting fault for 1m0s",
+				"pod-2:" + EventReasonFaultEnded + ":Fault injection ended",
+				"pod-2:" + EventReasonFaultInjected + ":Injecting fault for 1m0s",
+			},
+		},
+		{
+			title:    "events disabled",
+			events:   false,
+			expected: []string{},
+		},
+		{
+			title:    "dry run",
+			events:   true,
+			dryRun:   true,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objs := []runtime.Object{}
+			for _, name := range []string{"pod-1", "pod-2"} {
+				pod := buildInjectedPod(name, "http", 80)
+				objs = append(objs, &pod)
+			}
+
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{Events: tc.events, DryRun: tc.dryRun}, objs...)
+
+			fault := HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500}
+			err := disruptor.InjectHTTPFaults(context.TODO(), fault, 60*time.Second, HTTPDisruptionOptions{})
+			if err != nil {
+				t.Fatalf("injecting http fault: %v", err)
+			}
+
+			// events are recorded asynchronously
+			var events []string
+			for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+				list, err := k.Client().CoreV1().Events("test-ns").List(context.TODO(), metav1.ListOptions{})
+				if err != nil {
+					t.Fatalf("listing events: %v", err)
+				}
+
+				events = []string{}
+				for _, event := range list.Items {
+					events = append(events, event.InvolvedObject.Name+":"+event.Reason+":"+event.Message)
+				}
+
+				if len(events) >= len(tc.expected) && len(tc.expected) > 0 {
+					break
+				}
+			}
+
+			sort.Strings(events)
+			if diff := cmp.Diff(tc.expected, events); diff != "" {
+				t.Fatalf("recorded events do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorResolvedFaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		targetPort    int32
+		fault         HTTPFault
+		options       HTTPDisruptionOptions
+		expectedPorts []ResolvedPort
+	}{
+		{
+			title:      "default ports",
+			targetPort: 80,
+			fault:      HTTPFault{ErrorRate: 0.1, ErrorCode: 500},
+			options:    HTTPDisruptionOptions{},
+			expectedPorts: []ResolvedPort{
+				{Port: intstr.FromInt32(80), ProxyPort: DefaultHTTPProxyPort, Container: "my-app-pod"},
+			},
+		},
+		{
+			title:      "named target port",
+			targetPort: 8000,
+			fault:      HTTPFault{Port: intstr.FromString("http"), ErrorRate: 0.1, ErrorCode: 500},
+			options:    HTTPDisruptionOptions{},
+			expectedPorts: []ResolvedPort{
+				{Port: intstr.FromInt32(8000), ProxyPort: DefaultHTTPProxyPort, Container: "my-app-pod"},
+			},
+		},
+		{
+			title:      "default proxy port is the target port",
+			targetPort: 8080,
+			fault:      HTTPFault{Port: intstr.FromInt32(8080), ErrorRate: 0.1, ErrorCode: 500},
+			options:    HTTPDisruptionOptions{},
+			expectedPorts: []ResolvedPort{
+				{Port: intstr.FromInt32(8080), ProxyPort: DefaultHTTPProxyPort + 1, Container: "my-app-pod"},
+			},
+		},
+		{
+			title:         "explicit proxy port",
+			targetPort:    80,
+			fault:         HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500},
+			options:       HTTPDisruptionOptions{ProxyPort: 9000},
+			expectedPorts: []ResolvedPort{{Port: intstr.FromInt32(80), ProxyPort: 9000, Container: "my-app-pod"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", tc.targetPort)
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{}, &pod)
+
+			if resolved := disruptor.ResolvedFaults(); len(resolved) != 0 {
+				t.Fatalf("expected no resolved faults before the fault injection got %v", resolved)
+			}
+
+			err := disruptor.InjectHTTPFaults(context.TODO(), tc.fault, 60*time.Second, tc.options)
+			if err != nil {
+				t.Fatalf("injecting fault: %v", err)
+			}
+
+			resolved, found := disruptor.ResolvedFaults()["test-ns/my-app-pod"]
+			if !found {
+				t.Fatalf("no resolved fault for the target")
+			}
+
+			if diff := cmp.Diff(tc.expectedPorts, resolved.Ports); diff != "" {
+				t.Errorf("resolved ports do not match expected:\n%s", diff)
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			if diff := cmp.Diff(history[0].Command, resolved.Command); diff != "" {
+				t.Errorf("resolved command does not match executed command:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorTargetsPhase(t *testing.T) {
+	t.Parallel()
+
+	terminating := builders.NewPodBuilder("terminating").
+		WithNamespace("test-ns").
+		WithLabel("app", "test").
+		WithPhase(corev1.PodRunning).
+		Build()
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	terminating.Finalizers = []string{"test"}
+
+	pods := []corev1.Pod{
+		builders.NewPodBuilder("pending").
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			WithPhase(corev1.PodPending).
+			Build(),
+		builders.NewPodBuilder("running").
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			WithPhase(corev1.PodRunning).
+			Build(),
+		builders.NewPodBuilder("succeeded").
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			WithPhase(corev1.PodSucceeded).
+			Build(),
+		terminating,
+	}
+
+	testCases := []struct {
+		title       string
+		pods        []corev1.Pod
+		options     PodDisruptorOptions
+		expectError bool
+		expected    []string
+	}{
+		{
+			title:       "only running pods",
+			pods:        pods,
+			options:     PodDisruptorOptions{},
+			expectError: false,
+			expected:    []string{"running"},
+		},
+		{
+			title:       "include not running pods",
+			pods:        pods,
+			options:     PodDisruptorOptions{IncludeNotRunning: true},
+			expectError: false,
+			expected:    []string{"pending", "running", "succeeded", "terminating"},
+		},
+		{
+			title:       "no running pods",
+			pods:        []corev1.Pod{pods[0], pods[2]},
+			options:     PodDisruptorOptions{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			var objs []runtime.Object
+			for p := range tc.pods {
+				objs = append(objs, &tc.pods[p])
+			}
+
+			client := fake.NewSimpleClientset(objs...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			disruptor, err := NewPodDisruptor(
+				context.TODO(),
+				k,
+				PodSelectorSpec{
+					Namespace: "test-ns",
+					Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
+				},
+				tc.options,
+			)
+			if err != nil {
+				t.Fatalf("creating disruptor: %v", err)
+			}
+
+			targets, err := disruptor.Targets(context.TODO())
+			if tc.expectError && !errors.Is(err, ErrSelectorNoPods) {
+				t.Fatalf("expected %v got %v", ErrSelectorNoPods, err)
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sort.Strings(targets)
+			if diff := cmp.Diff(tc.expected, targets); diff != "" {
+				t.Fatalf("expected targets do not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorNamespaces(t *testing.T) {
+	t.Parallel()
+
+	pods := []corev1.Pod{}
+	for _, namespace := range []string{"team-a", "team-b", "team-c"} {
+		pod := builders.NewPodBuilder("frontend").
+			WithNamespace(namespace).
+			WithLabel("app", "frontend").
+			WithIP("192.0.2.6").
+			WithContainer(builders.NewContainerBuilder("frontend").WithPort("http", 80).Build()).
+			Build()
+		injectAgent(&pod)
+		pods = append(pods, pod)
+	}
+
+	client := fake.NewSimpleClientset(&pods[0], &pods[1], &pods[2])
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	disruptor, err := NewPodDisruptor(
+		context.TODO(),
+		k,
+		PodSelectorSpec{
+			Namespaces: []string{"team-a", "team-b"},
+			Select:     PodAttributes{Labels: map[string]string{"app": "frontend"}},
+		},
+		PodDisruptorOptions{},
+	)
+	if err != nil {
+		t.Fatalf("creating disruptor: %v", err)
+	}
+
+	targets, err := disruptor.TargetsDetailed(context.TODO())
+	if err != nil {
+		t.Fatalf("getting targets: %v", err)
+	}
+
+	namespaces := []string{}
+	for _, target := range targets {
+		namespaces = append(namespaces, target.Namespace)
+	}
+	sort.Strings(namespaces)
+
+	if diff := cmp.Diff([]string{"team-a", "team-b"}, namespaces); diff != "" {
+		t.Fatalf("expected namespaces of the targets do not match returned:\n%s", diff)
+	}
+
+	fault := HTTPFault{
+		Port:      intstr.FromInt32(80),
+		ErrorRate: 0.1,
+		ErrorCode: 500,
+	}
+
+	err = disruptor.InjectHTTPFaults(context.TODO(), fault, 60*time.Second, HTTPDisruptionOptions{})
+	if err != nil {
+		t.Fatalf("injecting fault: %v", err)
+	}
+
+	// the command must be executed in the namespace of each target
+	executed := []string{}
+	for _, cmd := range k.GetFakeProcessExecutor().GetHistory() {
+		executed = append(executed, cmd.Namespace+"/"+cmd.Pod)
+	}
+	sort.Strings(executed)
+
+	if diff := cmp.Diff([]string{"team-a/frontend", "team-b/frontend"}, executed); diff != "" {
+		t.Fatalf("expected executions do not match executed:\n%s", diff)
+	}
+
+	// the faults resolved for pods with the same name in different namespaces must not overwrite each other
+	resolved := []string{}
+	for key := range disruptor.ResolvedFaults() {
+		resolved = append(resolved, key)
+	}
+	sort.Strings(resolved)
+
+	if diff := cmp.Diff([]string{"team-a/frontend", "team-b/frontend"}, resolved); diff != "" {
+		t.Fatalf("expected resolved faults do not match returned:\n%s", diff)
+	}
+}
+
+func Test_PodDisruptorNamespaceAndNamespaces(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset()
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	_, err := NewPodDisruptor(
+		context.TODO(),
+		k,
+		PodSelectorSpec{
+			Namespace:  "team-a",
+			Namespaces: []string{"team-b"},
+		},
+		PodDisruptorOptions{},
+	)
+	if err == nil {
+		t.Fatalf("should had failed")
+	}
+}
+
+func Test_PodDisruptorSelectorCriteria(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		spec        PodSelectorSpec
+		expectError error
+	}{
+		{
+			title:       "empty selector",
+			spec:        PodSelectorSpec{Namespace: "test-ns"},
+			expectError: ErrSelectorNoCriteria,
+		},
+		{
+			title: "only excluded labels",
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Exclude:   PodAttributes{Labels: map[string]string{"app": "db"}},
+			},
+			expectError: ErrSelectorNoCriteria,
+		},
+		{
+			title:       "explicit select all",
+			spec:        PodSelectorSpec{Namespace: "test-ns", SelectAll: true},
+			expectError: nil,
+		},
+		{
+			title: "selected labels",
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select:    PodAttributes{Labels: map[string]string{"app": "frontend"}},
+			},
+			expectError: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("frontend").
+				WithNamespace("test-ns").
+				WithLabel("app", "frontend").
+				Build()
+
+			client := fake.NewSimpleClientset(&pod)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			_, err := NewPodDisruptor(context.TODO(), k, tc.spec, PodDisruptorOptions{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected error %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+// releaseExecutor is a PodCommandExecutor that blocks the execution of the command until it is released or its
+// context is cancelled
+type releaseExecutor struct {
+	mutex   sync.Mutex
+	release chan struct{}
+	// started receives a value when the execution of a command starts, if not nil
+	started chan struct{}
+	// cleanup is set if the cleanup command was executed
+	cleanup bool
+}
+
+func (e *releaseExecutor) Exec(
+	ctx context.Context,
+	_ string,
+	_ string,
+	_ string,
+	command []string,
+	_ []byte,
+) ([]byte, []byte, error) {
+	if command[0] == "cleanup" {
+		e.mutex.Lock()
+		e.cleanup = true
+		e.mutex.Unlock()
+		return nil, nil, nil
+	}
+
+	if e.started != nil {
+		e.started <- struct{}{}
+	}
+
+	select {
+	case <-e.release:
+		return nil, nil, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func Test_PodDisruptorVerify(t *testing.T) {
+	t.Parallel()
+
+	verifyErr := errors.New("fault not observed")
+
+	testCases := []struct {
+		title           string
+		verifyErr       error
+		expectedCleanup bool
+	}{
+		{
+			title:           "verification passes",
+			verifyErr:       nil,
+			expectedCleanup: false,
+		},
+		{
+			title:           "verification fails",
+			verifyErr:       verifyErr,
+			expectedCleanup: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			targets := []corev1.Pod{}
+			for _, name := range []string{"pod-1", "pod-2"} {
+				pod := builders.NewPodBuilder(name).WithNamespace("test-ns").Build()
+				injectAgent(&pod)
+				targets = append(targets, pod)
+			}
+
+			client := fake.NewSimpleClientset(&targets[0], &targets[1])
+			executor := &releaseExecutor{release: make(chan struct{})}
+			helper := helpers.NewPodHelper(client, executor, "test-ns")
+			visitor := NewPodAgentVisitor(helper, PodAgentVisitorOptions{Timeout: -1}, visitCommands())
+
+			verified := false
+			disruptor := &podDisruptor{
+				helper: helper,
+				options: PodDisruptorOptions{
+					VerifyFunc: func() error {
+						verified = true
+						if tc.verifyErr == nil {
+							close(executor.release)
+						}
+						return tc.verifyErr
+					},
+				},
+			}
+
+			err := disruptor.visit(context.TODO(), targets, visitor, time.Minute)
+			if !errors.Is(err, tc.verifyErr) {
+				t.Fatalf("expected error %v got %v", tc.verifyErr, err)
+			}
+
+			if !verified {
+				t.Fatalf("verification was not invoked")
+			}
+
+			executor.mutex.Lock()
+			defer executor.mutex.Unlock()
+
+			if executor.cleanup != tc.expectedCleanup {
+				t.Fatalf("expected cleanup %t got %t", tc.expectedCleanup, executor.cleanup)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorExecTimeout(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod-1").WithNamespace("test-ns").Build()
+	injectAgent(&pod)
+
+	client := fake.NewSimpleClientset(&pod)
+	// the command is never released, as if the agent hangs
+	executor := &releaseExecutor{release: make(chan struct{})}
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+
+	disruptor := &podDisruptor{
+		helper: helper,
+		options: PodDisruptorOptions{
+			InjectTimeout: -1,
+			ExecTimeout:   100 * time.Millisecond,
+		},
+	}
+	visitor := NewPodAgentVisitor(helper, disruptor.visitorOptions(100*time.Millisecond), visitCommands())
+
+	err := disruptor.visit(context.TODO(), []corev1.Pod{pod}, visitor, 100*time.Millisecond)
+	if !errors.Is(err, ErrExecTimeout) {
+		t.Fatalf("expected error %v got %v", ErrExecTimeout, err)
+	}
+
+	if !strings.Contains(err.Error(), "pod-1") {
+		t.Fatalf("expected error naming the target got %v", err)
+	}
+
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+
+	if !executor.cleanup {
+		t.Fatalf("cleanup command was not executed after the command timed out")
+	}
+}
+
+// heartbeatExecutor is a PodCommandExecutor that blocks the execution of the fault command until its context is
+// cancelled, and fails the status command after a number of heartbeats, as if the agent died
+type heartbeatExecutor struct {
+	mutex sync.Mutex
+	// number of heartbeats that succeed
+	heartbeats int
+	// status commands executed
+	statuses int
+	// cleanup is set if the cleanup command was executed
+	cleanup bool
+}
+
+func (e *heartbeatExecutor) Exec(
+	ctx context.Context,
+	_ string,
+	_ string,
+	_ string,
+	command []string,
+	_ []byte,
+) ([]byte, []byte, error) {
+	status := len(command) > 1 && command[1] == "status"
+	if command[0] != "cleanup" && !status {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if command[0] == "cleanup" {
+		e.cleanup = true
+		return nil, nil, nil
+	}
+
+	e.statuses++
+	if e.statuses > e.heartbeats {
+		return nil, []byte("no fault injection is running"), errors.New("command terminated with exit code 1")
+	}
+
+	return nil, nil, nil
+}
+
+func Test_PodDisruptorHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod-1").WithNamespace("test-ns").Build()
+	injectAgent(&pod)
+
+	client := fake.NewSimpleClientset(&pod)
+	executor := &heartbeatExecutor{heartbeats: 3}
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+
+	disruptor := &podDisruptor{
+		helper: helper,
+		options: PodDisruptorOptions{
+			InjectTimeout:     -1,
+			HeartbeatInterval: 10 * time.Millisecond,
+		},
+	}
+	visitor := NewPodAgentVisitor(helper, disruptor.visitorOptions(time.Minute), visitCommands())
+
+	err := disruptor.visit(context.TODO(), []corev1.Pod{pod}, visitor, time.Minute)
+	if !errors.Is(err, ErrAgentNotResponding) {
+		t.Fatalf("expected error %v got %v", ErrAgentNotResponding, err)
+	}
+
+	if !strings.Contains(err.Error(), "pod-1") {
+		t.Fatalf("expected error naming the target got %v", err)
+	}
+
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+
+	if executor.statuses != executor.heartbeats+1 {
+		t.Fatalf("expected %d heartbeats got %d", executor.heartbeats+1, executor.statuses)
+	}
+
+	if !executor.cleanup {
+		t.Fatalf("cleanup command was not executed after the agent stopped responding")
+	}
+}
+
+func Test_PodDisruptorStatus(t *testing.T) {
+	t.Parallel()
+
+	targets := []corev1.Pod{}
+	for _, name := range []string{"pod-1", "pod-2"} {
+		pod := builders.NewPodBuilder(name).WithNamespace("test-ns").Build()
+		injectAgent(&pod)
+		targets = append(targets, pod)
+	}
+
+	client := fake.NewSimpleClientset(&targets[0], &targets[1])
+	executor := &releaseExecutor{release: make(chan struct{}), started: make(chan struct{}, 2)}
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+	// the command is executed in one target at a time, so only one of them is active
+	visitor := NewPodAgentVisitor(helper, PodAgentVisitorOptions{Timeout: -1, MaxConcurrency: 1}, visitCommands())
+
+	disruptor := &podDisruptor{helper: helper}
+
+	done := make(chan error)
+	go func() {
+		done <- disruptor.visit(context.TODO(), targets, visitor, time.Minute)
+	}()
+
+	select {
+	case <-executor.started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("command was not executed")
+	}
+
+	status, err := disruptor.Status()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if status.ActiveTargets != 1 || status.TotalTargets != 2 {
+		t.Fatalf("expected 1 of 2 active targets got %d of %d", status.ActiveTargets, status.TotalTargets)
+	}
+
+	if status.Remaining <= 0 || status.Remaining > time.Minute {
+		t.Fatalf("expected remaining time in (0, 1m] got %s", status.Remaining)
+	}
+
+	close(executor.release)
+	if err = <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status, _ = disruptor.Status()
+	if diff := cmp.Diff(DisruptionStatus{}, status); diff != "" {
+		t.Fatalf("expected no fault injection in progress:\n%s", diff)
+	}
+}
+
+func Test_PodDisruptorMinReadyTargets(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		pods        int
+		readyPods   int
+		readyDelay  time.Duration
+		minReady    uint
+		timeout     time.Duration
+		expectError error
+	}{
+		{
+			title:       "targets already ready",
+			pods:        3,
+			readyPods:   3,
+			readyDelay:  0,
+			minReady:    2,
+			timeout:     time.Second,
+			expectError: nil,
+		},
+		{
+			title:       "targets become ready",
+			pods:        3,
+			readyPods:   2,
+			readyDelay:  500 * time.Millisecond,
+			minReady:    2,
+			timeout:     5 * time.Second,
+			expectError: nil,
+		},
+		{
+			title:       "not enough targets become ready",
+			pods:        3,
+			readyPods:   1,
+			readyDelay:  100 * time.Millisecond,
+			minReady:    2,
+			timeout:     time.Second,
+			expectError: ErrNotEnoughReadyTargets,
+		},
+		{
+			title:       "not enough targets",
+			pods:        1,
+			readyPods:   1,
+			readyDelay:  0,
+			minReady:    2,
+			timeout:     time.Second,
+			expectError: ErrNotEnoughReadyTargets,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+			pods := []corev1.Pod{}
+			for i := 0; i < tc.pods; i++ {
+				pod := builders.NewPodBuilder(fmt.Sprintf("pod-%d", i)).
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build()
+
+				if i < tc.readyPods && tc.readyDelay == 0 {
+					pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+				}
+
+				_, err := client.CoreV1().Pods("test-ns").Create(context.TODO(), &pod, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("creating pod: %v", err)
+				}
+				pods = append(pods, pod)
+			}
+
+			// make the pods ready after the delay
+			if tc.readyDelay > 0 {
+				go func() {
+					time.Sleep(tc.readyDelay)
+					for i := 0; i < tc.readyPods; i++ {
+						pod := pods[i]
+						pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+						_, err := client.CoreV1().Pods("test-ns").UpdateStatus(context.TODO(), &pod, metav1.UpdateOptions{})
+						if err != nil {
+							t.Errorf("updating pod: %v", err)
+						}
+					}
+				}()
+			}
+
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			_, err := NewPodDisruptor(
+				context.TODO(),
+				k,
+				PodSelectorSpec{
+					Namespace: "test-ns",
+					Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
+				},
+				PodDisruptorOptions{
+					MinReadyTargets: tc.minReady,
+					ReadyTimeout:    tc.timeout,
+				},
+			)
+			if tc.expectError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected error %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorReadyCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		readyPods       []string
+		notReadyPods    []string
+		options         PodDisruptorOptions
+		expectedTargets []string
+		expectError     error
+	}{
+		{
+			title:           "ready targets",
+			readyPods:       []string{"pod-1", "pod-2"},
+			options:         PodDisruptorOptions{RequireReady: true},
+			expectedTargets: []string{"pod-1", "pod-2"},
+			expectError:     nil,
+		},
+		{
+			title:           "not ready targets skipped",
+			readyPods:       []string{"pod-1"},
+			notReadyPods:    []string{"pod-2"},
+			options:         PodDisruptorOptions{SkipNotReady: true},
+			expectedTargets: []string{"pod-1"},
+			expectError:     nil,
+		},
+		{
+			title:           "not ready targets not checked",
+			readyPods:       []string{"pod-1"},
+			notReadyPods:    []string{"pod-2"},
+			options:         PodDisruptorOptions{},
+			expectedTargets: []string{"pod-1", "pod-2"},
+			expectError:     nil,
+		},
+		{
+			title:        "not ready targets required",
+			readyPods:    []string{"pod-1"},
+			notReadyPods: []string{"pod-2"},
+			options:      PodDisruptorOptions{RequireReady: true},
+			expectError:  ErrTargetsNotReady,
+		},
+		{
+			title:        "all targets skipped",
+			notReadyPods: []string{"pod-1"},
+			options:      PodDisruptorOptions{SkipNotReady: true},
+			expectError:  ErrSelectorNoPods,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+			pods := []corev1.Pod{}
+			for _, name := range tc.readyPods {
+				pods = append(pods, builders.NewPodBuilder(name).
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithCondition(corev1.PodReady, corev1.ConditionTrue, time.Now()).
+					Build())
+			}
+			for _, name := range tc.notReadyPods {
+				pods = append(pods, builders.NewPodBuilder(name).
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithCondition(corev1.PodReady, corev1.ConditionFalse, time.Now()).
+					Build())
+			}
+
+			for i := range pods {
+				_, err := client.CoreV1().Pods("test-ns").Create(context.TODO(), &pods[i], metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("creating pod: %v", err)
+				}
+			}
+
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			d, err := NewPodDisruptor(
+				context.TODO(),
+				k,
+				PodSelectorSpec{
+					Namespace: "test-ns",
+					Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
+				},
+				tc.options,
+			)
+			if err != nil {
+				if !errors.Is(err, tc.expectError) {
+					t.Fatalf("expected error %v got %v", tc.expectError, err)
+				}
+				return
+			}
+
+			targets, err := d.Targets(context.TODO())
+			if tc.expectError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected error %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			sort.Strings(targets)
+			if diff := cmp.Diff(tc.expectedTargets, targets); diff != "" {
+				t.Errorf("expected targets does not match returned targets:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorWaitAgentReady(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		agentRunning bool
+		expectError  bool
+	}{
+		{
+			title:        "agent starts running",
+			agentRunning: true,
+			expectError:  false,
+		},
+		{
+			title:        "agent keeps waiting",
+			agentRunning: false,
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+
+			// the agent was injected in this pod, but its container is still starting
+			pod := builders.NewPodBuilder("pod-with-agent").
+				WithNamespace("test-ns").
+				WithLabel("app", "test").
+				Build()
+			pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+				{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "xk6-agent"}},
+			}
+			pod.Status.EphemeralContainerStatuses = agentStatus(false)
+
+			// the agent was not injected in this pod, so it is not waited for
+			other := builders.NewPodBuilder("pod-without-agent").
+				WithNamespace("test-ns").
+				WithLabel("app", "test").
+				Build()
+
+			for _, p := range []corev1.Pod{pod, other} {
+				_, err := client.CoreV1().Pods("test-ns").Create(context.TODO(), &p, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("creating pod: %v", err)
+				}
+			}
+
+			if tc.agentRunning {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					pod.Status.EphemeralContainerStatuses = agentStatus(true)
+					_, err := client.CoreV1().Pods("test-ns").UpdateStatus(context.TODO(), &pod, metav1.UpdateOptions{})
+					if err != nil {
+						t.Errorf("updating pod: %v", err)
+					}
+				}()
+			}
+
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			_, err := NewPodDisruptor(
+				context.TODO(),
+				k,
+				PodSelectorSpec{
+					Namespace: "test-ns",
+					Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
+				},
+				PodDisruptorOptions{
+					WaitAgentReady: true,
+					InjectTimeout:  time.Second,
+				},
+			)
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+		})
+	}
+}
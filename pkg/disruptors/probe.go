@@ -0,0 +1,52 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Prober defines the method for checking the connectivity to the targets of a disruptor
+type Prober interface {
+	// Probe checks the given port accepts connections in all the targets. The returned error describes
+	// all the targets that are not reachable.
+	Probe(ctx context.Context, port intstr.IntOrString) error
+}
+
+// probeTargets probes a port in all the targets. Contrary to other visits, the failure in one target
+// does not stop the probing of the others, so all the unreachable targets are reported.
+func probeTargets(
+	ctx context.Context,
+	helper helpers.PodHelper,
+	options PodAgentVisitorOptions,
+	targets []corev1.Pod,
+	port intstr.IntOrString,
+) error {
+	visitor := NewPodAgentVisitor(helper, options, PodProbeCommand{port: port})
+
+	var (
+		mtx  sync.Mutex
+		errs []error
+	)
+
+	collector := PodVisitorFunc(func(ctx context.Context, pod corev1.Pod) error {
+		if err := visitor.Visit(ctx, pod); err != nil {
+			mtx.Lock()
+			errs = append(errs, err)
+			mtx.Unlock()
+		}
+
+		return nil
+	})
+
+	if err := NewPodController(targets).Visit(ctx, collector); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
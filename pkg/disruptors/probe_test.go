@@ -0,0 +1,165 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/command"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+func Test_PodProbeCommandGenerator(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		target      corev1.Pod
+		port        intstr.IntOrString
+		expectedCmd string
+		expectError bool
+	}{
+		{
+			title:       "port number",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			port:        intstr.FromInt32(80),
+			expectedCmd: "xk6-disruptor-agent probe -t 80 --upstream-host 192.0.2.6",
+			expectError: false,
+		},
+		{
+			title:       "port name",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			port:        intstr.FromString("http"),
+			expectedCmd: "xk6-disruptor-agent probe -t 80 --upstream-host 192.0.2.6",
+			expectError: false,
+		},
+		{
+			title:       "container port not found",
+			target:      buildPodWithPort("my-app-pod", "http", 80),
+			port:        intstr.FromInt32(8080),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmds, err := PodProbeCommand{port: tc.port}.Commands(tc.target)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error : %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			if !command.AssertCmdEquals(strings.Join(cmds.Exec, " "), tc.expectedCmd) {
+				t.Errorf("expected command: %s got: %s", tc.expectedCmd, cmds.Exec)
+			}
+
+			if cmds.Cleanup != nil {
+				t.Errorf("probe should not have a cleanup command")
+			}
+		})
+	}
+}
+
+func Test_ProbeTargets(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		targets     []string
+		failing     []string
+		expectError bool
+	}{
+		{
+			title:       "all targets reachable",
+			targets:     []string{"pod-1", "pod-2", "pod-3"},
+			failing:     []string{},
+			expectError: false,
+		},
+		{
+			title:       "some targets not reachable",
+			targets:     []string{"pod-1", "pod-2", "pod-3"},
+			failing:     []string{"pod-1", "pod-3"},
+			expectError: true,
+		},
+		{
+			title:       "all targets not reachable",
+			targets:     []string{"pod-1", "pod-2"},
+			failing:     []string{"pod-1", "pod-2"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			targets := []corev1.Pod{}
+			for _, name := range tc.targets {
+				pod := buildPodWithPort(name, "http", 80)
+				injectAgent(&pod)
+				targets = append(targets, pod)
+			}
+
+			client := fake.NewSimpleClientset()
+			for i := range targets {
+				_ = client.Tracker().Add(&targets[i])
+			}
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			executor := k.GetFakeProcessExecutor()
+			for _, name := range tc.failing {
+				executor.SetPodResult(name, nil, []byte("port 80 is not reachable"), errors.New("exit status 1"))
+			}
+
+			err := probeTargets(
+				context.TODO(),
+				k.PodHelper("test-ns"),
+				PodAgentVisitorOptions{Timeout: -1},
+				targets,
+				intstr.FromInt32(80),
+			)
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error : %v", err)
+			}
+
+			// all targets must be probed even if some fail
+			if len(executor.GetHistory()) != len(tc.targets) {
+				t.Fatalf("expected %d targets probed, got %d", len(tc.targets), len(executor.GetHistory()))
+			}
+
+			// the error must report all the failing targets, and only them
+			for _, name := range tc.targets {
+				reported := err != nil && strings.Contains(err.Error(), `"`+name+`"`)
+				failing := false
+				for _, f := range tc.failing {
+					failing = failing || f == name
+				}
+
+				if reported != failing {
+					t.Errorf("target %q: expected reported as failing %t got %t", name, failing, reported)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ProcessFaultInjector defines the methods for pausing the processes of the disruptor's targets
+type ProcessFaultInjector interface {
+	// InjectProcessFaults pauses the processes of the disruptor's targets for the specified duration, and
+	// resumes them afterwards
+	InjectProcessFaults(
+		ctx context.Context,
+		fault ProcessFault,
+		duration time.Duration,
+		options ProcessDisruptionOptions,
+	) error
+}
+
+// DefaultProcessSignal is the signal sent for pausing the processes if none is specified
+const DefaultProcessSignal = "STOP"
+
+// ProcessFault specifies the processes to be paused and how. The processes are resumed with SIGCONT once the
+// fault ends. The pod must share its process namespace for the agent to see the processes of other containers.
+type ProcessFault struct {
+	// signal that pauses the processes, either "STOP" or "TSTP". Defaults to "STOP"
+	Signal string `js:"signal"`
+	// name of the executable of the processes to pause. If empty, all the processes in the pod are paused
+	Process string `js:"process"`
+}
+
+// ProcessDisruptionOptions defines options for the injection of process faults in a target pod
+type ProcessDisruptionOptions struct{}
+
+// validate checks the fault's attributes are consistent
+func (f ProcessFault) validate() error {
+	switch strings.ToUpper(f.Signal) {
+	case "", "STOP", "TSTP":
+	default:
+		return fmt.Errorf("invalid signal %q: must be either STOP or TSTP", f.Signal)
+	}
+
+	if strings.ContainsAny(f.Process, " \t\n\x00") {
+		return fmt.Errorf("invalid process name %q: must not contain blanks", f.Process)
+	}
+
+	return nil
+}
+
+func buildProcessFaultCmd(fault ProcessFault, duration time.Duration) []string {
+	signal := strings.ToUpper(fault.Signal)
+	if signal == "" {
+		signal = DefaultProcessSignal
+	}
+
+	cmd := []string{
+		"xk6-disruptor-agent",
+		"process",
+		"-d", utils.DurationSeconds(duration),
+		"-s", signal,
+	}
+
+	if fault.Process != "" {
+		cmd = append(cmd, "-n", fault.Process)
+	}
+
+	return cmd
+}
+
+// PodProcessFaultCommand implements the PodVisitCommands interface for injecting ProcessFaults in a Pod
+type PodProcessFaultCommand struct {
+	fault    ProcessFault
+	duration time.Duration
+	options  ProcessDisruptionOptions
+}
+
+// Commands return the command for injecting a ProcessFault in a Pod
+func (c PodProcessFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
+	// without a shared process namespace the agent can only see its own processes
+	if pod.Spec.ShareProcessNamespace == nil || !*pod.Spec.ShareProcessNamespace {
+		return VisitCommands{}, fmt.Errorf(
+			"fault cannot be injected because pod %q does not share its process namespace",
+			pod.Name,
+		)
+	}
+
+	return VisitCommands{
+		Exec:    buildProcessFaultCmd(c.fault, c.duration),
+		Cleanup: buildCleanupCmd(),
+	}, nil
+}
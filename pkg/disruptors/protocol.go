@@ -2,9 +2,15 @@ package disruptors
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+	"golang.org/x/net/http/httpguts"
 )
 
 // ProtocolFaultInjector defines the methods for injecting protocol faults
@@ -17,40 +23,647 @@ type ProtocolFaultInjector interface {
 	InjectGrpcFaults(ctx context.Context, fault GrpcFault, duration time.Duration, options GrpcDisruptionOptions) error
 }
 
+// AsyncProtocolFaultInjector defines the methods for injecting protocol faults without waiting for them to end
+type AsyncProtocolFaultInjector interface {
+	// InjectHTTPFaultsAsync injects faults in the HTTP requests sent to the disruptor's targets for the specified
+	// duration. Errors setting up the faults are returned immediately. Otherwise, it returns without waiting
+	// for the faults to end and the returned channel delivers the result of the fault injection once they end.
+	InjectHTTPFaultsAsync(
+		ctx context.Context,
+		fault HTTPFault,
+		duration time.Duration,
+		options HTTPDisruptionOptions,
+	) (<-chan error, error)
+}
+
+// GroupProtocolFaultInjector defines the methods for injecting a different protocol fault in each group of targets
+type GroupProtocolFaultInjector interface {
+	// InjectHTTPFaultsByGroup injects a different fault in the HTTP requests sent to each group of the disruptor's
+	// targets for the specified duration. The faults are indexed by the label selector of their group (e.g.
+	// "track=canary"), which selects a subset of the targets. Targets not matching any group are not disrupted.
+	InjectHTTPFaultsByGroup(
+		ctx context.Context,
+		faults map[string]HTTPFault,
+		duration time.Duration,
+		options HTTPDisruptionOptions,
+	) error
+}
+
+// PortFaultInjector defines the methods for injecting faults in multiple ports simultaneously
+type PortFaultInjector interface {
+	// InjectPortFaults injects faults simultaneously in the requests sent to multiple ports of the disruptor's
+	// targets for the specified duration
+	InjectPortFaults(ctx context.Context, faults []PortFault, duration time.Duration) error
+}
+
+// Default ports used by the agent's proxy for listening to each protocol when the options of a fault do not
+// specify a proxy port. If the default port is used by the target pod, the next free port is used instead.
+var (
+	DefaultHTTPProxyPort uint = 8080 //nolint:gochecknoglobals
+	DefaultGrpcProxyPort uint = 3000 //nolint:gochecknoglobals
+)
+
+// Protocols supported by a PortFault
+const (
+	ProtocolHTTP = "http"
+	ProtocolGrpc = "grpc"
+)
+
+// PortFault specifies a fault to be injected in the requests sent to a port using a given protocol
+type PortFault struct {
+	// port the fault will be applied to
+	Port intstr.IntOrString `js:"port"`
+	// Protocol of the requests sent to the port. Either "http" or "grpc"
+	Protocol string `js:"protocol"`
+	// Fault injected if the protocol is "http". Its port is ignored.
+	HTTPFault HTTPFault
+	// Fault injected if the protocol is "grpc". Its port is ignored.
+	GrpcFault GrpcFault
+	// Port used by the agent for listening. Each PortFault must use a different proxy port.
+	// Defaults to the default proxy port of the protocol, skipping the ports used by the target pod.
+	ProxyPort uint `js:"proxyPort"`
+}
+
 // HTTPDisruptionOptions defines options for the injection of HTTP faults in a target pod
 type HTTPDisruptionOptions struct {
-	// Port used by the agent for listening
+	// Port used by the agent for listening. Defaults to DefaultHTTPProxyPort.
 	ProxyPort uint `js:"proxyPort"`
+	// Maximum time given to in-flight requests to complete when the disruption ends.
+	// If not set, the agent's default is used.
+	StopGracePeriod time.Duration `js:"stopGracePeriod"`
+	// Base seed for the random selection of delays and errors, for reproducible disruptions. The seed of each
+	// target is derived from this seed and the target's name. Zero means a random seed.
+	Seed int64 `js:"seed"`
+	// Size in bytes of the buffers used by the agent's proxy for copying data between the client and the target.
+	// Larger buffers (e.g. 256KiB to 1MiB) improve the throughput of large payloads at the cost of memory per
+	// connection. Must be in the range [MinProxyBufferSize, MaxProxyBufferSize]. Zero means the agent's default
+	// of 32KiB.
+	BufferSize uint `js:"bufferSize"`
+	// Time at the start of the disruption during which requests are forwarded without faults, for example to let
+	// clients warm up their connection pools. It must be shorter than the duration of the disruption.
+	GracePeriod time.Duration `js:"gracePeriod"`
+	// Accept and forward requests using HTTP/2 over cleartext (h2c) instead of HTTP/1.1, for targets that only
+	// speak h2c. Cannot be combined with BufferSize.
+	HTTP2 bool `js:"http2"`
+	// Direction of the disrupted traffic: DirectionIngress, for the requests the targets receive in the fault's port,
+	// or DirectionEgress, for the requests they send to the fault's port. Defaults to DirectionIngress.
+	Direction string `js:"direction"`
 }
 
 // GrpcDisruptionOptions defines options for the injection of grpc faults in a target pod
 type GrpcDisruptionOptions struct {
-	// Port used by the agent for listening
+	// Port used by the agent for listening. Defaults to DefaultGrpcProxyPort.
 	ProxyPort uint `js:"proxyPort"`
+	// Maximum time given to in-flight requests to complete when the disruption ends.
+	// If not set, the agent's default is used.
+	StopGracePeriod time.Duration `js:"stopGracePeriod"`
+	// Base seed for the random selection of delays and errors, for reproducible disruptions. The seed of each
+	// target is derived from this seed and the target's name. Zero means a random seed.
+	Seed int64 `js:"seed"`
+	// Size in bytes of the buffers used by the agent's proxy for copying data between the client and the target.
+	// Larger buffers (e.g. 256KiB to 1MiB) improve the throughput of large payloads at the cost of memory per
+	// connection. Must be in the range [MinProxyBufferSize, MaxProxyBufferSize]. Zero means the agent's default
+	// of 32KiB.
+	BufferSize uint `js:"bufferSize"`
+	// Use TLS in the connection from the agent's proxy to the target
+	UpstreamTLS bool `js:"upstreamTLS"`
+	// Do not verify the certificate of the target when using TLS, for example if it is self-signed.
+	// It must only be used in test environments.
+	UpstreamInsecureSkipVerify bool `js:"upstreamInsecureSkipVerify"`
+	// Time at the start of the disruption during which requests are forwarded without faults, for example to let
+	// clients warm up their connection pools. It must be shorter than the duration of the disruption.
+	GracePeriod time.Duration `js:"gracePeriod"`
+	// Direction of the disrupted traffic: DirectionIngress, for the requests the targets receive in the fault's port,
+	// or DirectionEgress, for the requests they send to the fault's port. Defaults to DirectionIngress.
+	Direction string `js:"direction"`
+}
+
+// Range of valid sizes of the buffers used by the agent's proxy
+const (
+	MinProxyBufferSize = 4 * 1024
+	MaxProxyBufferSize = 16 * 1024 * 1024
+)
+
+// validateBufferSize checks the size of the proxy buffers is zero (default) or in the valid range
+func validateBufferSize(size uint) error {
+	if size != 0 && (size < MinProxyBufferSize || size > MaxProxyBufferSize) {
+		return fmt.Errorf(
+			"buffer size must be in the range [%d, %d] bytes: %d",
+			MinProxyBufferSize,
+			MaxProxyBufferSize,
+			size,
+		)
+	}
+
+	return nil
+}
+
+// validateGracePeriod checks the grace period is a positive duration shorter than the duration of the disruption
+func validateGracePeriod(gracePeriod time.Duration, duration time.Duration) error {
+	if gracePeriod < 0 {
+		return fmt.Errorf("grace period must be a positive duration: %s", gracePeriod)
+	}
+
+	if gracePeriod > 0 && gracePeriod >= duration {
+		return fmt.Errorf("grace period (%s) must be shorter than the duration (%s)", gracePeriod, duration)
+	}
+
+	return nil
+}
+
+// validateStopGracePeriod checks the stop grace period is shorter than the time the agent is given to complete the
+// fault command once the duration has elapsed, so the command is not stopped while the proxy drains the requests.
+// A zero exec timeout means no timeout.
+func validateStopGracePeriod(stopGracePeriod time.Duration, execTimeout time.Duration) error {
+	if execTimeout > 0 && stopGracePeriod >= execTimeout {
+		return fmt.Errorf(
+			"stop grace period (%s) must be shorter than the exec timeout (%s)",
+			stopGracePeriod,
+			execTimeout,
+		)
+	}
+
+	return nil
+}
+
+// Directions of the traffic disrupted by HTTP and gRPC faults
+const (
+	DirectionIngress = "ingress"
+	DirectionEgress  = "egress"
+)
+
+// validateDirection checks the direction is empty (ingress) or one of the supported directions
+func validateDirection(direction string) error {
+	switch direction {
+	case "", DirectionIngress, DirectionEgress:
+		return nil
+	default:
+		return fmt.Errorf("direction must be %q or %q: %q", DirectionIngress, DirectionEgress, direction)
+	}
+}
+
+// validate checks the options are consistent with the duration of the disruption and the time the agent is given
+// to complete the fault command once the duration has elapsed (zero means no timeout)
+func (o HTTPDisruptionOptions) validate(duration time.Duration, execTimeout time.Duration) error {
+	if o.HTTP2 && o.BufferSize != 0 {
+		return fmt.Errorf("buffer size cannot be combined with http2")
+	}
+
+	if err := validateDirection(o.Direction); err != nil {
+		return err
+	}
+
+	if err := validateGracePeriod(o.GracePeriod, duration); err != nil {
+		return err
+	}
+
+	if err := validateStopGracePeriod(o.StopGracePeriod, execTimeout); err != nil {
+		return err
+	}
+
+	return validateBufferSize(o.BufferSize)
+}
+
+// validate checks the options are consistent with the duration of the disruption and the time the agent is given
+// to complete the fault command once the duration has elapsed (zero means no timeout)
+func (o GrpcDisruptionOptions) validate(duration time.Duration, execTimeout time.Duration) error {
+	if o.UpstreamInsecureSkipVerify && !o.UpstreamTLS {
+		return fmt.Errorf("skipping the verification of the upstream certificate requires TLS")
+	}
+
+	if err := validateDirection(o.Direction); err != nil {
+		return err
+	}
+
+	if err := validateGracePeriod(o.GracePeriod, duration); err != nil {
+		return err
+	}
+
+	if err := validateStopGracePeriod(o.StopGracePeriod, execTimeout); err != nil {
+		return err
+	}
+
+	return validateBufferSize(o.BufferSize)
 }
 
 // HTTPFault specifies a fault to be injected in http requests
 type HTTPFault struct {
 	// port the disruptions will be applied to
 	Port intstr.IntOrString
+	// ports the disruptions will be applied to simultaneously, instead of Port. The agent listens for the requests
+	// sent to each port on its own proxy port, starting from DefaultHTTPProxyPort.
+	Ports []intstr.IntOrString `js:"ports"`
+	// name of the container that must expose the port, for example a sidecar. If empty, the port can be exposed
+	// by any container of the target.
+	Container string `js:"container"`
 	// Average delay introduced to requests
 	AverageDelay time.Duration `js:"averageDelay"`
-	// Variation in the delay (with respect of the average delay)
+	// Variation in the delay (with respect of the average delay). If no average delay is specified, requests are
+	// delayed randomly up to the variation.
 	DelayVariation time.Duration `js:"delayVariation"`
 	// Fraction (in the range 0.0 to 1.0) of requests that will return an error
 	ErrorRate float32 `js:"errorRate"`
 	// Error code to be returned by requests selected in the error rate
 	ErrorCode uint `js:"errorCode"`
+	// Error codes returned by requests selected in the error rate, instead of ErrorCode, each one with a
+	// probability proportional to its weight. The weights do not need to add up to 1.
+	ErrorCodes []WeightedErrorCode `js:"errorCodes"`
 	// Body to be returned when an error is injected
 	ErrorBody string `js:"errorBody"`
+	// Number of bytes of the response body sent to the requests selected in the error rate before their connection
+	// is closed, simulating a server that drops the connection mid-body. The requests are forwarded to the
+	// target instead of returning an error code, so it cannot be combined with ErrorCode, ErrorCodes or Responses.
+	TruncateAfterBytes uint `js:"truncateAfterBytes"`
 	// Comma-separated list of url paths to be excluded from disruptions
 	Exclude string
+	// Regular expression matching the url paths to be excluded from disruptions, in addition to Exclude.
+	// The expression must match the whole path (e.g. "/internal/.*").
+	ExcludeRegex string `js:"excludeRegex"`
+	// Maximum rate of requests per second. Requests above this rate are rejected with RateLimitCode
+	RateLimit float32 `js:"rateLimit"`
+	// Status code returned to requests rejected by the rate limit. Defaults to 429 (Too Many Requests)
+	RateLimitCode uint `js:"rateLimitCode"`
+	// Number of bytes of the response body sent in each DripInterval. Sending a body of N bytes takes
+	// N/DripBytesPerInterval intervals (plus any delay), which may exceed the client's request timeout
+	DripBytesPerInterval uint `js:"dripBytesPerInterval"`
+	// Interval between the chunks of the response body
+	DripInterval time.Duration `js:"dripInterval"`
+	// Maximum number of bytes per second read from the request body sent by the client and from the response
+	// body sent by the upstream, simulating a slow consumer. Unlike drip, which delays the response sent to the
+	// client, reading slowly applies backpressure to the senders as the socket buffers fill up. Zero means
+	// no limit.
+	ReadRateBytesPerSec uint `js:"readRateBytesPerSec"`
+	// Header whose value is hashed for selecting the requests that return an error, instead of random sampling.
+	// Requests with the same value in this header are consistently selected (or not).
+	HashHeader string `js:"hashHeader"`
+	// Responses returned in turns to the requests selected by the error rate, instead of ErrorCode and ErrorBody
+	Responses []CannedResponse `js:"responses"`
+	// Windows the fault steps through, each one setting the delay and errors injected during its duration.
+	// The durations of the windows must add up to the duration of the fault.
+	Windows []HTTPFaultWindow `js:"windows"`
+	// Rules that set the error rate and error code of the requests by the prefix of their path, instead of
+	// ErrorRate and ErrorCode. Requests whose path does not match any rule do not return errors.
+	Rules []HTTPFaultRule `js:"rules"`
+	// Mix of errors and delays injected in the requests, instead of ErrorRate. Each request selected for a fault
+	// either returns an error or is delayed, but not both.
+	FaultMix FaultMix `js:"faultMix"`
+	// Headers that requests must have, with the given values, for the fault to be applied. Requests that do not
+	// match all of them are forwarded without disruption. Empty means the fault is applied to all requests.
+	Headers map[string]string `js:"headers"`
+}
+
+// WeightedErrorCode defines an error code returned with a probability proportional to its weight
+type WeightedErrorCode struct {
+	// Status code returned
+	Code uint `js:"code"`
+	// Weight of the code, relative to the weights of the other codes
+	Weight float32 `js:"weight"`
+}
+
+// FaultMix defines a fault that either returns an error or delays each of the requests selected for a fault
+type FaultMix struct {
+	// Fraction (in the range 0.0 to 1.0) of the requests selected for a fault. Zero means no mix.
+	Rate float32 `js:"rate"`
+	// Fraction (in the range 0.0 to 1.0) of the requests selected for a fault that return an error.
+	// The rest of them are delayed.
+	ErrorShare float32 `js:"errorShare"`
+}
+
+// HTTPFaultRule defines the errors returned to the requests whose path starts with a prefix
+type HTTPFaultRule struct {
+	// Prefix of the path of the requests the rule applies to (e.g. "/api/")
+	PathPrefix string `js:"pathPrefix"`
+	// Fraction (in the range 0.0 to 1.0) of the requests that will return an error
+	ErrorRate float32 `js:"errorRate"`
+	// Error code to be returned by requests selected in the error rate
+	ErrorCode uint `js:"errorCode"`
+}
+
+// HTTPFaultWindow defines the intensity of a HTTP fault during a window of the fault's duration
+type HTTPFaultWindow struct {
+	// Duration of the window
+	Duration time.Duration `js:"duration"`
+	// Average delay introduced to requests during the window
+	AverageDelay time.Duration `js:"averageDelay"`
+	// Variation in the delay (with respect of the average delay)
+	DelayVariation time.Duration `js:"delayVariation"`
+	// Fraction (in the range 0.0 to 1.0) of requests that will return an error during the window
+	ErrorRate float32 `js:"errorRate"`
+	// Error code to be returned by requests selected in the error rate
+	ErrorCode uint `js:"errorCode"`
+}
+
+// CannedResponse defines a response returned to the requests selected for returning an error
+type CannedResponse struct {
+	// Status code of the response
+	Code uint `js:"code"`
+	// Body of the response
+	Body string `js:"body"`
+	// Headers of the response
+	Headers map[string]string `js:"headers"`
+}
+
+// DefaultRateLimitCode defines the default status code returned to requests rejected by the rate limit
+const DefaultRateLimitCode = 429
+
+// validate checks the fault's attributes are consistent
+func (f HTTPFault) validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be in the range [0.0, 1.0]: %f", f.ErrorRate)
+	}
+
+	if f.AverageDelay < 0 {
+		return fmt.Errorf("average delay must be a positive duration: %s", f.AverageDelay)
+	}
+
+	if f.DelayVariation < 0 {
+		return fmt.Errorf("delay variation must be a positive duration: %s", f.DelayVariation)
+	}
+
+	// a variation without average delay injects only jitter
+	if f.AverageDelay > 0 && f.DelayVariation > f.AverageDelay {
+		return fmt.Errorf(
+			"delay variation (%s) must not be larger than average delay (%s)",
+			f.DelayVariation,
+			f.AverageDelay,
+		)
+	}
+
+	if f.ErrorRate > 0 && f.ErrorCode == 0 && len(f.ErrorCodes) == 0 && len(f.Responses) == 0 &&
+		f.TruncateAfterBytes == 0 {
+		return fmt.Errorf(
+			"error code, error codes, responses or truncate after bytes must be specified when error rate is set",
+		)
+	}
+
+	if err := f.validateErrorCodes(); err != nil {
+		return err
+	}
+
+	if err := f.validateTruncate(); err != nil {
+		return err
+	}
+
+	if err := f.validatePorts(); err != nil {
+		return err
+	}
+
+	if f.RateLimit < 0 {
+		return fmt.Errorf("rate limit must be a positive number of requests per second")
+	}
+
+	if f.RateLimitCode != 0 && (f.RateLimitCode < 400 || f.RateLimitCode > 499) {
+		return fmt.Errorf("rate limit code must be a 4xx status code: %d", f.RateLimitCode)
+	}
+
+	if f.DripInterval < 0 {
+		return fmt.Errorf("drip interval must be a positive duration")
+	}
+
+	if (f.DripBytesPerInterval > 0) != (f.DripInterval > 0) {
+		return fmt.Errorf("drip bytes per interval and drip interval must both be specified")
+	}
+
+	if err := validateArgValue("error body", f.ErrorBody); err != nil {
+		return err
+	}
+
+	if err := validateExclude(f.Exclude); err != nil {
+		return err
+	}
+
+	if err := validateArgValue("exclude regex", f.ExcludeRegex); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(f.ExcludeRegex); err != nil {
+		return fmt.Errorf("invalid exclude regex %q: %w", f.ExcludeRegex, err)
+	}
+
+	if f.HashHeader != "" && !httpguts.ValidHeaderFieldName(f.HashHeader) {
+		return fmt.Errorf("invalid hash header name %q", f.HashHeader)
+	}
+
+	for header, value := range f.Headers {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return fmt.Errorf("invalid match header name %q", header)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for match header %q", header)
+		}
+	}
+
+	if len(f.Responses) > 0 && f.ErrorRate == 0 && f.FaultMix.Rate == 0 {
+		return fmt.Errorf("responses require an error rate")
+	}
+
+	if err := f.validateFaultMix(); err != nil {
+		return err
+	}
+
+	for i, response := range f.Responses {
+		if err := response.validate(); err != nil {
+			return fmt.Errorf("invalid response %d: %w", i, err)
+		}
+	}
+
+	for i, window := range f.Windows {
+		if err := window.validate(); err != nil {
+			return fmt.Errorf("invalid window %d: %w", i, err)
+		}
+	}
+
+	if len(f.Rules) > 0 && (f.ErrorRate > 0 || len(f.ErrorCodes) > 0 || len(f.Responses) > 0) {
+		return fmt.Errorf("rules cannot be combined with error rate, error codes or responses")
+	}
+
+	for i, rule := range f.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid rule %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validatePorts checks the ports are not combined with Port and are not repeated
+func (f HTTPFault) validatePorts() error {
+	if len(f.Ports) == 0 {
+		return nil
+	}
+
+	if !f.Port.IsNull() && !f.Port.IsZero() {
+		return fmt.Errorf("port and ports cannot both be specified")
+	}
+
+	ports := map[intstr.IntOrString]bool{}
+	for _, port := range f.Ports {
+		if port.IsNull() || port.IsZero() {
+			return fmt.Errorf("ports cannot be empty")
+		}
+
+		if ports[port] {
+			return fmt.Errorf("port %s is specified more than once", port.Str())
+		}
+		ports[port] = true
+	}
+
+	return nil
+}
+
+// validateTruncate checks the truncation of the responses is not combined with the attributes that define the
+// errors returned to the requests selected in the error rate
+func (f HTTPFault) validateTruncate() error {
+	if f.TruncateAfterBytes == 0 {
+		return nil
+	}
+
+	if f.ErrorCode != 0 || len(f.ErrorCodes) > 0 || len(f.Responses) > 0 || len(f.Rules) > 0 || len(f.Windows) > 0 {
+		return fmt.Errorf("truncate after bytes cannot be combined with error codes, responses, rules or windows")
+	}
+
+	if f.ErrorRate == 0 {
+		return fmt.Errorf("truncate after bytes requires an error rate")
+	}
+
+	return nil
+}
+
+// validateFaultMix checks the fault mix, if any, is consistent with the other attributes of the fault
+func (f HTTPFault) validateFaultMix() error {
+	mix := f.FaultMix
+	if mix.Rate < 0 || mix.Rate > 1 {
+		return fmt.Errorf("fault mix rate must be in the range [0.0, 1.0]: %f", mix.Rate)
+	}
+
+	if mix.ErrorShare < 0 || mix.ErrorShare > 1 {
+		return fmt.Errorf("fault mix error share must be in the range [0.0, 1.0]: %f", mix.ErrorShare)
+	}
+
+	if mix.Rate == 0 {
+		return nil
+	}
+
+	if f.ErrorRate > 0 || len(f.Windows) > 0 || len(f.Rules) > 0 || f.HashHeader != "" {
+		return fmt.Errorf("fault mix cannot be combined with error rate, windows, rules or hash header")
+	}
+
+	if mix.ErrorShare > 0 && f.ErrorCode == 0 && len(f.ErrorCodes) == 0 && len(f.Responses) == 0 {
+		return fmt.Errorf("error code, error codes or responses must be specified when fault mix error share is set")
+	}
+
+	if mix.ErrorShare < 1 && f.AverageDelay == 0 {
+		return fmt.Errorf("average delay must be specified when fault mix delays requests")
+	}
+
+	return nil
+}
+
+// validateErrorCodes checks the weighted error codes, if any, are valid and not combined with other errors
+func (f HTTPFault) validateErrorCodes() error {
+	if len(f.ErrorCodes) == 0 {
+		return nil
+	}
+
+	if f.ErrorCode != 0 || len(f.Responses) > 0 {
+		return fmt.Errorf("error codes cannot be combined with error code or responses")
+	}
+
+	if f.ErrorRate == 0 && f.FaultMix.Rate == 0 {
+		return fmt.Errorf("error codes require an error rate")
+	}
+
+	for _, code := range f.ErrorCodes {
+		if code.Code < 100 || code.Code > 599 {
+			return fmt.Errorf("error code must be a valid http status code: %d", code.Code)
+		}
+
+		if code.Weight <= 0 {
+			return fmt.Errorf("weight of error code %d must be positive: %f", code.Code, code.Weight)
+		}
+	}
+
+	return nil
+}
+
+// validateWindows checks the durations of the fault's windows, if any, add up to the duration of the fault
+func (f HTTPFault) validateWindows(duration time.Duration) error {
+	if len(f.Windows) == 0 {
+		return nil
+	}
+
+	total := time.Duration(0)
+	for _, window := range f.Windows {
+		total += window.Duration
+	}
+
+	if total != duration {
+		return fmt.Errorf("windows add up to %s but the duration of the fault is %s", total, duration)
+	}
+
+	return nil
+}
+
+// validate checks the window's attributes are consistent
+func (w HTTPFaultWindow) validate() error {
+	if w.Duration <= 0 {
+		return fmt.Errorf("duration must be a positive duration")
+	}
+
+	if w.DelayVariation > w.AverageDelay {
+		return fmt.Errorf("delay variation must be less than average delay")
+	}
+
+	if w.ErrorRate < 0 || w.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be in the range [0.0, 1.0]: %f", w.ErrorRate)
+	}
+
+	if w.ErrorRate > 0 && w.ErrorCode == 0 {
+		return fmt.Errorf("error code must be specified when error rate is set")
+	}
+
+	return nil
+}
+
+// validate checks the rule's attributes are consistent
+func (r HTTPFaultRule) validate() error {
+	if !strings.HasPrefix(r.PathPrefix, "/") {
+		return fmt.Errorf("path prefix must start with '/': %q", r.PathPrefix)
+	}
+
+	if r.ErrorRate < 0 || r.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be in the range [0.0, 1.0]: %f", r.ErrorRate)
+	}
+
+	if r.ErrorRate > 0 && r.ErrorCode == 0 {
+		return fmt.Errorf("error code must be specified when error rate is set")
+	}
+
+	return nil
+}
+
+// validate checks the response's attributes are valid
+func (r CannedResponse) validate() error {
+	if r.Code < 100 || r.Code > 599 {
+		return fmt.Errorf("code must be a valid http status code: %d", r.Code)
+	}
+
+	for header := range r.Headers {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return fmt.Errorf("invalid header name %q", header)
+		}
+	}
+
+	return nil
 }
 
 // GrpcFault specifies a fault to be injected in grpc requests
 type GrpcFault struct {
 	// port the disruptions will be applied to
 	Port intstr.IntOrString
+	// name of the container that must expose the port, for example a sidecar. If empty, the port can be exposed
+	// by any container of the target.
+	Container string `js:"container"`
 	// Average delay introduced to requests
 	AverageDelay time.Duration `js:"averageDelay"`
 	// Variation in the delay (with respect of the average delay)
@@ -61,6 +674,182 @@ type GrpcFault struct {
 	StatusCode int32 `js:"statusCode"`
 	// Status message to be returned in requests selected to return an error
 	StatusMessage string `js:"statusMessage"`
+	// Trailing metadata attached to the errors returned to requests selected to return an error. Keys must be
+	// valid gRPC metadata keys and cannot use the reserved 'grpc-' prefix. Values of keys without the '-bin'
+	// suffix must be printable ASCII.
+	Metadata map[string]string `js:"metadata"`
 	// List of grpc services to be excluded from disruptions
 	Exclude string `js:"exclude"`
+	// Authority (host and optional port) of the requests to be disrupted, as in their :authority header.
+	// Requests to other authorities are not disrupted. Empty means all.
+	MatchAuthority string `js:"matchAuthority"`
+}
+
+// validate checks the fault's attributes are consistent
+func (f GrpcFault) validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be in the range [0.0, 1.0]: %f", f.ErrorRate)
+	}
+
+	if f.AverageDelay < 0 {
+		return fmt.Errorf("average delay must be a positive duration: %s", f.AverageDelay)
+	}
+
+	if f.DelayVariation < 0 {
+		return fmt.Errorf("delay variation must be a positive duration: %s", f.DelayVariation)
+	}
+
+	if f.DelayVariation > f.AverageDelay {
+		return fmt.Errorf(
+			"delay variation (%s) must not be larger than average delay (%s)",
+			f.DelayVariation,
+			f.AverageDelay,
+		)
+	}
+
+	if f.ErrorRate > 0 && f.StatusCode == 0 {
+		return fmt.Errorf("status code must be specified when error rate is set")
+	}
+
+	if err := validateArgValue("status message", f.StatusMessage); err != nil {
+		return err
+	}
+
+	if len(f.Metadata) > 0 && f.ErrorRate == 0 {
+		return fmt.Errorf("metadata requires an error rate")
+	}
+
+	for key, value := range f.Metadata {
+		if err := validateMetadata(key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := validateExclude(f.Exclude); err != nil {
+		return err
+	}
+
+	if f.MatchAuthority != "" {
+		if err := validateAuthority(f.MatchAuthority); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateArgValue checks the value can be passed as a single argument of the agent's command. The command is
+// not interpreted by a shell, so spaces and quotes are kept as they are, but a NUL character would truncate it.
+func validateArgValue(name string, value string) error {
+	if strings.ContainsRune(value, 0) {
+		return fmt.Errorf("%s cannot contain NUL characters", name)
+	}
+
+	return nil
+}
+
+// validateExclude checks the elements of a comma-separated exclude list are parsed as such by the agent, which
+// reads the list as a CSV record. Therefore, elements cannot contain quotes or blanks.
+func validateExclude(exclude string) error {
+	if exclude == "" {
+		return nil
+	}
+
+	for _, element := range strings.Split(exclude, ",") {
+		if strings.ContainsAny(element, "\" \t\r\n\x00") {
+			return fmt.Errorf("invalid exclude element %q: cannot contain quotes or blanks", element)
+		}
+	}
+
+	return nil
+}
+
+// validateMetadata checks the key is a valid gRPC metadata key that is not reserved, and the value of a
+// non-binary key is printable ASCII
+func validateMetadata(key string, value string) error {
+	if key == "" {
+		return fmt.Errorf("metadata key cannot be empty")
+	}
+
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("invalid metadata key %q: must contain only lowercase letters, digits, '-', '_' or '.'", key)
+		}
+	}
+
+	if strings.HasPrefix(key, "grpc-") {
+		return fmt.Errorf("invalid metadata key %q: the 'grpc-' prefix is reserved", key)
+	}
+
+	if strings.HasSuffix(key, "-bin") {
+		return validateArgValue(fmt.Sprintf("value of metadata key %q", key), value)
+	}
+
+	for _, c := range value {
+		if c < 0x20 || c > 0x7E {
+			return fmt.Errorf("invalid value of metadata key %q: must contain only printable ASCII characters", key)
+		}
+	}
+
+	return nil
+}
+
+// validateAuthority checks the authority is a host with an optional port, without user info
+func validateAuthority(authority string) error {
+	u, err := url.Parse("//" + authority)
+	if err != nil || u.Host != authority || u.User != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid authority %q: must be a host with an optional port", authority)
+	}
+
+	if port := u.Port(); port != "" {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("invalid authority %q: invalid port %q", authority, port)
+		}
+	}
+
+	return nil
+}
+
+// validatePortFaults checks the faults are valid and can be applied simultaneously
+func validatePortFaults(faults []PortFault) ([]PortFault, error) {
+	if len(faults) == 0 {
+		return nil, fmt.Errorf("at least one port fault must be specified")
+	}
+
+	ports := map[string]bool{}
+	proxyPorts := map[uint]bool{}
+	validated := make([]PortFault, 0, len(faults))
+	for _, fault := range faults {
+		if fault.Port.IsNull() {
+			return nil, fmt.Errorf("port must be specified in port faults")
+		}
+
+		var err error
+		switch fault.Protocol {
+		case ProtocolHTTP:
+			err = fault.HTTPFault.validate()
+		case ProtocolGrpc:
+			err = fault.GrpcFault.validate()
+		default:
+			err = fmt.Errorf("unsupported protocol %q", fault.Protocol)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault for port %s: %w", fault.Port.Str(), err)
+		}
+
+		if ports[fault.Port.Str()] {
+			return nil, fmt.Errorf("port %s is specified in more than one fault", fault.Port.Str())
+		}
+		ports[fault.Port.Str()] = true
+
+		// default proxy ports are allocated in each target pod
+		if fault.ProxyPort != 0 && proxyPorts[fault.ProxyPort] {
+			return nil, fmt.Errorf("proxy port %d is used in more than one fault", fault.ProxyPort)
+		}
+		proxyPorts[fault.ProxyPort] = true
+
+		validated = append(validated, fault)
+	}
+
+	return validated, nil
 }
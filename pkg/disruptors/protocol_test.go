@@ -0,0 +1,936 @@
+package disruptors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+func Test_HTTPFaultValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		fault        HTTPFault
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			title:        "error rate larger than 1",
+			fault:        HTTPFault{ErrorRate: 100, ErrorCode: 500},
+			expectError:  true,
+			errorMessage: "error rate must be in the range [0.0, 1.0]: 100.000000",
+		},
+		{
+			title:        "negative error rate",
+			fault:        HTTPFault{ErrorRate: -0.1, ErrorCode: 500},
+			expectError:  true,
+			errorMessage: "error rate must be in the range [0.0, 1.0]: -0.100000",
+		},
+		{
+			title:        "negative average delay",
+			fault:        HTTPFault{AverageDelay: -time.Second},
+			expectError:  true,
+			errorMessage: "average delay must be a positive duration: -1s",
+		},
+		{
+			title:        "negative delay variation",
+			fault:        HTTPFault{AverageDelay: time.Second, DelayVariation: -time.Second},
+			expectError:  true,
+			errorMessage: "delay variation must be a positive duration: -1s",
+		},
+		{
+			title:       "delay variation equal to average delay",
+			fault:       HTTPFault{AverageDelay: time.Second, DelayVariation: time.Second},
+			expectError: false,
+		},
+		{
+			title:        "delay variation larger than average delay",
+			fault:        HTTPFault{AverageDelay: time.Second, DelayVariation: 2 * time.Second},
+			expectError:  true,
+			errorMessage: "delay variation (2s) must not be larger than average delay (1s)",
+		},
+		{
+			title:       "delay variation without average delay",
+			fault:       HTTPFault{DelayVariation: time.Millisecond},
+			expectError: false,
+		},
+		{
+			title:       "no rate limit",
+			fault:       HTTPFault{},
+			expectError: false,
+		},
+		{
+			title: "rate limit with default code",
+			fault: HTTPFault{
+				RateLimit: 10,
+			},
+			expectError: false,
+		},
+		{
+			title: "rate limit with 4xx code",
+			fault: HTTPFault{
+				RateLimit:     10,
+				RateLimitCode: 403,
+			},
+			expectError: false,
+		},
+		{
+			title: "negative rate limit",
+			fault: HTTPFault{
+				RateLimit: -1,
+			},
+			expectError: true,
+		},
+		{
+			title: "rate limit code is not 4xx",
+			fault: HTTPFault{
+				RateLimit:     10,
+				RateLimitCode: 500,
+			},
+			expectError: true,
+		},
+		{
+			title: "drip",
+			fault: HTTPFault{
+				DripBytesPerInterval: 10,
+				DripInterval:         100 * time.Millisecond,
+			},
+			expectError: false,
+		},
+		{
+			title: "canned responses",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				Responses: []CannedResponse{
+					{Code: 500, Body: "internal error"},
+					{Code: 503, Headers: map[string]string{"Retry-After": "1"}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			title: "canned responses without error rate",
+			fault: HTTPFault{
+				Responses: []CannedResponse{{Code: 500}},
+			},
+			expectError: true,
+		},
+		{
+			title: "canned response with invalid code",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				Responses: []CannedResponse{{Code: 500}, {Code: 0}},
+			},
+			expectError: true,
+		},
+		{
+			title: "canned response with invalid header",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				Responses: []CannedResponse{{Code: 500, Headers: map[string]string{"Retry After": "1"}}},
+			},
+			expectError: true,
+		},
+		{
+			title: "weighted error codes",
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCodes: []WeightedErrorCode{{Code: 500, Weight: 0.7}, {Code: 503, Weight: 0.3}},
+			},
+			expectError: false,
+		},
+		{
+			title: "weighted error codes without error rate",
+			fault: HTTPFault{
+				ErrorCodes: []WeightedErrorCode{{Code: 500, Weight: 1}},
+			},
+			expectError:  true,
+			errorMessage: "error codes require an error rate",
+		},
+		{
+			title: "weighted error codes with error code",
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCode:  500,
+				ErrorCodes: []WeightedErrorCode{{Code: 503, Weight: 1}},
+			},
+			expectError:  true,
+			errorMessage: "error codes cannot be combined with error code or responses",
+		},
+		{
+			title: "weighted error code with invalid code",
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCodes: []WeightedErrorCode{{Code: 500, Weight: 0.5}, {Code: 0, Weight: 0.5}},
+			},
+			expectError: true,
+		},
+		{
+			title: "weighted error code with zero weight",
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCodes: []WeightedErrorCode{{Code: 500, Weight: 1}, {Code: 503, Weight: 0}},
+			},
+			expectError:  true,
+			errorMessage: "weight of error code 503 must be positive: 0.000000",
+		},
+		{
+			title: "valid truncate after bytes",
+			fault: HTTPFault{
+				ErrorRate:          0.1,
+				TruncateAfterBytes: 100,
+			},
+			expectError: false,
+		},
+		{
+			title: "truncate after bytes with error code",
+			fault: HTTPFault{
+				ErrorRate:          0.1,
+				ErrorCode:          500,
+				TruncateAfterBytes: 100,
+			},
+			expectError:  true,
+			errorMessage: "truncate after bytes cannot be combined with error codes, responses, rules or windows",
+		},
+		{
+			title: "truncate after bytes with error codes",
+			fault: HTTPFault{
+				ErrorRate:          0.1,
+				ErrorCodes:         []WeightedErrorCode{{Code: 500, Weight: 1}},
+				TruncateAfterBytes: 100,
+			},
+			expectError:  true,
+			errorMessage: "truncate after bytes cannot be combined with error codes, responses, rules or windows",
+		},
+		{
+			title: "truncate after bytes without error rate",
+			fault: HTTPFault{
+				TruncateAfterBytes: 100,
+			},
+			expectError:  true,
+			errorMessage: "truncate after bytes requires an error rate",
+		},
+		{
+			title: "valid exclude regex",
+			fault: HTTPFault{
+				ErrorRate:    0.1,
+				ErrorCode:    500,
+				ExcludeRegex: "/internal/.*",
+			},
+			expectError: false,
+		},
+		{
+			title: "invalid exclude regex",
+			fault: HTTPFault{
+				ErrorRate:    0.1,
+				ErrorCode:    500,
+				ExcludeRegex: "/internal/(.*",
+			},
+			expectError:  true,
+			errorMessage: "invalid exclude regex \"/internal/(.*\": error parsing regexp: missing closing ): `/internal/(.*`",
+		},
+		{
+			title: "valid hash header",
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCode:  500,
+				HashHeader: "X-User-Id",
+			},
+			expectError: false,
+		},
+		{
+			title: "invalid hash header",
+			fault: HTTPFault{
+				ErrorRate:  0.1,
+				ErrorCode:  500,
+				HashHeader: "X User Id",
+			},
+			expectError: true,
+		},
+		{
+			title: "valid match headers",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X-Canary": "true"},
+			},
+			expectError: false,
+		},
+		{
+			title: "invalid match header name",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X Canary": "true"},
+			},
+			expectError: true,
+		},
+		{
+			title: "invalid match header value",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Headers:   map[string]string{"X-Canary": "true\n"},
+			},
+			expectError: true,
+		},
+		{
+			title: "windows",
+			fault: HTTPFault{
+				Windows: []HTTPFaultWindow{
+					{Duration: 5 * time.Minute, AverageDelay: 100 * time.Millisecond},
+					{Duration: 5 * time.Minute, ErrorRate: 0.5, ErrorCode: 500},
+				},
+			},
+			expectError: false,
+		},
+		{
+			title: "window without duration",
+			fault: HTTPFault{
+				Windows: []HTTPFaultWindow{{AverageDelay: 100 * time.Millisecond}},
+			},
+			expectError: true,
+		},
+		{
+			title: "window with error rate without error code",
+			fault: HTTPFault{
+				Windows: []HTTPFaultWindow{{Duration: time.Minute, ErrorRate: 0.5}},
+			},
+			expectError: true,
+		},
+		{
+			title: "window with invalid error rate",
+			fault: HTTPFault{
+				Windows: []HTTPFaultWindow{{Duration: time.Minute, ErrorRate: 1.5, ErrorCode: 500}},
+			},
+			expectError: true,
+		},
+		{
+			title: "window with variation larger than delay",
+			fault: HTTPFault{
+				Windows: []HTTPFaultWindow{
+					{Duration: time.Minute, AverageDelay: 10 * time.Millisecond, DelayVariation: 20 * time.Millisecond},
+				},
+			},
+			expectError: true,
+		},
+		{
+			title: "rules",
+			fault: HTTPFault{
+				Rules: []HTTPFaultRule{
+					{PathPrefix: "/api/", ErrorRate: 0.5, ErrorCode: 500},
+					{PathPrefix: "/health"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			title: "rules with error rate",
+			fault: HTTPFault{
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+				Rules:     []HTTPFaultRule{{PathPrefix: "/api/", ErrorRate: 0.5, ErrorCode: 500}},
+			},
+			expectError: true,
+		},
+		{
+			title: "rule with path prefix not starting with /",
+			fault: HTTPFault{
+				Rules: []HTTPFaultRule{{PathPrefix: "api", ErrorRate: 0.5, ErrorCode: 500}},
+			},
+			expectError: true,
+		},
+		{
+			title: "rule with error rate without error code",
+			fault: HTTPFault{
+				Rules: []HTTPFaultRule{{PathPrefix: "/api/", ErrorRate: 0.5}},
+			},
+			expectError: true,
+		},
+		{
+			title: "drip without interval",
+			fault: HTTPFault{
+				DripBytesPerInterval: 10,
+			},
+			expectError: true,
+		},
+		{
+			title: "drip interval without bytes",
+			fault: HTTPFault{
+				DripInterval: 100 * time.Millisecond,
+			},
+			expectError: true,
+		},
+		{
+			title: "negative drip interval",
+			fault: HTTPFault{
+				DripBytesPerInterval: 10,
+				DripInterval:         -1,
+			},
+			expectError: true,
+		},
+		{
+			title: "fault mix",
+			fault: HTTPFault{
+				ErrorCode:    500,
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 0.5, ErrorShare: 0.7},
+			},
+			expectError: false,
+		},
+		{
+			title: "fault mix with only errors",
+			fault: HTTPFault{
+				ErrorCode: 500,
+				FaultMix:  FaultMix{Rate: 0.5, ErrorShare: 1},
+			},
+			expectError: false,
+		},
+		{
+			title: "fault mix with canned responses",
+			fault: HTTPFault{
+				Responses: []CannedResponse{{Code: 503}},
+				FaultMix:  FaultMix{Rate: 0.5, ErrorShare: 1},
+			},
+			expectError: false,
+		},
+		{
+			title: "fault mix rate larger than 1",
+			fault: HTTPFault{
+				ErrorCode:    500,
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 1.5, ErrorShare: 0.7},
+			},
+			expectError: true,
+		},
+		{
+			title: "fault mix error share larger than 1",
+			fault: HTTPFault{
+				ErrorCode:    500,
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 0.5, ErrorShare: 1.1},
+			},
+			expectError: true,
+		},
+		{
+			title: "fault mix negative error share",
+			fault: HTTPFault{
+				ErrorCode:    500,
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 0.5, ErrorShare: -0.1},
+			},
+			expectError: true,
+		},
+		{
+			title: "fault mix with error rate",
+			fault: HTTPFault{
+				ErrorRate:    0.1,
+				ErrorCode:    500,
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 0.5, ErrorShare: 0.7},
+			},
+			expectError: true,
+		},
+		{
+			title: "fault mix without error code",
+			fault: HTTPFault{
+				AverageDelay: 100 * time.Millisecond,
+				FaultMix:     FaultMix{Rate: 0.5, ErrorShare: 0.7},
+			},
+			expectError: true,
+		},
+		{
+			title: "fault mix without average delay",
+			fault: HTTPFault{
+				ErrorCode: 500,
+				FaultMix:  FaultMix{Rate: 0.5, ErrorShare: 0.7},
+			},
+			expectError: true,
+		},
+		{
+			title:       "error body with spaces, commas and quotes",
+			fault:       HTTPFault{ErrorRate: 0.1, ErrorCode: 500, ErrorBody: `{"error": "not found, retry later"}`},
+			expectError: false,
+		},
+		{
+			title:        "error body with NUL character",
+			fault:        HTTPFault{ErrorRate: 0.1, ErrorCode: 500, ErrorBody: "not\x00found"},
+			expectError:  true,
+			errorMessage: "error body cannot contain NUL characters",
+		},
+		{
+			title:       "exclude list",
+			fault:       HTTPFault{Exclude: "/health,/metrics"},
+			expectError: false,
+		},
+		{
+			title:        "exclude element with spaces",
+			fault:        HTTPFault{Exclude: "/health,/my path"},
+			expectError:  true,
+			errorMessage: `invalid exclude element "/my path": cannot contain quotes or blanks`,
+		},
+		{
+			title:        "exclude element with quotes",
+			fault:        HTTPFault{Exclude: `/health,"/metrics"`},
+			expectError:  true,
+			errorMessage: `invalid exclude element "\"/metrics\"": cannot contain quotes or blanks`,
+		},
+		{
+			title:       "multiple ports",
+			fault:       HTTPFault{Ports: []intstr.IntOrString{intstr.FromInt32(8080), intstr.FromString("admin")}},
+			expectError: false,
+		},
+		{
+			title: "port and ports",
+			fault: HTTPFault{
+				Port:  intstr.FromInt32(80),
+				Ports: []intstr.IntOrString{intstr.FromInt32(8080)},
+			},
+			expectError:  true,
+			errorMessage: "port and ports cannot both be specified",
+		},
+		{
+			title:        "repeated port",
+			fault:        HTTPFault{Ports: []intstr.IntOrString{intstr.FromInt32(8080), intstr.FromInt32(8080)}},
+			expectError:  true,
+			errorMessage: "port 8080 is specified more than once",
+		},
+		{
+			title:        "empty port in ports",
+			fault:        HTTPFault{Ports: []intstr.IntOrString{intstr.FromInt32(8080), intstr.FromInt32(0)}},
+			expectError:  true,
+			errorMessage: "ports cannot be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.fault.validate()
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if tc.errorMessage != "" && err != nil && err.Error() != tc.errorMessage {
+				t.Errorf("expected error %q got %q", tc.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+func Test_HTTPFaultWindowsDuration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		windows     []HTTPFaultWindow
+		duration    time.Duration
+		expectError bool
+	}{
+		{
+			title:       "no windows",
+			windows:     nil,
+			duration:    time.Minute,
+			expectError: false,
+		},
+		{
+			title: "windows add up to duration",
+			windows: []HTTPFaultWindow{
+				{Duration: 5 * time.Minute},
+				{Duration: 5 * time.Minute},
+			},
+			duration:    10 * time.Minute,
+			expectError: false,
+		},
+		{
+			title: "windows shorter than duration",
+			windows: []HTTPFaultWindow{
+				{Duration: 5 * time.Minute},
+			},
+			duration:    10 * time.Minute,
+			expectError: true,
+		},
+		{
+			title: "windows longer than duration",
+			windows: []HTTPFaultWindow{
+				{Duration: 5 * time.Minute},
+				{Duration: 10 * time.Minute},
+			},
+			duration:    10 * time.Minute,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := HTTPFault{Windows: tc.windows}.validateWindows(tc.duration)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_GrpcFaultValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		fault        GrpcFault
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			title:        "error rate larger than 1",
+			fault:        GrpcFault{ErrorRate: 100, StatusCode: 14},
+			expectError:  true,
+			errorMessage: "error rate must be in the range [0.0, 1.0]: 100.000000",
+		},
+		{
+			title:        "negative error rate",
+			fault:        GrpcFault{ErrorRate: -0.1, StatusCode: 14},
+			expectError:  true,
+			errorMessage: "error rate must be in the range [0.0, 1.0]: -0.100000",
+		},
+		{
+			title:        "negative average delay",
+			fault:        GrpcFault{AverageDelay: -time.Second},
+			expectError:  true,
+			errorMessage: "average delay must be a positive duration: -1s",
+		},
+		{
+			title:        "negative delay variation",
+			fault:        GrpcFault{AverageDelay: time.Second, DelayVariation: -time.Second},
+			expectError:  true,
+			errorMessage: "delay variation must be a positive duration: -1s",
+		},
+		{
+			title:       "delay variation equal to average delay",
+			fault:       GrpcFault{AverageDelay: time.Second, DelayVariation: time.Second},
+			expectError: false,
+		},
+		{
+			title:        "delay variation larger than average delay",
+			fault:        GrpcFault{AverageDelay: time.Second, DelayVariation: 2 * time.Second},
+			expectError:  true,
+			errorMessage: "delay variation (2s) must not be larger than average delay (1s)",
+		},
+		{
+			title:        "delay variation without average delay",
+			fault:        GrpcFault{DelayVariation: time.Millisecond},
+			expectError:  true,
+			errorMessage: "delay variation (1ms) must not be larger than average delay (0s)",
+		},
+		{
+			title:       "no authority",
+			fault:       GrpcFault{},
+			expectError: false,
+		},
+		{
+			title:       "authority with host",
+			fault:       GrpcFault{MatchAuthority: "api.example.com"},
+			expectError: false,
+		},
+		{
+			title:       "authority with host and port",
+			fault:       GrpcFault{MatchAuthority: "api.example.com:8443"},
+			expectError: false,
+		},
+		{
+			title:       "authority with IPv6 host and port",
+			fault:       GrpcFault{MatchAuthority: "[2001:db8::1]:8443"},
+			expectError: false,
+		},
+		{
+			title:       "authority with path",
+			fault:       GrpcFault{MatchAuthority: "api.example.com/v1"},
+			expectError: true,
+		},
+		{
+			title:       "authority with user info",
+			fault:       GrpcFault{MatchAuthority: "user@api.example.com"},
+			expectError: true,
+		},
+		{
+			title:       "authority with invalid port",
+			fault:       GrpcFault{MatchAuthority: "api.example.com:http"},
+			expectError: true,
+		},
+		{
+			title:       "authority without host",
+			fault:       GrpcFault{MatchAuthority: ":8443"},
+			expectError: true,
+		},
+		{
+			title:       "authority with spaces",
+			fault:       GrpcFault{MatchAuthority: "api example.com"},
+			expectError: true,
+		},
+		{
+			title:       "status message with spaces, commas and quotes",
+			fault:       GrpcFault{ErrorRate: 0.1, StatusCode: 14, StatusMessage: `service "users" unavailable, retry`},
+			expectError: false,
+		},
+		{
+			title:        "status message with NUL character",
+			fault:        GrpcFault{ErrorRate: 0.1, StatusCode: 14, StatusMessage: "internal\x00error"},
+			expectError:  true,
+			errorMessage: "status message cannot contain NUL characters",
+		},
+		{
+			title: "metadata",
+			fault: GrpcFault{
+				ErrorRate:  0.1,
+				StatusCode: 14,
+				Metadata:   map[string]string{"x-retry-after": "10", "x-details-bin": "\xff\xfe", "x.region_id": "eu"},
+			},
+			expectError: false,
+		},
+		{
+			title:        "metadata without error rate",
+			fault:        GrpcFault{Metadata: map[string]string{"x-retry-after": "10"}},
+			expectError:  true,
+			errorMessage: "metadata requires an error rate",
+		},
+		{
+			title:        "metadata key with uppercase letters",
+			fault:        GrpcFault{ErrorRate: 0.1, StatusCode: 14, Metadata: map[string]string{"X-Retry-After": "10"}},
+			expectError:  true,
+			errorMessage: `invalid metadata key "X-Retry-After": must contain only lowercase letters, digits, '-', '_' or '.'`,
+		},
+		{
+			title:        "metadata key with reserved prefix",
+			fault:        GrpcFault{ErrorRate: 0.1, StatusCode: 14, Metadata: map[string]string{"grpc-status": "0"}},
+			expectError:  true,
+			errorMessage: `invalid metadata key "grpc-status": the 'grpc-' prefix is reserved`,
+		},
+		{
+			title:        "empty metadata key",
+			fault:        GrpcFault{ErrorRate: 0.1, StatusCode: 14, Metadata: map[string]string{"": "10"}},
+			expectError:  true,
+			errorMessage: "metadata key cannot be empty",
+		},
+		{
+			title:        "metadata value with non printable characters",
+			fault:        GrpcFault{ErrorRate: 0.1, StatusCode: 14, Metadata: map[string]string{"x-retry-after": "1\n0"}},
+			expectError:  true,
+			errorMessage: `invalid value of metadata key "x-retry-after": must contain only printable ASCII characters`,
+		},
+		{
+			title:        "binary metadata value with NUL character",
+			fault:        GrpcFault{ErrorRate: 0.1, StatusCode: 14, Metadata: map[string]string{"x-details-bin": "\x00"}},
+			expectError:  true,
+			errorMessage: `value of metadata key "x-details-bin" cannot contain NUL characters`,
+		},
+		{
+			title:       "exclude list",
+			fault:       GrpcFault{Exclude: "service1,service2"},
+			expectError: false,
+		},
+		{
+			title:        "exclude element with spaces",
+			fault:        GrpcFault{Exclude: "service1, service2"},
+			expectError:  true,
+			errorMessage: `invalid exclude element " service2": cannot contain quotes or blanks`,
+		},
+		{
+			title:        "exclude element with quotes",
+			fault:        GrpcFault{Exclude: `"service1"`},
+			expectError:  true,
+			errorMessage: `invalid exclude element "\"service1\"": cannot contain quotes or blanks`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.fault.validate()
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if tc.errorMessage != "" && err != nil && err.Error() != tc.errorMessage {
+				t.Errorf("expected error %q got %q", tc.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+func Test_DisruptionOptionsValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		bufferSize      uint
+		gracePeriod     time.Duration
+		stopGracePeriod time.Duration
+		execTimeout     time.Duration
+		expectError     bool
+	}{
+		{
+			title:       "default options",
+			expectError: false,
+		},
+		{
+			title:       "valid buffer size",
+			bufferSize:  256 * 1024,
+			expectError: false,
+		},
+		{
+			title:       "buffer size too small",
+			bufferSize:  MinProxyBufferSize - 1,
+			expectError: true,
+		},
+		{
+			title:       "buffer size too large",
+			bufferSize:  MaxProxyBufferSize + 1,
+			expectError: true,
+		},
+		{
+			title:       "valid grace period",
+			gracePeriod: 10 * time.Second,
+			expectError: false,
+		},
+		{
+			title:       "negative grace period",
+			gracePeriod: -10 * time.Second,
+			expectError: true,
+		},
+		{
+			title:       "grace period as long as the duration",
+			gracePeriod: time.Minute,
+			expectError: true,
+		},
+		{
+			title:           "stop grace period shorter than the exec timeout",
+			stopGracePeriod: 10 * time.Second,
+			execTimeout:     DefaultExecTimeout,
+			expectError:     false,
+		},
+		{
+			title:           "stop grace period as long as the exec timeout",
+			stopGracePeriod: 10 * time.Second,
+			execTimeout:     10 * time.Second,
+			expectError:     true,
+		},
+		{
+			title:           "stop grace period without exec timeout",
+			stopGracePeriod: 10 * time.Minute,
+			expectError:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			httpOptions := HTTPDisruptionOptions{
+				BufferSize:      tc.bufferSize,
+				GracePeriod:     tc.gracePeriod,
+				StopGracePeriod: tc.stopGracePeriod,
+			}
+			grpcOptions := GrpcDisruptionOptions{
+				BufferSize:      tc.bufferSize,
+				GracePeriod:     tc.gracePeriod,
+				StopGracePeriod: tc.stopGracePeriod,
+			}
+
+			for _, err := range []error{
+				httpOptions.validate(time.Minute, tc.execTimeout),
+				grpcOptions.validate(time.Minute, tc.execTimeout),
+			} {
+				if tc.expectError && err == nil {
+					t.Errorf("should had failed")
+				}
+
+				if !tc.expectError && err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func Test_HTTPDisruptionOptionsValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		options     HTTPDisruptionOptions
+		expectError bool
+	}{
+		{
+			title:       "http2",
+			options:     HTTPDisruptionOptions{HTTP2: true},
+			expectError: false,
+		},
+		{
+			title:       "http2 with grace period",
+			options:     HTTPDisruptionOptions{HTTP2: true, GracePeriod: 10 * time.Second},
+			expectError: false,
+		},
+		{
+			title:       "http2 with buffer size",
+			options:     HTTPDisruptionOptions{HTTP2: true, BufferSize: 256 * 1024},
+			expectError: true,
+		},
+		{
+			title:       "ingress direction",
+			options:     HTTPDisruptionOptions{Direction: DirectionIngress},
+			expectError: false,
+		},
+		{
+			title:       "egress direction",
+			options:     HTTPDisruptionOptions{Direction: DirectionEgress},
+			expectError: false,
+		},
+		{
+			title:       "invalid direction",
+			options:     HTTPDisruptionOptions{Direction: "inbound"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.options.validate(time.Minute, DefaultExecTimeout)
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
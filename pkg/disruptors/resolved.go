@@ -0,0 +1,60 @@
+package disruptors
+
+import (
+	"sync"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+// ResolvedFaultsReporter defines the method for inspecting the configuration of the faults applied to each target
+type ResolvedFaultsReporter interface {
+	// ResolvedFaults returns the configuration applied to each target by the last fault injection, after
+	// resolving its defaults, by the namespace/name of the target. In dry-run mode, the configuration that would have
+	// been applied is returned.
+	ResolvedFaults() map[string]ResolvedFault
+}
+
+// ResolvedFault describes the configuration of a fault applied to a target, with the defaults resolved
+type ResolvedFault struct {
+	// ports of the target the fault is applied to
+	Ports []ResolvedPort `js:"ports"`
+	// command sent to the agent in the target
+	Command []string `js:"command"`
+}
+
+// ResolvedPort describes a port of a target a fault is applied to
+type ResolvedPort struct {
+	// port of the target, as a number
+	Port intstr.IntOrString `js:"port"`
+	// port used by the agent's proxy for listening. Zero if the fault does not use a proxy.
+	ProxyPort uint `js:"proxyPort"`
+	// name of the container of the target that exposes the port
+	Container string `js:"container"`
+}
+
+// resolvedFaultsLog records the configuration applied to each target by the last fault injection
+type resolvedFaultsLog struct {
+	mutex  sync.Mutex
+	faults map[string]ResolvedFault
+}
+
+// set replaces the configurations recorded in the log
+func (l *resolvedFaultsLog) set(faults map[string]ResolvedFault) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.faults = faults
+}
+
+// get returns a copy of the configurations recorded in the log
+func (l *resolvedFaultsLog) get() map[string]ResolvedFault {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	faults := make(map[string]ResolvedFault, len(l.faults))
+	for target, fault := range l.faults {
+		faults[target] = fault
+	}
+
+	return faults
+}
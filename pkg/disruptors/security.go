@@ -0,0 +1,174 @@
+package disruptors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrNetAdminRequired is returned when a network fault is injected but the security context of the agent does not
+// add the NET_ADMIN capability, which the agent requires for redirecting the traffic of the targets
+var ErrNetAdminRequired = errors.New("network faults require the NET_ADMIN capability in the agent")
+
+// ErrKillRequired is returned when a process fault is injected but the security context of the agent drops the KILL
+// capability, which the agent requires for signaling the processes of the targets
+var ErrKillRequired = errors.New("process faults require the KILL capability in the agent")
+
+// ErrAgentSecurityPolicy is returned when the agent container is rejected by the Pod Security Admission policy of
+// the namespace of a target, for example because it forbids the NET_ADMIN capability
+var ErrAgentSecurityPolicy = errors.New("agent security context not allowed by the pod security policy")
+
+// netAdminCapability is the capability the agent requires for injecting network faults
+const netAdminCapability = "NET_ADMIN"
+
+// killCapability is the capability the agent requires for injecting process faults. It is granted by default to the
+// containers, so the agent only lacks it if the security context drops it.
+const killCapability = "KILL"
+
+// allCapabilities drops every capability not explicitly added
+const allCapabilities = "ALL"
+
+// seccomp profiles supported in the security context of the agent
+var seccompProfiles = map[string]corev1.SeccompProfileType{ //nolint:gochecknoglobals
+	string(corev1.SeccompProfileTypeRuntimeDefault): corev1.SeccompProfileTypeRuntimeDefault,
+	string(corev1.SeccompProfileTypeUnconfined):     corev1.SeccompProfileTypeUnconfined,
+}
+
+// AgentSecurityContext defines overrides of the security context of the agent container, for example for complying
+// with the Pod Security Admission policy of the namespace of the targets. Unset fields keep the default, which runs
+// the agent as root with the NET_ADMIN capability.
+type AgentSecurityContext struct {
+	// capabilities added to the agent container. Empty keeps the default (NET_ADMIN).
+	Capabilities []string `js:"capabilities"`
+	// capabilities dropped from the agent container (e.g. "ALL"). Dropping NET_ADMIN prevents adding it by default,
+	// for namespaces that forbid it, but network faults cannot be injected without NET_ADMIN. Likewise, process faults
+	// cannot be injected if KILL is dropped, either explicitly or by dropping ALL, unless it is added back.
+	DropCapabilities []string `js:"dropCapabilities"`
+	// user the agent runs as. Zero keeps the default (root).
+	RunAsUser int64 `js:"runAsUser"`
+	// group the agent runs as. Zero keeps the default (root).
+	RunAsGroup int64 `js:"runAsGroup"`
+	// require the agent to run as a non-root user. Requires RunAsUser.
+	RunAsNonRoot bool `js:"runAsNonRoot"`
+	// type of the seccomp profile of the agent container ("RuntimeDefault" or "Unconfined"). Empty means not set.
+	SeccompProfile string `js:"seccompProfile"`
+}
+
+func (s AgentSecurityContext) validate() error {
+	if s.RunAsNonRoot && s.RunAsUser == 0 {
+		return fmt.Errorf("run as non root requires a non-root user")
+	}
+
+	if s.RunAsUser < 0 || s.RunAsGroup < 0 {
+		return fmt.Errorf("user and group of the agent must be non-negative")
+	}
+
+	if _, found := seccompProfiles[s.SeccompProfile]; s.SeccompProfile != "" && !found {
+		return fmt.Errorf("seccomp profile must be RuntimeDefault or Unconfined: %q", s.SeccompProfile)
+	}
+
+	return nil
+}
+
+// hasCapability returns true if the list contains the capability
+func hasCapability(capabilities []string, capability string) bool {
+	for _, c := range capabilities {
+		if strings.EqualFold(c, capability) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// added returns the capabilities added to the agent container
+func (s AgentSecurityContext) added() []string {
+	if len(s.Capabilities) > 0 {
+		return s.Capabilities
+	}
+
+	if hasCapability(s.DropCapabilities, netAdminCapability) {
+		return nil
+	}
+
+	return []string{netAdminCapability}
+}
+
+// hasNetAdmin returns true if the agent container has the NET_ADMIN capability
+func (s AgentSecurityContext) hasNetAdmin() bool {
+	return hasCapability(s.added(), netAdminCapability)
+}
+
+// hasKill returns true if the agent container has the KILL capability, either because it is added or because it is
+// not dropped from the capabilities granted by default
+func (s AgentSecurityContext) hasKill() bool {
+	if hasCapability(s.added(), killCapability) {
+		return true
+	}
+
+	return !hasCapability(s.DropCapabilities, killCapability) && !hasCapability(s.DropCapabilities, allCapabilities)
+}
+
+// build returns the security context of the agent container, merging the overrides with the default
+func (s AgentSecurityContext) build() *corev1.SecurityContext {
+	var (
+		user         = s.RunAsUser
+		group        = s.RunAsGroup
+		runAsNonRoot = s.RunAsNonRoot
+	)
+
+	var add []corev1.Capability
+	for _, capability := range s.added() {
+		add = append(add, corev1.Capability(capability))
+	}
+
+	var drop []corev1.Capability
+	for _, capability := range s.DropCapabilities {
+		drop = append(drop, corev1.Capability(capability))
+	}
+
+	securityContext := &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{
+			Add:  add,
+			Drop: drop,
+		},
+		RunAsUser:    &user,
+		RunAsGroup:   &group,
+		RunAsNonRoot: &runAsNonRoot,
+	}
+
+	if s.SeccompProfile != "" {
+		securityContext.SeccompProfile = &corev1.SeccompProfile{Type: seccompProfiles[s.SeccompProfile]}
+	}
+
+	return securityContext
+}
+
+// requireNetAdmin returns an error if the agent cannot inject network faults because its security context does not
+// add the NET_ADMIN capability
+func requireNetAdmin(securityContext AgentSecurityContext) error {
+	if securityContext.hasNetAdmin() {
+		return nil
+	}
+
+	return fmt.Errorf("%w, which is not added by the agent security context", ErrNetAdminRequired)
+}
+
+// requireKill returns an error if the agent cannot inject process faults because its security context drops the KILL
+// capability
+func requireKill(securityContext AgentSecurityContext) error {
+	if securityContext.hasKill() {
+		return nil
+	}
+
+	return fmt.Errorf("%w, which is dropped by the agent security context", ErrKillRequired)
+}
+
+// isSecurityPolicyViolation returns true if the error is the rejection of a pod by the Pod Security Admission
+func isSecurityPolicyViolation(err error) bool {
+	return apierrors.IsForbidden(err) && strings.Contains(err.Error(), "violates PodSecurity")
+}
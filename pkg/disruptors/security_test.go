@@ -0,0 +1,225 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+func Test_AgentSecurityContextValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		securityContext AgentSecurityContext
+		expectError     bool
+	}{
+		{
+			title:           "default",
+			securityContext: AgentSecurityContext{},
+			expectError:     false,
+		},
+		{
+			title: "non-root user",
+			securityContext: AgentSecurityContext{
+				RunAsUser:    1000,
+				RunAsNonRoot: true,
+			},
+			expectError: false,
+		},
+		{
+			title: "non-root without user",
+			securityContext: AgentSecurityContext{
+				RunAsNonRoot: true,
+			},
+			expectError: true,
+		},
+		{
+			title: "negative user",
+			securityContext: AgentSecurityContext{
+				RunAsUser: -1,
+			},
+			expectError: true,
+		},
+		{
+			title: "seccomp profile",
+			securityContext: AgentSecurityContext{
+				SeccompProfile: "RuntimeDefault",
+			},
+			expectError: false,
+		},
+		{
+			title: "invalid seccomp profile",
+			securityContext: AgentSecurityContext{
+				SeccompProfile: "Localhost",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.securityContext.validate()
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorNetAdminRequired(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		securityContext AgentSecurityContext
+		expectError     bool
+	}{
+		{
+			title:           "default capabilities",
+			securityContext: AgentSecurityContext{},
+			expectError:     false,
+		},
+		{
+			title: "NET_ADMIN capability",
+			securityContext: AgentSecurityContext{
+				Capabilities:     []string{"NET_ADMIN"},
+				DropCapabilities: []string{"ALL"},
+			},
+			expectError: false,
+		},
+		{
+			title: "NET_ADMIN dropped",
+			securityContext: AgentSecurityContext{
+				DropCapabilities: []string{"NET_ADMIN"},
+			},
+			expectError: true,
+		},
+		{
+			title: "other capabilities",
+			securityContext: AgentSecurityContext{
+				Capabilities: []string{"NET_RAW"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			disruptor, _ := newTestPodDisruptor(t, PodDisruptorOptions{
+				AgentStartupTimeout:  -1,
+				AgentSecurityContext: tc.securityContext,
+			}, &pod)
+
+			httpFault := HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500}
+			err := disruptor.InjectHTTPFaults(context.TODO(), httpFault, 10*time.Second, HTTPDisruptionOptions{})
+			if tc.expectError && !errors.Is(err, ErrNetAdminRequired) {
+				t.Fatalf("expected error %v got %v", ErrNetAdminRequired, err)
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			tcpFault := TCPFault{Port: intstr.FromInt32(80), ResetRate: 0.1}
+			err = disruptor.InjectTCPFaults(context.TODO(), tcpFault, 10*time.Second, TCPDisruptionOptions{})
+			if tc.expectError && !errors.Is(err, ErrNetAdminRequired) {
+				t.Fatalf("expected error %v got %v", ErrNetAdminRequired, err)
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorKillRequired(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		securityContext AgentSecurityContext
+		expectError     bool
+	}{
+		{
+			title:           "default capabilities",
+			securityContext: AgentSecurityContext{},
+			expectError:     false,
+		},
+		{
+			title: "KILL dropped",
+			securityContext: AgentSecurityContext{
+				DropCapabilities: []string{"KILL"},
+			},
+			expectError: true,
+		},
+		{
+			title: "ALL dropped",
+			securityContext: AgentSecurityContext{
+				DropCapabilities: []string{"ALL"},
+			},
+			expectError: true,
+		},
+		{
+			title: "ALL dropped and KILL added",
+			securityContext: AgentSecurityContext{
+				Capabilities:     []string{"NET_ADMIN", "KILL"},
+				DropCapabilities: []string{"ALL"},
+			},
+			expectError: false,
+		},
+		{
+			title: "NET_ADMIN dropped",
+			securityContext: AgentSecurityContext{
+				DropCapabilities: []string{"NET_ADMIN"},
+			},
+			expectError: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod("my-app-pod", "http", 80)
+			shareProcessNamespace := true
+			pod.Spec.ShareProcessNamespace = &shareProcessNamespace
+			disruptor, k := newTestPodDisruptor(t, PodDisruptorOptions{
+				AgentStartupTimeout:  -1,
+				AgentSecurityContext: tc.securityContext,
+			}, &pod)
+
+			err := disruptor.InjectProcessFaults(context.TODO(), ProcessFault{}, 10*time.Second, ProcessDisruptionOptions{})
+			if tc.expectError && !errors.Is(err, ErrKillRequired) {
+				t.Fatalf("expected error %v got %v", ErrKillRequired, err)
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed unexpectedly: %v", err)
+			}
+
+			if tc.expectError && len(k.GetFakeProcessExecutor().GetHistory()) != 0 {
+				t.Fatalf("expected no command executed")
+			}
+		})
+	}
+}
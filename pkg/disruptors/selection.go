@@ -0,0 +1,137 @@
+package disruptors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SelectionHasher defines the method for detecting changes in the targets of a disruptor
+type SelectionHasher interface {
+	// SelectionHash returns a hash of the disruptor's targets that does not depend on the order in which they
+	// are selected. The hash changes when targets are added or removed.
+	SelectionHash(ctx context.Context) (string, error)
+}
+
+// selectionHash returns a hex-encoded hash of the sorted target names
+func selectionHash(names []string) string {
+	sorted := make([]string, len(names))
+	copy(sorted, names)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, name := range sorted {
+		// the separator prevents different lists from producing the same input (e.g. ["ab"] and ["a", "b"])
+		_, _ = hash.Write([]byte(name))
+		_, _ = hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// podSelectionHash returns the selection hash of the pods, identified by their namespace and name
+func podSelectionHash(pods []corev1.Pod) string {
+	keys := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		keys = append(keys, podKey(pod))
+	}
+
+	return selectionHash(keys)
+}
+
+// validateSample checks the sample is either a positive count or a percentage in the range (0, 100]
+func validateSample(sample intstr.IntOrString) error {
+	if sample.IsNull() {
+		return nil
+	}
+
+	if sample.IsInt() {
+		if sample.Int32() <= 0 {
+			return fmt.Errorf("sample must be greater than zero: %s", sample)
+		}
+
+		return nil
+	}
+
+	percentage, ok := sample.AsPercentage()
+	if !ok {
+		return fmt.Errorf("sample must be a count or a percentage: %s", sample)
+	}
+
+	if percentage <= 0 || percentage > 100 {
+		return fmt.Errorf("sample percentage must be in the range (0, 100]: %s", sample)
+	}
+
+	return nil
+}
+
+// samplePods returns a random sample of the pods. The sample depends only on the seed and the pods, not on
+// the order of the pods. A count larger than the number of pods selects all of them.
+func samplePods(pods []corev1.Pod, sample intstr.IntOrString, seed int64) ([]corev1.Pod, error) {
+	if sample.IsInt() && int(sample.Int32()) >= len(pods) {
+		return pods, nil
+	}
+
+	shuffled := make([]corev1.Pod, len(pods))
+	copy(shuffled, pods)
+	sort.Slice(shuffled, func(i, j int) bool {
+		return podKey(shuffled[i]) < podKey(shuffled[j])
+	})
+
+	random := rand.New(rand.NewSource(seed)) //nolint:gosec // not used for cryptographic purposes
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return utils.Sample(shuffled, sample)
+}
+
+// groupPods splits the pods in groups, by the label selector of each group. Pods that do not match any group are
+// ignored. It fails if a selector is invalid or empty, a group has no pods, or a pod matches more than one group.
+func groupPods(pods []corev1.Pod, groups []string) (map[string][]corev1.Pod, error) {
+	selectors := make(map[string]labels.Selector, len(groups))
+	for _, group := range groups {
+		selector, err := labels.Parse(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group selector %q: %w", group, err)
+		}
+		if selector.Empty() {
+			return nil, fmt.Errorf("group selector %q does not select a subset of the targets", group)
+		}
+		selectors[group] = selector
+	}
+
+	// the group of each pod, by namespace and name of the pod
+	podGroups := map[types.NamespacedName]string{}
+	grouped := make(map[string][]corev1.Pod, len(groups))
+	for _, group := range groups {
+		for _, pod := range pods {
+			if !selectors[group].Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+
+			key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+			if other, found := podGroups[key]; found {
+				return nil, fmt.Errorf("pod %q matches groups %q and %q", key, other, group)
+			}
+			podGroups[key] = group
+			grouped[group] = append(grouped[group], pod)
+		}
+
+		if len(grouped[group]) == 0 {
+			return nil, fmt.Errorf("finding pods matching group %q: %w", group, ErrSelectorNoPods)
+		}
+	}
+
+	return grouped, nil
+}
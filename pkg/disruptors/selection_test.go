@@ -0,0 +1,381 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_SelectionHash(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title      string
+		first      []string
+		second     []string
+		expectSame bool
+	}{
+		{
+			title:      "same targets",
+			first:      []string{"pod-1", "pod-2"},
+			second:     []string{"pod-1", "pod-2"},
+			expectSame: true,
+		},
+		{
+			title:      "different order",
+			first:      []string{"pod-1", "pod-2", "pod-3"},
+			second:     []string{"pod-3", "pod-1", "pod-2"},
+			expectSame: true,
+		},
+		{
+			title:      "added target",
+			first:      []string{"pod-1", "pod-2"},
+			second:     []string{"pod-1", "pod-2", "pod-3"},
+			expectSame: false,
+		},
+		{
+			title:      "removed target",
+			first:      []string{"pod-1", "pod-2"},
+			second:     []string{"pod-1"},
+			expectSame: false,
+		},
+		{
+			title:      "replaced target",
+			first:      []string{"pod-1", "pod-2"},
+			second:     []string{"pod-1", "pod-3"},
+			expectSame: false,
+		},
+		{
+			title:      "concatenated names",
+			first:      []string{"pod-1", "pod-2"},
+			second:     []string{"pod-1pod-2"},
+			expectSame: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			first := selectionHash(tc.first)
+			second := selectionHash(tc.second)
+
+			if tc.expectSame && first != second {
+				t.Fatalf("expected same hash got %q and %q", first, second)
+			}
+
+			if !tc.expectSame && first == second {
+				t.Fatalf("expected different hashes got %q", first)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorSelectionHash(t *testing.T) {
+	t.Parallel()
+
+	objs := []runtime.Object{}
+	for _, name := range []string{"pod-2", "pod-1"} {
+		pod := builders.NewPodBuilder(name).
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			Build()
+		objs = append(objs, &pod)
+	}
+
+	client := fake.NewSimpleClientset(objs...)
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	disruptor, err := NewPodDisruptor(
+		context.TODO(),
+		k,
+		PodSelectorSpec{
+			Namespace: "test-ns",
+			Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
+		},
+		PodDisruptorOptions{},
+	)
+	if err != nil {
+		t.Fatalf("creating disruptor: %v", err)
+	}
+
+	hash, err := disruptor.SelectionHash(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := selectionHash([]string{"test-ns/pod-1", "test-ns/pod-2"}); hash != expected {
+		t.Fatalf("expected hash %q got %q", expected, hash)
+	}
+
+	pod := builders.NewPodBuilder("pod-3").WithNamespace("test-ns").WithLabel("app", "test").Build()
+	_, err = client.CoreV1().Pods("test-ns").Create(context.TODO(), &pod, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating pod: %v", err)
+	}
+
+	changed, err := disruptor.SelectionHash(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if changed == hash {
+		t.Fatalf("expected hash to change when a target is added")
+	}
+}
+
+func Test_GroupPods(t *testing.T) {
+	t.Parallel()
+
+	// pods with the same name in different namespaces
+	canary := builders.NewPodBuilder("my-app").WithNamespace("ns-1").WithLabel("track", "canary").Build()
+	baseline := builders.NewPodBuilder("my-app").WithNamespace("ns-2").WithLabel("track", "baseline").Build()
+	both := builders.NewPodBuilder("my-app").
+		WithNamespace("ns-3").
+		WithLabel("track", "canary").
+		WithLabel("tier", "backend").
+		Build()
+
+	testCases := []struct {
+		title       string
+		pods        []corev1.Pod
+		groups      []string
+		expected    map[string][]string
+		expectError bool
+	}{
+		{
+			title:  "pods with the same name in different namespaces",
+			pods:   []corev1.Pod{canary, baseline},
+			groups: []string{"track=canary", "track=baseline"},
+			expected: map[string][]string{
+				"track=canary":   {"ns-1/my-app"},
+				"track=baseline": {"ns-2/my-app"},
+			},
+			expectError: false,
+		},
+		{
+			title:       "pod matches more than one group",
+			pods:        []corev1.Pod{canary, both},
+			groups:      []string{"track=canary", "tier=backend"},
+			expectError: true,
+		},
+		{
+			title:       "group without pods",
+			pods:        []corev1.Pod{canary},
+			groups:      []string{"track=canary", "track=baseline"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			grouped, err := groupPods(tc.pods, tc.groups)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			names := map[string][]string{}
+			for group, pods := range grouped {
+				for _, pod := range pods {
+					names[group] = append(names[group], pod.Namespace+"/"+pod.Name)
+				}
+			}
+
+			if diff := cmp.Diff(tc.expected, names); diff != "" {
+				t.Fatalf("expected groups do not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_SamplePods(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		pods         int
+		sample       intstr.IntOrString
+		expectedSize int
+		expectError  bool
+	}{
+		{
+			title:        "percentage",
+			pods:         8,
+			sample:       intstr.FromString("25%"),
+			expectedSize: 2,
+		},
+		{
+			title:        "percentage rounding to zero",
+			pods:         2,
+			sample:       intstr.FromString("10%"),
+			expectedSize: 1,
+		},
+		{
+			title:        "count",
+			pods:         8,
+			sample:       intstr.FromInt32(3),
+			expectedSize: 3,
+		},
+		{
+			title:        "count larger than the targets",
+			pods:         2,
+			sample:       intstr.FromInt32(3),
+			expectedSize: 2,
+		},
+		{
+			title:       "zero count",
+			pods:        2,
+			sample:      intstr.FromInt32(0),
+			expectError: true,
+		},
+		{
+			title:       "zero percentage",
+			pods:        2,
+			sample:      intstr.FromString("0%"),
+			expectError: true,
+		},
+		{
+			title:       "percentage over 100",
+			pods:        2,
+			sample:      intstr.FromString("150%"),
+			expectError: true,
+		},
+		{
+			title:       "invalid sample",
+			pods:        2,
+			sample:      intstr.FromString("some"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateSample(tc.sample)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			pods := []corev1.Pod{}
+			for i := 0; i < tc.pods; i++ {
+				pods = append(pods, builders.NewPodBuilder(fmt.Sprintf("pod-%d", i)).Build())
+			}
+
+			sample, err := samplePods(pods, tc.sample, 42)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(sample) != tc.expectedSize {
+				t.Fatalf("expected %d pods got %d", tc.expectedSize, len(sample))
+			}
+
+			// the sample does not depend on the order of the pods
+			reversed := make([]corev1.Pod, 0, len(pods))
+			for i := len(pods) - 1; i >= 0; i-- {
+				reversed = append(reversed, pods[i])
+			}
+
+			other, err := samplePods(reversed, tc.sample, 42)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			names := utils.PodNames(sample)
+			sort.Strings(names)
+			otherNames := utils.PodNames(other)
+			sort.Strings(otherNames)
+			if diff := cmp.Diff(names, otherNames); diff != "" {
+				t.Fatalf("sample depends on the order of the pods:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PodDisruptorSample(t *testing.T) {
+	t.Parallel()
+
+	objs := []runtime.Object{}
+	for i := 0; i < 8; i++ {
+		pod := builders.NewPodBuilder(fmt.Sprintf("pod-%d", i)).
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			Build()
+		objs = append(objs, &pod)
+	}
+
+	client := fake.NewSimpleClientset(objs...)
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	// disruptors with the same seed select the same sample
+	samples := [][]string{}
+	for i := 0; i < 2; i++ {
+		disruptor, err := NewPodDisruptor(
+			context.TODO(),
+			k,
+			PodSelectorSpec{
+				Namespace: "test-ns",
+				Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
+			},
+			PodDisruptorOptions{
+				Sample:     intstr.FromString("25%"),
+				SampleSeed: 42,
+			},
+		)
+		if err != nil {
+			t.Fatalf("creating disruptor: %v", err)
+		}
+
+		targets, err := disruptor.Targets(context.TODO())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(targets) != 2 {
+			t.Fatalf("expected 2 targets got %v", targets)
+		}
+
+		samples = append(samples, targets)
+	}
+
+	if diff := cmp.Diff(samples[0], samples[1]); diff != "" {
+		t.Fatalf("samples with the same seed do not match:\n%s", diff)
+	}
+}
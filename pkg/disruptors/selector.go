@@ -2,50 +2,151 @@ package disruptors
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+	"github.com/grafana/xk6-disruptor/pkg/utils"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // ErrSelectorNoPods is returned by NewPodDisruptor when the selector passed to it does not match any pod in the
 // cluster.
 var ErrSelectorNoPods = errors.New("no pods found matching selector")
 
+// ErrNoLeader is returned when selecting the leader pod and no pod matching the selector is the leader.
+var ErrNoLeader = errors.New("no leader pod found matching selector")
+
+// ErrDivergentLabels is returned when some of the pods matching the selector do not have the labels required to be
+// common to all of them.
+var ErrDivergentLabels = errors.New("pods matching selector do not share the required labels")
+
 // ErrServiceNoTargets is returned by NewServiceDisruptor when passed a service without any pod matching its selector.
 var ErrServiceNoTargets = errors.New("service does not have any backing pods")
 
 // PodSelector returns the target of a PodSelectorSpec
 type PodSelector struct {
-	helper helpers.PodHelper
-	spec   PodSelectorSpec
+	helper      helpers.PodHelper
+	nodes       helpers.NodeHelper
+	replicaSets helpers.ReplicaSetHelper
+	deployments helpers.DeploymentHelper
+	metrics     helpers.PodMetricsHelper
+	spec        PodSelectorSpec
+	// name of the container of the agent, for excluding the pods under disruption
+	agentContainer string
 }
 
-// NewPodSelector creates a new PodSelector
-func NewPodSelector(spec PodSelectorSpec, helper helpers.PodHelper) (*PodSelector, error) {
+// NewPodSelector creates a new PodSelector. The NodeHelper is used for resolving the nodes of the pods when
+// selecting by node conditions, the ReplicaSetHelper for resolving the replica set when selecting by
+// replica set, the DeploymentHelper for resolving the deployment when selecting by deployment, and the
+// PodMetricsHelper for retrieving the resource usage of the pods when selecting by utilization. The pods under
+// disruption are those with the agent injected in the agentContainer. If empty, DefaultAgentContainerName is used.
+func NewPodSelector(
+	spec PodSelectorSpec,
+	helper helpers.PodHelper,
+	nodes helpers.NodeHelper,
+	replicaSets helpers.ReplicaSetHelper,
+	deployments helpers.DeploymentHelper,
+	metrics helpers.PodMetricsHelper,
+	agentContainer string,
+) (*PodSelector, error) {
 	// validate selector
 	emptySelect := reflect.DeepEqual(spec.Select, PodAttributes{})
 	emptyExclude := reflect.DeepEqual(spec.Exclude, PodAttributes{})
-	if spec.Namespace == "" && emptySelect && emptyExclude {
-		return nil, fmt.Errorf("namespace, select and exclude attributes in pod selector cannot all be empty")
+	if spec.Namespace == "" && emptySelect && emptyExclude && spec.ReplicaSet == "" && spec.Deployment == "" {
+		return nil, fmt.Errorf(
+			"namespace, select, exclude, replica set and deployment attributes in pod selector cannot all be empty",
+		)
+	}
+
+	if spec.MaxAge < 0 {
+		return nil, fmt.Errorf("max age in pod selector cannot be negative")
+	}
+
+	for _, relation := range spec.LabelRelations {
+		if err := relation.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !spec.Leader && (spec.LeaderAnnotation != "" || spec.LeaderLabel != "") {
+		return nil, fmt.Errorf("leader annotation and label in pod selector require leader selection")
+	}
+
+	if len(spec.NodeConditions) > 0 && nodes == nil {
+		return nil, fmt.Errorf("selecting pods by node conditions requires a node helper")
+	}
+
+	if spec.ReplicaSet != "" && replicaSets == nil {
+		return nil, fmt.Errorf("selecting pods by replica set requires a replica set helper")
+	}
+
+	if spec.Deployment != "" && deployments == nil {
+		return nil, fmt.Errorf("selecting pods by deployment requires a deployment helper")
+	}
+
+	if (spec.MinCPUUtilization > 0 || spec.MinMemoryUtilization > 0) && metrics == nil {
+		return nil, fmt.Errorf("selecting pods by resource utilization requires a pod metrics helper")
 	}
 
 	return &PodSelector{
-		spec:   spec,
-		helper: helper,
+		spec:           spec,
+		helper:         helper,
+		nodes:          nodes,
+		replicaSets:    replicaSets,
+		deployments:    deployments,
+		metrics:        metrics,
+		agentContainer: agentContainerName(agentContainer),
 	}, nil
 }
 
 // Targets returns the list of target pods
 func (s *PodSelector) Targets(ctx context.Context) ([]corev1.Pod, error) {
 	filter := helpers.PodFilter{
-		Select:  s.spec.Select.Labels,
-		Exclude: s.spec.Exclude.Labels,
+		Select: s.spec.Select.Labels,
+	}
+
+	var deploymentLabels map[string]string
+	if s.spec.Deployment != "" {
+		deployment, err := s.deployments.Get(ctx, s.spec.Deployment)
+		if err != nil {
+			return nil, err
+		}
+
+		// a selector without labels would match all the pods in the namespace
+		if deployment.Spec.Selector == nil || len(deployment.Spec.Selector.MatchLabels) == 0 {
+			return nil, fmt.Errorf("deployment %q does not select its pods by labels", s.spec.Deployment)
+		}
+		deploymentLabels = deployment.Spec.Selector.MatchLabels
+
+		// narrow the pods listed to those matching the deployment's labels
+		filter.Select = mergeLabels(filter.Select, deploymentLabels)
+	}
+
+	var replicaSet appsv1.ReplicaSet
+	if s.spec.ReplicaSet != "" {
+		var err error
+		replicaSet, err = s.replicaSets.Get(ctx, s.spec.ReplicaSet)
+		if err != nil {
+			return nil, err
+		}
+
+		// narrow the pods listed to those matching the replica set's labels
+		if replicaSet.Spec.Selector != nil {
+			filter.Select = mergeLabels(filter.Select, replicaSet.Spec.Selector.MatchLabels)
+		}
 	}
 
 	targets, err := s.helper.List(ctx, filter)
@@ -53,13 +154,389 @@ func (s *PodSelector) Targets(ctx context.Context) ([]corev1.Pod, error) {
 		return nil, err
 	}
 
+	// the selected labels take precedence when merged, so pods must be checked against the deployment's labels
+	if s.spec.Deployment != "" {
+		targets = filterMatchingLabels(targets, deploymentLabels)
+	}
+
+	if s.spec.ReplicaSet != "" {
+		targets = filterOwnedBy(targets, replicaSet.UID)
+	}
+
+	if len(s.spec.Exclude.Labels) > 0 {
+		targets = filterExcluded(targets, s.spec.Exclude.Labels)
+	}
+
+	if s.spec.MaxAge > 0 {
+		targets = filterByAge(targets, s.spec.MaxAge, time.Now())
+	}
+
+	if s.spec.ExcludeDisrupted {
+		targets = filterDisrupted(targets, s.agentContainer)
+	}
+
+	if len(s.spec.NodeConditions) > 0 {
+		targets, err = s.filterByNodeConditions(ctx, targets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.spec.LabelRelations) > 0 {
+		targets = filterByLabelRelations(targets, s.spec.LabelRelations)
+	}
+
+	if s.spec.MinCPUUtilization > 0 || s.spec.MinMemoryUtilization > 0 {
+		var usage map[string]corev1.ResourceList
+		usage, err = s.metrics.Usage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.spec.MinCPUUtilization > 0 {
+			targets = filterByUtilization(targets, usage, corev1.ResourceCPU, s.spec.MinCPUUtilization)
+		}
+
+		if s.spec.MinMemoryUtilization > 0 {
+			targets = filterByUtilization(targets, usage, corev1.ResourceMemory, s.spec.MinMemoryUtilization)
+		}
+	}
+
 	if len(targets) == 0 {
 		return nil, fmt.Errorf("finding pods matching '%s': %w", s.spec, ErrSelectorNoPods)
 	}
 
+	if s.spec.Leader {
+		targets, err = selectLeader(targets, s.spec.LeaderAnnotation, s.spec.LeaderLabel)
+		if err != nil {
+			return nil, fmt.Errorf("finding leader of pods matching '%s': %w", s.spec, err)
+		}
+	}
+
+	if len(s.spec.RequireCommonLabel) > 0 {
+		err = checkCommonLabels(targets, s.spec.RequireCommonLabel)
+		if err != nil {
+			return nil, fmt.Errorf("checking pods matching '%s': %w", s.spec, err)
+		}
+	}
+
 	return targets, nil
 }
 
+// checkCommonLabels checks all the pods have the given labels, reporting the pods that do not have them
+func checkCommonLabels(pods []corev1.Pod, labels map[string]string) error {
+	divergent := []string{}
+	for _, pod := range pods {
+		for label, value := range labels {
+			if actual, found := pod.Labels[label]; !found || actual != value {
+				divergent = append(divergent, pod.Name)
+				break
+			}
+		}
+	}
+
+	if len(divergent) > 0 {
+		return fmt.Errorf("%w %v: %s", ErrDivergentLabels, labels, strings.Join(divergent, ", "))
+	}
+
+	return nil
+}
+
+// mergeLabels returns the labels in selected and the labels in other that are not in selected
+func mergeLabels(selected map[string]string, other map[string]string) map[string]string {
+	merged := map[string]string{}
+	for label, value := range other {
+		merged[label] = value
+	}
+	for label, value := range selected {
+		merged[label] = value
+	}
+
+	return merged
+}
+
+// filterMatchingLabels returns the pods that have all the given labels
+func filterMatchingLabels(pods []corev1.Pod, labels map[string]string) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		if hasAllLabels(pod, labels) {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
+
+// filterOwnedBy returns the pods that have an owner reference to the object with the given UID
+func filterOwnedBy(pods []corev1.Pod, owner types.UID) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		for _, ref := range pod.OwnerReferences {
+			if ref.UID == owner {
+				filtered = append(filtered, pod)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// filterExcluded returns the pods that do not have any of the excluded labels
+func filterExcluded(pods []corev1.Pod, excluded map[string]string) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		if !hasAnyLabel(pod, excluded) {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
+
+// hasAnyLabel returns true if the pod has any of the given labels with the given value
+func hasAnyLabel(pod corev1.Pod, labels map[string]string) bool {
+	for label, value := range labels {
+		if podValue, found := pod.Labels[label]; found && podValue == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasAllLabels returns true if the pod has all the given labels with the given value
+func hasAllLabels(pod corev1.Pod, labels map[string]string) bool {
+	for label, value := range labels {
+		if podValue, found := pod.Labels[label]; !found || podValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterDisrupted returns the pods where the disruptor agent has not been injected in the given container
+func filterDisrupted(pods []corev1.Pod, agentContainer string) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		if !hasAgent(pod, agentContainer) {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
+
+// filterByAge returns the pods that were started (or became ready) within maxAge from now
+func filterByAge(pods []corev1.Pod, maxAge time.Duration, now time.Time) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		started, ok := utils.PodStartTime(pod)
+		if !ok || now.Sub(started) > maxAge {
+			continue
+		}
+		filtered = append(filtered, pod)
+	}
+
+	return filtered
+}
+
+// filterByUtilization returns the pods using more than the given percentage of the resource they request.
+// Pods that do not request the resource, or whose usage is unknown, are not selected.
+func filterByUtilization(
+	pods []corev1.Pod,
+	usage map[string]corev1.ResourceList,
+	name corev1.ResourceName,
+	percentage uint,
+) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		requested := resource.Quantity{}
+		for _, container := range pod.Spec.Containers {
+			if request, found := container.Resources.Requests[name]; found {
+				requested.Add(request)
+			}
+		}
+
+		used, found := usage[pod.Name][name]
+		if !found || requested.IsZero() {
+			continue
+		}
+
+		utilization := used.AsApproximateFloat64() / requested.AsApproximateFloat64() * 100
+		if utilization > float64(percentage) {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
+
+// filterByNodeConditions returns the pods scheduled on nodes with any of the selector's node conditions set to True.
+// Nodes are looked up once, as many pods are usually scheduled on the same node.
+func (s *PodSelector) filterByNodeConditions(ctx context.Context, pods []corev1.Pod) ([]corev1.Pod, error) {
+	matches := map[string]bool{}
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+
+		match, cached := matches[pod.Spec.NodeName]
+		if !cached {
+			node, err := s.nodes.Get(ctx, pod.Spec.NodeName)
+			if err != nil {
+				return nil, err
+			}
+
+			match = hasAnyCondition(node, s.spec.NodeConditions)
+			matches[pod.Spec.NodeName] = match
+		}
+
+		if match {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered, nil
+}
+
+// hasAnyCondition returns true if any of the given conditions is set to True in the node
+func hasAnyCondition(node corev1.Node, conditions []string) bool {
+	for _, nodeCondition := range node.Status.Conditions {
+		if nodeCondition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		for _, condition := range conditions {
+			if string(nodeCondition.Type) == condition {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// leaderElectionRecord is the subset of the leader election record stored in the leader annotation
+type leaderElectionRecord struct {
+	HolderIdentity string `json:"holderIdentity"`
+}
+
+// selectLeader returns the single leader pod among the pods. The leader is the pod marked with the label, if
+// given, or with the annotation otherwise.
+func selectLeader(pods []corev1.Pod, annotation string, label string) ([]corev1.Pod, error) {
+	if annotation == "" && label == "" {
+		annotation = DefaultLeaderAnnotation
+	}
+
+	labelKey, labelValue, hasValue := strings.Cut(label, "=")
+
+	leaders := []corev1.Pod{}
+	for _, pod := range pods {
+		if label != "" {
+			value, found := pod.Labels[labelKey]
+			if found && (!hasValue || value == labelValue) {
+				leaders = append(leaders, pod)
+			}
+			continue
+		}
+
+		if isAnnotatedLeader(pod, annotation) {
+			leaders = append(leaders, pod)
+		}
+	}
+
+	switch len(leaders) {
+	case 0:
+		return nil, ErrNoLeader
+	case 1:
+		return leaders, nil
+	default:
+		return nil, fmt.Errorf("found %d pods marked as leader: %v", len(leaders), utils.PodNames(leaders))
+	}
+}
+
+// isAnnotatedLeader returns true if the pod has the leader annotation. If the annotation contains a leader
+// election record, the holder identity must match the pod's name (possibly followed by an unique suffix).
+func isAnnotatedLeader(pod corev1.Pod, annotation string) bool {
+	value, found := pod.Annotations[annotation]
+	if !found || value == "" {
+		return false
+	}
+
+	record := leaderElectionRecord{}
+	if err := json.Unmarshal([]byte(value), &record); err != nil || record.HolderIdentity == "" {
+		return true
+	}
+
+	return record.HolderIdentity == pod.Name || strings.HasPrefix(record.HolderIdentity, pod.Name+"_")
+}
+
+// filterByLabelRelations returns the pods whose labels satisfy all the relations
+func filterByLabelRelations(pods []corev1.Pod, relations []LabelRelation) []corev1.Pod {
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		match := true
+		for _, relation := range relations {
+			if !relation.matches(pod.Labels) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
+
+// validate checks the relation is well-formed
+func (r LabelRelation) validate() error {
+	if r.Label == "" || r.OtherLabel == "" {
+		return fmt.Errorf("label relation requires both labels to be specified")
+	}
+
+	switch r.Operator {
+	case "", LabelRelationEquals, LabelRelationNotEquals:
+		return nil
+	default:
+		return fmt.Errorf("unsupported label relation operator %q", r.Operator)
+	}
+}
+
+// matches returns true if the labels satisfy the relation
+func (r LabelRelation) matches(labels map[string]string) bool {
+	value, found := labels[r.Label]
+	if !found {
+		return false
+	}
+
+	other, found := labels[r.OtherLabel]
+	if !found {
+		return false
+	}
+
+	if r.Operator == LabelRelationNotEquals {
+		return value != other
+	}
+
+	return value == other
+}
+
+// String returns a human-readable representation of the relation
+func (r LabelRelation) String() string {
+	operator := "=="
+	if r.Operator == LabelRelationNotEquals {
+		operator = "!="
+	}
+
+	return fmt.Sprintf("%s%s%s", r.Label, operator, r.OtherLabel)
+}
+
 // NamespaceOrDefault returns the configured namespace for this selector, and the name of the default namespace if it
 // is not configured.
 func (p PodSelectorSpec) NamespaceOrDefault() string {
@@ -83,7 +560,58 @@ func (p PodSelectorSpec) String() string {
 		str = strings.TrimSuffix(str, ", ")
 	}
 
-	str += fmt.Sprintf(" in ns %q", p.NamespaceOrDefault())
+	if len(p.Namespaces) > 0 {
+		namespaces := []string{}
+		for _, namespace := range p.Namespaces {
+			namespaces = append(namespaces, fmt.Sprintf("%q", namespace))
+		}
+		str += fmt.Sprintf(" in ns %s", strings.Join(namespaces, ", "))
+	} else {
+		str += fmt.Sprintf(" in ns %q", p.NamespaceOrDefault())
+	}
+
+	if p.MaxAge > 0 {
+		str += fmt.Sprintf(" started within %s", p.MaxAge)
+	}
+
+	if len(p.NodeConditions) > 0 {
+		str += fmt.Sprintf(" on nodes with %s", strings.Join(p.NodeConditions, " or "))
+	}
+
+	if p.ReplicaSet != "" {
+		str += fmt.Sprintf(" owned by replicaset %q", p.ReplicaSet)
+	}
+
+	if p.Deployment != "" {
+		str += fmt.Sprintf(" of deployment %q", p.Deployment)
+	}
+
+	utilization := []string{}
+	if p.MinCPUUtilization > 0 {
+		utilization = append(utilization, fmt.Sprintf("%d%% of requested cpu", p.MinCPUUtilization))
+	}
+	if p.MinMemoryUtilization > 0 {
+		utilization = append(utilization, fmt.Sprintf("%d%% of requested memory", p.MinMemoryUtilization))
+	}
+	if len(utilization) > 0 {
+		str += fmt.Sprintf(" using more than %s", strings.Join(utilization, " and "))
+	}
+
+	if p.ExcludeDisrupted {
+		str += " not under disruption"
+	}
+
+	if p.Leader {
+		str = "leader of " + str
+	}
+
+	if len(p.LabelRelations) > 0 {
+		relations := []string{}
+		for _, relation := range p.LabelRelations {
+			relations = append(relations, relation.String())
+		}
+		str += fmt.Sprintf(" with labels %s", strings.Join(relations, ", "))
+	}
 
 	return str
 }
@@ -106,11 +634,21 @@ func (PodSelectorSpec) groupLabels(groupName string, labels map[string]string) s
 	return group
 }
 
+// ServicePodSelectorOptions defines options that control the selection of the targets of a Service
+type ServicePodSelectorOptions struct {
+	// Percentage of the ready endpoints of the service to select. Zero means all the pods of the service.
+	ReadyEndpointsPercentage uint
+	// Endpoints of the service, already retrieved by the caller. If set, the targets are resolved from the
+	// endpoints instead of looking up the pods matching the selector of the service.
+	Endpoints *corev1.Endpoints
+}
+
 // ServicePodSelector returns the targets of a Service
 type ServicePodSelector struct {
 	service   string
 	namespace string
 	helper    helpers.ServiceHelper
+	options   ServicePodSelectorOptions
 }
 
 // NewServicePodSelector returns a new ServicePodSelector
@@ -118,24 +656,101 @@ func NewServicePodSelector(
 	service string,
 	namespace string,
 	helper helpers.ServiceHelper,
+	options ServicePodSelectorOptions,
 ) (*ServicePodSelector, error) {
+	if options.ReadyEndpointsPercentage > 100 {
+		return nil, fmt.Errorf("ready endpoints percentage must be in the range [0, 100]")
+	}
+
+	if ep := options.Endpoints; ep != nil && (ep.Name != service || ep.Namespace != namespace) {
+		return nil, fmt.Errorf(
+			"endpoints %s/%s do not match service %s/%s",
+			ep.Namespace, ep.Name, namespace, service,
+		)
+	}
+
 	return &ServicePodSelector{
 		service:   service,
 		namespace: namespace,
 		helper:    helper,
+		options:   options,
 	}, nil
 }
 
 // Targets returns the list of target pods
 func (s *ServicePodSelector) Targets(ctx context.Context) ([]corev1.Pod, error) {
-	targets, err := s.helper.GetTargets(ctx, s.service)
+	var (
+		targets []corev1.Pod
+		err     error
+	)
+	if s.options.Endpoints != nil {
+		targets, err = s.helper.GetEndpointsTargets(ctx, s.options.Endpoints)
+	} else {
+		targets, err = s.helper.GetTargets(ctx, s.service)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if s.options.ReadyEndpointsPercentage > 0 {
+		targets, err = s.sampleReadyEndpoints(ctx, targets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if len(targets) == 0 {
 		return nil, fmt.Errorf("finding pods matching%s/%s: %w", s.service, s.namespace, ErrServiceNoTargets)
 	}
 
 	return targets, nil
 }
+
+// sampleReadyEndpoints returns the pods backing a percentage of the ready endpoints of the service.
+// The sample is deterministic: endpoints are ordered by a hash of their address, so the same endpoints are selected
+// as long as the set of ready endpoints does not change.
+func (s *ServicePodSelector) sampleReadyEndpoints(ctx context.Context, pods []corev1.Pod) ([]corev1.Pod, error) {
+	var addresses []corev1.EndpointAddress
+	if s.options.Endpoints != nil {
+		addresses = helpers.ReadyAddresses(s.options.Endpoints)
+	} else {
+		var err error
+		addresses, err = s.helper.GetReadyEndpoints(ctx, s.service)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	podsByName := map[string]corev1.Pod{}
+	for _, pod := range pods {
+		podsByName[pod.Name] = pod
+	}
+
+	ready := []corev1.Pod{}
+	hashes := map[string]uint32{}
+	for _, address := range addresses {
+		if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+			continue
+		}
+
+		pod, found := podsByName[address.TargetRef.Name]
+		if !found {
+			continue
+		}
+
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(address.IP))
+		hashes[pod.Name] = hash.Sum32()
+		ready = append(ready, pod)
+	}
+
+	if len(ready) == 0 {
+		return nil, fmt.Errorf("finding ready endpoints of %s/%s: %w", s.service, s.namespace, ErrServiceNoTargets)
+	}
+
+	sort.Slice(ready, func(i, j int) bool {
+		return hashes[ready[i].Name] < hashes[ready[j].Name]
+	})
+
+	return utils.Sample(ready, intstr.FromString(fmt.Sprintf("%d%%", s.options.ReadyEndpointsPercentage)))
+}
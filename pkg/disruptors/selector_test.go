@@ -2,16 +2,24 @@ package disruptors
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
 	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
 	"github.com/grafana/xk6-disruptor/pkg/utils"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"k8s.io/client-go/kubernetes/fake"
@@ -36,108 +44,1038 @@ func Test_NewPodSelector(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			title: "negative max age",
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				MaxAge:    -1 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			title: "label relation without other label",
+			spec: PodSelectorSpec{
+				Namespace:      "test-ns",
+				LabelRelations: []LabelRelation{{Label: "shard"}},
+			},
+			expectError: true,
+		},
+		{
+			title: "label relation with unsupported operator",
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				LabelRelations: []LabelRelation{
+					{Label: "shard", Operator: "GreaterThan", OtherLabel: "primary-shard"},
+				},
+			},
+			expectError: true,
+		},
+		{
+			title: "leader label without leader selection",
+			spec: PodSelectorSpec{
+				Namespace:   "test-ns",
+				LeaderLabel: "role=leader",
+			},
+			expectError: true,
+		},
 		{
 			title:       "empty specs",
 			spec:        PodSelectorSpec{},
 			expectError: true,
 		},
-	}
-
-	for _, tc := range testCases {
-		tc := tc
-
-		t.Run(tc.title, func(t *testing.T) {
-			t.Parallel()
-
-			client := fake.NewSimpleClientset()
-			k, _ := kubernetes.NewFakeKubernetes(client)
-			helper := k.PodHelper(tc.spec.Namespace)
-
-			_, err := NewPodSelector(tc.spec, helper)
-
-			if tc.expectError && err != nil {
-				return
-			}
-
-			if !tc.expectError && err != nil {
-				t.Fatalf("unexpected error creating pod selector: %v", err)
-			}
-
-			if tc.expectError && err == nil {
-				t.Fatalf("should had failed creating pod selector")
-			}
-		})
-	}
-}
-
-func Test_PodSelectorString(t *testing.T) {
-	t.Parallel()
-
-	for _, tc := range []struct {
-		name     string
-		selector PodSelectorSpec
-		expected string
-	}{
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+			k, _ := kubernetes.NewFakeKubernetes(client)
+			helper := k.PodHelper(tc.spec.Namespace)
+
+			_, err := NewPodSelector(
+				tc.spec,
+				helper,
+				k.NodeHelper(),
+				k.ReplicaSetHelper(tc.spec.NamespaceOrDefault()),
+				k.DeploymentHelper(tc.spec.NamespaceOrDefault()),
+				k.PodMetricsHelper(tc.spec.NamespaceOrDefault()),
+				"",
+			)
+
+			if tc.expectError && err != nil {
+				return
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error creating pod selector: %v", err)
+			}
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed creating pod selector")
+			}
+		})
+	}
+}
+
+func Test_PodSelectorString(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		selector PodSelectorSpec
+		expected string
+	}{
+		{
+			name:     "Empty selector",
+			expected: `all pods in ns "default"`,
+		},
+		{
+			name: "Only inclusions",
+			selector: PodSelectorSpec{
+				Namespace: "testns",
+				Select:    PodAttributes{map[string]string{"foo": "bar"}},
+			},
+			expected: `pods including(foo=bar) in ns "testns"`,
+		},
+		{
+			name: "Only exclusions",
+			selector: PodSelectorSpec{
+				Namespace: "testns",
+				Exclude:   PodAttributes{map[string]string{"foo": "bar"}},
+			},
+			expected: `pods excluding(foo=bar) in ns "testns"`,
+		},
+		{
+			name: "Both inclusions and exclusions",
+			selector: PodSelectorSpec{
+				Namespace: "testns",
+				Select:    PodAttributes{map[string]string{"foo": "bar"}},
+				Exclude:   PodAttributes{map[string]string{"boo": "baa"}},
+			},
+			expected: `pods including(foo=bar), excluding(boo=baa) in ns "testns"`,
+		},
+		{
+			name: "Multiple namespaces",
+			selector: PodSelectorSpec{
+				Namespaces: []string{"team-a", "team-b"},
+				Select:     PodAttributes{map[string]string{"foo": "bar"}},
+			},
+			expected: `pods including(foo=bar) in ns "team-a", "team-b"`,
+		},
+		{
+			name: "Max age",
+			selector: PodSelectorSpec{
+				Namespace: "testns",
+				Select:    PodAttributes{map[string]string{"foo": "bar"}},
+				MaxAge:    time.Minute,
+			},
+			expected: `pods including(foo=bar) in ns "testns" started within 1m0s`,
+		},
+		{
+			name: "Replica set",
+			selector: PodSelectorSpec{
+				Namespace:  "testns",
+				ReplicaSet: "api-v1",
+			},
+			expected: `all pods in ns "testns" owned by replicaset "api-v1"`,
+		},
+		{
+			name: "Resource utilization",
+			selector: PodSelectorSpec{
+				Namespace:            "testns",
+				MinCPUUtilization:    80,
+				MinMemoryUtilization: 90,
+			},
+			expected: `all pods in ns "testns" using more than 80% of requested cpu and 90% of requested memory`,
+		},
+		{
+			name: "Exclude disrupted",
+			selector: PodSelectorSpec{
+				Namespace:        "testns",
+				Select:           PodAttributes{map[string]string{"foo": "bar"}},
+				ExcludeDisrupted: true,
+			},
+			expected: `pods including(foo=bar) in ns "testns" not under disruption`,
+		},
+		{
+			name: "Label relations",
+			selector: PodSelectorSpec{
+				Namespace: "testns",
+				LabelRelations: []LabelRelation{
+					{Label: "shard", OtherLabel: "primary-shard"},
+					{Label: "zone", Operator: LabelRelationNotEquals, OtherLabel: "primary-zone"},
+				},
+			},
+			expected: `all pods in ns "testns" with labels shard==primary-shard, zone!=primary-zone`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			output := tc.selector.String()
+			if tc.expected != output {
+				t.Fatalf("expected string does not match output string:\n%s\n%s", tc.expected, output)
+			}
+		})
+	}
+}
+
+// rolloutReplicaSets returns the old and new replica sets of a deployment during a rollout
+func rolloutReplicaSets() []appsv1.ReplicaSet {
+	replicaSets := []appsv1.ReplicaSet{}
+	for _, version := range []string{"v1", "v2"} {
+		labels := map[string]string{"app": "api", "pod-template-hash": version}
+		replicaSets = append(replicaSets, appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "api-" + version,
+				Namespace: "test-ns",
+				UID:       types.UID("uid-api-" + version),
+				Labels:    labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", UID: "uid-api"},
+				},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+			},
+		})
+	}
+
+	return replicaSets
+}
+
+// rolloutDeployment returns the deployment of the replica sets returned by rolloutReplicaSets
+func rolloutDeployment() appsv1.Deployment {
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "test-ns",
+			UID:       types.UID("uid-api"),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		},
+	}
+}
+
+// rolloutPods returns the pods of the replica sets returned by rolloutReplicaSets, and a pod that
+// matches the labels of the old replica set but is not owned by it
+func rolloutPods() []corev1.Pod {
+	pods := []corev1.Pod{}
+	for _, version := range []string{"v1", "v2"} {
+		for _, suffix := range []string{"a", "b"} {
+			pods = append(pods, builders.NewPodBuilder("api-"+version+"-"+suffix).
+				WithNamespace("test-ns").
+				WithLabel("app", "api").
+				WithLabel("pod-template-hash", version).
+				WithOwner("ReplicaSet", "api-"+version, types.UID("uid-api-"+version)).
+				Build(),
+			)
+		}
+	}
+
+	pods = append(pods, builders.NewPodBuilder("orphan").
+		WithNamespace("test-ns").
+		WithLabel("app", "api").
+		WithLabel("pod-template-hash", "v1").
+		Build(),
+	)
+
+	return pods
+}
+
+// utilizationPods returns pods with known resource requests, and the usage of these pods as reported by the
+// metrics-server. Pod "cpu-high" uses 90% of its requested cpu, pod "memory-high" uses 95% of its requested memory,
+// pod "no-requests" does not request resources and pod "no-usage" does not have its usage reported.
+func utilizationPods() ([]corev1.Pod, map[string]corev1.ResourceList) {
+	names := []string{"cpu-high", "memory-high", "no-requests", "no-usage"}
+	pods := []corev1.Pod{}
+	for _, name := range names {
+		container := builders.NewContainerBuilder("app")
+		if name != "no-requests" {
+			container = container.
+				WithResourceRequest(corev1.ResourceCPU, "100m").
+				WithResourceRequest(corev1.ResourceMemory, "100Mi")
+		}
+
+		pods = append(pods, builders.NewPodBuilder(name).
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			WithContainer(container.Build()).
+			Build())
+	}
+
+	usage := map[string]corev1.ResourceList{
+		"cpu-high": {
+			corev1.ResourceCPU:    resource.MustParse("90m"),
+			corev1.ResourceMemory: resource.MustParse("20Mi"),
+		},
+		"memory-high": {
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+			corev1.ResourceMemory: resource.MustParse("95Mi"),
+		},
+		"no-requests": {
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("500Mi"),
+		},
+	}
+
+	return pods, usage
+}
+
+// disruptedPods returns a pod where the disruptor agent is running, a pod where the agent has terminated and
+// a pod without the agent
+func disruptedPods() []corev1.Pod {
+	pods := []corev1.Pod{}
+	for _, name := range []string{"running-agent", "terminated-agent", "no-agent"} {
+		pods = append(pods, builders.NewPodBuilder(name).
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			Build())
+	}
+
+	agent := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "xk6-agent"},
+	}
+
+	pods[0].Spec.EphemeralContainers = []corev1.EphemeralContainer{agent}
+	pods[0].Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name:  "xk6-agent",
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		},
+	}
+
+	pods[1].Spec.EphemeralContainers = []corev1.EphemeralContainer{agent}
+	pods[1].Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name:  "xk6-agent",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+		},
+	}
+
+	return pods
+}
+
+func Test_PodSelectorTargets(t *testing.T) {
+	t.Parallel()
+
+	pods, usage := utilizationPods()
+
+	testCases := []struct {
+		title       string
+		namespace   string
+		pods        []corev1.Pod
+		nodes       []corev1.Node
+		replicaSets []appsv1.ReplicaSet
+		deployments []appsv1.Deployment
+		usage       map[string]corev1.ResourceList
+		metricsErr  error
+		// name of the agent container. If empty, the default is used
+		agentContainer string
+		spec           PodSelectorSpec
+		expectError    bool
+		expected       []string
+	}{
+		{
+			title:     "matching pods",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("pod-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+			},
+			expectError: false,
+			expected:    []string{"pod-1"},
+		},
+		{
+			title:     "excluded pods",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("stable-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					WithLabel("track", "stable").
+					Build(),
+				builders.NewPodBuilder("stable-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					Build(),
+				builders.NewPodBuilder("canary").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					WithLabel("track", "canary").
+					Build(),
+				builders.NewPodBuilder("other-app").
+					WithNamespace("test-ns").
+					WithLabel("app", "other").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "api",
+				}},
+				Exclude: PodAttributes{Labels: map[string]string{
+					"track": "canary",
+				}},
+			},
+			expectError: false,
+			expected:    []string{"stable-1", "stable-2"},
+		},
+		{
+			title:     "pods matching any exclusion label",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("stable").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					WithLabel("track", "stable").
+					WithLabel("env", "prod").
+					Build(),
+				builders.NewPodBuilder("canary").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					WithLabel("track", "canary").
+					WithLabel("env", "prod").
+					Build(),
+				builders.NewPodBuilder("dev").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					WithLabel("track", "stable").
+					WithLabel("env", "dev").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "api",
+				}},
+				Exclude: PodAttributes{Labels: map[string]string{
+					"track": "canary",
+					"env":   "dev",
+				}},
+			},
+			expectError: false,
+			expected:    []string{"stable"},
+		},
+		{
+			title:     "exclusion takes precedence over selection",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("canary").
+					WithNamespace("test-ns").
+					WithLabel("app", "api").
+					WithLabel("track", "canary").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"track": "canary",
+				}},
+				Exclude: PodAttributes{Labels: map[string]string{
+					"track": "canary",
+				}},
+			},
+			expectError: true,
+		},
+		{
+			title:       "pods owned by replica set",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			replicaSets: rolloutReplicaSets(),
+			spec: PodSelectorSpec{
+				Namespace:  "test-ns",
+				ReplicaSet: "api-v1",
+			},
+			expectError: false,
+			expected:    []string{"api-v1-a", "api-v1-b"},
+		},
+		{
+			title:       "pods owned by replica set matching labels",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			replicaSets: rolloutReplicaSets(),
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "api",
+				}},
+				ReplicaSet: "api-v2",
+			},
+			expectError: false,
+			expected:    []string{"api-v2-a", "api-v2-b"},
+		},
+		{
+			title:       "replica set and labels do not match",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			replicaSets: rolloutReplicaSets(),
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"pod-template-hash": "v2",
+				}},
+				ReplicaSet: "api-v1",
+			},
+			expectError: true,
+		},
+		{
+			title:       "replica set does not exist",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			replicaSets: rolloutReplicaSets(),
+			spec: PodSelectorSpec{
+				Namespace:  "test-ns",
+				ReplicaSet: "api-v3",
+			},
+			expectError: true,
+		},
+		{
+			title:     "pods of deployment",
+			namespace: "test-ns",
+			pods: append(
+				rolloutPods(),
+				builders.NewPodBuilder("web").WithNamespace("test-ns").WithLabel("app", "web").Build(),
+			),
+			deployments: []appsv1.Deployment{rolloutDeployment()},
+			spec: PodSelectorSpec{
+				Namespace:  "test-ns",
+				Deployment: "api",
+			},
+			expectError: false,
+			expected:    []string{"api-v1-a", "api-v1-b", "api-v2-a", "api-v2-b", "orphan"},
+		},
+		{
+			title:       "pods of deployment matching labels",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			deployments: []appsv1.Deployment{rolloutDeployment()},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"pod-template-hash": "v2",
+				}},
+				Deployment: "api",
+			},
+			expectError: false,
+			expected:    []string{"api-v2-a", "api-v2-b"},
+		},
+		{
+			title:       "deployment and labels do not match",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			deployments: []appsv1.Deployment{rolloutDeployment()},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "web",
+				}},
+				Deployment: "api",
+			},
+			expectError: true,
+		},
+		{
+			title:       "deployment does not exist",
+			namespace:   "test-ns",
+			pods:        rolloutPods(),
+			deployments: []appsv1.Deployment{rolloutDeployment()},
+			spec: PodSelectorSpec{
+				Namespace:  "test-ns",
+				Deployment: "web",
+			},
+			expectError: true,
+		},
+		{
+			title:     "pods started within max age",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("recent").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithStartTime(time.Now().Add(-10 * time.Second)).
+					Build(),
+				builders.NewPodBuilder("old").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithStartTime(time.Now().Add(-1 * time.Hour)).
+					Build(),
+				builders.NewPodBuilder("recently-ready").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithStartTime(time.Now().Add(-1*time.Hour)).
+					WithCondition(corev1.PodReady, corev1.ConditionTrue, time.Now().Add(-5*time.Second)).
+					Build(),
+				builders.NewPodBuilder("not-started").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				MaxAge: time.Minute,
+			},
+			expectError: false,
+			expected:    []string{"recent", "recently-ready"},
+		},
+		{
+			title:     "no pods started within max age",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("old").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithStartTime(time.Now().Add(-1 * time.Hour)).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				MaxAge: time.Minute,
+			},
+			expectError: true,
+		},
+		{
+			title:     "pods on nodes with conditions",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("disk-pressure").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-1").
+					Build(),
+				builders.NewPodBuilder("memory-pressure").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-2").
+					Build(),
+				builders.NewPodBuilder("memory-pressure-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-2").
+					Build(),
+				builders.NewPodBuilder("healthy").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-3").
+					Build(),
+				builders.NewPodBuilder("not-scheduled").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+			},
+			nodes: []corev1.Node{
+				builders.NewNodeBuilder("node-1").
+					WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue).
+					WithCondition(corev1.NodeMemoryPressure, corev1.ConditionFalse).
+					Build(),
+				builders.NewNodeBuilder("node-2").
+					WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse).
+					WithCondition(corev1.NodeMemoryPressure, corev1.ConditionTrue).
+					Build(),
+				builders.NewNodeBuilder("node-3").
+					WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse).
+					WithCondition(corev1.NodeMemoryPressure, corev1.ConditionFalse).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				NodeConditions: []string{"MemoryPressure"},
+			},
+			expectError: false,
+			expected:    []string{"memory-pressure", "memory-pressure-2"},
+		},
+		{
+			title:     "pods on nodes with any of the conditions",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("disk-pressure").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-1").
+					Build(),
+				builders.NewPodBuilder("healthy").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-2").
+					Build(),
+			},
+			nodes: []corev1.Node{
+				builders.NewNodeBuilder("node-1").
+					WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue).
+					Build(),
+				builders.NewNodeBuilder("node-2").
+					WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				NodeConditions: []string{"MemoryPressure", "DiskPressure"},
+			},
+			expectError: false,
+			expected:    []string{"disk-pressure"},
+		},
+		{
+			title:     "node does not exist",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("pod-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithNodeName("node-1").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				NodeConditions: []string{"DiskPressure"},
+			},
+			expectError: true,
+		},
+		{
+			title:     "pods with equal label values",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("primary").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("shard", "1").
+					WithLabel("primary-shard", "1").
+					Build(),
+				builders.NewPodBuilder("replica").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("shard", "2").
+					WithLabel("primary-shard", "1").
+					Build(),
+				builders.NewPodBuilder("no-shard").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("primary-shard", "1").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				LabelRelations: []LabelRelation{
+					{Label: "shard", Operator: LabelRelationEquals, OtherLabel: "primary-shard"},
+				},
+			},
+			expectError: false,
+			expected:    []string{"primary"},
+		},
+		{
+			title:     "pods with different label values",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("primary").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("shard", "1").
+					WithLabel("primary-shard", "1").
+					Build(),
+				builders.NewPodBuilder("replica").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("shard", "2").
+					WithLabel("primary-shard", "1").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				LabelRelations: []LabelRelation{
+					{Label: "shard", Operator: LabelRelationNotEquals, OtherLabel: "primary-shard"},
+				},
+			},
+			expectError: false,
+			expected:    []string{"replica"},
+		},
+		{
+			title:     "no pods satisfy label relation",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("replica").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("shard", "2").
+					WithLabel("primary-shard", "1").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				LabelRelations: []LabelRelation{
+					{Label: "shard", OtherLabel: "primary-shard"},
+				},
+			},
+			expectError: true,
+			expected:    nil,
+		},
+		{
+			title:     "leader by annotation",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("controller-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+				builders.NewPodBuilder("controller-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "leader").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+				builders.NewPodBuilder("controller-3").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "follower").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				Leader: true,
+			},
+			expectError: false,
+			expected:    []string{"controller-2"},
+		},
+		{
+			title:     "leader by label",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("controller-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+				builders.NewPodBuilder("controller-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "leader").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+				builders.NewPodBuilder("controller-3").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "follower").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				Leader:      true,
+				LeaderLabel: "role=leader",
+			},
+			expectError: false,
+			expected:    []string{"controller-2"},
+		},
+		{
+			title:     "multiple leaders",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("controller-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+				builders.NewPodBuilder("controller-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "leader").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+				builders.NewPodBuilder("controller-3").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "follower").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				Leader:      true,
+				LeaderLabel: "role",
+			},
+			expectError: true,
+		},
+		{
+			title:     "no leader",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("controller-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+				builders.NewPodBuilder("controller-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "leader").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+				builders.NewPodBuilder("controller-3").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("role", "follower").
+					WithAnnotation(DefaultLeaderAnnotation, `{"holderIdentity":"controller-2_5f3a"}`).
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				Leader:           true,
+				LeaderAnnotation: "example.com/leader",
+			},
+			expectError: true,
+		},
 		{
-			name:     "Empty selector",
-			expected: `all pods in ns "default"`,
+			title:     "exclude disrupted pods",
+			namespace: "test-ns",
+			pods:      disruptedPods(),
+			spec: PodSelectorSpec{
+				Namespace:        "test-ns",
+				Select:           PodAttributes{Labels: map[string]string{"app": "test"}},
+				ExcludeDisrupted: true,
+			},
+			expectError: false,
+			expected:    []string{"no-agent"},
 		},
 		{
-			name: "Only inclusions",
-			selector: PodSelectorSpec{
-				Namespace: "testns",
-				Select:    PodAttributes{map[string]string{"foo": "bar"}},
+			title:          "exclude pods disrupted by agents in another container",
+			namespace:      "test-ns",
+			pods:           disruptedPods(),
+			agentContainer: "chaos-agent",
+			spec: PodSelectorSpec{
+				Namespace:        "test-ns",
+				Select:           PodAttributes{Labels: map[string]string{"app": "test"}},
+				ExcludeDisrupted: true,
 			},
-			expected: `pods including(foo=bar) in ns "testns"`,
+			expectError: false,
+			expected:    []string{"no-agent", "running-agent", "terminated-agent"},
 		},
 		{
-			name: "Only exclusions",
-			selector: PodSelectorSpec{
-				Namespace: "testns",
-				Exclude:   PodAttributes{map[string]string{"foo": "bar"}},
+			title:     "include disrupted pods",
+			namespace: "test-ns",
+			pods:      disruptedPods(),
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select:    PodAttributes{Labels: map[string]string{"app": "test"}},
 			},
-			expected: `pods excluding(foo=bar) in ns "testns"`,
+			expectError: false,
+			expected:    []string{"no-agent", "running-agent", "terminated-agent"},
 		},
 		{
-			name: "Both inclusions and exclusions",
-			selector: PodSelectorSpec{
-				Namespace: "testns",
-				Select:    PodAttributes{map[string]string{"foo": "bar"}},
-				Exclude:   PodAttributes{map[string]string{"boo": "baa"}},
+			title:     "pods above cpu utilization",
+			namespace: "test-ns",
+			pods:      pods,
+			usage:     usage,
+			spec: PodSelectorSpec{
+				Namespace:         "test-ns",
+				MinCPUUtilization: 80,
 			},
-			expected: `pods including(foo=bar), excluding(boo=baa) in ns "testns"`,
+			expectError: false,
+			expected:    []string{"cpu-high"},
 		},
-	} {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-
-			output := tc.selector.String()
-			if tc.expected != output {
-				t.Fatalf("expected string does not match output string:\n%s\n%s", tc.expected, output)
-			}
-		})
-	}
-}
-
-func Test_PodSelectorTargets(t *testing.T) {
-	t.Parallel()
-
-	testCases := []struct {
-		title       string
-		namespace   string
-		pods        []corev1.Pod
-		spec        PodSelectorSpec
-		expectError bool
-		expected    []string
-	}{
 		{
-			title:     "matching pods",
+			title:     "pods above memory utilization",
+			namespace: "test-ns",
+			pods:      pods,
+			usage:     usage,
+			spec: PodSelectorSpec{
+				Namespace:            "test-ns",
+				MinMemoryUtilization: 80,
+			},
+			expectError: false,
+			expected:    []string{"memory-high"},
+		},
+		{
+			title:     "pods above low cpu utilization",
+			namespace: "test-ns",
+			pods:      pods,
+			usage:     usage,
+			spec: PodSelectorSpec{
+				Namespace:         "test-ns",
+				MinCPUUtilization: 5,
+			},
+			expectError: false,
+			expected:    []string{"cpu-high", "memory-high"},
+		},
+		{
+			title:     "no pods above cpu and memory utilization",
+			namespace: "test-ns",
+			pods:      pods,
+			usage:     usage,
+			spec: PodSelectorSpec{
+				Namespace:            "test-ns",
+				MinCPUUtilization:    80,
+				MinMemoryUtilization: 80,
+			},
+			expectError: true,
+		},
+		{
+			title:      "metrics not available",
+			namespace:  "test-ns",
+			pods:       pods,
+			metricsErr: errors.New("the server could not find the requested resource"),
+			spec: PodSelectorSpec{
+				Namespace:         "test-ns",
+				MinCPUUtilization: 80,
+			},
+			expectError: true,
+		},
+		{
+			title:     "pods sharing required label",
 			namespace: "test-ns",
 			pods: []corev1.Pod{
 				builders.NewPodBuilder("pod-1").
 					WithNamespace("test-ns").
 					WithLabel("app", "test").
+					WithLabel("team", "payments").
+					Build(),
+				builders.NewPodBuilder("pod-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("team", "payments").
 					Build(),
 			},
 			spec: PodSelectorSpec{
@@ -145,9 +1083,57 @@ func Test_PodSelectorTargets(t *testing.T) {
 				Select: PodAttributes{Labels: map[string]string{
 					"app": "test",
 				}},
+				RequireCommonLabel: map[string]string{"team": "payments"},
 			},
 			expectError: false,
-			expected:    []string{"pod-1"},
+			expected:    []string{"pod-1", "pod-2"},
+		},
+		{
+			title:     "pods with divergent required label",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("pod-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("team", "payments").
+					Build(),
+				builders.NewPodBuilder("pod-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("team", "checkout").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				RequireCommonLabel: map[string]string{"team": "payments"},
+			},
+			expectError: true,
+		},
+		{
+			title:     "pods missing required label",
+			namespace: "test-ns",
+			pods: []corev1.Pod{
+				builders.NewPodBuilder("pod-1").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					WithLabel("team", "payments").
+					Build(),
+				builders.NewPodBuilder("pod-2").
+					WithNamespace("test-ns").
+					WithLabel("app", "test").
+					Build(),
+			},
+			spec: PodSelectorSpec{
+				Namespace: "test-ns",
+				Select: PodAttributes{Labels: map[string]string{
+					"app": "test",
+				}},
+				RequireCommonLabel: map[string]string{"team": "payments"},
+			},
+			expectError: true,
 		},
 		{
 			title:     "no matching pods",
@@ -174,11 +1160,32 @@ func Test_PodSelectorTargets(t *testing.T) {
 			for p := range tc.pods {
 				objs = append(objs, &tc.pods[p])
 			}
+			for n := range tc.nodes {
+				objs = append(objs, &tc.nodes[n])
+			}
+			for r := range tc.replicaSets {
+				objs = append(objs, &tc.replicaSets[r])
+			}
+			for d := range tc.deployments {
+				objs = append(objs, &tc.deployments[d])
+			}
 
 			client := fake.NewSimpleClientset(objs...)
 			k, _ := kubernetes.NewFakeKubernetes(client)
+			for pod, resources := range tc.usage {
+				k.GetFakePodMetrics().SetPodUsage(pod, resources)
+			}
+			k.GetFakePodMetrics().SetError(tc.metricsErr)
 
-			s, err := NewPodSelector(tc.spec, k.PodHelper(tc.namespace))
+			s, err := NewPodSelector(
+				tc.spec,
+				k.PodHelper(tc.namespace),
+				k.NodeHelper(),
+				k.ReplicaSetHelper(tc.namespace),
+				k.DeploymentHelper(tc.namespace),
+				k.PodMetricsHelper(tc.namespace),
+				tc.agentContainer,
+			)
 			if err != nil {
 				t.Fatalf("failed%v", err)
 			}
@@ -300,6 +1307,7 @@ func Test_ServicePodSelectorTargets(t *testing.T) {
 				tc.name,
 				tc.namespace,
 				k.ServiceHelper(tc.namespace),
+				ServicePodSelectorOptions{},
 			)
 			if err != nil {
 				t.Fatalf("failed%v", err)
@@ -330,3 +1338,171 @@ func Test_ServicePodSelectorTargets(t *testing.T) {
 		})
 	}
 }
+
+func Test_ServicePodSelectorReadyEndpoints(t *testing.T) {
+	t.Parallel()
+
+	ready := []string{}
+	notReady := []string{}
+	objs := []runtime.Object{
+		builders.NewServiceBuilder("test-svc").
+			WithNamespace("test-ns").
+			WithSelectorLabel("app", "test").
+			WithPort("http", 80, intstr.FromInt(80)).
+			BuildAsPtr(),
+	}
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("pod-%d", i)
+		if i < 10 {
+			ready = append(ready, name)
+		} else {
+			notReady = append(notReady, name)
+		}
+
+		pod := builders.NewPodBuilder(name).
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			Build()
+		objs = append(objs, &pod)
+	}
+	objs = append(objs, builders.NewEndPointsBuilder("test-svc").
+		WithNamespace("test-ns").
+		WithSubset("http", 80, ready).
+		WithNotReadyAddresses("http", 80, notReady).
+		BuildAsPtr(),
+	)
+
+	client := fake.NewSimpleClientset(objs...)
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	testCases := []struct {
+		title       string
+		percentage  uint
+		expectError bool
+		expected    int
+	}{
+		{
+			title:      "all pods",
+			percentage: 0,
+			expected:   12,
+		},
+		{
+			title:      "all ready endpoints",
+			percentage: 100,
+			expected:   10,
+		},
+		{
+			title:      "percentage of ready endpoints",
+			percentage: 30,
+			expected:   3,
+		},
+		{
+			title:      "at least one endpoint",
+			percentage: 1,
+			expected:   1,
+		},
+		{
+			title:       "invalid percentage",
+			percentage:  150,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := NewServicePodSelector(
+				"test-svc",
+				"test-ns",
+				k.ServiceHelper("test-ns"),
+				ServicePodSelectorOptions{ReadyEndpointsPercentage: tc.percentage},
+			)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			targets, err := s.Targets(context.TODO())
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(targets) != tc.expected {
+				t.Fatalf("expected %d targets got %d", tc.expected, len(targets))
+			}
+
+			if tc.percentage == 0 {
+				return
+			}
+
+			targetNames := utils.PodNames(targets)
+			for _, name := range targetNames {
+				if slices.Contains(notReady, name) {
+					t.Fatalf("pod %q is not ready but was selected", name)
+				}
+			}
+
+			// selection must be deterministic
+			again, err := s.Targets(context.TODO())
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if diff := cmp.Diff(targetNames, utils.PodNames(again)); diff != "" {
+				t.Fatalf("selection is not deterministic\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ServicePodSelectorNoReadyEndpoints(t *testing.T) {
+	t.Parallel()
+
+	notReady := []string{"pod-1", "pod-2"}
+	objs := []runtime.Object{
+		builders.NewServiceBuilder("test-svc").
+			WithNamespace("test-ns").
+			WithSelectorLabel("app", "test").
+			WithPort("http", 80, intstr.FromInt(80)).
+			BuildAsPtr(),
+		builders.NewEndPointsBuilder("test-svc").
+			WithNamespace("test-ns").
+			WithNotReadyAddresses("http", 80, notReady).
+			BuildAsPtr(),
+	}
+	for _, name := range notReady {
+		pod := builders.NewPodBuilder(name).
+			WithNamespace("test-ns").
+			WithLabel("app", "test").
+			Build()
+		objs = append(objs, &pod)
+	}
+
+	client := fake.NewSimpleClientset(objs...)
+	k, _ := kubernetes.NewFakeKubernetes(client)
+
+	s, err := NewServicePodSelector(
+		"test-svc",
+		"test-ns",
+		k.ServiceHelper("test-ns"),
+		ServicePodSelectorOptions{ReadyEndpointsPercentage: 50},
+	)
+	if err != nil {
+		t.Fatalf("failed: %v", err)
+	}
+
+	_, err = s.Targets(context.TODO())
+	if !errors.Is(err, ErrServiceNoTargets) {
+		t.Fatalf("expected error %v got %v", ErrServiceNoTargets, err)
+	}
+}
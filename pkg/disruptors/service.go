@@ -2,14 +2,17 @@ package disruptors
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
 	"github.com/grafana/xk6-disruptor/pkg/utils"
 
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -18,6 +21,10 @@ type ServiceDisruptor interface {
 	Disruptor
 	ProtocolFaultInjector
 	PodFaultInjector
+	PortFaultInjector
+	Prober
+	ResolvedFaultsReporter
+	WarningsReporter
 }
 
 // ServiceDisruptorOptions defines options that controls the behavior of the ServiceDisruptor
@@ -25,14 +32,64 @@ type ServiceDisruptorOptions struct {
 	// timeout when waiting agent to be injected (default 30s). A zero value forces default.
 	// A Negative value forces no waiting.
 	InjectTimeout time.Duration `js:"injectTimeout"`
+	// timeout when waiting the agent to respond to a ping once its container is running (default 10s).
+	// A zero value forces default. A negative value forces no waiting.
+	AgentStartupTimeout time.Duration `js:"agentStartupTimeout"`
+	// number of times the injection of the agent in a target is retried if it fails, for example because the
+	// API server is busy. Zero means no retries.
+	InjectRetries uint `js:"injectRetries"`
+	// time to wait before the first retry of the injection of the agent (default 1s). It is doubled after
+	// each retry. A zero value forces default.
+	InjectBackoff time.Duration `js:"injectBackoff"`
+	// fail the injection of the agent as soon as its image cannot be pulled, instead of waiting
+	// for the InjectTimeout to expire.
+	FailOnImagePullError bool `js:"failOnImagePullError"`
+	// maximum duration of the faults. Longer durations are capped to this value. Zero means no limit.
+	MaxDuration time.Duration `js:"maxDuration"`
+	// maximum number of targets the fault command is executed in concurrently. The injection of the agent
+	// is not limited by this option. Zero means no limit.
+	MaxConcurrency uint `js:"maxConcurrency"`
+	// image of the agent injected in the targets, for example from a private registry. If empty, the
+	// image matching the version of the disruptor is used.
+	AgentImage string `js:"agentImage"`
+	// name of the ephemeral container of the agent injected in the targets (default "xk6-agent"). Disruptors
+	// using different names inject separate agents, so they do not interfere when disrupting the same targets.
+	AgentContainerName string `js:"agentContainerName"`
+	// percentage of the ready endpoints of the service to inject faults into. Endpoints are selected
+	// deterministically by their address. Zero means all the pods backing the service.
+	ReadyEndpointsPercentage uint `js:"readyEndpointsPercentage"`
+	// name of the port of the service (e.g. "http") the HTTP faults are injected into when the fault does not
+	// specify a port. Allows targeting services that expose more than one port.
+	TargetPort string `js:"targetPort"`
+	// check the namespace of the service exists when the disruptor is created, failing with an error that
+	// names the namespace instead of reporting the service as not found.
+	CheckNamespace bool `js:"checkNamespace"`
+	// number or percentage (e.g. "25%") of the pods backing the service the faults are injected into, chosen
+	// randomly after resolving the endpoints of the service. A percentage selects at least one pod. Empty means
+	// all the pods.
+	Sample intstr.IntOrString `js:"sample"`
+	// seed for the random selection of the Sample of the pods, for reproducible disruptions. Zero means a
+	// random seed, chosen when the disruptor is created.
+	SampleSeed int64 `js:"sampleSeed"`
+	// overrides of the security context of the agent injected in the targets, for example for complying with the
+	// Pod Security Admission policy of their namespace. Network faults require the NET_ADMIN capability.
+	AgentSecurityContext AgentSecurityContext `js:"agentSecurityContext"`
 }
 
+// ErrNamespaceNotFound is returned by NewServiceDisruptor when CheckNamespace is set and the namespace of the
+// service does not exist
+var ErrNamespaceNotFound = errors.New("namespace not found")
+
 // serviceDisruptor is an instance of a ServiceDisruptor
 type serviceDisruptor struct {
 	service  corev1.Service
 	helper   helpers.PodHelper
 	selector *ServicePodSelector
 	options  ServiceDisruptorOptions
+	resolved resolvedFaultsLog
+	warnings warningsLog
+	// seed of the random selection of the sample of the targets
+	sampleSeed int64
 }
 
 // NewServiceDisruptor creates a new instance of a ServiceDisruptor that targets the given service
@@ -42,6 +99,36 @@ func NewServiceDisruptor(
 	service string,
 	namespace string,
 	options ServiceDisruptorOptions,
+) (ServiceDisruptor, error) {
+	return newServiceDisruptor(ctx, k8s, service, namespace, nil, options)
+}
+
+// NewServiceDisruptorWithEndpoints creates a new instance of a ServiceDisruptor that targets the pods referenced
+// by the given endpoints of the service, which must have been retrieved by the caller. This avoids looking up the
+// pods matching the selector of the service.
+func NewServiceDisruptorWithEndpoints(
+	ctx context.Context,
+	k8s kubernetes.Kubernetes,
+	service string,
+	namespace string,
+	endpoints *corev1.Endpoints,
+	options ServiceDisruptorOptions,
+) (ServiceDisruptor, error) {
+	if endpoints == nil {
+		return nil, fmt.Errorf("must specify the endpoints of the service")
+	}
+
+	return newServiceDisruptor(ctx, k8s, service, namespace, endpoints, options)
+}
+
+// newServiceDisruptor creates a ServiceDisruptor that resolves its targets from the endpoints, if not nil
+func newServiceDisruptor(
+	ctx context.Context,
+	k8s kubernetes.Kubernetes,
+	service string,
+	namespace string,
+	endpoints *corev1.Endpoints,
+	options ServiceDisruptorOptions,
 ) (ServiceDisruptor, error) {
 	if service == "" {
 		return nil, fmt.Errorf("must specify a service name")
@@ -51,22 +138,91 @@ func NewServiceDisruptor(
 		return nil, fmt.Errorf("must specify a namespace")
 	}
 
+	if options.CheckNamespace {
+		_, err := k8s.Client().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf(
+				"namespace %q of service %q must be created before the disruptor: %w",
+				namespace,
+				service,
+				ErrNamespaceNotFound,
+			)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("checking namespace %q: %w", namespace, err)
+		}
+	}
+
 	svc, err := k8s.Client().CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 
-	selector, err := NewServicePodSelector(service, namespace, k8s.ServiceHelper(namespace))
+	if err = validateSample(options.Sample); err != nil {
+		return nil, err
+	}
+
+	if err = options.AgentSecurityContext.validate(); err != nil {
+		return nil, err
+	}
+
+	if options.TargetPort != "" {
+		if _, err = utils.GetTargetPort(*svc, intstr.FromString(options.TargetPort)); err != nil {
+			return nil, err
+		}
+	}
+
+	selector, err := NewServicePodSelector(
+		service,
+		namespace,
+		k8s.ServiceHelper(namespace),
+		ServicePodSelectorOptions{
+			ReadyEndpointsPercentage: options.ReadyEndpointsPercentage,
+			Endpoints:                endpoints,
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &serviceDisruptor{
-		service:  *svc,
-		helper:   k8s.PodHelper(namespace),
-		selector: selector,
-		options:  options,
-	}, nil
+	d := &serviceDisruptor{
+		service:    *svc,
+		helper:     k8s.PodHelper(namespace),
+		selector:   selector,
+		options:    options,
+		sampleSeed: options.SampleSeed,
+	}
+
+	// the same seed is used in all the selections, so the sample does not change while the targets do not change
+	if d.sampleSeed == 0 {
+		d.sampleSeed = time.Now().UnixNano()
+	}
+
+	return d, nil
+}
+
+// targets returns the pods backing the service. If a Sample is set, only the sampled pods are returned.
+func (d *serviceDisruptor) targets(ctx context.Context) ([]corev1.Pod, error) {
+	targets, err := d.selector.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.options.Sample.IsNull() {
+		return targets, nil
+	}
+
+	return samplePods(targets, d.options.Sample, d.sampleSeed)
+}
+
+// faultPort returns the port of the service a fault is injected into: the port of the fault or, if not
+// specified, the TargetPort option
+func (d *serviceDisruptor) faultPort(port intstr.IntOrString) intstr.IntOrString {
+	if (port.IsNull() || port.IsZero()) && d.options.TargetPort != "" {
+		return intstr.FromString(d.options.TargetPort)
+	}
+
+	return port
 }
 
 func (d *serviceDisruptor) InjectHTTPFaults(
@@ -74,35 +230,81 @@ func (d *serviceDisruptor) InjectHTTPFaults(
 	fault HTTPFault,
 	duration time.Duration,
 	options HTTPDisruptionOptions,
-) error {
-	// Map service port to a target pod port
-	port, err := utils.GetTargetPort(d.service, fault.Port)
-	if err != nil {
+) (err error) {
+	ctx, span := startSpan(ctx, "ServiceDisruptor.InjectHTTPFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
 		return err
 	}
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	// the fault commands of the service disruptor are not given an exec timeout
+	if err = options.validate(duration, 0); err != nil {
+		return err
+	}
+
+	// the ports of the service are not the ports the targets send requests to
+	if options.Direction == DirectionEgress {
+		return fmt.Errorf("egress faults cannot be injected in a service")
+	}
+
+	if err = fault.validateWindows(duration); err != nil {
+		return err
+	}
+
+	if len(fault.Ports) > 0 && options.ProxyPort != 0 {
+		return fmt.Errorf("proxy port cannot be specified when the fault has multiple ports")
+	}
+
 	podFault := fault
-	podFault.Port = port
+	if len(fault.Ports) > 0 {
+		// Map each service port to a target pod port
+		podFault.Ports = make([]intstr.IntOrString, 0, len(fault.Ports))
+		for _, faultPort := range fault.Ports {
+			port, portErr := utils.GetTargetPort(d.service, faultPort)
+			if portErr != nil {
+				return portErr
+			}
+			podFault.Ports = append(podFault.Ports, port)
+		}
+	} else {
+		// Map service port to a target pod port
+		podFault.Port, err = utils.GetTargetPort(d.service, d.faultPort(fault.Port))
+		if err != nil {
+			return err
+		}
+	}
 
 	command := PodHTTPFaultCommand{
 		fault:    podFault,
-		duration: duration,
+		duration: capDuration(duration, d.options.MaxDuration),
 		options:  options,
 	}
 
 	visitor := NewPodAgentVisitor(
 		d.helper,
-		PodAgentVisitorOptions{Timeout: d.options.InjectTimeout},
+		d.visitorOptions(),
 		command,
 	)
 
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return err
 	}
 
+	span.SetAttributes(targetsAttribute(targets))
+
 	controller := NewPodController(targets)
 
-	return controller.Visit(ctx, visitor)
+	err = controller.Visit(ctx, visitor)
+	d.resolved.set(visitor.ResolvedFaults())
+	d.warnings.set(injectionWarnings(targets, durationWarning(duration, d.options.MaxDuration), visitor.Warnings()))
+
+	return err
 }
 
 func (d *serviceDisruptor) InjectGrpcFaults(
@@ -110,7 +312,28 @@ func (d *serviceDisruptor) InjectGrpcFaults(
 	fault GrpcFault,
 	duration time.Duration,
 	options GrpcDisruptionOptions,
-) error {
+) (err error) {
+	ctx, span := startSpan(ctx, "ServiceDisruptor.InjectGrpcFaults", faultAttributes(fault, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	if err = fault.validate(); err != nil {
+		return err
+	}
+
+	// the fault commands of the service disruptor are not given an exec timeout
+	if err = options.validate(duration, 0); err != nil {
+		return err
+	}
+
+	// the ports of the service are not the ports the targets send requests to
+	if options.Direction == DirectionEgress {
+		return fmt.Errorf("egress faults cannot be injected in a service")
+	}
+
 	// Map service port to a target pod port
 	port, err := utils.GetTargetPort(d.service, fault.Port)
 	if err != nil {
@@ -120,29 +343,118 @@ func (d *serviceDisruptor) InjectGrpcFaults(
 	podFault.Port = port
 
 	command := PodGrpcFaultCommand{
-		fault:    fault,
-		duration: duration,
+		fault:    podFault,
+		duration: capDuration(duration, d.options.MaxDuration),
 		options:  options,
 	}
 
 	visitor := NewPodAgentVisitor(
 		d.helper,
-		PodAgentVisitorOptions{Timeout: d.options.InjectTimeout},
+		d.visitorOptions(),
 		command,
 	)
 
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return err
 	}
 
+	span.SetAttributes(targetsAttribute(targets))
+
 	controller := NewPodController(targets)
 
-	return controller.Visit(ctx, visitor)
+	err = controller.Visit(ctx, visitor)
+	d.resolved.set(visitor.ResolvedFaults())
+	d.warnings.set(injectionWarnings(targets, durationWarning(duration, d.options.MaxDuration), visitor.Warnings()))
+
+	return err
+}
+
+func (d *serviceDisruptor) InjectPortFaults(
+	ctx context.Context,
+	faults []PortFault,
+	duration time.Duration,
+) (err error) {
+	ctx, span := startSpan(ctx, "ServiceDisruptor.InjectPortFaults", faultAttributes(faults, duration)...)
+	defer func() { endSpan(span, err) }()
+
+	if err = requireNetAdmin(d.options.AgentSecurityContext); err != nil {
+		return err
+	}
+
+	faults, err = validatePortFaults(faults)
+	if err != nil {
+		return err
+	}
+
+	for _, fault := range faults {
+		if fault.Protocol != ProtocolHTTP {
+			continue
+		}
+		if err = fault.HTTPFault.validateWindows(duration); err != nil {
+			return fmt.Errorf("invalid fault for port %s: %w", fault.Port.Str(), err)
+		}
+	}
+
+	podFaults, err := targetPortFaults(d.service, faults)
+	if err != nil {
+		return err
+	}
+
+	command := PodPortFaultsCommand{
+		faults:   podFaults,
+		duration: capDuration(duration, d.options.MaxDuration),
+	}
+
+	visitor := NewPodAgentVisitor(
+		d.helper,
+		d.visitorOptions(),
+		command,
+	)
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(targetsAttribute(targets))
+
+	controller := NewPodController(targets)
+
+	err = controller.Visit(ctx, visitor)
+	d.resolved.set(visitor.ResolvedFaults())
+	d.warnings.set(injectionWarnings(targets, durationWarning(duration, d.options.MaxDuration), visitor.Warnings()))
+
+	return err
+}
+
+// targetPortFaults maps the service port of each fault to the corresponding target pod port
+func targetPortFaults(service corev1.Service, faults []PortFault) ([]PortFault, error) {
+	podFaults := make([]PortFault, 0, len(faults))
+	for _, fault := range faults {
+		port, err := utils.GetTargetPort(service, fault.Port)
+		if err != nil {
+			return nil, err
+		}
+		fault.Port = port
+		podFaults = append(podFaults, fault)
+	}
+
+	return podFaults, nil
+}
+
+// ResolvedFaults returns the configuration applied to each target by the last fault injection
+func (d *serviceDisruptor) ResolvedFaults() map[string]ResolvedFault {
+	return d.resolved.get()
+}
+
+// Warnings returns the warnings of the last fault injection in each target
+func (d *serviceDisruptor) Warnings() map[string]string {
+	return d.warnings.get()
 }
 
 func (d *serviceDisruptor) Targets(ctx context.Context) ([]string, error) {
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -150,12 +462,52 @@ func (d *serviceDisruptor) Targets(ctx context.Context) ([]string, error) {
 	return utils.PodNames(targets), nil
 }
 
+func (d *serviceDisruptor) TargetsDetailed(ctx context.Context) ([]Target, error) {
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return podTargets(targets), nil
+}
+
+// visitorOptions returns the options of the visitor that injects the agent in the targets
+func (d *serviceDisruptor) visitorOptions() PodAgentVisitorOptions {
+	return PodAgentVisitorOptions{
+		Timeout:              d.options.InjectTimeout,
+		StartupTimeout:       d.options.AgentStartupTimeout,
+		InjectRetries:        d.options.InjectRetries,
+		InjectBackoff:        d.options.InjectBackoff,
+		FailOnImagePullError: d.options.FailOnImagePullError,
+		MaxConcurrency:       d.options.MaxConcurrency,
+		AgentImage:           d.options.AgentImage,
+		ContainerName:        d.options.AgentContainerName,
+		SecurityContext:      d.options.AgentSecurityContext,
+	}
+}
+
+// Probe checks the service port accepts connections in all the target pods
+func (d *serviceDisruptor) Probe(ctx context.Context, port intstr.IntOrString) error {
+	// Map service port to a target pod port
+	podPort, err := utils.GetTargetPort(d.service, port)
+	if err != nil {
+		return err
+	}
+
+	targets, err := d.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	return probeTargets(ctx, d.helper, d.visitorOptions(), targets, podPort)
+}
+
 // TerminatePods terminates a subset of the target pods of the disruptor
 func (d *serviceDisruptor) TerminatePods(
 	ctx context.Context,
 	fault PodTerminationFault,
 ) ([]string, error) {
-	targets, err := d.selector.Targets(ctx)
+	targets, err := d.targets(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -2,16 +2,24 @@ package disruptors
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sintstr "k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
 	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func Test_NewServiceDisruptor(t *testing.T) {
@@ -32,7 +40,7 @@ func Test_NewServiceDisruptor(t *testing.T) {
 			service: builders.NewServiceBuilder("test-svc").
 				WithNamespace("test-ns").
 				WithSelectorLabel("app", "test").
-				WithPort("http", 80, intstr.FromInt(80)).
+				WithPort("http", 80, k8sintstr.FromInt(80)).
 				BuildAsPtr(),
 
 			options: ServiceDisruptorOptions{
@@ -55,7 +63,7 @@ func Test_NewServiceDisruptor(t *testing.T) {
 			service: builders.NewServiceBuilder("test-svc").
 				WithNamespace("test-ns").
 				WithSelectorLabel("app", "test").
-				WithPort("http", 80, intstr.FromInt(80)).
+				WithPort("http", 80, k8sintstr.FromInt(80)).
 				BuildAsPtr(),
 			options:     ServiceDisruptorOptions{},
 			expectError: true,
@@ -67,7 +75,7 @@ func Test_NewServiceDisruptor(t *testing.T) {
 			service: builders.NewServiceBuilder("test-svc").
 				WithNamespace("test-ns").
 				WithSelectorLabel("app", "test").
-				WithPort("http", 80, intstr.FromInt(80)).
+				WithPort("http", 80, k8sintstr.FromInt(80)).
 				BuildAsPtr(),
 			options:     ServiceDisruptorOptions{},
 			expectError: true,
@@ -112,3 +120,580 @@ func Test_NewServiceDisruptor(t *testing.T) {
 		})
 	}
 }
+
+func Test_ServiceDisruptorCheckNamespace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		namespace     *corev1.Namespace
+		options       ServiceDisruptorOptions
+		expectedError error
+	}{
+		{
+			title:         "namespace exists",
+			namespace:     &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-ns"}},
+			options:       ServiceDisruptorOptions{CheckNamespace: true},
+			expectedError: nil,
+		},
+		{
+			title:         "namespace does not exist",
+			namespace:     nil,
+			options:       ServiceDisruptorOptions{CheckNamespace: true},
+			expectedError: ErrNamespaceNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objs := []runtime.Object{
+				builders.NewServiceBuilder("test-svc").
+					WithNamespace("test-ns").
+					WithSelectorLabel("app", "test").
+					WithPort("http", 80, k8sintstr.FromInt(80)).
+					BuildAsPtr(),
+			}
+			if tc.namespace != nil {
+				objs = append(objs, tc.namespace)
+			}
+
+			client := fake.NewSimpleClientset(objs...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			_, err := NewServiceDisruptor(context.TODO(), k, "test-svc", "test-ns", tc.options)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error %v got %v", tc.expectedError, err)
+			}
+
+			if tc.expectedError != nil && !strings.Contains(err.Error(), "test-ns") {
+				t.Fatalf("error does not name the namespace: %v", err)
+			}
+		})
+	}
+}
+
+func Test_NewServiceDisruptorWithEndpoints(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		endpoints       *corev1.Endpoints
+		expectError     bool
+		expectedTargets []string
+	}{
+		{
+			title: "targets from endpoints",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, []string{"pod-1", "pod-2"}).
+				WithNotReadyAddresses("http", 80, []string{"pod-3"}).
+				BuildAsPtr(),
+			expectError:     false,
+			expectedTargets: []string{"pod-1", "pod-2", "pod-3"},
+		},
+		{
+			title: "endpoints of other service",
+			endpoints: builders.NewEndPointsBuilder("other-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, []string{"pod-1"}).
+				BuildAsPtr(),
+			expectError: true,
+		},
+		{
+			title: "endpoints in other namespace",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("other-ns").
+				WithSubset("http", 80, []string{"pod-1"}).
+				BuildAsPtr(),
+			expectError: true,
+		},
+		{
+			title:       "no endpoints",
+			endpoints:   nil,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// the selector of the service does not match any pod, so the targets can only come from the endpoints
+			objs := []runtime.Object{
+				builders.NewServiceBuilder("test-svc").
+					WithNamespace("test-ns").
+					WithSelectorLabel("app", "none").
+					WithPort("http", 80, k8sintstr.FromInt(80)).
+					BuildAsPtr(),
+			}
+			for _, name := range []string{"pod-1", "pod-2", "pod-3", "pod-4"} {
+				pod := builders.NewPodBuilder(name).WithNamespace("test-ns").Build()
+				objs = append(objs, &pod)
+			}
+
+			client := fake.NewSimpleClientset(objs...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			disruptor, err := NewServiceDisruptorWithEndpoints(
+				context.TODO(),
+				k,
+				"test-svc",
+				"test-ns",
+				tc.endpoints,
+				ServiceDisruptorOptions{},
+			)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			targets, err := disruptor.Targets(context.TODO())
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			sort.Strings(targets)
+			if diff := cmp.Diff(tc.expectedTargets, targets); diff != "" {
+				t.Fatalf("expected targets do not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ServiceDisruptorSample(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		sample        intstr.IntOrString
+		expectError   bool
+		expectedCount int
+	}{
+		{
+			title:         "no sample",
+			sample:        intstr.NullValue,
+			expectError:   false,
+			expectedCount: 4,
+		},
+		{
+			title:         "count",
+			sample:        intstr.FromInt32(3),
+			expectError:   false,
+			expectedCount: 3,
+		},
+		{
+			title:         "percentage",
+			sample:        intstr.FromString("50%"),
+			expectError:   false,
+			expectedCount: 2,
+		},
+		{
+			title:         "percentage selects at least one pod",
+			sample:        intstr.FromString("10%"),
+			expectError:   false,
+			expectedCount: 1,
+		},
+		{
+			title:       "zero count",
+			sample:      intstr.FromInt32(0),
+			expectError: true,
+		},
+		{
+			title:       "invalid percentage",
+			sample:      intstr.FromString("150%"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pods := []string{"pod-1", "pod-2", "pod-3", "pod-4"}
+			objs := []runtime.Object{
+				builders.NewServiceBuilder("test-svc").
+					WithNamespace("test-ns").
+					WithSelectorLabel("app", "none").
+					WithPort("http", 80, k8sintstr.FromInt(80)).
+					BuildAsPtr(),
+			}
+			for _, name := range pods {
+				pod := builders.NewPodBuilder(name).WithNamespace("test-ns").Build()
+				objs = append(objs, &pod)
+			}
+			endpoints := builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, pods).
+				BuildAsPtr()
+
+			client := fake.NewSimpleClientset(objs...)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			options := ServiceDisruptorOptions{Sample: tc.sample, SampleSeed: 1}
+			disruptor, err := NewServiceDisruptorWithEndpoints(context.TODO(), k, "test-svc", "test-ns", endpoints, options)
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			targets, err := disruptor.Targets(context.TODO())
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(targets) != tc.expectedCount {
+				t.Fatalf("expected %d targets got %d: %v", tc.expectedCount, len(targets), targets)
+			}
+
+			// the sample does not change between selections
+			again, err := disruptor.Targets(context.TODO())
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if diff := cmp.Diff(targets, again); diff != "" {
+				t.Fatalf("sample changed between selections:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ServiceDisruptorSecurityContext(t *testing.T) {
+	t.Parallel()
+
+	user := int64(1000)
+
+	testCases := []struct {
+		title           string
+		securityContext AgentSecurityContext
+		expectError     bool
+		expectedUser    *int64
+	}{
+		{
+			title:           "overridden security context",
+			securityContext: AgentSecurityContext{RunAsUser: 1000},
+			expectError:     false,
+			expectedUser:    &user,
+		},
+		{
+			title:           "agent without NET_ADMIN",
+			securityContext: AgentSecurityContext{DropCapabilities: []string{"NET_ADMIN"}},
+			expectError:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			service := builders.NewServiceBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSelectorLabel("app", "none").
+				WithPort("http", 80, k8sintstr.FromInt(80)).
+				BuildAsPtr()
+			pod := buildPodWithPort("pod-1", "http", 80)
+			endpoints := builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, []string{"pod-1"}).
+				BuildAsPtr()
+
+			client := fake.NewSimpleClientset(service, &pod)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			options := ServiceDisruptorOptions{
+				InjectTimeout:        -1,
+				AgentStartupTimeout:  -1,
+				AgentSecurityContext: tc.securityContext,
+			}
+			disruptor, err := NewServiceDisruptorWithEndpoints(context.TODO(), k, "test-svc", "test-ns", endpoints, options)
+			if err != nil {
+				t.Fatalf("creating disruptor: %v", err)
+			}
+
+			fault := HTTPFault{Port: intstr.FromInt32(80), ErrorRate: 0.1, ErrorCode: 500}
+			err = disruptor.InjectHTTPFaults(context.TODO(), fault, time.Second, HTTPDisruptionOptions{})
+			if tc.expectError && !errors.Is(err, ErrNetAdminRequired) {
+				t.Fatalf("expected %v got %v", ErrNetAdminRequired, err)
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			injected, err := client.CoreV1().Pods("test-ns").Get(context.TODO(), "pod-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("retrieving pod: %v", err)
+			}
+
+			if len(injected.Spec.EphemeralContainers) != 1 {
+				t.Fatalf("expected 1 ephemeral container got %d", len(injected.Spec.EphemeralContainers))
+			}
+
+			securityContext := injected.Spec.EphemeralContainers[0].SecurityContext
+			if diff := cmp.Diff(tc.expectedUser, securityContext.RunAsUser); diff != "" {
+				t.Fatalf("expected user of the agent does not match returned:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ServiceDisruptorTargetPort(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		targetPort  string
+		faultPort   intstr.IntOrString
+		expectError bool
+		expectedCmd string
+	}{
+		{
+			title:       "http port",
+			targetPort:  "http",
+			expectError: false,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 8080 -r 0.1 -e 500 -p 8081 --upstream-host 192.0.2.6",
+		},
+		{
+			title:       "admin port",
+			targetPort:  "admin",
+			expectError: false,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 9090 -r 0.1 -e 500 -p 8081 --upstream-host 192.0.2.6",
+		},
+		{
+			title:       "port of the fault takes precedence",
+			targetPort:  "admin",
+			faultPort:   intstr.FromString("http"),
+			expectError: false,
+			expectedCmd: "xk6-disruptor-agent http -d 60s -t 8080 -r 0.1 -e 500 -p 8081 --upstream-host 192.0.2.6",
+		},
+		{
+			title:       "port not exposed by the service",
+			targetPort:  "metrics",
+			expectError: true,
+		},
+		{
+			title:       "no target port",
+			targetPort:  "",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// service exposing two named ports, mapped to the named ports of the container
+			service := builders.NewServiceBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSelectorLabel("app", "test").
+				WithPort("http", 80, k8sintstr.FromString("web")).
+				WithPort("admin", 8081, k8sintstr.FromString("mgmt")).
+				BuildAsPtr()
+
+			pod := builders.NewPodBuilder("pod-1").
+				WithNamespace("test-ns").
+				WithLabel("app", "test").
+				WithIP("192.0.2.6").
+				WithContainer(
+					builders.NewContainerBuilder("app").
+						WithPort("web", 8080).
+						WithPort("mgmt", 9090).
+						Build(),
+				).
+				Build()
+			injectAgent(&pod)
+
+			client := fake.NewSimpleClientset(service, &pod)
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			disruptor, err := NewServiceDisruptor(
+				context.TODO(),
+				k,
+				"test-svc",
+				"test-ns",
+				ServiceDisruptorOptions{TargetPort: tc.targetPort},
+			)
+			if err == nil {
+				fault := HTTPFault{Port: tc.faultPort, ErrorRate: 0.1, ErrorCode: 500}
+				err = disruptor.InjectHTTPFaults(context.TODO(), fault, 60*time.Second, HTTPDisruptionOptions{})
+			}
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			history := k.GetFakeProcessExecutor().GetHistory()
+			if len(history) == 0 {
+				t.Fatalf("no command was executed")
+			}
+
+			cmd := strings.Join(history[0].Command, " ")
+			if cmd != tc.expectedCmd {
+				t.Fatalf("expected command: %s got: %s", tc.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func Test_ServicePortFaults(t *testing.T) {
+	t.Parallel()
+
+	// service exposing both a http and a grpc port
+	service := builders.NewServiceBuilder("test-svc").
+		WithNamespace("test-ns").
+		WithSelectorLabel("app", "test").
+		WithPort("http", 80, k8sintstr.FromInt(8080)).
+		WithPort("grpc", 9000, k8sintstr.FromString("grpc")).
+		Build()
+
+	httpFault := HTTPFault{ErrorRate: 0.1, ErrorCode: 500}
+	grpcFault := GrpcFault{AverageDelay: 100 * time.Millisecond}
+
+	testCases := []struct {
+		title       string
+		faults      []PortFault
+		expected    []PortFault
+		expectError bool
+	}{
+		{
+			title: "http and grpc ports",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, HTTPFault: httpFault},
+				{Port: intstr.FromString("grpc"), Protocol: ProtocolGrpc, GrpcFault: grpcFault},
+			},
+			expected: []PortFault{
+				{Port: intstr.FromInt32(8080), Protocol: ProtocolHTTP, HTTPFault: httpFault},
+				{Port: intstr.FromString("grpc"), Protocol: ProtocolGrpc, GrpcFault: grpcFault},
+			},
+			expectError: false,
+		},
+		{
+			title: "explicit proxy ports",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, ProxyPort: 9080},
+				{Port: intstr.FromInt32(9000), Protocol: ProtocolGrpc, ProxyPort: 9090},
+			},
+			expected: []PortFault{
+				{Port: intstr.FromInt32(8080), Protocol: ProtocolHTTP, ProxyPort: 9080},
+				{Port: intstr.FromString("grpc"), Protocol: ProtocolGrpc, ProxyPort: 9090},
+			},
+			expectError: false,
+		},
+		{
+			title:       "no faults",
+			faults:      []PortFault{},
+			expectError: true,
+		},
+		{
+			title: "port not specified",
+			faults: []PortFault{
+				{Protocol: ProtocolHTTP},
+			},
+			expectError: true,
+		},
+		{
+			title: "port not exposed by service",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(8080), Protocol: ProtocolHTTP},
+			},
+			expectError: true,
+		},
+		{
+			title: "unsupported protocol",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: "tcp"},
+			},
+			expectError: true,
+		},
+		{
+			title: "invalid fault",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, HTTPFault: HTTPFault{ErrorRate: 0.1}},
+			},
+			expectError: true,
+		},
+		{
+			title: "duplicated port",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP},
+				{Port: intstr.FromInt32(80), Protocol: ProtocolGrpc},
+			},
+			expectError: true,
+		},
+		{
+			title: "duplicated proxy port",
+			faults: []PortFault{
+				{Port: intstr.FromInt32(80), Protocol: ProtocolHTTP, ProxyPort: 8001},
+				{Port: intstr.FromInt32(9000), Protocol: ProtocolGrpc, ProxyPort: 8001},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			faults, err := validatePortFaults(tc.faults)
+			if err == nil {
+				faults, err = targetPortFaults(service, faults)
+			}
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			if diff := cmp.Diff(tc.expected, faults); diff != "" {
+				t.Fatalf("expected faults do not match returned:\n%s", diff)
+			}
+		})
+	}
+}
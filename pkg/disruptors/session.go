@@ -0,0 +1,91 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// InjectFunc is a function that injects faults until the context is cancelled or the faults end. For example:
+//
+//	func(ctx context.Context) error {
+//		return disruptor.InjectHTTPFaults(ctx, fault, duration, options)
+//	}
+type InjectFunc func(ctx context.Context) error
+
+// Session is a fault injection running in the background. Closing the session stops the fault injection,
+// which allows using it as any other resource:
+//
+//	session := StartSession(ctx, inject)
+//	defer session.Close()
+type Session struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	started time.Time
+	mutex   sync.Mutex
+	ended   time.Time
+	err     error
+}
+
+// StartSession starts the fault injection in the background and returns the session that controls it
+func StartSession(ctx context.Context, inject InjectFunc) *Session {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s := &Session{
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		started: time.Now(),
+	}
+
+	go func() {
+		defer close(s.done)
+		defer cancel()
+
+		err := inject(ctx)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.ended = time.Now()
+		s.err = err
+	}()
+
+	return s
+}
+
+// Wait waits for the fault injection to end and returns its error, if any
+func (s *Session) Wait() error {
+	<-s.done
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+// Close stops the fault injection and waits for it to end. The faults are stopped in the targets as when
+// the context of the injection is cancelled. Returns the error of the fault injection, if it failed for
+// a reason other than being stopped.
+func (s *Session) Close() error {
+	s.cancel()
+
+	err := s.Wait()
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	return err
+}
+
+// Started returns the time the fault injection started
+func (s *Session) Started() time.Time {
+	return s.started
+}
+
+// Ended returns the time the fault injection ended, or the zero time if it is still running
+func (s *Session) Ended() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.ended
+}
@@ -0,0 +1,102 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes/helpers"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+)
+
+func Test_SessionClose(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod-1").
+		WithNamespace("test-ns").
+		Build()
+	injectAgent(&pod)
+
+	client := fake.NewSimpleClientset(&pod)
+	executor := &cancelExecutor{started: make(chan struct{})}
+	helper := helpers.NewPodHelper(client, executor, "test-ns")
+	visitor := NewPodAgentVisitor(helper, PodAgentVisitorOptions{Timeout: -1}, visitCommands())
+
+	session := StartSession(context.TODO(), func(ctx context.Context) error {
+		return NewPodController([]corev1.Pod{pod}).Visit(ctx, visitor)
+	})
+
+	select {
+	case <-executor.started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("command was not executed")
+	}
+
+	if !session.Ended().IsZero() {
+		t.Fatalf("session ended before being closed")
+	}
+
+	if err := session.Close(); err != nil {
+		t.Fatalf("unexpected error closing session: %v", err)
+	}
+
+	if session.Ended().Before(session.Started()) {
+		t.Fatalf("session end %v is before its start %v", session.Ended(), session.Started())
+	}
+
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+
+	if !executor.closed {
+		t.Fatalf("command execution was not cancelled")
+	}
+
+	if !executor.cleanup {
+		t.Fatalf("cleanup command was not executed after the session was closed")
+	}
+}
+
+func Test_SessionError(t *testing.T) {
+	t.Parallel()
+
+	injectErr := errors.New("injection failed")
+
+	testCases := []struct {
+		title       string
+		inject      InjectFunc
+		expectError error
+	}{
+		{
+			title:       "injection completes",
+			inject:      func(_ context.Context) error { return nil },
+			expectError: nil,
+		},
+		{
+			title:       "injection fails",
+			inject:      func(_ context.Context) error { return injectErr },
+			expectError: injectErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			session := StartSession(context.TODO(), tc.inject)
+
+			if err := session.Wait(); !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			// closing an ended session returns the result of the injection
+			if err := session.Close(); !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
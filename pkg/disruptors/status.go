@@ -0,0 +1,106 @@
+package disruptors
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DisruptionStatus describes the progress of the fault injections in progress in a disruptor
+type DisruptionStatus struct {
+	// number of targets where the fault command is running
+	ActiveTargets int `js:"activeTargets"`
+	// number of targets of the fault injections in progress
+	TotalTargets int `js:"totalTargets"`
+	// time until the end of the fault injections in progress. Zero if no fault injection is in progress.
+	Remaining time.Duration `js:"remaining"`
+}
+
+// StatusReporter defines the method for inspecting the progress of the fault injections of a disruptor
+type StatusReporter interface {
+	// Status returns the status of the fault injections in progress
+	Status() (DisruptionStatus, error)
+}
+
+// injectionProgress tracks the targets where the fault command of a fault injection is running
+type injectionProgress struct {
+	mutex   sync.Mutex
+	targets int
+	end     time.Time
+	// targets where the fault command is running, by namespace and name
+	active map[string]bool
+}
+
+func podKey(pod corev1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// started records the fault command is running in the pod
+func (p *injectionProgress) started(pod corev1.Pod) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.active[podKey(pod)] = true
+}
+
+// ended records the fault command is no longer running in the pod
+func (p *injectionProgress) ended(pod corev1.Pod) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.active, podKey(pod))
+}
+
+// statusTracker tracks the progress of the fault injections in progress in a disruptor
+type statusTracker struct {
+	mutex      sync.Mutex
+	injections map[*injectionProgress]bool
+}
+
+// start records the start of a fault injection in the targets for the given duration
+func (t *statusTracker) start(targets int, duration time.Duration) *injectionProgress {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	progress := &injectionProgress{
+		targets: targets,
+		end:     time.Now().Add(duration),
+		active:  map[string]bool{},
+	}
+
+	if t.injections == nil {
+		t.injections = map[*injectionProgress]bool{}
+	}
+	t.injections[progress] = true
+
+	return progress
+}
+
+// finish records the end of a fault injection
+func (t *statusTracker) finish(progress *injectionProgress) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.injections, progress)
+}
+
+// status returns the combined status of the fault injections in progress
+func (t *statusTracker) status() DisruptionStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	status := DisruptionStatus{}
+	now := time.Now()
+	for progress := range t.injections {
+		progress.mutex.Lock()
+		status.ActiveTargets += len(progress.active)
+		status.TotalTargets += progress.targets
+		if remaining := progress.end.Sub(now); remaining > status.Remaining {
+			status.Remaining = remaining
+		}
+		progress.mutex.Unlock()
+	}
+
+	return status
+}
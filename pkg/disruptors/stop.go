@@ -0,0 +1,61 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Stopper defines the method for stopping the faults injected in the targets of a disruptor
+type Stopper interface {
+	// Stop signals the agent in all the targets to stop any ongoing fault injection. The agent container
+	// cannot be removed from the targets, but it exits once the fault injection is stopped.
+	// The returned error describes all the targets where the faults could not be stopped.
+	Stop(ctx context.Context) error
+}
+
+// hasAgent returns true if the disruptor agent has been injected in the pod in the given container
+func hasAgent(pod corev1.Pod, agentContainer string) bool {
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == agentContainer {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stopTargets executes the cleanup command in all the targets that have the agent injected in the given container,
+// using the PodHelper of the namespace of each target. Contrary to other visits, the failure in one target does
+// not stop the others, so all the failures are reported.
+func stopTargets(ctx context.Context, helper PodHelperFunc, container string, targets []corev1.Pod) error {
+	var (
+		mtx  sync.Mutex
+		errs []error
+	)
+
+	visitor := PodVisitorFunc(func(ctx context.Context, pod corev1.Pod) error {
+		// nothing to stop if the agent was never injected
+		if !hasAgent(pod, container) {
+			return nil
+		}
+
+		_, stderr, err := helper(pod.Namespace).Exec(ctx, pod.Name, container, buildCleanupCmd(), []byte{})
+		if err != nil {
+			mtx.Lock()
+			errs = append(errs, fmt.Errorf("stopping agent in pod %q: %w \n%s", pod.Name, err, string(stderr)))
+			mtx.Unlock()
+		}
+
+		return nil
+	})
+
+	if err := NewPodController(targets).Visit(ctx, visitor); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
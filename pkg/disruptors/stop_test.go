@@ -0,0 +1,119 @@
+package disruptors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/kubernetes"
+)
+
+func Test_StopTargets(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		targets     []string
+		withAgent   []string
+		failing     []string
+		expectError bool
+	}{
+		{
+			title:       "stop all targets",
+			targets:     []string{"pod-1", "pod-2", "pod-3"},
+			withAgent:   []string{"pod-1", "pod-2", "pod-3"},
+			failing:     []string{},
+			expectError: false,
+		},
+		{
+			title:       "targets without agent",
+			targets:     []string{"pod-1", "pod-2", "pod-3"},
+			withAgent:   []string{"pod-2"},
+			failing:     []string{},
+			expectError: false,
+		},
+		{
+			title:       "no target with agent",
+			targets:     []string{"pod-1", "pod-2"},
+			withAgent:   []string{},
+			failing:     []string{},
+			expectError: false,
+		},
+		{
+			title:       "some targets fail",
+			targets:     []string{"pod-1", "pod-2", "pod-3"},
+			withAgent:   []string{"pod-1", "pod-2", "pod-3"},
+			failing:     []string{"pod-1", "pod-3"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			targets := []corev1.Pod{}
+			for _, name := range tc.targets {
+				pod := buildPodWithPort(name, "http", 80)
+				if slices.Contains(tc.withAgent, name) {
+					pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+						{
+							EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "xk6-agent"},
+						},
+					}
+				}
+				targets = append(targets, pod)
+			}
+
+			client := fake.NewSimpleClientset()
+			for i := range targets {
+				_ = client.Tracker().Add(&targets[i])
+			}
+			k, _ := kubernetes.NewFakeKubernetes(client)
+
+			executor := k.GetFakeProcessExecutor()
+			for _, name := range tc.failing {
+				executor.SetPodResult(name, nil, []byte("no such process"), errors.New("exit status 1"))
+			}
+
+			err := stopTargets(context.TODO(), k.PodHelper, DefaultAgentContainerName, targets)
+
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error : %v", err)
+			}
+
+			// the cleanup command must be sent to all the targets with the agent, even if some fail
+			stopped := []string{}
+			for _, cmd := range executor.GetHistory() {
+				if strings.Join(cmd.Command, " ") != "xk6-disruptor-agent cleanup" {
+					t.Errorf("unexpected command %q in pod %q", cmd.Command, cmd.Pod)
+				}
+				stopped = append(stopped, cmd.Pod)
+			}
+			sort.Strings(stopped)
+
+			if diff := cmp.Diff(tc.withAgent, stopped); diff != "" {
+				t.Errorf("expected stopped targets do not match:\n%s", diff)
+			}
+
+			// the error must report all the failing targets
+			for _, name := range tc.failing {
+				if !strings.Contains(err.Error(), `"`+name+`"`) {
+					t.Errorf("target %q not reported as failing: %v", name, err)
+				}
+			}
+		})
+	}
+}
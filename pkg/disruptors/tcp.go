@@ -0,0 +1,80 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+	"github.com/grafana/xk6-disruptor/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TCPFaultInjector defines the methods for injecting faults in TCP connections
+type TCPFaultInjector interface {
+	// InjectTCPFaults injects faults in the TCP connections to the disruptor's targets for the specified duration
+	InjectTCPFaults(ctx context.Context, fault TCPFault, duration time.Duration, options TCPDisruptionOptions) error
+}
+
+// TCPFault specifies a fault to be injected in TCP connections
+type TCPFault struct {
+	// port the disruptions will be applied to
+	Port intstr.IntOrString
+	// Fraction (in the range 0.0 to 1.0) of connections that will be reset
+	ResetRate float32 `js:"resetRate"`
+}
+
+// TCPDisruptionOptions defines options for the injection of TCP faults in a target pod
+type TCPDisruptionOptions struct{}
+
+// validate checks the fault's attributes are consistent
+func (f TCPFault) validate() error {
+	if f.Port.IsNull() || f.Port.IsZero() {
+		return fmt.Errorf("port must be specified for TCP faults")
+	}
+
+	if f.ResetRate < 0 || f.ResetRate > 1 {
+		return fmt.Errorf("reset rate must be in the range [0.0, 1.0]: %f", f.ResetRate)
+	}
+
+	return nil
+}
+
+func buildTCPFaultCmd(fault TCPFault, duration time.Duration) []string {
+	return []string{
+		"xk6-disruptor-agent",
+		"tcp-drop",
+		"-d", utils.DurationSeconds(duration),
+		"-p", fault.Port.Str(),
+		"-r", fmt.Sprint(fault.ResetRate),
+	}
+}
+
+// PodTCPFaultCommand implements the PodVisitCommands interface for injecting TCPFaults in a Pod
+type PodTCPFaultCommand struct {
+	fault    TCPFault
+	duration time.Duration
+	options  TCPDisruptionOptions
+}
+
+// Commands return the command for injecting a TCPFault in a Pod
+func (c PodTCPFaultCommand) Commands(pod corev1.Pod) (VisitCommands, error) {
+	if utils.HasHostNetwork(pod) {
+		return VisitCommands{}, fmt.Errorf("fault cannot be safely injected because pod %q uses hostNetwork", pod.Name)
+	}
+
+	// find the container port for fault injection
+	port, container, err := utils.FindContainerPort(c.fault.Port, pod, "")
+	if err != nil {
+		return VisitCommands{}, err
+	}
+	podFault := c.fault
+	podFault.Port = port
+
+	return VisitCommands{
+		Exec:    buildTCPFaultCmd(podFault, c.duration),
+		Cleanup: buildCleanupCmd(),
+		Ports:   []ResolvedPort{{Port: port, Container: container}},
+	}, nil
+}
@@ -13,6 +13,8 @@ import (
 type PodTerminationVisitor struct {
 	helper  helpers.PodHelper
 	timeout time.Duration
+	// returns the PodHelper for the namespace of each pod. If nil, helper is used for all pods.
+	namespaceHelper PodHelperFunc
 }
 
 // Visit executes a Terminate action on the target Pod
@@ -20,7 +22,13 @@ func (c PodTerminationVisitor) Visit(ctx context.Context, pod corev1.Pod) error
 	if c.timeout == 0 {
 		c.timeout = 10 * time.Second
 	}
-	return c.helper.Terminate(ctx, pod.Name, c.timeout)
+
+	helper := c.helper
+	if c.namespaceHelper != nil {
+		helper = c.namespaceHelper(pod.Namespace)
+	}
+
+	return helper.Terminate(ctx, pod.Name, c.timeout)
 }
 
 // PodFaultInjector defines methods for injecting faults into Pods
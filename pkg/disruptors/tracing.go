@@ -0,0 +1,51 @@
+package disruptors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// tracerName is the name of the tracer used for instrumenting the disruptors
+const tracerName = "github.com/grafana/xk6-disruptor/pkg/disruptors"
+
+// startSpan starts a span for an operation using the global tracer provider.
+// Spans are not recorded unless a tracer provider is registered with otel.SetTracerProvider.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, recording the error returned by the operation, if any
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// faultAttributes returns the span attributes that describe a fault injection
+func faultAttributes(fault any, duration time.Duration) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("disruptor.fault", fmt.Sprintf("%+v", fault)),
+		attribute.String("disruptor.duration", duration.String()),
+	}
+}
+
+// targetsAttribute returns the span attribute with the names of the target pods
+func targetsAttribute(targets []corev1.Pod) attribute.KeyValue {
+	names := make([]string, 0, len(targets))
+	for _, pod := range targets {
+		names = append(names, pod.Name)
+	}
+
+	return attribute.StringSlice("disruptor.targets", names)
+}
@@ -0,0 +1,122 @@
+package disruptors
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
+)
+
+// findSpan returns the span with the given name that has the given attribute
+func findSpan(spans []sdktrace.ReadOnlySpan, name string, attr attribute.KeyValue) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() != name {
+			continue
+		}
+		for _, a := range span.Attributes() {
+			if a.Key == attr.Key && a.Value.Emit() == attr.Value.Emit() {
+				return span
+			}
+		}
+	}
+
+	return nil
+}
+
+func Test_InjectionSpans(t *testing.T) {
+	t.Parallel()
+
+	// the tracer provider is global, so spans from all test cases are recorded together.
+	// Each test case uses a different target pod to tell its spans apart.
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	testCases := []struct {
+		title       string
+		pod         string
+		fault       HTTPFault
+		expectError bool
+	}{
+		{
+			title: "successful injection",
+			pod:   "traced-pod",
+			fault: HTTPFault{
+				Port:      intstr.FromInt32(80),
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+			},
+			expectError: false,
+		},
+		{
+			title: "failed injection",
+			pod:   "traced-failed-pod",
+			fault: HTTPFault{
+				Port:      intstr.FromInt32(8080),
+				ErrorRate: 0.1,
+				ErrorCode: 500,
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := buildInjectedPod(tc.pod, "http", 80)
+			disruptor, _ := newTestPodDisruptor(t, PodDisruptorOptions{}, &pod)
+
+			err := disruptor.InjectHTTPFaults(context.TODO(), tc.fault, 60*time.Second, HTTPDisruptionOptions{})
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			spans := recorder.Ended()
+
+			injectSpan := findSpan(
+				spans,
+				"PodDisruptor.InjectHTTPFaults",
+				attribute.StringSlice("disruptor.targets", []string{tc.pod}),
+			)
+			if injectSpan == nil {
+				t.Fatalf("no span recorded for the injection")
+			}
+
+			if !slices.Contains(injectSpan.Attributes(), attribute.String("disruptor.duration", "1m0s")) {
+				t.Errorf("injection span does not record the duration: %v", injectSpan.Attributes())
+			}
+
+			agentSpan := findSpan(spans, "InjectDisruptorAgent", attribute.String("disruptor.pod", tc.pod))
+			if agentSpan == nil {
+				t.Fatalf("no span recorded for the injection of the agent")
+			}
+
+			if agentSpan.Parent().SpanID() != injectSpan.SpanContext().SpanID() {
+				t.Errorf("the agent injection span is not a child of the injection span")
+			}
+
+			expectedStatus := codes.Unset
+			if tc.expectError {
+				expectedStatus = codes.Error
+			}
+
+			if injectSpan.Status().Code != expectedStatus {
+				t.Errorf("expected status %s got %s", expectedStatus, injectSpan.Status().Code)
+			}
+		})
+	}
+}
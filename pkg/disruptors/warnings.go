@@ -0,0 +1,37 @@
+package disruptors
+
+import "sync"
+
+// WarningsReporter defines the method for inspecting the warnings of the fault injections in each target
+type WarningsReporter interface {
+	// Warnings returns the non-fatal issues found in each target during the last fault injection, such as those
+	// reported by the agent, by the namespace/name of the target. Targets without warnings are not included.
+	Warnings() map[string]string
+}
+
+// warningsLog records the warnings of the last fault injection in each target
+type warningsLog struct {
+	mutex    sync.Mutex
+	warnings map[string]string
+}
+
+// set replaces the warnings recorded in the log
+func (l *warningsLog) set(warnings map[string]string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.warnings = warnings
+}
+
+// get returns a copy of the warnings recorded in the log
+func (l *warningsLog) get() map[string]string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	warnings := make(map[string]string, len(l.warnings))
+	for target, warning := range l.warnings {
+		warnings[target] = warning
+	}
+
+	return warnings
+}
@@ -14,6 +14,7 @@ type FakeKubernetes struct {
 	client   *fake.Clientset
 	ctx      context.Context
 	executor *helpers.FakePodCommandExecutor
+	metrics  *helpers.FakePodMetricsHelper
 }
 
 // NewFakeKubernetes returns a new fake implementation of Kubernetes from fake Clientset
@@ -22,6 +23,7 @@ func NewFakeKubernetes(clientset *fake.Clientset) (*FakeKubernetes, error) {
 		client:   clientset,
 		ctx:      context.TODO(),
 		executor: helpers.NewFakePodCommandExecutor(),
+		metrics:  helpers.NewFakePodMetricsHelper(),
 	}, nil
 }
 
@@ -42,6 +44,31 @@ func (f *FakeKubernetes) ServiceHelper(namespace string) helpers.ServiceHelper {
 	)
 }
 
+// NodeHelper returns a NodeHelper
+func (f *FakeKubernetes) NodeHelper() helpers.NodeHelper {
+	return helpers.NewNodeHelper(f.client)
+}
+
+// ReplicaSetHelper returns a ReplicaSetHelper for the given namespace
+func (f *FakeKubernetes) ReplicaSetHelper(namespace string) helpers.ReplicaSetHelper {
+	return helpers.NewReplicaSetHelper(f.client, namespace)
+}
+
+// DeploymentHelper returns a DeploymentHelper for the given namespace
+func (f *FakeKubernetes) DeploymentHelper(namespace string) helpers.DeploymentHelper {
+	return helpers.NewDeploymentHelper(f.client, namespace)
+}
+
+// PodMetricsHelper returns the FakePodMetricsHelper, regardless of the namespace
+func (f *FakeKubernetes) PodMetricsHelper(_ string) helpers.PodMetricsHelper {
+	return f.metrics
+}
+
+// EventHelper returns an EventHelper that records the events using the fake Clientset
+func (f *FakeKubernetes) EventHelper() helpers.EventHelper {
+	return helpers.NewEventHelper(f.client)
+}
+
 // Client return a kubernetes client
 func (f *FakeKubernetes) Client() kubernetes.Interface {
 	return f.client
@@ -52,3 +79,8 @@ func (f *FakeKubernetes) Client() kubernetes.Interface {
 func (f *FakeKubernetes) GetFakeProcessExecutor() *helpers.FakePodCommandExecutor {
 	return f.executor
 }
+
+// GetFakePodMetrics returns the FakePodMetricsHelper used for mocking the resource usage of pods
+func (f *FakeKubernetes) GetFakePodMetrics() *helpers.FakePodMetricsHelper {
+	return f.metrics
+}
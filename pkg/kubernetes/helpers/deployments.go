@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DeploymentHelper implements functions for dealing with deployments
+type DeploymentHelper interface {
+	// Get returns the deployment with the given name
+	Get(ctx context.Context, name string) (appsv1.Deployment, error)
+}
+
+// deploymentHelper struct holds the data required by the helpers
+type deploymentHelper struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewDeploymentHelper returns a DeploymentHelper for the given namespace
+func NewDeploymentHelper(client kubernetes.Interface, namespace string) DeploymentHelper {
+	return &deploymentHelper{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+func (h *deploymentHelper) Get(ctx context.Context, name string) (appsv1.Deployment, error) {
+	deployment, err := h.client.AppsV1().Deployments(h.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return appsv1.Deployment{}, fmt.Errorf("retrieving deployment %q: %w", name, err)
+	}
+
+	return *deployment, nil
+}
@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// EventComponent is the component reported as the source of the events recorded by the EventHelper
+const EventComponent = "xk6-disruptor"
+
+// EventHelper implements functions for recording Kubernetes events
+type EventHelper interface {
+	// Record records an event of type Normal with the given reason and message referencing the pod.
+	// Events are sent to the API server asynchronously.
+	Record(pod corev1.Pod, reason string, message string)
+}
+
+// eventHelper struct holds the data required by the helpers
+type eventHelper struct {
+	recorder record.EventRecorder
+}
+
+// NewEventHelper returns an EventHelper that records the events in the namespace of the object they reference
+func NewEventHelper(client kubernetes.Interface) EventHelper {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+
+	return &eventHelper{
+		recorder: broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: EventComponent}),
+	}
+}
+
+func (h *eventHelper) Record(pod corev1.Pod, reason string, message string) {
+	h.recorder.Event(&pod, corev1.EventTypeNormal, reason, message)
+}
@@ -3,6 +3,8 @@ package helpers
 import (
 	"context"
 	"sync"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Command records the execution of a command in a Pod
@@ -17,11 +19,19 @@ type Command struct {
 // FakePodCommandExecutor mocks the execution of a command in a pod
 // recording the command history and returning a predefined stdout, stderr, and error
 type FakePodCommandExecutor struct {
-	mutex   sync.Mutex
-	history []Command
-	stdout  []byte
-	stderr  []byte
-	err     error
+	mutex      sync.Mutex
+	history    []Command
+	stdout     []byte
+	stderr     []byte
+	err        error
+	podResults map[string]fakeResult
+}
+
+// fakeResult is the result of the execution of a command
+type fakeResult struct {
+	stdout []byte
+	stderr []byte
+	err    error
 }
 
 // Exec records the execution of a command and returns the pre-defined
@@ -41,8 +51,13 @@ func (f *FakePodCommandExecutor) Exec(
 		Command:   cmd,
 		Stdin:     stdin,
 	})
+	result, found := f.podResults[pod]
 	f.mutex.Unlock()
 
+	if found {
+		return result.stdout, result.stderr, result.err
+	}
+
 	return f.stdout, f.stderr, f.err
 }
 
@@ -53,6 +68,15 @@ func (f *FakePodCommandExecutor) SetResult(stdout []byte, stderr []byte, err err
 	f.err = err
 }
 
+// SetPodResult sets the results to be returned for each invocation in the given pod, overriding the results
+// set with SetResult
+func (f *FakePodCommandExecutor) SetPodResult(pod string, stdout []byte, stderr []byte, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.podResults[pod] = fakeResult{stdout: stdout, stderr: stderr, err: err}
+}
+
 // GetHistory returns the history of commands executed by the FakePodCommandExecutor
 func (f *FakePodCommandExecutor) GetHistory() []Command {
 	return f.history
@@ -61,5 +85,54 @@ func (f *FakePodCommandExecutor) GetHistory() []Command {
 // NewFakePodCommandExecutor creates a new instance of FakePodCommandExecutor
 // with default attributes
 func NewFakePodCommandExecutor() *FakePodCommandExecutor {
-	return &FakePodCommandExecutor{}
+	return &FakePodCommandExecutor{
+		podResults: map[string]fakeResult{},
+	}
+}
+
+// FakePodMetricsHelper mocks the retrieval of the resource usage of pods, returning a predefined usage
+type FakePodMetricsHelper struct {
+	mutex sync.Mutex
+	usage map[string]corev1.ResourceList
+	err   error
+}
+
+// NewFakePodMetricsHelper creates a new instance of FakePodMetricsHelper without any usage
+func NewFakePodMetricsHelper() *FakePodMetricsHelper {
+	return &FakePodMetricsHelper{
+		usage: map[string]corev1.ResourceList{},
+	}
+}
+
+// Usage returns the predefined usage of the pods
+func (f *FakePodMetricsHelper) Usage(_ context.Context) (map[string]corev1.ResourceList, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	usage := make(map[string]corev1.ResourceList, len(f.usage))
+	for pod, resources := range f.usage {
+		usage[pod] = resources.DeepCopy()
+	}
+
+	return usage, nil
+}
+
+// SetPodUsage sets the resource usage returned for the given pod
+func (f *FakePodMetricsHelper) SetPodUsage(pod string, usage corev1.ResourceList) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.usage[pod] = usage
+}
+
+// SetError sets the error returned when retrieving the usage, for example if the metrics-server is not installed
+func (f *FakePodMetricsHelper) SetError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.err = err
 }
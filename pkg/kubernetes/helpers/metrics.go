@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PodMetricsHelper implements functions for retrieving the resource usage of pods.
+// The usage is obtained from the resource metrics API, which requires the metrics-server to be installed
+// in the cluster.
+type PodMetricsHelper interface {
+	// Usage returns the resource usage of the pods in the namespace by pod name, adding up the usage of
+	// their containers
+	Usage(ctx context.Context) (map[string]corev1.ResourceList, error)
+}
+
+// podMetricsHelper struct holds the data required by the helpers
+type podMetricsHelper struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewPodMetricsHelper returns a PodMetricsHelper for the given namespace
+func NewPodMetricsHelper(client kubernetes.Interface, namespace string) PodMetricsHelper {
+	return &podMetricsHelper{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+// podMetricsList is the subset of the PodMetricsList object of the resource metrics API used by the helper
+type podMetricsList struct {
+	Items []struct {
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+func (h *podMetricsHelper) Usage(ctx context.Context) (map[string]corev1.ResourceList, error) {
+	path := fmt.Sprintf("/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods", h.namespace)
+	data, err := h.client.Discovery().RESTClient().Get().AbsPath(path).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving pod metrics (is metrics-server installed?): %w", err)
+	}
+
+	metrics := podMetricsList{}
+	if err = json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("parsing pod metrics: %w", err)
+	}
+
+	usage := map[string]corev1.ResourceList{}
+	for _, pod := range metrics.Items {
+		podUsage := corev1.ResourceList{}
+		for _, container := range pod.Containers {
+			addResources(podUsage, container.Usage)
+		}
+		usage[pod.Metadata.Name] = podUsage
+	}
+
+	return usage, nil
+}
+
+// addResources adds the quantities of the resources in other to the resources in list
+func addResources(list corev1.ResourceList, other corev1.ResourceList) {
+	for name, quantity := range other {
+		total, found := list[name]
+		if !found {
+			total = resource.Quantity{}
+		}
+		total.Add(quantity)
+		list[name] = total
+	}
+}
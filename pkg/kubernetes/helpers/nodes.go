@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeHelper implements functions for dealing with nodes
+type NodeHelper interface {
+	// Get returns the node with the given name
+	Get(ctx context.Context, name string) (corev1.Node, error)
+	// List returns the nodes that match all the given labels
+	List(ctx context.Context, labels map[string]string) ([]corev1.Node, error)
+}
+
+// nodeHelper struct holds the data required by the helpers
+type nodeHelper struct {
+	client kubernetes.Interface
+}
+
+// NewNodeHelper returns a NodeHelper
+func NewNodeHelper(client kubernetes.Interface) NodeHelper {
+	return &nodeHelper{
+		client: client,
+	}
+}
+
+func (h *nodeHelper) Get(ctx context.Context, name string) (corev1.Node, error) {
+	node, err := h.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return corev1.Node{}, fmt.Errorf("retrieving node %q: %w", name, err)
+	}
+
+	return *node, nil
+}
+
+func (h *nodeHelper) List(ctx context.Context, nodeLabels map[string]string) ([]corev1.Node, error) {
+	nodes, err := h.client.CoreV1().Nodes().List(
+		ctx,
+		metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(nodeLabels).String(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	return nodes.Items, nil
+}
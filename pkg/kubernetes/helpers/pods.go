@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -25,6 +26,14 @@ type PodHelper interface {
 	// WaitPodRunning waits for the Pod to be running for up to given timeout and returns a boolean indicating
 	// if the status was reached. If the pod is Failed returns error.
 	WaitPodRunning(ctx context.Context, name string, timeout time.Duration) (bool, error)
+	// WaitEphemeralContainerRunning waits for an ephemeral container of the Pod to be running for up to given timeout
+	// and returns a boolean indicating if the status was reached
+	WaitEphemeralContainerRunning(
+		ctx context.Context,
+		name string,
+		container string,
+		timeout time.Duration,
+	) (bool, error)
 	// WaitPodDeleted waits for the Pod to be deleted for up to given timeout
 	WaitPodDeleted(ctx context.Context, name string, timeout time.Duration) error
 	// Exec executes a non-interactive command described in options and returns the stdout and stderr outputs
@@ -40,6 +49,13 @@ type PodHelper interface {
 	List(ctx context.Context, filter PodFilter) ([]corev1.Pod, error)
 	// Terminate terminates the execution of a running Pod
 	Terminate(ctx context.Context, name string, timeout time.Duration) error
+	// Create creates a Pod in the namespace of the helper
+	Create(ctx context.Context, pod corev1.Pod) error
+	// Logs returns the logs of a container of the Pod
+	Logs(ctx context.Context, pod string, container string) ([]byte, error)
+	// StreamLogs returns a stream that follows the logs of a container of the Pod until it is closed
+	// or the container ends
+	StreamLogs(ctx context.Context, pod string, container string) (io.ReadCloser, error)
 }
 
 // helpers struct holds the data required by the helpers
@@ -73,6 +89,9 @@ type AttachOptions struct {
 	// IgnoreIfExists causes AttachEphemeralContainer to return successfully if the ephemeral container already exists
 	// when set to true. If set to false, it will exit with an error if the container already exists.
 	IgnoreIfExists bool
+	// FailOnImagePullError causes AttachEphemeralContainer to fail as soon as the image of the container
+	// cannot be pulled, instead of waiting until the timeout expires.
+	FailOnImagePullError bool
 }
 
 // podConditionChecker defines a function that checks if a pod satisfies a condition
@@ -124,7 +143,7 @@ func (h *podHelper) waitForCondition(
 			return false, nil
 		case event := <-watcher.ResultChan():
 			if event.Type == watch.Error {
-				return false, fmt.Errorf("error watching for pod: %v", event.Object)
+				return false, fmt.Errorf("error watching for pod: %w", k8serrors.FromObject(event.Object))
 			}
 			if event.Type == watch.Modified {
 				pod, isPod := event.Object.(*corev1.Pod)
@@ -158,6 +177,28 @@ func (h *podHelper) WaitPodRunning(ctx context.Context, name string, timeout tim
 	)
 }
 
+func (h *podHelper) WaitEphemeralContainerRunning(
+	ctx context.Context,
+	name string,
+	container string,
+	timeout time.Duration,
+) (bool, error) {
+	return h.waitForCondition(
+		ctx,
+		h.namespace,
+		name,
+		timeout,
+		func(pod *corev1.Pod) (bool, error) {
+			for _, cs := range pod.Status.EphemeralContainerStatuses {
+				if cs.Name == container && cs.State.Running != nil {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	)
+}
+
 func (h *podHelper) Exec(
 	ctx context.Context,
 	pod string,
@@ -232,12 +273,18 @@ func (h *podHelper) AttachEphemeralContainer(
 	if options.Timeout == 0 {
 		return nil
 	}
+
+	checker := checkEphemeralContainerIsRunning
+	if options.FailOnImagePullError {
+		checker = checkEphemeralContainerImagePulled(container.Name, checker)
+	}
+
 	running, err := h.waitForCondition(
 		ctx,
 		h.namespace,
 		podName,
 		options.Timeout,
-		checkEphemeralContainerIsRunning,
+		checker,
 	)
 	if err != nil {
 		return fmt.Errorf("waiting for ephemeral container of %q to start: %w", pod.Name, err)
@@ -260,6 +307,36 @@ func checkEphemeralContainerIsRunning(pod *corev1.Pod) (bool, error) {
 	return false, nil
 }
 
+// imagePullErrors are the reasons reported by a waiting container when its image cannot be pulled
+var imagePullErrors = map[string]bool{ //nolint:gochecknoglobals
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// checkEphemeralContainerImagePulled returns a podConditionChecker that fails if the image of the given
+// ephemeral container cannot be pulled and otherwise delegates to the given checker
+func checkEphemeralContainerImagePulled(name string, checker podConditionChecker) podConditionChecker {
+	return func(pod *corev1.Pod) (bool, error) {
+		for _, cs := range pod.Status.EphemeralContainerStatuses {
+			if cs.Name != name || cs.State.Waiting == nil {
+				continue
+			}
+
+			if imagePullErrors[cs.State.Waiting.Reason] {
+				return false, fmt.Errorf(
+					"pulling image for ephemeral container %q: %s: %s",
+					name,
+					cs.State.Waiting.Reason,
+					cs.State.Waiting.Message,
+				)
+			}
+		}
+
+		return checker(pod)
+	}
+}
+
 // buildLabelSelector builds a label selector to be used in the k8s api, from a PodSelector
 func buildLabelSelector(f PodFilter) (labels.Selector, error) {
 	labelsSelector := labels.NewSelector()
@@ -336,7 +413,7 @@ func (h *podHelper) WaitPodDeleted(ctx context.Context, pod string, timeout time
 			return fmt.Errorf("pod '%s/%s' not terminated after %fs", h.namespace, pod, timeout.Seconds())
 		case event := <-watcher.ResultChan():
 			if event.Type == watch.Error {
-				return fmt.Errorf("error watching for pod: %v", event.Object)
+				return fmt.Errorf("error watching for pod: %w", k8serrors.FromObject(event.Object))
 			}
 			if event.Type == watch.Deleted {
 				return nil
@@ -354,3 +431,34 @@ func (h *podHelper) Terminate(ctx context.Context, pod string, timeout time.Dura
 
 	return h.WaitPodDeleted(ctx, pod, timeout)
 }
+
+func (h *podHelper) Create(ctx context.Context, pod corev1.Pod) error {
+	_, err := h.client.CoreV1().Pods(h.namespace).Create(ctx, &pod, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating pod %q: %w", pod.Name, err)
+	}
+
+	return nil
+}
+
+func (h *podHelper) Logs(ctx context.Context, pod string, container string) ([]byte, error) {
+	logs, err := h.client.CoreV1().Pods(h.namespace).
+		GetLogs(pod, &corev1.PodLogOptions{Container: container}).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting logs of container %q in pod %q: %w", container, pod, err)
+	}
+
+	return logs, nil
+}
+
+func (h *podHelper) StreamLogs(ctx context.Context, pod string, container string) (io.ReadCloser, error) {
+	stream, err := h.client.CoreV1().Pods(h.namespace).
+		GetLogs(pod, &corev1.PodLogOptions{Container: container, Follow: true}).
+		Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("streaming logs of container %q in pod %q: %w", container, pod, err)
+	}
+
+	return stream, nil
+}
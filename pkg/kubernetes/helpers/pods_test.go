@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"context"
+	"io"
 	"testing"
 	"time"
 
@@ -9,7 +10,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/grafana/xk6-disruptor/pkg/testutils/assertions"
 	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
@@ -87,7 +90,10 @@ func TestPods_Wait(t *testing.T) {
 				return
 			}
 
-			pod := builders.NewPodBuilder(tc.name).WithNamespace(testNamespace).Build()
+			pod := builders.NewPodBuilder(tc.name).
+				WithNamespace(testNamespace).
+				WithPhase(corev1.PodPending).
+				Build()
 			_, err = client.CoreV1().Pods(testNamespace).Create(context.TODO(), &pod, metav1.CreateOptions{})
 			if !tc.expectError && err != nil {
 				t.Errorf("unexpected error: %v", err)
@@ -117,6 +123,121 @@ func TestPods_Wait(t *testing.T) {
 	}
 }
 
+func TestPods_WaitEphemeralContainerRunning(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test           string
+		container      string
+		delay          time.Duration
+		expectedResult bool
+	}{
+		{
+			test:           "wait container running",
+			container:      "xk6-agent",
+			delay:          500 * time.Millisecond,
+			expectedResult: true,
+		},
+		{
+			test:           "timeout waiting container running",
+			container:      "xk6-agent",
+			delay:          3 * time.Second,
+			expectedResult: false,
+		},
+		{
+			test:           "other container running",
+			container:      "other",
+			delay:          500 * time.Millisecond,
+			expectedResult: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			// the container transitions from waiting to running after the delay
+			observer := func(event builders.ObjectEvent, pod *corev1.Pod) (*corev1.Pod, bool, error) {
+				time.Sleep(tc.delay)
+				pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+					{
+						Name:  tc.container,
+						State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+					},
+				}
+				// update pod and stop watching updates
+				return pod, false, nil
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			client, err := builders.NewClientBuilder().
+				WithContext(ctx).
+				WithPodObserver(testNamespace, builders.ObjectEventAdded, observer).
+				Build()
+			if err != nil {
+				t.Fatalf("failed to create k8s client %v", err)
+			}
+
+			pod := builders.NewPodBuilder("pod-running").
+				WithNamespace(testNamespace).
+				WithPhase(corev1.PodRunning).
+				Build()
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name:  "xk6-agent",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+				},
+			}
+			_, err = client.CoreV1().Pods(testNamespace).Create(context.TODO(), &pod, metav1.CreateOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			h := NewPodHelper(client, nil, testNamespace)
+			result, err := h.WaitEphemeralContainerRunning(context.TODO(), pod.Name, "xk6-agent", 2*time.Second)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != tc.expectedResult {
+				t.Errorf("expected result %t but %t returned", tc.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestPods_WaitWatchError(t *testing.T) {
+	t.Parallel()
+
+	pod := builders.NewPodBuilder("pod-pending").
+		WithNamespace(testNamespace).
+		WithPhase(corev1.PodPending).
+		Build()
+
+	client := fake.NewSimpleClientset(&pod)
+
+	// the watch fails with the status returned by the API server
+	watcher := watch.NewFakeWithChanSize(1, false)
+	watcher.Error(&metav1.Status{
+		Status: metav1.StatusFailure,
+		Reason: metav1.StatusReasonForbidden,
+		Code:   403,
+	})
+	client.PrependWatchReactor("pods", func(_ k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+
+	h := NewPodHelper(client, nil, testNamespace)
+	_, err := h.WaitPodRunning(context.TODO(), pod.Name, 5*time.Second)
+	if !errors.IsForbidden(err) {
+		t.Fatalf("expected forbidden error got %v", err)
+	}
+}
+
 func TestPods_AddEphemeralContainer(t *testing.T) {
 	t.Parallel()
 
@@ -175,6 +296,61 @@ func TestPods_AddEphemeralContainer(t *testing.T) {
 				IgnoreIfExists: true,
 			},
 		},
+		{
+			test:        "Fail pulling image",
+			podName:     "test-pod",
+			expectError: true,
+			status: corev1.ContainerStatus{
+				Name: "ephemeral",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{
+						Reason:  "ErrImagePull",
+						Message: "image not found",
+					},
+				},
+			},
+			options: AttachOptions{
+				Timeout:              30 * time.Second,
+				IgnoreIfExists:       true,
+				FailOnImagePullError: true,
+			},
+		},
+		{
+			test:        "Fail pulling image with back off",
+			podName:     "test-pod",
+			expectError: true,
+			status: corev1.ContainerStatus{
+				Name: "ephemeral",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{
+						Reason:  "ImagePullBackOff",
+						Message: "back-off pulling image",
+					},
+				},
+			},
+			options: AttachOptions{
+				Timeout:              30 * time.Second,
+				IgnoreIfExists:       true,
+				FailOnImagePullError: true,
+			},
+		},
+		{
+			test:        "Ignore image pull error",
+			podName:     "test-pod",
+			expectError: true,
+			status: corev1.ContainerStatus{
+				Name: "ephemeral",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{
+						Reason: "ErrImagePull",
+					},
+				},
+			},
+			options: AttachOptions{
+				Timeout:        1 * time.Second,
+				IgnoreIfExists: true,
+			},
+		},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -213,16 +389,31 @@ func TestPods_AddEphemeralContainer(t *testing.T) {
 			}
 
 			h := NewPodHelper(client, nil, testNamespace)
+			start := time.Now()
 			err = h.AttachEphemeralContainer(
 				context.TODO(),
 				tc.podName,
-				corev1.EphemeralContainer{},
+				corev1.EphemeralContainer{
+					EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name: "ephemeral",
+					},
+				},
 				tc.options,
 			)
 			if !tc.expectError && err != nil {
 				t.Errorf("failed: %v", err)
 				return
 			}
+
+			if tc.expectError && err == nil {
+				t.Errorf("should had failed")
+				return
+			}
+
+			// errors must be reported before the timeout expires
+			if tc.expectError && tc.options.FailOnImagePullError && time.Since(start) >= tc.options.Timeout {
+				t.Errorf("image pull error was not reported before the timeout")
+			}
 		})
 	}
 }
@@ -551,3 +742,80 @@ func Test_WaitPodDeleted(t *testing.T) {
 		})
 	}
 }
+
+func Test_PodLogs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		container string
+		follow    bool
+	}{
+		{
+			title:     "logs",
+			container: "xk6-agent",
+			follow:    false,
+		},
+		{
+			title:     "stream logs",
+			container: "xk6-agent",
+			follow:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			pod := builders.NewPodBuilder("pod-1").WithNamespace(testNamespace).Build()
+			client := fake.NewSimpleClientset(&pod)
+			helper := NewPodHelper(client, nil, testNamespace)
+
+			var logs []byte
+			var err error
+			if tc.follow {
+				var stream io.ReadCloser
+				stream, err = helper.StreamLogs(context.TODO(), "pod-1", tc.container)
+				if err == nil {
+					logs, err = io.ReadAll(stream)
+					_ = stream.Close()
+				}
+			} else {
+				logs, err = helper.Logs(context.TODO(), "pod-1", tc.container)
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the fake client always returns the same canned logs
+			if string(logs) != "fake logs" {
+				t.Fatalf("expected %q got %q", "fake logs", string(logs))
+			}
+
+			// the logs must be requested for the container
+			requested := false
+			for _, action := range client.Actions() {
+				if action.GetSubresource() != "log" {
+					continue
+				}
+
+				opts, ok := action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+				if !ok {
+					t.Fatalf("unexpected log options %v", action)
+				}
+
+				if opts.Container != tc.container || opts.Follow != tc.follow {
+					t.Fatalf("unexpected log options %+v", opts)
+				}
+
+				requested = true
+			}
+
+			if !requested {
+				t.Fatalf("logs were not requested")
+			}
+		})
+	}
+}
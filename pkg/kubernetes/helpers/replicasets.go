@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ReplicaSetHelper implements functions for dealing with replica sets
+type ReplicaSetHelper interface {
+	// Get returns the replica set with the given name
+	Get(ctx context.Context, name string) (appsv1.ReplicaSet, error)
+}
+
+// replicaSetHelper struct holds the data required by the helpers
+type replicaSetHelper struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewReplicaSetHelper returns a ReplicaSetHelper for the given namespace
+func NewReplicaSetHelper(client kubernetes.Interface, namespace string) ReplicaSetHelper {
+	return &replicaSetHelper{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+func (h *replicaSetHelper) Get(ctx context.Context, name string) (appsv1.ReplicaSet, error) {
+	rs, err := h.client.AppsV1().ReplicaSets(h.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return appsv1.ReplicaSet{}, fmt.Errorf("retrieving replica set %q: %w", name, err)
+	}
+
+	return *rs, nil
+}
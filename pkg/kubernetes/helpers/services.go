@@ -21,6 +21,11 @@ type ServiceHelper interface {
 	WaitIngressReady(ctx context.Context, ingress string, timeout time.Duration) error
 	// GetTargets returns the list of pods that match the service selector criteria
 	GetTargets(ctx context.Context, service string) ([]corev1.Pod, error)
+	// GetReadyEndpoints returns the addresses of the ready endpoints of the service
+	GetReadyEndpoints(ctx context.Context, service string) ([]corev1.EndpointAddress, error)
+	// GetEndpointsTargets returns the list of pods referenced by the addresses of the given endpoints,
+	// either ready or not
+	GetEndpointsTargets(ctx context.Context, endpoints *corev1.Endpoints) ([]corev1.Pod, error)
 }
 
 // helpers struct holds the data required by the helpers
@@ -89,3 +94,63 @@ func (h *serviceHelper) GetTargets(ctx context.Context, name string) ([]corev1.P
 
 	return pods.Items, err
 }
+
+func (h *serviceHelper) GetReadyEndpoints(ctx context.Context, name string) ([]corev1.EndpointAddress, error) {
+	ep, err := h.client.CoreV1().Endpoints(h.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve endpoints of service %s: %w", name, err)
+	}
+
+	return ReadyAddresses(ep), nil
+}
+
+// ReadyAddresses returns the addresses of the ready endpoints. The same address can appear in multiple subsets
+// if the endpoint exposes different sets of ports, but it is returned only once.
+func ReadyAddresses(ep *corev1.Endpoints) []corev1.EndpointAddress {
+	seen := map[string]bool{}
+	addresses := []corev1.EndpointAddress{}
+	for _, subset := range ep.Subsets {
+		for _, address := range subset.Addresses {
+			if seen[address.IP] {
+				continue
+			}
+			seen[address.IP] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+func (h *serviceHelper) GetEndpointsTargets(ctx context.Context, ep *corev1.Endpoints) ([]corev1.Pod, error) {
+	names := map[string]bool{}
+	addPods := func(addresses []corev1.EndpointAddress) {
+		for _, address := range addresses {
+			if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+				names[address.TargetRef.Name] = true
+			}
+		}
+	}
+	for _, subset := range ep.Subsets {
+		addPods(subset.Addresses)
+		addPods(subset.NotReadyAddresses)
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	pods, err := h.client.CoreV1().Pods(h.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of endpoints %s: %w", ep.Name, err)
+	}
+
+	targets := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if names[pod.Name] {
+			targets = append(targets, pod)
+		}
+	}
+
+	return targets, nil
+}
@@ -10,6 +10,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -357,3 +358,141 @@ func Test_Targets(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetReadyEndpoints(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		endpoints    *corev1.Endpoints
+		expectError  bool
+		expectedPods []string
+	}{
+		{
+			title: "ready and not ready endpoints",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, []string{"pod-1", "pod-2"}).
+				WithNotReadyAddresses("http", 80, []string{"pod-3"}).
+				BuildAsPtr(),
+			expectError:  false,
+			expectedPods: []string{"pod-1", "pod-2"},
+		},
+		{
+			title: "no ready endpoints",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithNotReadyAddresses("http", 80, []string{"pod-1"}).
+				BuildAsPtr(),
+			expectError:  false,
+			expectedPods: []string{},
+		},
+		{
+			title:       "endpoints do not exist",
+			endpoints:   nil,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+			if tc.endpoints != nil {
+				_, err := client.CoreV1().Endpoints("test-ns").Create(context.TODO(), tc.endpoints, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("error creating endpoints: %v", err)
+				}
+			}
+
+			helper := NewServiceHelper(client, "test-ns")
+			addresses, err := helper.GetReadyEndpoints(context.TODO(), "test-svc")
+			if tc.expectError && err == nil {
+				t.Fatalf("should had failed")
+			}
+
+			if !tc.expectError && err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if tc.expectError {
+				return
+			}
+
+			names := []string{}
+			for _, address := range addresses {
+				names = append(names, address.TargetRef.Name)
+			}
+			if !assertions.CompareStringArrays(tc.expectedPods, names) {
+				t.Errorf("result does not match expected value. Expected: %s\nActual: %s\n", tc.expectedPods, names)
+			}
+		})
+	}
+}
+
+func Test_GetEndpointsTargets(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		endpoints    *corev1.Endpoints
+		expectedPods []string
+	}{
+		{
+			title: "ready and not ready endpoints",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, []string{"pod-1", "pod-2"}).
+				WithNotReadyAddresses("http", 80, []string{"pod-3"}).
+				BuildAsPtr(),
+			expectedPods: []string{"pod-1", "pod-2", "pod-3"},
+		},
+		{
+			title: "endpoint without pod",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				WithSubset("http", 80, []string{"pod-1", "other-pod"}).
+				BuildAsPtr(),
+			expectedPods: []string{"pod-1"},
+		},
+		{
+			title: "no endpoints",
+			endpoints: builders.NewEndPointsBuilder("test-svc").
+				WithNamespace("test-ns").
+				BuildAsPtr(),
+			expectedPods: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objs := []runtime.Object{}
+			for _, name := range []string{"pod-1", "pod-2", "pod-3", "pod-4"} {
+				pod := builders.NewPodBuilder(name).WithNamespace("test-ns").Build()
+				objs = append(objs, &pod)
+			}
+			client := fake.NewSimpleClientset(objs...)
+
+			helper := NewServiceHelper(client, "test-ns")
+			pods, err := helper.GetEndpointsTargets(context.TODO(), tc.endpoints)
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			names := []string{}
+			for _, pod := range pods {
+				names = append(names, pod.Name)
+			}
+			if !assertions.CompareStringArrays(tc.expectedPods, names) {
+				t.Errorf("result does not match expected value. Expected: %s\nActual: %s\n", tc.expectedPods, names)
+			}
+		})
+	}
+}
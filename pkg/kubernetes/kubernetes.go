@@ -23,6 +23,16 @@ type Kubernetes interface {
 	ServiceHelper(namespace string) helpers.ServiceHelper
 	// PodHelper returns a helpers.PodHelper scoped for the given namespace
 	PodHelper(namespace string) helpers.PodHelper
+	// NodeHelper returns a helpers.NodeHelper
+	NodeHelper() helpers.NodeHelper
+	// ReplicaSetHelper returns a helpers.ReplicaSetHelper scoped for the given namespace
+	ReplicaSetHelper(namespace string) helpers.ReplicaSetHelper
+	// DeploymentHelper returns a helpers.DeploymentHelper scoped for the given namespace
+	DeploymentHelper(namespace string) helpers.DeploymentHelper
+	// PodMetricsHelper returns a helpers.PodMetricsHelper scoped for the given namespace
+	PodMetricsHelper(namespace string) helpers.PodMetricsHelper
+	// EventHelper returns a helpers.EventHelper
+	EventHelper() helpers.EventHelper
 }
 
 // k8s Holds the reference to the helpers for interacting with kubernetes
@@ -125,6 +135,31 @@ func (k *k8s) PodHelper(namespace string) helpers.PodHelper {
 	)
 }
 
+// NodeHelper returns a NodeHelper
+func (k *k8s) NodeHelper() helpers.NodeHelper {
+	return helpers.NewNodeHelper(k.Interface)
+}
+
+// ReplicaSetHelper returns a ReplicaSetHelper for the given namespace
+func (k *k8s) ReplicaSetHelper(namespace string) helpers.ReplicaSetHelper {
+	return helpers.NewReplicaSetHelper(k.Interface, namespace)
+}
+
+// DeploymentHelper returns a DeploymentHelper for the given namespace
+func (k *k8s) DeploymentHelper(namespace string) helpers.DeploymentHelper {
+	return helpers.NewDeploymentHelper(k.Interface, namespace)
+}
+
+// PodMetricsHelper returns a PodMetricsHelper for the given namespace
+func (k *k8s) PodMetricsHelper(namespace string) helpers.PodMetricsHelper {
+	return helpers.NewPodMetricsHelper(k.Interface, namespace)
+}
+
+// EventHelper returns an EventHelper
+func (k *k8s) EventHelper() helpers.EventHelper {
+	return helpers.NewEventHelper(k.Interface)
+}
+
 func (k *k8s) Client() kubernetes.Interface {
 	return k.Interface
 }
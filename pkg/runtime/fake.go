@@ -65,12 +65,19 @@ func (p *FakeExecutor) Invocations() int {
 	return p.invocations
 }
 
-// Reset clears the history of invocations to the FakeProcessExecutor
+// Reset clears the history of invocations to the FakeExecutor, so it can be reused for another sequence of
+// commands. After a Reset, Invoked returns false and Cmd returns an empty string until Exec is invoked again.
 func (p *FakeExecutor) Reset() {
 	p.invocations = 0
 	p.commands = []string{}
 }
 
+// SetResult sets the output and error returned by the following invocations to the FakeExecutor
+func (p *FakeExecutor) SetResult(output []byte, err error) {
+	p.output = output
+	p.err = err
+}
+
 // ExecCallback defines a function that can receive the forward of an Exec invocation
 // The function must return the output of the invocation and the execution error, if any
 type ExecCallback func(cmd string, args ...string) ([]byte, error)
@@ -135,6 +135,64 @@ func Test_MultipleExecutions(t *testing.T) {
 	}
 }
 
+func Test_Reset(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeExecutor([]byte("first"), nil)
+	for _, cmdline := range []string{"cat -n 'hello'", "cat -n 'world'"} {
+		cmd := strings.Split(cmdline, " ")[0]
+		args := strings.Split(cmdline, " ")[1:]
+		_, _ = fake.Exec(cmd, args...)
+	}
+
+	fake.Reset()
+
+	if fake.Invoked() {
+		t.Error("Invoked method should return false after Reset")
+	}
+
+	if fake.Cmd() != "" {
+		t.Errorf("Cmd method should return an empty command after Reset. Actual: %s", fake.Cmd())
+	}
+
+	expectedErr := fmt.Errorf("command exited with rc 1")
+	fake.SetResult([]byte("second"), expectedErr)
+
+	cmdLines := []string{"ls -l", "cat -n 'again'"}
+	for _, cmdline := range cmdLines {
+		cmd := strings.Split(cmdline, " ")[0]
+		args := strings.Split(cmdline, " ")[1:]
+		out, err := fake.Exec(cmd, args...)
+
+		if !errors.Is(err, expectedErr) {
+			t.Errorf(
+				"returned error does not match expected value.\nExpected: %v\nActual: %v",
+				expectedErr,
+				err,
+			)
+		}
+
+		if string(out) != "second" {
+			t.Errorf("returned output does not match expected value.\nExpected: second\nActual: %s", string(out))
+		}
+	}
+
+	// the history only reflects the commands executed after the Reset
+	expected := strings.Join(cmdLines, "\n")
+	actual := strings.Join(fake.CmdHistory(), "\n")
+	if actual != expected {
+		t.Errorf(
+			"command history does not match expected value.\nExpected: %v\nActual: %v",
+			expected,
+			actual,
+		)
+	}
+
+	if fake.Invocations() != len(cmdLines) {
+		t.Errorf("expected %d invocations got %d", len(cmdLines), fake.Invocations())
+	}
+}
+
 func Test_Callbacks(t *testing.T) {
 	t.Parallel()
 
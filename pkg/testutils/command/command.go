@@ -67,3 +67,68 @@ func AssertCmdEquals(expected, actual string) bool {
 
 	return true
 }
+
+// parseFlags returns the values of the flags in the arguments of a command, by the name of the flag.
+// Each argument is a single token, so values can contain spaces. A flag followed by another flag or by the end
+// of the arguments has an empty value. Repeated flags have one value for each occurrence.
+func parseFlags(args []string) map[string][]string {
+	flags := map[string][]string{}
+
+	for i := 0; i < len(args); i++ {
+		if !isFlag(args[i]) {
+			continue
+		}
+
+		flag := args[i]
+		value := ""
+		if next := i + 1; next < len(args) && !isFlag(args[next]) {
+			value = args[next]
+			i++
+		}
+
+		flags[flag] = append(flags[flag], value)
+	}
+
+	return flags
+}
+
+// AssertCmdContainsFlag asserts if the arguments of a command contain the flag with the given value, regardless
+// of its position. Use an empty value for flags without value. If the flag is repeated, any of its occurrences
+// can match.
+//
+// Examples:
+// cmd                                  flag  value             result
+// -------------------------------------------------------------------
+// [cmd -r 0.1 -m "internal error"]     -m    internal error    true
+// [cmd -m "internal error" -r 0.1]     -m    internal error    true
+// [cmd -m "internal error"]            -m    internal          false
+// [cmd -x a -x b]                      -x    b                 true
+// [cmd -r 0.1]                         -m    internal error    false
+func AssertCmdContainsFlag(cmd []string, flag string, value string) bool {
+	for _, v := range parseFlags(cmd)[flag] {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AssertCmdFlags asserts if the arguments of a command have exactly the given flags with the given values,
+// regardless of their order. Flags must appear only once; repeated flags can be checked with
+// AssertCmdContainsFlag.
+func AssertCmdFlags(cmd []string, flags map[string]string) bool {
+	actual := parseFlags(cmd)
+	if len(actual) != len(flags) {
+		return false
+	}
+
+	for flag, value := range flags {
+		values := actual[flag]
+		if len(values) != 1 || values[0] != value {
+			return false
+		}
+	}
+
+	return true
+}
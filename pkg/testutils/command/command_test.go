@@ -56,3 +56,142 @@ func Test_CompareCommands(t *testing.T) {
 		})
 	}
 }
+
+func Test_AssertCmdContainsFlag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		cmd    []string
+		flag   string
+		value  string
+		result bool
+	}{
+		{
+			title:  "single word value",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1", "-s", "14"},
+			flag:   "-s",
+			value:  "14",
+			result: true,
+		},
+		{
+			title:  "multi-word value",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1", "-m", "internal error", "--upstream-host", "host"},
+			flag:   "-m",
+			value:  "internal error",
+			result: true,
+		},
+		{
+			title:  "multi-word value in a different position",
+			cmd:    []string{"cmd", "subcmd", "-m", "internal error", "-r", "0.1", "--upstream-host", "host"},
+			flag:   "-m",
+			value:  "internal error",
+			result: true,
+		},
+		{
+			title:  "partial value",
+			cmd:    []string{"cmd", "subcmd", "-m", "internal error"},
+			flag:   "-m",
+			value:  "internal",
+			result: false,
+		},
+		{
+			title:  "flag without value",
+			cmd:    []string{"cmd", "subcmd", "--upstream-tls", "-r", "0.1"},
+			flag:   "--upstream-tls",
+			value:  "",
+			result: true,
+		},
+		{
+			title:  "repeated flag",
+			cmd:    []string{"cmd", "subcmd", "-H", "X-Canary:true", "-H", "X-Region:eu"},
+			flag:   "-H",
+			value:  "X-Region:eu",
+			result: true,
+		},
+		{
+			title:  "missing flag",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1"},
+			flag:   "-m",
+			value:  "internal error",
+			result: false,
+		},
+		{
+			title:  "value used as argument",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1", "internal error"},
+			flag:   "-m",
+			value:  "internal error",
+			result: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if AssertCmdContainsFlag(tc.cmd, tc.flag, tc.value) != tc.result {
+				t.Errorf("cmd: %q flag: %s value: %q result %t", tc.cmd, tc.flag, tc.value, tc.result)
+			}
+		})
+	}
+}
+
+func Test_AssertCmdFlags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		cmd    []string
+		flags  map[string]string
+		result bool
+	}{
+		{
+			title:  "same flags",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1", "-m", "internal error"},
+			flags:  map[string]string{"-r": "0.1", "-m": "internal error"},
+			result: true,
+		},
+		{
+			title:  "different order of flags",
+			cmd:    []string{"cmd", "subcmd", "-m", "internal error", "-r", "0.1"},
+			flags:  map[string]string{"-r": "0.1", "-m": "internal error"},
+			result: true,
+		},
+		{
+			title:  "different value",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1", "-m", "internal error"},
+			flags:  map[string]string{"-r": "0.1", "-m": "internal"},
+			result: false,
+		},
+		{
+			title:  "missing flag",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1"},
+			flags:  map[string]string{"-r": "0.1", "-m": "internal error"},
+			result: false,
+		},
+		{
+			title:  "extra flag",
+			cmd:    []string{"cmd", "subcmd", "-r", "0.1", "-m", "internal error"},
+			flags:  map[string]string{"-r": "0.1"},
+			result: false,
+		},
+		{
+			title:  "repeated flag",
+			cmd:    []string{"cmd", "subcmd", "-x", "a", "-x", "b"},
+			flags:  map[string]string{"-x": "a"},
+			result: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if AssertCmdFlags(tc.cmd, tc.flags) != tc.result {
+				t.Errorf("cmd: %q flags: %v result %t", tc.cmd, tc.flags, tc.result)
+			}
+		})
+	}
+}
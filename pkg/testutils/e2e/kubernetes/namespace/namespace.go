@@ -3,15 +3,30 @@ package namespace
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/grafana/xk6-disruptor/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	// TestNamespaceLabel is the label added to the namespaces created by CreateTestNamespace
+	TestNamespaceLabel = "xk6-disruptor.grafana.com/test-namespace"
+	// DefaultOrphanSelector is the label selector used by CleanupOrphans for identifying test namespaces
+	DefaultOrphanSelector = TestNamespaceLabel + "=true"
+	// agentContainer is the name of the ephemeral container injected by the disruptor
+	agentContainer = "xk6-agent"
+)
+
+// ErrOrphanAgents is returned by CleanupOrphans when agents are still present in pods outside the deleted namespaces
+var ErrOrphanAgents = errors.New("disruptor agents still present")
+
 // TestNamespaceOption allows modifying an TestNamespaceConfig
 type TestNamespaceOption func(TestNamespaceConfig) (TestNamespaceConfig, error)
 
@@ -95,6 +110,7 @@ func CreateTestNamespace(
 	} else {
 		ns.ObjectMeta = metav1.ObjectMeta{Name: config.name}
 	}
+	ns.ObjectMeta.Labels = map[string]string{TestNamespaceLabel: "true"}
 
 	ns, err = k8s.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
 	if err != nil {
@@ -111,3 +127,52 @@ func CreateTestNamespace(
 
 	return ns.GetName(), nil
 }
+
+// CleanupOrphans deletes the test namespaces matching the label selector, left behind by test runs that crashed
+// before their cleanup. If the selector is empty, DefaultOrphanSelector is used.
+// As injected agents cannot be removed from a pod, the pods outside the deleted namespaces that still have an agent
+// are reported in an ErrOrphanAgents error.
+func CleanupOrphans(ctx context.Context, k8s kubernetes.Interface, labelSelector string) error {
+	if labelSelector == "" {
+		labelSelector = DefaultOrphanSelector
+	}
+
+	namespaces, err := k8s.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return fmt.Errorf("listing test namespaces: %w", err)
+	}
+
+	deleted := map[string]bool{}
+	for _, ns := range namespaces.Items {
+		err = k8s.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("deleting namespace %q: %w", ns.Name, err)
+		}
+		deleted[ns.Name] = true
+	}
+
+	pods, err := k8s.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing pods: %w", err)
+	}
+
+	orphans := []string{}
+	for _, pod := range pods.Items {
+		if deleted[pod.Namespace] {
+			continue
+		}
+
+		for _, container := range pod.Spec.EphemeralContainers {
+			if container.Name == agentContainer {
+				orphans = append(orphans, pod.Namespace+"/"+pod.Name)
+				break
+			}
+		}
+	}
+
+	if len(orphans) > 0 {
+		return fmt.Errorf("%w in pods: %s", ErrOrphanAgents, strings.Join(orphans, ", "))
+	}
+
+	return nil
+}
@@ -2,10 +2,16 @@ package namespace
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/xk6-disruptor/pkg/testutils/kubernetes/builders"
+	corev1 "k8s.io/api/core/v1"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/rand"
@@ -97,3 +103,106 @@ func Test_CreateNamespace(t *testing.T) {
 		})
 	}
 }
+
+func buildNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func buildPodWithAgent(name string, namespace string) *corev1.Pod {
+	pod := builders.NewPodBuilder(name).WithNamespace(namespace).Build()
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+		{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "xk6-agent"}},
+	}
+	return &pod
+}
+
+func Test_CleanupOrphans(t *testing.T) {
+	t.Parallel()
+
+	testLabels := map[string]string{TestNamespaceLabel: "true"}
+
+	testCases := []struct {
+		title        string
+		objects      []runtime.Object
+		selector     string
+		expectError  error
+		expectRemain []string
+	}{
+		{
+			title: "delete labeled namespaces",
+			objects: []runtime.Object{
+				buildNamespace("testns-1", testLabels),
+				buildNamespace("testns-2", testLabels),
+				buildNamespace("other", nil),
+			},
+			selector:     "",
+			expectError:  nil,
+			expectRemain: []string{"other"},
+		},
+		{
+			title: "custom selector",
+			objects: []runtime.Object{
+				buildNamespace("testns-1", testLabels),
+				buildNamespace("custom", map[string]string{"suite": "e2e"}),
+			},
+			selector:     "suite=e2e",
+			expectError:  nil,
+			expectRemain: []string{"testns-1"},
+		},
+		{
+			title: "agents in deleted namespaces are not reported",
+			objects: []runtime.Object{
+				buildNamespace("testns-1", testLabels),
+				buildPodWithAgent("pod", "testns-1"),
+			},
+			selector:     "",
+			expectError:  nil,
+			expectRemain: []string{},
+		},
+		{
+			title: "agents outside test namespaces are reported",
+			objects: []runtime.Object{
+				buildNamespace("testns-1", testLabels),
+				buildNamespace("other", nil),
+				buildPodWithAgent("pod", "other"),
+			},
+			selector:     "",
+			expectError:  ErrOrphanAgents,
+			expectRemain: []string{"other"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(tc.objects...)
+
+			err := CleanupOrphans(context.TODO(), client, tc.selector)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected error %v got %v", tc.expectError, err)
+			}
+
+			namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("listing namespaces: %v", err)
+			}
+
+			remain := []string{}
+			for _, ns := range namespaces.Items {
+				remain = append(remain, ns.Name)
+			}
+			sort.Strings(remain)
+
+			if diff := cmp.Diff(tc.expectRemain, remain); diff != "" {
+				t.Fatalf("remaining namespaces do not match expected:\n%s", diff)
+			}
+		})
+	}
+}
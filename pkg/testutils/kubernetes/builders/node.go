@@ -0,0 +1,67 @@
+package builders
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeBuilder defines the methods for building a Node
+type NodeBuilder interface {
+	// WithLabel adds a label to the Node
+	WithLabel(name string, value string) NodeBuilder
+	// WithCondition sets the status of a condition of the Node
+	WithCondition(condition corev1.NodeConditionType, status corev1.ConditionStatus) NodeBuilder
+	// Build returns a Node with the attributes defined in the NodeBuilder
+	Build() corev1.Node
+	// BuildAsPtr returns a reference to the Node
+	BuildAsPtr() *corev1.Node
+}
+
+// nodeBuilder defines the attributes for building a node
+type nodeBuilder struct {
+	name       string
+	labels     map[string]string
+	conditions []corev1.NodeCondition
+}
+
+// NewNodeBuilder creates a new instance of NodeBuilder with the given node name
+func NewNodeBuilder(name string) NodeBuilder {
+	return &nodeBuilder{
+		name:   name,
+		labels: map[string]string{},
+	}
+}
+
+func (b *nodeBuilder) WithLabel(name string, value string) NodeBuilder {
+	b.labels[name] = value
+	return b
+}
+
+func (b *nodeBuilder) WithCondition(condition corev1.NodeConditionType, status corev1.ConditionStatus) NodeBuilder {
+	b.conditions = append(b.conditions, corev1.NodeCondition{
+		Type:   condition,
+		Status: status,
+	})
+	return b
+}
+
+func (b *nodeBuilder) Build() corev1.Node {
+	return corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   b.name,
+			Labels: b.labels,
+		},
+		Status: corev1.NodeStatus{
+			Conditions: b.conditions,
+		},
+	}
+}
+
+func (b *nodeBuilder) BuildAsPtr() *corev1.Node {
+	node := b.Build()
+	return &node
+}
@@ -1,8 +1,11 @@
 package builders
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // PodBuilder defines the methods for building a Pod
@@ -28,6 +31,14 @@ type PodBuilder interface {
 	WithHostNetwork(hostNetwork bool) PodBuilder
 	// WithContainer add a container to the pod
 	WithContainer(c corev1.Container) PodBuilder
+	// WithNodeName sets the name of the node the pod is scheduled on
+	WithNodeName(node string) PodBuilder
+	// WithStartTime sets the time the pod was started
+	WithStartTime(t time.Time) PodBuilder
+	// WithCondition adds a condition with the given status and last transition time to the pod
+	WithCondition(condition corev1.PodConditionType, status corev1.ConditionStatus, transition time.Time) PodBuilder
+	// WithOwner adds a reference to an owner of the pod
+	WithOwner(kind string, name string, uid types.UID) PodBuilder
 }
 
 // podBuilder defines the attributes for building a pod
@@ -40,15 +51,20 @@ type podBuilder struct {
 	ip          string
 	hostNetwork bool
 	containers  []corev1.Container
+	nodeName    string
+	startTime   *metav1.Time
+	conditions  []corev1.PodCondition
+	owners      []metav1.OwnerReference
 }
 
 // NewPodBuilder creates a new instance of PodBuilder with the given pod name
-// and default attributes such as containers and namespace
+// and default attributes such as containers, namespace and the Running phase
 func NewPodBuilder(name string) PodBuilder {
 	return &podBuilder{
 		name:        name,
 		annotations: map[string]string{},
 		labels:      map[string]string{},
+		phase:       corev1.PodRunning,
 	}
 }
 
@@ -97,6 +113,40 @@ func (b *podBuilder) WithContainer(c corev1.Container) PodBuilder {
 	return b
 }
 
+func (b *podBuilder) WithNodeName(node string) PodBuilder {
+	b.nodeName = node
+	return b
+}
+
+func (b *podBuilder) WithStartTime(t time.Time) PodBuilder {
+	startTime := metav1.NewTime(t)
+	b.startTime = &startTime
+	return b
+}
+
+func (b *podBuilder) WithCondition(
+	condition corev1.PodConditionType,
+	status corev1.ConditionStatus,
+	transition time.Time,
+) PodBuilder {
+	b.conditions = append(b.conditions, corev1.PodCondition{
+		Type:               condition,
+		Status:             status,
+		LastTransitionTime: metav1.NewTime(transition),
+	})
+	return b
+}
+
+func (b *podBuilder) WithOwner(kind string, name string, uid types.UID) PodBuilder {
+	b.owners = append(b.owners, metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       name,
+		UID:        uid,
+	})
+	return b
+}
+
 func (b *podBuilder) Build() corev1.Pod {
 	pod := corev1.Pod{
 		TypeMeta: metav1.TypeMeta{
@@ -104,18 +154,22 @@ func (b *podBuilder) Build() corev1.Pod {
 			Kind:       "Pod",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        b.name,
-			Namespace:   b.namespace,
-			Labels:      b.labels,
-			Annotations: b.annotations,
+			Name:            b.name,
+			Namespace:       b.namespace,
+			Labels:          b.labels,
+			Annotations:     b.annotations,
+			OwnerReferences: b.owners,
 		},
 		Spec: corev1.PodSpec{
 			Containers:          b.containers,
 			HostNetwork:         b.hostNetwork,
+			NodeName:            b.nodeName,
 			EphemeralContainers: nil,
 		},
 		Status: corev1.PodStatus{
-			Phase: b.phase,
+			Phase:      b.phase,
+			StartTime:  b.startTime,
+			Conditions: b.conditions,
 		},
 	}
 
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/grafana/xk6-disruptor/pkg/types/intstr"
 	corev1 "k8s.io/api/core/v1"
@@ -30,27 +31,77 @@ func GetTargetPort(service corev1.Service, svcPort intstr.IntOrString) (intstr.I
 
 // FindPort returns the port in the Pod that maps to the given port by port number or name
 func FindPort(port intstr.IntOrString, pod corev1.Pod) (intstr.IntOrString, error) {
-	switch port.Type() {
-	case intstr.ValueTypeString:
-		for _, container := range pod.Spec.Containers {
-			for _, p := range container.Ports {
-				if p.Name == port.Str() {
-					return intstr.FromInt32(p.ContainerPort), nil
-				}
+	found, _, err := FindContainerPort(port, pod, "")
+	return found, err
+}
+
+// FindContainerPort returns the port in the Pod that maps to the given port by port number or name, and the name
+// of the container that exposes it. The ports of the sidecar containers (init containers that keep running while
+// the pod runs) are also considered. If container is not empty, the port must be exposed by that container.
+func FindContainerPort(
+	port intstr.IntOrString,
+	pod corev1.Pod,
+	container string,
+) (intstr.IntOrString, string, error) {
+	containers := append([]corev1.Container{}, pod.Spec.Containers...)
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			containers = append(containers, c)
+		}
+	}
+
+	containerFound := false
+	for _, c := range containers {
+		if container != "" && c.Name != container {
+			continue
+		}
+		containerFound = true
+
+		for _, p := range c.Ports {
+			if matchesPort(port, p) {
+				return intstr.FromInt32(p.ContainerPort), c.Name, nil
 			}
 		}
+	}
+
+	if container == "" {
+		return intstr.NullValue, "", fmt.Errorf("no container in pod %q exposes port %q", pod.Name, port.Str())
+	}
 
-	case intstr.ValueTypeInt:
-		for _, container := range pod.Spec.Containers {
-			for _, p := range container.Ports {
-				if p.ContainerPort == port.Int32() {
-					return intstr.FromInt32(p.ContainerPort), nil
-				}
+	if !containerFound {
+		return intstr.NullValue, "", fmt.Errorf("pod %q has no container %q", pod.Name, container)
+	}
+
+	// report the container that exposes the port, if any, as the port may have been expected in it
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if matchesPort(port, p) {
+				return intstr.NullValue, "", fmt.Errorf(
+					"port %q is exposed by container %q instead of %q in pod %q",
+					port.Str(),
+					c.Name,
+					container,
+					pod.Name,
+				)
 			}
 		}
 	}
 
-	return intstr.NullValue, fmt.Errorf("pod %q does exports port %q", pod.Name, port.Str())
+	return intstr.NullValue, "", fmt.Errorf(
+		"container %q in pod %q does not expose port %q",
+		container,
+		pod.Name,
+		port.Str(),
+	)
+}
+
+// matchesPort returns true if the container port matches the given port by port number or name
+func matchesPort(port intstr.IntOrString, containerPort corev1.ContainerPort) bool {
+	if port.IsInt() {
+		return containerPort.ContainerPort == port.Int32()
+	}
+
+	return containerPort.Name == port.Str()
 }
 
 // HasHostNetwork returns whether a pod has HostNetwork enabled, i.e. it shares the host's network namespace.
@@ -68,6 +119,34 @@ func PodIP(pod corev1.Pod) (string, error) {
 	return "", fmt.Errorf("pod %s/%s does not have an IP address", pod.Namespace, pod.Name)
 }
 
+// PodStartTime returns the time the pod last became ready or, if it is not ready, the time it was started.
+// Returns false if the pod has not been started yet.
+func PodStartTime(pod corev1.Pod) (time.Time, bool) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue &&
+			!condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time, true
+	}
+
+	return time.Time{}, false
+}
+
+// PodReady returns true if the pod's Ready condition is True
+func PodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
 // PodNames return the name of the pods in a list
 func PodNames(pods []corev1.Pod) []string {
 	names := make([]string, 0, len(pods))
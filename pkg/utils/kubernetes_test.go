@@ -101,6 +101,123 @@ func Test_FindPort(t *testing.T) {
 	}
 }
 
+func buildPodWithSidecar() corev1.Pod {
+	app := builders.NewContainerBuilder("app").
+		WithPort("http", 80).
+		Build()
+
+	proxy := builders.NewContainerBuilder("proxy").
+		WithPort("proxy", 8080).
+		Build()
+
+	sidecar := builders.NewContainerBuilder("sidecar").
+		WithPort("metrics", 9090).
+		Build()
+	always := corev1.ContainerRestartPolicyAlways
+	sidecar.RestartPolicy = &always
+
+	setup := builders.NewContainerBuilder("setup").
+		WithPort("setup", 7070).
+		Build()
+
+	pod := builders.NewPodBuilder("pod-1").
+		WithContainer(app).
+		WithContainer(proxy).
+		Build()
+	pod.Spec.InitContainers = []corev1.Container{setup, sidecar}
+
+	return pod
+}
+
+func Test_FindContainerPort(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title             string
+		port              intstr.IntOrString
+		container         string
+		expectedPort      intstr.IntOrString
+		expectedContainer string
+		expectedError     string
+	}{
+		{
+			title:             "port in any container",
+			port:              intstr.FromInt32(8080),
+			expectedPort:      intstr.FromInt32(8080),
+			expectedContainer: "proxy",
+		},
+		{
+			title:             "named port in sidecar",
+			port:              intstr.FromString("metrics"),
+			expectedPort:      intstr.FromInt32(9090),
+			expectedContainer: "sidecar",
+		},
+		{
+			title:             "port in named container",
+			port:              intstr.FromInt32(9090),
+			container:         "sidecar",
+			expectedPort:      intstr.FromInt32(9090),
+			expectedContainer: "sidecar",
+		},
+		{
+			title:         "port in init container",
+			port:          intstr.FromInt32(7070),
+			expectedError: `no container in pod "pod-1" exposes port "7070"`,
+		},
+		{
+			title:         "port not exposed",
+			port:          intstr.FromInt32(8000),
+			expectedError: `no container in pod "pod-1" exposes port "8000"`,
+		},
+		{
+			title:         "port in other container",
+			port:          intstr.FromInt32(80),
+			container:     "sidecar",
+			expectedError: `port "80" is exposed by container "app" instead of "sidecar" in pod "pod-1"`,
+		},
+		{
+			title:         "port not exposed by named container",
+			port:          intstr.FromInt32(8000),
+			container:     "app",
+			expectedError: `container "app" in pod "pod-1" does not expose port "8000"`,
+		},
+		{
+			title:         "container not found",
+			port:          intstr.FromInt32(80),
+			container:     "other",
+			expectedError: `pod "pod-1" has no container "other"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			port, container, err := FindContainerPort(tc.port, buildPodWithSidecar(), tc.container)
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Fatalf("expected error %q got %v", tc.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectedPort != port {
+				t.Errorf("expected port %q got %q", tc.expectedPort.Str(), port.Str())
+			}
+
+			if tc.expectedContainer != container {
+				t.Errorf("expected container %q got %q", tc.expectedContainer, container)
+			}
+		})
+	}
+}
+
 func Test_GetTargetPort(t *testing.T) {
 	t.Parallel()
 